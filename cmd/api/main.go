@@ -14,8 +14,10 @@ import (
 	"github.com/toluhikay/fx-exchange/internal/config"
 	"github.com/toluhikay/fx-exchange/internal/db"
 	"github.com/toluhikay/fx-exchange/internal/fx"
+	"github.com/toluhikay/fx-exchange/internal/mailer"
 	"github.com/toluhikay/fx-exchange/internal/middleware"
 	"github.com/toluhikay/fx-exchange/internal/routes"
+	"github.com/toluhikay/fx-exchange/internal/sanctions"
 	"github.com/toluhikay/fx-exchange/pkg/jwt"
 )
 
@@ -28,7 +30,20 @@ func main() {
 		"host=%s port=%s user=%s password=%s dbname=%s sslmode=disable timezone=UTC connect_timeout=5",
 		cfg.DbHost, cfg.DbPort, cfg.DbUser, cfg.DbPassword, cfg.DbName,
 	)
-	fxProvider := fx.NewMockFXProvider(true)
+	var fxProvider fx.FXProvider
+	if os.Getenv("USE_MOCK_FX") == "true" {
+		fxProvider = fx.NewMockFXProvider(true)
+	} else {
+		fxProvider = fx.NewFXClient()
+	}
+	mail, err := mailer.NewMailer(cfg.Mailer)
+	if err != nil {
+		log.Fatal("error setting up mailer: ", err)
+	}
+	screener, err := sanctions.NewScreener(cfg.Sanctions)
+	if err != nil {
+		log.Fatal("error loading sanctions list: ", err)
+	}
 	auth := jwt.NewAuth(cfg.Auth)
 	customMiddleware := middleware.NewMiddleware(&cfg.Auth)
 
@@ -42,9 +57,9 @@ func main() {
 
 	fmt.Println("db connected successfuly")
 
-	go fxProvider.StartRateUpdates(ctx, dbInstance, 5*time.Hour)
+	go fxProvider.StartRateUpdates(ctx, dbInstance, cfg.FxRateInterval)
 
-	routesInstance := routes.NewRouteConfig(dbInstance, ctx, fxProvider, *auth, customMiddleware)
+	routesInstance := routes.NewRouteConfig(dbInstance, ctx, cfg, fxProvider, mail, screener, *auth, customMiddleware)
 
 	srv := &http.Server{
 		Addr:    ":" + cfg.Port,
@@ -1,9 +1,18 @@
 package config
 
 import (
+	"fmt"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/toluhikay/fx-exchange/internal/fraud"
+	"github.com/toluhikay/fx-exchange/internal/limits"
+	"github.com/toluhikay/fx-exchange/internal/mailer"
+	"github.com/toluhikay/fx-exchange/internal/ratelimit"
+	"github.com/toluhikay/fx-exchange/internal/sanctions"
+	"github.com/toluhikay/fx-exchange/internal/storage"
 	"github.com/toluhikay/fx-exchange/pkg/jwt"
 )
 
@@ -15,6 +24,124 @@ type Config struct {
 	DbUser     string
 	DbPassword string
 	Auth       jwt.Auth
+	Mailer     mailer.Config
+	User       UserSettings
+	RateLimit  RateLimitSettings
+	// TrustedProxies are the load balancers, as CIDRs or addresses, whose
+	// X-Forwarded-For and X-Real-IP headers are believed.
+	TrustedProxies []string
+	Storage        storage.Config
+	// KycLimits caps transaction amounts per kyc level and currency.
+	KycLimits limits.Table
+	// FraudRules are evaluated on every deposit, swap and transfer.
+	FraudRules []fraud.Rule
+	Sanctions  sanctions.Config
+	Approvals  ApprovalSettings
+	Holds      HoldSettings
+	Disputes   DisputeSettings
+	Schedules  ScheduleSettings
+	Orders     OrderSettings
+	Alerts     AlertSettings
+	// Markets are the peer-to-peer order book pairs, written BASE/QUOTE.
+	Markets []string
+	// FxRateInterval is how often the FX provider publishes new rates.
+	FxRateInterval time.Duration
+	Rates          RateSettings
+}
+
+// OrderSettings control swap orders. Orders past their expiry are closed and
+// their holds released every ExpiryInterval.
+type OrderSettings struct {
+	ExpiryInterval time.Duration
+}
+
+// RateSettings control the fx_rates rollups. Every RollupInterval, ticks are
+// rolled up into minute, hour and day candles and raw ticks older than
+// TickRetention are pruned.
+type RateSettings struct {
+	RollupInterval time.Duration
+	TickRetention  time.Duration
+}
+
+// AlertSettings cap how many rate alerts a user may have active at once.
+type AlertSettings struct {
+	MaxActive int
+}
+
+// ScheduleSettings control the scheduled transfer runner. A run that fails
+// for lack of funds is tried again after RetryDelay, up to MaxRetries times.
+type ScheduleSettings struct {
+	Interval   time.Duration
+	RetryDelay time.Duration
+	MaxRetries int
+}
+
+// DisputeSettings bound how old a transfer may be to dispute and how long the
+// receiver's funds stay held while it is open.
+type DisputeSettings struct {
+	Window     time.Duration
+	HoldExpiry time.Duration
+}
+
+// HoldSettings bound how long merchant holds may reserve funds.
+type HoldSettings struct {
+	DefaultExpiry  time.Duration
+	MaxExpiry      time.Duration
+	ExpiryInterval time.Duration
+}
+
+// ApprovalSettings control maker-checker sign-off on wallets that have
+// approvers. ThresholdUSD applies to wallets without their own threshold.
+type ApprovalSettings struct {
+	ThresholdUSD   float64
+	Expiry         time.Duration
+	ExpiryInterval time.Duration
+}
+
+// RateLimitSettings holds one policy per route group.
+type RateLimitSettings struct {
+	Auth   ratelimit.Policy
+	API    ratelimit.Policy
+	Admin  ratelimit.Policy
+	Public ratelimit.Policy
+}
+
+// UserSettings holds the knobs for the account lifecycle flows.
+type UserSettings struct {
+	OtpExpireAt    time.Duration
+	OtpMaxAttempts int
+	// OtpWindow is the period the verification codes of one account are
+	// counted over: OtpMaxAttempts wrong guesses and OtpMaxResends new codes
+	// at most, whichever code they were made against.
+	OtpWindow             time.Duration
+	OtpMaxResends         int
+	PasswordResetExpireAt time.Duration
+	MfaIssuer             string
+	// EncryptionKey seals the email verification tokens and the stored MFA
+	// secrets. Changing it invalidates both.
+	EncryptionKey string
+	// MfaStepUpThresholdUSD is the transfer value above which users who enrolled
+	// in two-factor authentication must provide a fresh code.
+	MfaStepUpThresholdUSD float64
+	PinMaxAttempts        int
+	PinLockoutDuration    time.Duration
+	Login                 LoginSettings
+	// DeletionRetention is how long a closed account keeps its personal data
+	// before the anonymiser scrubs it.
+	DeletionRetention time.Duration
+	AnonymiseInterval time.Duration
+}
+
+// LoginSettings control brute-force protection on the login endpoint. Failures
+// beyond FreeAttempts back off exponentially from BackoffBase up to BackoffMax,
+// and an account is locked for LockoutDuration after LockoutThreshold failures.
+type LoginSettings struct {
+	FreeAttempts     int
+	BackoffBase      time.Duration
+	BackoffMax       time.Duration
+	FailureWindow    time.Duration
+	LockoutThreshold int
+	LockoutDuration  time.Duration
 }
 
 func LoadConfig() Config {
@@ -32,6 +159,81 @@ func LoadConfig() Config {
 			Secret:               getOrDefaultEnv("JWT_SECRET", "fx-exchange-jwt-secret"),
 			TokenExpireAt:        time.Minute * 15,
 			RefreshTokenExpireAt: time.Hour * 4,
+			MfaTokenExpireAt:     getOrDefaultDuration("MFA_TOKEN_EXPIRE_AT", time.Minute*5),
+		},
+		Mailer: mailer.Config{
+			Driver:   getOrDefaultEnv("MAILER_DRIVER", mailer.DriverStdout),
+			From:     getOrDefaultEnv("MAILER_FROM", "no-reply@fx-exchange.com"),
+			FilePath: getOrDefaultEnv("MAILER_FILE_PATH", "mail.log"),
+		},
+		User: UserSettings{
+			OtpExpireAt:           getOrDefaultDuration("OTP_EXPIRE_AT", time.Minute*10),
+			OtpMaxAttempts:        getOrDefaultInt("OTP_MAX_ATTEMPTS", 5),
+			OtpWindow:             getOrDefaultDuration("OTP_WINDOW", time.Hour),
+			OtpMaxResends:         getOrDefaultInt("OTP_MAX_RESENDS", 3),
+			PasswordResetExpireAt: getOrDefaultDuration("PASSWORD_RESET_EXPIRE_AT", time.Minute*30),
+			MfaIssuer:             getOrDefaultEnv("MFA_ISSUER", "FX Exchange"),
+			EncryptionKey:         getOrDefaultEnv("ENCRYPTION_KEY", "fx-exchange-encryption-key"),
+			MfaStepUpThresholdUSD: getOrDefaultFloat("MFA_STEP_UP_THRESHOLD_USD", 1000),
+			PinMaxAttempts:        getOrDefaultInt("PIN_MAX_ATTEMPTS", 3),
+			PinLockoutDuration:    getOrDefaultDuration("PIN_LOCKOUT_DURATION", time.Minute*30),
+			Login: LoginSettings{
+				FreeAttempts:     getOrDefaultInt("LOGIN_FREE_ATTEMPTS", 3),
+				BackoffBase:      getOrDefaultDuration("LOGIN_BACKOFF_BASE", time.Second*2),
+				BackoffMax:       getOrDefaultDuration("LOGIN_BACKOFF_MAX", time.Minute*15),
+				FailureWindow:    getOrDefaultDuration("LOGIN_FAILURE_WINDOW", time.Hour),
+				LockoutThreshold: getOrDefaultInt("LOGIN_LOCKOUT_THRESHOLD", 10),
+				LockoutDuration:  getOrDefaultDuration("LOGIN_LOCKOUT_DURATION", time.Hour),
+			},
+			DeletionRetention: getOrDefaultDuration("ACCOUNT_DELETION_RETENTION", time.Hour*24*30),
+			AnonymiseInterval: getOrDefaultDuration("ACCOUNT_ANONYMISE_INTERVAL", time.Hour),
+		},
+		RateLimit: RateLimitSettings{
+			Auth:   getOrDefaultPolicy("auth", "RATE_LIMIT_AUTH", "10/1m"),
+			API:    getOrDefaultPolicy("api", "RATE_LIMIT_API", "120/1m"),
+			Admin:  getOrDefaultPolicy("admin", "RATE_LIMIT_ADMIN", "300/1m"),
+			Public: getOrDefaultPolicy("public", "RATE_LIMIT_PUBLIC", "60/1m"),
+		},
+		TrustedProxies: getOrDefaultList("TRUSTED_PROXIES", nil),
+		Storage: storage.Config{
+			Dir: getOrDefaultEnv("UPLOAD_DIR", "uploads"),
+		},
+		KycLimits:  getOrDefaultLimits("KYC_LIMITS_FILE"),
+		FraudRules: getOrDefaultFraudRules("FRAUD_RULES_FILE"),
+		Sanctions: sanctions.Config{
+			File:      getOrDefaultEnv("SANCTIONS_LIST_FILE", ""),
+			Threshold: getOrDefaultFloat("SANCTIONS_MATCH_THRESHOLD", 0.9),
+		},
+		Approvals: ApprovalSettings{
+			ThresholdUSD:   getOrDefaultFloat("APPROVAL_THRESHOLD_USD", 10000),
+			Expiry:         getOrDefaultDuration("APPROVAL_EXPIRY", time.Hour*24),
+			ExpiryInterval: getOrDefaultDuration("APPROVAL_EXPIRY_INTERVAL", time.Minute*5),
+		},
+		Holds: HoldSettings{
+			DefaultExpiry:  getOrDefaultDuration("HOLD_DEFAULT_EXPIRY", time.Hour*24*7),
+			MaxExpiry:      getOrDefaultDuration("HOLD_MAX_EXPIRY", time.Hour*24*30),
+			ExpiryInterval: getOrDefaultDuration("HOLD_EXPIRY_INTERVAL", time.Minute*5),
+		},
+		Disputes: DisputeSettings{
+			Window:     getOrDefaultDuration("DISPUTE_WINDOW", time.Hour*24*90),
+			HoldExpiry: getOrDefaultDuration("DISPUTE_HOLD_EXPIRY", time.Hour*24*90),
+		},
+		Schedules: ScheduleSettings{
+			Interval:   getOrDefaultDuration("SCHEDULED_TRANSFER_INTERVAL", time.Minute),
+			RetryDelay: getOrDefaultDuration("SCHEDULED_TRANSFER_RETRY_DELAY", time.Hour),
+			MaxRetries: getOrDefaultInt("SCHEDULED_TRANSFER_MAX_RETRIES", 3),
+		},
+		Orders: OrderSettings{
+			ExpiryInterval: getOrDefaultDuration("ORDER_EXPIRY_INTERVAL", time.Minute),
+		},
+		Alerts: AlertSettings{
+			MaxActive: getOrDefaultInt("RATE_ALERT_MAX_ACTIVE", 20),
+		},
+		Markets:        getOrDefaultList("P2P_MARKETS", []string{"USDx/cNGN", "EURx/cNGN", "USDx/cXAF", "EURx/cXAF", "EURx/USDx"}),
+		FxRateInterval: getOrDefaultDuration("FX_RATE_INTERVAL", time.Minute),
+		Rates: RateSettings{
+			RollupInterval: getOrDefaultDuration("FX_ROLLUP_INTERVAL", time.Minute),
+			TickRetention:  getOrDefaultDuration("FX_TICK_RETENTION", time.Hour*24*7),
 		},
 	}
 }
@@ -44,3 +246,107 @@ func getOrDefaultEnv(key, fallback string) string {
 
 	return val
 }
+
+// getOrDefaultLimits loads the kyc limits table from the json file named by
+// key, falling back to the built-in table when unset or unreadable.
+func getOrDefaultLimits(key string) limits.Table {
+	path := getOrDefaultEnv(key, "")
+	if path == "" {
+		return limits.DefaultTable()
+	}
+
+	table, err := limits.LoadFile(path)
+	if err != nil {
+		fmt.Println("error loading kyc limits, using defaults: ", err)
+		return limits.DefaultTable()
+	}
+
+	return table
+}
+
+// getOrDefaultFraudRules loads the risk rules from the json file named by key,
+// falling back to the built-in rules when unset or unreadable.
+func getOrDefaultFraudRules(key string) []fraud.Rule {
+	path := getOrDefaultEnv(key, "")
+	if path == "" {
+		return fraud.DefaultRules()
+	}
+
+	rules, err := fraud.LoadFile(path)
+	if err != nil {
+		fmt.Println("error loading fraud rules, using defaults: ", err)
+		return fraud.DefaultRules()
+	}
+
+	return rules
+}
+
+// getOrDefaultPolicy reads a rate limit written as "<limit>/<period>".
+func getOrDefaultPolicy(name, key, fallback string) ratelimit.Policy {
+	policy, err := ratelimit.ParsePolicy(name, getOrDefaultEnv(key, fallback))
+	if err != nil {
+		policy, _ = ratelimit.ParsePolicy(name, fallback)
+	}
+
+	return policy
+}
+
+// getOrDefaultList reads a comma separated list.
+func getOrDefaultList(key string, fallback []string) []string {
+	val, ok := os.LookupEnv(key)
+	if !ok {
+		return fallback
+	}
+
+	var list []string
+	for _, item := range strings.Split(val, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+
+	return list
+}
+
+func getOrDefaultInt(key string, fallback int) int {
+	val, ok := os.LookupEnv(key)
+	if !ok {
+		return fallback
+	}
+
+	i, err := strconv.Atoi(val)
+	if err != nil {
+		return fallback
+	}
+
+	return i
+}
+
+func getOrDefaultFloat(key string, fallback float64) float64 {
+	val, ok := os.LookupEnv(key)
+	if !ok {
+		return fallback
+	}
+
+	f, err := strconv.ParseFloat(val, 64)
+	if err != nil {
+		return fallback
+	}
+
+	return f
+}
+
+// getOrDefaultDuration accepts Go duration strings such as "10m" or "1h30m".
+func getOrDefaultDuration(key string, fallback time.Duration) time.Duration {
+	val, ok := os.LookupEnv(key)
+	if !ok {
+		return fallback
+	}
+
+	d, err := time.ParseDuration(val)
+	if err != nil {
+		return fallback
+	}
+
+	return d
+}
@@ -0,0 +1,61 @@
+package cron
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNextDayFields(t *testing.T) {
+	// a monday
+	from := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name string
+		expr string
+		want time.Time
+	}{
+		{"day of month only", "0 0 13 * *", time.Date(2026, 11, 13, 0, 0, 0, 0, time.UTC)},
+		{"day of week only", "0 0 * * 5", time.Date(2026, 10, 23, 0, 0, 0, 0, time.UTC)},
+		{"both restricted match either, day of week first", "0 0 13 * 5", time.Date(2026, 10, 23, 0, 0, 0, 0, time.UTC)},
+		{"both restricted match either, day of month first", "0 0 20 * 5", time.Date(2026, 10, 20, 0, 0, 0, 0, time.UTC)},
+		{"list of days of month or a weekday", "0 9 1,28 * 1", time.Date(2026, 10, 26, 9, 0, 0, 0, time.UTC)},
+		{"stepped day of month counts as unrestricted", "0 0 */2 * 1", time.Date(2026, 11, 9, 0, 0, 0, 0, time.UTC)},
+		{"stepped day of week counts as unrestricted", "0 0 24 * */2", time.Date(2026, 10, 24, 0, 0, 0, 0, time.UTC)},
+		{"seven is sunday", "0 0 * * 7", time.Date(2026, 10, 25, 0, 0, 0, 0, time.UTC)},
+		{"later the same day", "30 18 * * 1", time.Date(2026, 10, 19, 18, 30, 0, 0, time.UTC)},
+		{"never matches", "0 0 30 2 *", time.Time{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := Parse(tt.expr)
+			if err != nil {
+				t.Fatalf("Parse(%q): %v", tt.expr, err)
+			}
+			if got := s.Next(from); !got.Equal(tt.want) {
+				t.Errorf("Next(%s) = %s, want %s", from, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	tests := []string{
+		"",
+		"0 0 * *",
+		"60 * * * *",
+		"0 24 * * *",
+		"0 0 0 * *",
+		"0 0 * 13 *",
+		"0 0 * * 8",
+		"0 0 5-1 * *",
+		"*/0 * * * *",
+		"a * * * *",
+	}
+
+	for _, expr := range tests {
+		if _, err := Parse(expr); err == nil {
+			t.Errorf("Parse(%q) succeeded, want an error", expr)
+		}
+	}
+}
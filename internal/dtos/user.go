@@ -1,5 +1,11 @@
 package dtos
 
+import (
+	"time"
+
+	"github.com/toluhikay/fx-exchange/internal/models"
+)
+
 type RegisterUser struct {
 	Name            string `json:"name" validate:"required"`
 	Email           string `json:"email" validate:"required"`
@@ -11,3 +17,112 @@ type UserLogin struct {
 	Email    string `json:"email" validate:"required"`
 	Password string `json:"password" validate:"required"`
 }
+
+type VerifyEmail struct {
+	Token string `json:"verification_token" validate:"required"`
+	Otp   string `json:"otp" validate:"required"`
+}
+
+type ResendOtp struct {
+	Email string `json:"email" validate:"required"`
+}
+
+type ForgotPassword struct {
+	Email string `json:"email" validate:"required"`
+}
+
+type ResetPassword struct {
+	Token           string `json:"token" validate:"required"`
+	Password        string `json:"password" validate:"required"`
+	ConfirmPassword string `json:"confirm_password" validate:"required"`
+}
+
+type ChangePassword struct {
+	OldPassword     string `json:"old_password" validate:"required"`
+	Password        string `json:"password" validate:"required"`
+	ConfirmPassword string `json:"confirm_password" validate:"required"`
+}
+
+type MfaLogin struct {
+	MfaToken string `json:"mfa_token" validate:"required"`
+	Code     string `json:"code" validate:"required"`
+}
+
+type MfaCode struct {
+	Code string `json:"code" validate:"required"`
+}
+
+type DisableMfa struct {
+	Password string `json:"password" validate:"required"`
+	Code     string `json:"code" validate:"required"`
+}
+
+type MfaEnrolment struct {
+	Secret          string   `json:"secret"`
+	ProvisioningURI string   `json:"provisioning_uri"`
+	RecoveryCodes   []string `json:"recovery_codes"`
+}
+
+type SetPin struct {
+	Pin        string `json:"pin" validate:"required"`
+	ConfirmPin string `json:"confirm_pin" validate:"required"`
+}
+
+type ChangePin struct {
+	OldPin     string `json:"old_pin" validate:"required"`
+	Pin        string `json:"pin" validate:"required"`
+	ConfirmPin string `json:"confirm_pin" validate:"required"`
+}
+
+type CreateAPIKey struct {
+	Name       string   `json:"name" validate:"required"`
+	Scopes     []string `json:"scopes" validate:"required"`
+	AllowedIPs []string `json:"allowed_ips"`
+}
+
+// CreatedAPIKey is the only time the plaintext key is returned.
+type CreatedAPIKey struct {
+	Key    string         `json:"key"`
+	APIKey *models.APIKey `json:"api_key"`
+}
+
+type FreezeUser struct {
+	Reason string `json:"reason" validate:"required"`
+}
+
+type SetRole struct {
+	Role string `json:"role" validate:"required"`
+}
+
+type DeleteAccount struct {
+	Password          string `json:"password" validate:"required"`
+	PayoutDestination string `json:"payout_destination"`
+}
+
+// AccountExport is the archive returned by the data export endpoint.
+type AccountExport struct {
+	ExportedAt         time.Time                  `json:"exported_at"`
+	Profile            *models.User               `json:"profile"`
+	Wallet             *models.Wallet             `json:"wallet"`
+	Transactions       []models.Transaction       `json:"transactions"`
+	WalletStatusEvents []models.WalletStatusEvent `json:"wallet_status_events"`
+	AuditLogs          []models.AuditLog          `json:"audit_logs"`
+	APIKeys            []models.APIKey            `json:"api_keys"`
+	KycDocuments       []models.KycDocument       `json:"kyc_documents"`
+}
+
+type KycStatus struct {
+	Level     string               `json:"level"`
+	Documents []models.KycDocument `json:"documents"`
+}
+
+type ReviewKycDocument struct {
+	Decision string `json:"decision" validate:"required"`
+	Note     string `json:"note"`
+}
+
+// ResolveCase carries the reviewer's note on compliance cases and transfer
+// approvals.
+type ResolveCase struct {
+	Note string `json:"note"`
+}
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/jackc/pgconn"
 )
@@ -28,12 +29,155 @@ var (
 	ErrPinLength             = errors.New("pin must be 4 numbers")
 	ErrPasswordTooLong       = errors.New("password must not exceed 32 characters")
 	ErrPasswordMismatch      = errors.New("passwords must match")
+	ErrPinMismatch           = errors.New("pins must match")
 	ErrSendingOtp            = errors.New("error sending otp")
 	ErrEmptyRequest          = errors.New("request cant be null or empty")
 	ErrDoesNtExists          = errors.New("doesn't exists")
 	ErrExists                = errors.New("already exists")
+	ErrEmailNotVerified      = errors.New("email address has not been verified")
+	ErrEmailAlreadyVerified  = errors.New("email address is already verified")
+	ErrOtpExpired            = errors.New("otp has expired, please request a new one")
+	ErrTooManyOtpAttempts    = errors.New("too many invalid otp attempts, please try again later")
+	ErrTooManyOtpResends     = errors.New("too many verification codes requested, please try again later")
+	ErrSessionRevoked        = errors.New("session has been revoked, please sign in again")
+	ErrInvalidResetToken     = errors.New("password reset link is invalid or has expired")
+	ErrMfaAlreadyEnabled     = errors.New("two-factor authentication is already enabled")
+	ErrMfaNotEnabled         = errors.New("two-factor authentication is not enabled")
+	ErrMfaNotEnrolled        = errors.New("start two-factor enrolment before activating it")
+	ErrInvalidMfaCode        = errors.New("invalid two-factor authentication code")
+	ErrMfaRequired           = errors.New("a two-factor authentication code is required for this operation")
+	ErrPinRequired           = errors.New("transaction pin is required")
+	ErrPinNotSet             = errors.New("set a transaction pin before making this request")
+	ErrPinAlreadySet         = errors.New("transaction pin is already set")
+	ErrInvalidPin            = errors.New("invalid transaction pin")
+	ErrPinLocked             = errors.New("transaction pin is locked after too many failed attempts, try again later")
+	ErrTooManyLoginAttempts  = errors.New("too many failed login attempts, try again later")
+	ErrAccountLocked         = errors.New("account is temporarily locked after too many failed login attempts")
+	ErrForbidden             = errors.New("you do not have permission to perform this action")
+	ErrRateLimited           = errors.New("too many requests, slow down")
+	ErrInvalidAPIKey         = errors.New("invalid or revoked api key")
+	ErrInvalidScope          = errors.New("unknown api key scope")
+	ErrInvalidIPAddress      = errors.New("allowed ips must be ip addresses or cidr ranges")
+	ErrAPIKeyIPNotAllowed    = errors.New("api key is not allowed from this ip address")
+	ErrInsufficientScope     = errors.New("api key does not have the scope required for this request")
+	ErrAPIKeyStepUp          = errors.New("transfers above the two-factor threshold cannot be made with an api key, sign in instead")
+	ErrAccountFrozen         = errors.New("account is frozen, contact support")
+	ErrOpenComplianceCase    = errors.New("account has a compliance review in progress, contact support")
+	ErrInvalidRole           = errors.New("unknown role")
+	ErrWalletFrozen          = errors.New("wallet is frozen")
+	ErrWalletClosed          = errors.New("wallet is closed")
+	ErrInvalidWalletStatus   = errors.New("unknown wallet status")
+	ErrInvalidStatusChange   = errors.New("wallet cannot move to that status")
+	ErrInvalidReasonCode     = errors.New("unknown reason code")
+	ErrWalletNotEmpty        = errors.New("wallet still holds funds, provide a payout destination to close it")
+	ErrBalanceChanged        = errors.New("wallet balance changed while it was being closed, try again")
+	ErrLimitExceeded         = errors.New("transaction limit exceeded")
+	ErrInvalidKycLevel       = errors.New("kyc level must be higher than the current level")
+	ErrInvalidDocumentType   = errors.New("unknown document type")
+	ErrUnsupportedFileType   = errors.New("documents must be jpeg, png or pdf files")
+	ErrFileTooLarge          = errors.New("file is too large")
+	ErrDocumentReviewed      = errors.New("document has already been reviewed")
+	ErrInvalidDecision       = errors.New("decision must be approve or reject")
+	ErrSpendingControl       = errors.New("blocked by your spending controls")
+	ErrInvalidControls       = errors.New("invalid spending controls")
+	ErrHeldForReview         = errors.New("held for compliance review")
+	ErrBlockedByRules        = errors.New("blocked by risk rules")
+	ErrCaseResolved          = errors.New("case has already been resolved")
+	ErrPendingApproval       = errors.New("transfer is waiting for approval")
+	ErrApprovalResolved      = errors.New("transfer request has already been resolved")
+	ErrApprovalExpired       = errors.New("transfer request has expired")
+	ErrNotApprover           = errors.New("you are not an approver for this wallet")
+	ErrSelfApproval          = errors.New("transfers cannot be approved by the person who requested them")
+	ErrInvalidApprovers      = errors.New("invalid approval policy")
+	ErrHoldNotActive         = errors.New("hold is no longer active")
+	ErrInvalidCapture        = errors.New("capture amount is more than the hold has left")
+	ErrActiveHolds           = errors.New("wallet has active holds, release or capture them first")
+	ErrInvalidHoldExpiry     = errors.New("invalid hold expiry")
+	ErrNotReversible         = errors.New("only swaps and transfers can be reversed")
+	ErrAlreadyReversed       = errors.New("transaction has already been fully reversed")
+	ErrInvalidReversal       = errors.New("invalid reversal amount")
+	ErrFundsSpent            = errors.New("the funds to take back have already been spent")
+	ErrNotDisputable         = errors.New("only transfers you sent can be disputed")
+	ErrDisputeExists         = errors.New("this transfer already has an open dispute")
+	ErrDisputeWindowClosed   = errors.New("this transfer is too old to dispute")
+	ErrDisputeResolved       = errors.New("dispute has already been resolved")
+	ErrInvalidDisputeReason  = errors.New("unknown dispute reason")
+	ErrInvalidOutcome        = errors.New("outcome must be sender or receiver")
+	ErrInsufficientBalance   = errors.New("invalid amount or insufficient balance")
+	ErrInvalidSchedule       = errors.New("invalid schedule")
+	ErrScheduleFinished      = errors.New("schedule has been cancelled or completed")
+	ErrInvalidScheduleChange = errors.New("schedule cannot make that change in its current state")
+	ErrRateAboveMax          = errors.New("price is above the plan's maximum rate")
+	ErrInvalidOrder          = errors.New("invalid swap order")
+	ErrOrderNotOpen          = errors.New("order has already been filled, cancelled or expired")
+	ErrInvalidMarket         = errors.New("unknown market")
+	ErrInvalidAlert          = errors.New("invalid rate alert")
+	ErrTooManyAlerts         = errors.New("too many active rate alerts")
+	ErrAlertNotActive        = errors.New("rate alert has already been triggered or cancelled")
+	ErrInvalidRateQuery      = errors.New("invalid rate history query")
 )
 
+// SettlementError says which order of a trade could not settle, so the
+// matching engine knows which side to take off the book.
+type SettlementError struct {
+	OrderID string
+	Err     error
+}
+
+func (e *SettlementError) Error() string {
+	return fmt.Sprintf("order %s: %v", e.OrderID, e.Err)
+}
+
+func (e *SettlementError) Unwrap() error {
+	return e.Err
+}
+
+// RetryAfterError tells the client how long to wait before trying again.
+type RetryAfterError struct {
+	Err        error
+	RetryAfter time.Duration
+}
+
+func (e *RetryAfterError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *RetryAfterError) Unwrap() error {
+	return e.Err
+}
+
+func NewRetryAfterError(err error, retryAfter time.Duration) error {
+	return &RetryAfterError{Err: err, RetryAfter: retryAfter}
+}
+
+// CodedError attaches a stable, machine readable code to an error so clients
+// can tell apart failures that share an HTTP status.
+type CodedError struct {
+	Code string
+	Err  error
+}
+
+func (e *CodedError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *CodedError) Unwrap() error {
+	return e.Err
+}
+
+func NewCodedError(code string, err error) error {
+	return &CodedError{Code: code, Err: err}
+}
+
+// Code returns the code carried by err, or "" when it has none.
+func Code(err error) string {
+	var coded *CodedError
+	if errors.As(err, &coded) {
+		return coded.Code
+	}
+	return ""
+}
+
 func ErrorCode(err error) string {
 	var pgErr *pgconn.PgError
 	if errors.As(err, &pgErr) {
@@ -51,12 +195,88 @@ func ResolveHTTPStatus(err error) int {
 		return http.StatusBadRequest
 	case errors.Is(err, ErrInvalidCredentials):
 		return http.StatusBadRequest
-	case errors.Is(err, ErrUnauthorized), errors.Is(err, ErrExpiredToken):
+	case errors.Is(err, ErrUnauthorized), errors.Is(err, ErrExpiredToken), errors.Is(err, ErrSessionRevoked), errors.Is(err, ErrInvalidAPIKey):
 		return http.StatusUnauthorized
 	case errors.Is(err, ErrExpiredToken):
 		return http.StatusUnauthorized
-	case errors.Is(err, ErrInvalidOtp):
+	case errors.Is(err, ErrInvalidOtp), errors.Is(err, ErrOtpExpired), errors.Is(err, ErrInvalidResetToken):
+		return http.StatusBadRequest
+	case errors.Is(err, ErrTooManyOtpAttempts), errors.Is(err, ErrTooManyOtpResends):
+		return http.StatusTooManyRequests
+	case errors.Is(err, ErrEmailNotVerified):
+		return http.StatusForbidden
+	case errors.Is(err, ErrEmailAlreadyVerified), errors.Is(err, ErrMfaAlreadyEnabled):
+		return http.StatusConflict
+	case errors.Is(err, ErrInvalidMfaCode), errors.Is(err, ErrMfaNotEnabled), errors.Is(err, ErrMfaNotEnrolled):
+		return http.StatusBadRequest
+	case errors.Is(err, ErrMfaRequired):
+		return http.StatusForbidden
+	case errors.Is(err, ErrPinRequired), errors.Is(err, ErrPinNotSet), errors.Is(err, ErrPinLength):
+		return http.StatusBadRequest
+	case errors.Is(err, ErrInvalidPin):
+		return http.StatusUnauthorized
+	case errors.Is(err, ErrPinAlreadySet):
+		return http.StatusConflict
+	case errors.Is(err, ErrPinLocked), errors.Is(err, ErrAccountLocked):
+		return http.StatusLocked
+	case errors.Is(err, ErrTooManyLoginAttempts), errors.Is(err, ErrRateLimited):
+		return http.StatusTooManyRequests
+	case errors.Is(err, ErrForbidden), errors.Is(err, ErrAPIKeyIPNotAllowed), errors.Is(err, ErrInsufficientScope), errors.Is(err, ErrAPIKeyStepUp), errors.Is(err, ErrAccountFrozen):
+		return http.StatusForbidden
+	case errors.Is(err, ErrWalletFrozen), errors.Is(err, ErrWalletClosed):
+		return http.StatusForbidden
+	case errors.Is(err, ErrInvalidStatusChange), errors.Is(err, ErrWalletNotEmpty), errors.Is(err, ErrBalanceChanged), errors.Is(err, ErrOpenComplianceCase):
+		return http.StatusConflict
+	case errors.Is(err, ErrInvalidWalletStatus), errors.Is(err, ErrInvalidReasonCode):
+		return http.StatusBadRequest
+	case errors.Is(err, ErrLimitExceeded), errors.Is(err, ErrSpendingControl):
+		return http.StatusForbidden
+	case errors.Is(err, ErrInvalidControls):
+		return http.StatusBadRequest
+	case errors.Is(err, ErrHeldForReview):
+		return http.StatusAccepted
+	case errors.Is(err, ErrBlockedByRules):
+		return http.StatusForbidden
+	case errors.Is(err, ErrCaseResolved), errors.Is(err, ErrApprovalResolved), errors.Is(err, ErrApprovalExpired):
+		return http.StatusConflict
+	case errors.Is(err, ErrPendingApproval):
+		return http.StatusAccepted
+	case errors.Is(err, ErrNotApprover), errors.Is(err, ErrSelfApproval):
+		return http.StatusForbidden
+	case errors.Is(err, ErrInvalidApprovers):
+		return http.StatusBadRequest
+	case errors.Is(err, ErrHoldNotActive), errors.Is(err, ErrActiveHolds):
+		return http.StatusConflict
+	case errors.Is(err, ErrInvalidCapture), errors.Is(err, ErrInvalidHoldExpiry):
+		return http.StatusBadRequest
+	case errors.Is(err, ErrNotReversible), errors.Is(err, ErrInvalidReversal):
+		return http.StatusBadRequest
+	case errors.Is(err, ErrAlreadyReversed), errors.Is(err, ErrFundsSpent):
+		return http.StatusConflict
+	case errors.Is(err, ErrNotDisputable), errors.Is(err, ErrDisputeWindowClosed), errors.Is(err, ErrInvalidDisputeReason), errors.Is(err, ErrInvalidOutcome):
+		return http.StatusBadRequest
+	case errors.Is(err, ErrDisputeExists), errors.Is(err, ErrDisputeResolved):
+		return http.StatusConflict
+	case errors.Is(err, ErrInsufficientBalance), errors.Is(err, ErrInvalidSchedule):
+		return http.StatusBadRequest
+	case errors.Is(err, ErrScheduleFinished), errors.Is(err, ErrInvalidScheduleChange):
+		return http.StatusConflict
+	case errors.Is(err, ErrInvalidOrder), errors.Is(err, ErrInvalidMarket):
+		return http.StatusBadRequest
+	case errors.Is(err, ErrOrderNotOpen):
+		return http.StatusConflict
+	case errors.Is(err, ErrInvalidAlert), errors.Is(err, ErrInvalidRateQuery):
+		return http.StatusBadRequest
+	case errors.Is(err, ErrTooManyAlerts), errors.Is(err, ErrAlertNotActive):
+		return http.StatusConflict
+	case errors.Is(err, ErrInvalidKycLevel), errors.Is(err, ErrInvalidDocumentType), errors.Is(err, ErrUnsupportedFileType), errors.Is(err, ErrInvalidDecision):
 		return http.StatusBadRequest
+	case errors.Is(err, ErrFileTooLarge):
+		return http.StatusRequestEntityTooLarge
+	case errors.Is(err, ErrDocumentReviewed):
+		return http.StatusConflict
+	case errors.Is(err, ErrSendingOtp):
+		return http.StatusBadGateway
 	case errors.Is(err, ErrInvalidPayload):
 		return http.StatusBadRequest
 	case errors.Is(err, ErrPasswordTooShort), errors.Is(err, ErrPasswordTooLong):
@@ -67,7 +287,7 @@ func ResolveHTTPStatus(err error) int {
 		return http.StatusBadRequest
 	case errors.Is(err, ErrDuplicateEmail):
 		return http.StatusBadRequest
-	case errors.Is(err, ErrPasswordMismatch):
+	case errors.Is(err, ErrPasswordMismatch), errors.Is(err, ErrPinMismatch), errors.Is(err, ErrInvalidRole):
 		return http.StatusBadRequest
 	default:
 		return http.StatusBadRequest
@@ -0,0 +1,254 @@
+package fraud
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/toluhikay/fx-exchange/internal/models"
+)
+
+// Actions a rule can take, in increasing order of severity.
+const (
+	ActionAllow  = "allow"
+	ActionReview = "review"
+	ActionBlock  = "block"
+)
+
+// Rule types understood by the engine.
+const (
+	RuleRapidInOut     = "rapid_in_out"
+	RuleStructuring    = "structuring"
+	RuleNewDeviceLarge = "new_device_large_transfer"
+	RuleManyRecipients = "many_recipients"
+)
+
+// Duration reads Go duration strings such as "30m" from json.
+type Duration time.Duration
+
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	parsed, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// Rule is one configurable check. Only the params used by its type matter.
+type Rule struct {
+	Name    string `json:"name"`
+	Type    string `json:"type"`
+	Action  string `json:"action"`
+	Enabled bool   `json:"enabled"`
+	// Window is how far back history is considered.
+	Window Duration `json:"window,omitempty"`
+	// Ratio is the share of recent incoming funds that, sent straight back out,
+	// counts as rapid in-out.
+	Ratio float64 `json:"ratio,omitempty"`
+	// NearLimitRatio marks amounts at or above this share of the single
+	// transaction cap as being just below the limit.
+	NearLimitRatio float64 `json:"near_limit_ratio,omitempty"`
+	// Count is how many occurrences within Window trigger the rule.
+	Count int `json:"count,omitempty"`
+	// ThresholdUSD is the transfer value above which a new device is flagged.
+	ThresholdUSD float64 `json:"threshold_usd,omitempty"`
+	// DeviceAge is how long a device stays "new" after it is first seen.
+	DeviceAge Duration `json:"device_age,omitempty"`
+}
+
+// Operation describes what is about to happen. Type is one of the models.Op
+// constants; callers fill in the fields that apply.
+type Operation struct {
+	Type       string
+	UserID     string
+	WalletID   string
+	ReceiverID string
+	Currency   string
+	Amount     float64
+	AmountUSD  float64
+	// SingleLimit is the owner's single transaction cap in Currency, 0 if none.
+	SingleLimit float64
+	Device      string
+}
+
+// DataSource answers the history questions the rules ask.
+type DataSource interface {
+	IncomingSince(ctx context.Context, walletID, currency string, since time.Time) (float64, error)
+	CountAtLeast(ctx context.Context, walletID, currency string, minAmount float64, since time.Time) (int, error)
+	RecipientsSince(ctx context.Context, walletID string, since time.Time) ([]string, error)
+	DeviceFirstSeen(ctx context.Context, userID, device string) (*time.Time, error)
+}
+
+// Decision is the most severe action among the rules that fired.
+type Decision struct {
+	Action string           `json:"action"`
+	Hits   []models.RuleHit `json:"hits"`
+}
+
+type Engine struct {
+	rules []Rule
+	data  DataSource
+}
+
+func NewEngine(rules []Rule, data DataSource) *Engine {
+	return &Engine{rules: rules, data: data}
+}
+
+func (e *Engine) Evaluate(ctx context.Context, op Operation) (Decision, error) {
+	decision := Decision{Action: ActionAllow}
+	now := time.Now()
+
+	for _, rule := range e.rules {
+		if !rule.Enabled {
+			continue
+		}
+
+		reason, err := e.check(ctx, rule, op, now)
+		if err != nil {
+			return Decision{}, fmt.Errorf("rule %s: %w", rule.Name, err)
+		}
+		if reason == "" {
+			continue
+		}
+
+		decision.Hits = append(decision.Hits, models.RuleHit{Rule: rule.Name, Action: rule.Action, Reason: reason})
+		if severity(rule.Action) > severity(decision.Action) {
+			decision.Action = rule.Action
+		}
+	}
+
+	return decision, nil
+}
+
+// check returns why the rule fired, or "" when it did not.
+func (e *Engine) check(ctx context.Context, rule Rule, op Operation, now time.Time) (string, error) {
+	since := now.Add(-time.Duration(rule.Window))
+
+	switch rule.Type {
+	case RuleRapidInOut:
+		if op.Type == models.OpDeposit {
+			return "", nil
+		}
+		incoming, err := e.data.IncomingSince(ctx, op.WalletID, op.Currency, since)
+		if err != nil {
+			return "", err
+		}
+		if incoming > 0 && op.Amount >= rule.Ratio*incoming {
+			return fmt.Sprintf("moving out %.2f %s of %.2f received in the last %s", op.Amount, op.Currency, incoming, time.Duration(rule.Window)), nil
+		}
+
+	case RuleStructuring:
+		if op.SingleLimit <= 0 || op.Amount < rule.NearLimitRatio*op.SingleLimit || op.Amount > op.SingleLimit {
+			return "", nil
+		}
+		count, err := e.data.CountAtLeast(ctx, op.WalletID, op.Currency, rule.NearLimitRatio*op.SingleLimit, since)
+		if err != nil {
+			return "", err
+		}
+		if count+1 >= rule.Count {
+			return fmt.Sprintf("%d transactions just below the %.2f %s limit in the last %s", count+1, op.SingleLimit, op.Currency, time.Duration(rule.Window)), nil
+		}
+
+	case RuleNewDeviceLarge:
+		if op.Type != models.OpTransfer || op.AmountUSD < rule.ThresholdUSD {
+			return "", nil
+		}
+		if op.Device == "" {
+			// no fingerprint means the device cannot be recognised
+			return fmt.Sprintf("transfer worth %.2f USDx from an unknown device", op.AmountUSD), nil
+		}
+		firstSeen, err := e.data.DeviceFirstSeen(ctx, op.UserID, op.Device)
+		if err != nil {
+			return "", err
+		}
+		if firstSeen == nil || now.Sub(*firstSeen) < time.Duration(rule.DeviceAge) {
+			return fmt.Sprintf("transfer worth %.2f USDx from a device first seen less than %s ago", op.AmountUSD, time.Duration(rule.DeviceAge)), nil
+		}
+
+	case RuleManyRecipients:
+		if op.Type != models.OpTransfer {
+			return "", nil
+		}
+		recipients, err := e.data.RecipientsSince(ctx, op.WalletID, since)
+		if err != nil {
+			return "", err
+		}
+		if !slices.Contains(recipients, op.ReceiverID) {
+			recipients = append(recipients, op.ReceiverID)
+		}
+		if len(recipients) >= rule.Count {
+			return fmt.Sprintf("%d distinct recipients in the last %s", len(recipients), time.Duration(rule.Window)), nil
+		}
+	}
+
+	return "", nil
+}
+
+func severity(action string) int {
+	switch action {
+	case ActionBlock:
+		return 2
+	case ActionReview:
+		return 1
+	default:
+		return 0
+	}
+}
+
+func DefaultRules() []Rule {
+	return []Rule{
+		{Name: "rapid-in-out", Type: RuleRapidInOut, Action: ActionReview, Enabled: true, Window: Duration(time.Hour), Ratio: 0.9},
+		{Name: "structuring", Type: RuleStructuring, Action: ActionReview, Enabled: true, Window: Duration(24 * time.Hour), NearLimitRatio: 0.9, Count: 3},
+		{Name: "new-device-large-transfer", Type: RuleNewDeviceLarge, Action: ActionReview, Enabled: true, ThresholdUSD: 1000, DeviceAge: Duration(24 * time.Hour)},
+		{Name: "many-recipients", Type: RuleManyRecipients, Action: ActionReview, Enabled: true, Window: Duration(24 * time.Hour), Count: 10},
+	}
+}
+
+// LoadFile reads a json array of rules.
+func LoadFile(path string) ([]Rule, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read fraud rules file: %w", err)
+	}
+
+	var rules []Rule
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return nil, fmt.Errorf("failed to parse fraud rules file: %w", err)
+	}
+
+	for _, rule := range rules {
+		if !slices.Contains([]string{RuleRapidInOut, RuleStructuring, RuleNewDeviceLarge, RuleManyRecipients}, rule.Type) {
+			return nil, fmt.Errorf("fraud rule %s: unknown type %q", rule.Name, rule.Type)
+		}
+		if !slices.Contains([]string{ActionAllow, ActionReview, ActionBlock}, rule.Action) {
+			return nil, fmt.Errorf("fraud rule %s: unknown action %q", rule.Name, rule.Action)
+		}
+	}
+	return rules, nil
+}
+
+type deviceKey struct{}
+
+// WithDevice stores the fingerprint of the device making the request.
+func WithDevice(ctx context.Context, device string) context.Context {
+	return context.WithValue(ctx, deviceKey{}, device)
+}
+
+// DeviceFromContext returns the fingerprint set by WithDevice, or "" for
+// requests that did not come from a device, such as scheduled jobs.
+func DeviceFromContext(ctx context.Context) string {
+	device, _ := ctx.Value(deviceKey{}).(string)
+	return device
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"maps"
 	"math/rand"
+	"strings"
 	"sync"
 	"time"
 
@@ -14,10 +15,29 @@ import (
 
 type FXProvider interface {
 	GetRate(ctx context.Context, from, to string) (float64, error)
+	// GetRates returns every current rate, keyed by from and then to currency.
+	GetRates(ctx context.Context) (map[string]map[string]float64, error)
 	SubscribeRates() chan map[string]map[string]float64
 	StartRateUpdates(ctx context.Context, db *sql.DB, interval time.Duration)
 }
 
+// WatchRates calls handle with every set of rates provider publishes until ctx
+// is cancelled. The provider drops subscribers that fall behind, so a closed
+// channel is subscribed again unless the provider is shutting down.
+func WatchRates(ctx context.Context, provider FXProvider, handle func(rates map[string]map[string]float64)) {
+	for {
+		for rates := range provider.SubscribeRates() {
+			handle(rates)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(time.Second):
+		}
+	}
+}
+
 type MockFXProvider struct {
 	rates     map[string]map[string]float64
 	dynamic   bool
@@ -57,6 +77,16 @@ func (m *MockFXProvider) GetRate(ctx context.Context, from, to string) (float64,
 	return rate, nil
 }
 
+func (m *MockFXProvider) GetRates(ctx context.Context) (map[string]map[string]float64, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	rateCopy := make(map[string]map[string]float64, len(m.rates))
+	for from, rateMap := range m.rates {
+		rateCopy[from] = maps.Clone(rateMap)
+	}
+	return rateCopy, nil
+}
+
 func (m *MockFXProvider) SubscribeRates() chan map[string]map[string]float64 {
 	m.chansMu.Lock()
 	defer m.chansMu.Unlock()
@@ -85,12 +115,6 @@ func (m *MockFXProvider) StartRateUpdates(ctx context.Context, db *sql.DB, inter
 					for to, rate := range rateMap {
 						fluctuation := 1 + (rand.Float64()*0.01 - 0.005) // ±0.5%
 						m.rates[from][to] = rate * fluctuation
-						query := `INSERT INTO fx_rates (id, from_currency, to_currency, rate, timestamp) 
-                                 VALUES ($1, $2, $3, $4, $5)`
-						_, err := db.ExecContext(ctx, query, uuid.New().String(), from, to, m.rates[from][to], time.Now())
-						if err != nil {
-							fmt.Printf("Failed to log FX rate: %v\n", err)
-						}
 					}
 				}
 			}
@@ -101,6 +125,11 @@ func (m *MockFXProvider) StartRateUpdates(ctx context.Context, db *sql.DB, inter
 				maps.Copy(rateCopy[from], rateMap)
 			}
 			m.mu.Unlock()
+			if m.dynamic {
+				if err := logRates(ctx, db, rateCopy, time.Now().UTC()); err != nil {
+					fmt.Printf("Failed to log FX rates: %v\n", err)
+				}
+			}
 			m.chansMu.Lock()
 			activeChans := m.rateChans[:0]
 			for _, ch := range m.rateChans {
@@ -117,6 +146,28 @@ func (m *MockFXProvider) StartRateUpdates(ctx context.Context, db *sql.DB, inter
 	}
 }
 
+// logRates records one tick of every pair in a single statement. timestamp
+// should be in UTC, which is what the history and retention queries compare
+// the column against.
+func logRates(ctx context.Context, db *sql.DB, rates map[string]map[string]float64, timestamp time.Time) error {
+	var values []string
+	var args []any
+	for from, rateMap := range rates {
+		for to, rate := range rateMap {
+			n := len(args)
+			values = append(values, fmt.Sprintf("($%d, $%d, $%d, $%d, $%d)", n+1, n+2, n+3, n+4, n+5))
+			args = append(args, uuid.New().String(), from, to, rate, timestamp)
+		}
+	}
+	if len(values) == 0 {
+		return nil
+	}
+
+	query := `INSERT INTO fx_rates (id, from_currency, to_currency, rate, timestamp) VALUES ` + strings.Join(values, ", ")
+	_, err := db.ExecContext(ctx, query, args...)
+	return err
+}
+
 // Placeholder for live FX client
 type FXClient struct{}
 
@@ -128,6 +179,10 @@ func (c *FXClient) GetRate(ctx context.Context, from, to string) (float64, error
 	return 0, fmt.Errorf("live FX client not implemented")
 }
 
+func (c *FXClient) GetRates(ctx context.Context) (map[string]map[string]float64, error) {
+	return nil, fmt.Errorf("live FX client not implemented")
+}
+
 func (c *FXClient) SubscribeRates() chan map[string]map[string]float64 {
 	return make(chan map[string]map[string]float64)
 }
@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/toluhikay/fx-exchange/internal/dtos"
+	customErrors "github.com/toluhikay/fx-exchange/internal/errors"
+	"github.com/toluhikay/fx-exchange/internal/services"
+	"github.com/toluhikay/fx-exchange/pkg/jwt"
+	"github.com/toluhikay/fx-exchange/pkg/utils"
+)
+
+type AccountHandler struct {
+	accountSvc *services.AccountService
+	audit      *services.AuditService
+}
+
+func NewAccountHandler(accountSvc *services.AccountService, audit *services.AuditService) *AccountHandler {
+	return &AccountHandler{accountSvc: accountSvc, audit: audit}
+}
+
+func (ah *AccountHandler) DeleteAccount(w http.ResponseWriter, r *http.Request) {
+	claims := r.Context().Value("user_claims").(*jwt.JwtClaims)
+
+	var req dtos.DeleteAccount
+	if err := utils.ReadJSON(w, r, &req); err != nil {
+		utils.ErrorJSON(w, customErrors.ErrInvalidPayload, http.StatusBadRequest)
+		return
+	}
+
+	if err := ah.accountSvc.DeleteAccount(r.Context(), claims.ID, req); err != nil {
+		utils.ErrorJSON(w, err, customErrors.ResolveHTTPStatus(err))
+		return
+	}
+	ah.audit.Record(r.Context(), newAuditLog(r, "", "account_deleted", ""))
+
+	response := utils.JSONResponse{
+		Error:   false,
+		Message: "account closed",
+	}
+
+	utils.WriteJson(w, http.StatusOK, response)
+}
+
+// ExportData returns the user's data as a downloadable json file.
+func (ah *AccountHandler) ExportData(w http.ResponseWriter, r *http.Request) {
+	claims := r.Context().Value("user_claims").(*jwt.JwtClaims)
+
+	export, err := ah.accountSvc.ExportData(r.Context(), claims.ID)
+	if err != nil {
+		utils.ErrorJSON(w, err, customErrors.ResolveHTTPStatus(err))
+		return
+	}
+	ah.audit.Record(r.Context(), newAuditLog(r, "", "account_exported", ""))
+
+	headers := http.Header{}
+	headers.Set("Content-Disposition", fmt.Sprintf(`attachment; filename="fx-exchange-export-%s.json"`, export.ExportedAt.Format("20060102")))
+
+	utils.WriteJson(w, http.StatusOK, export, headers)
+}
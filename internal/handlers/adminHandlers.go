@@ -0,0 +1,431 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/toluhikay/fx-exchange/internal/dtos"
+	customErrors "github.com/toluhikay/fx-exchange/internal/errors"
+	"github.com/toluhikay/fx-exchange/internal/models"
+	"github.com/toluhikay/fx-exchange/internal/services"
+	"github.com/toluhikay/fx-exchange/pkg/jwt"
+	"github.com/toluhikay/fx-exchange/pkg/utils"
+)
+
+const (
+	defaultPageSize = 50
+	maxPageSize     = 200
+)
+
+type AdminHandler struct {
+	svc      *services.Service
+	userSvc  *services.UserServiceImpl
+	adminSvc *services.AdminService
+	audit    *services.AuditService
+}
+
+func NewAdminHandler(svc *services.Service, userSvc *services.UserServiceImpl, adminSvc *services.AdminService, audit *services.AuditService) *AdminHandler {
+	return &AdminHandler{svc: svc, userSvc: userSvc, adminSvc: adminSvc, audit: audit}
+}
+
+// pageParams reads limit and offset from the query string, falling back to the
+// defaults for missing or invalid values.
+func pageParams(r *http.Request) (int, int) {
+	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil || limit <= 0 {
+		limit = defaultPageSize
+	}
+	if limit > maxPageSize {
+		limit = maxPageSize
+	}
+	offset, err := strconv.Atoi(r.URL.Query().Get("offset"))
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+	return limit, offset
+}
+
+func idParam(r *http.Request) (uuid.UUID, error) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		return uuid.Nil, customErrors.ErrInvalidPayload
+	}
+	return id, nil
+}
+
+func (ah *AdminHandler) SearchUsers(w http.ResponseWriter, r *http.Request) {
+	limit, offset := pageParams(r)
+
+	users, err := ah.adminSvc.SearchUsers(r.Context(), r.URL.Query().Get("q"), limit, offset)
+	if err != nil {
+		utils.ErrorJSON(w, err, customErrors.ResolveHTTPStatus(err))
+		return
+	}
+
+	utils.WriteJson(w, http.StatusOK, utils.JSONResponse{Error: false, Data: users})
+}
+
+func (ah *AdminHandler) GetUser(w http.ResponseWriter, r *http.Request) {
+	userID, err := idParam(r)
+	if err != nil {
+		utils.ErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	user, err := ah.adminSvc.GetUser(r.Context(), userID)
+	if err != nil {
+		utils.ErrorJSON(w, err, customErrors.ResolveHTTPStatus(err))
+		return
+	}
+
+	utils.WriteJson(w, http.StatusOK, utils.JSONResponse{Error: false, Data: user})
+}
+
+func (ah *AdminHandler) UserAuditLogs(w http.ResponseWriter, r *http.Request) {
+	userID, err := idParam(r)
+	if err != nil {
+		utils.ErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+	limit, offset := pageParams(r)
+
+	logs, err := ah.adminSvc.UserAuditLogs(r.Context(), userID, limit, offset)
+	if err != nil {
+		utils.ErrorJSON(w, err, customErrors.ResolveHTTPStatus(err))
+		return
+	}
+
+	utils.WriteJson(w, http.StatusOK, utils.JSONResponse{Error: false, Data: logs})
+}
+
+func (ah *AdminHandler) SearchWallets(w http.ResponseWriter, r *http.Request) {
+	limit, offset := pageParams(r)
+
+	wallets, err := ah.adminSvc.SearchWallets(r.Context(), r.URL.Query().Get("q"), limit, offset)
+	if err != nil {
+		utils.ErrorJSON(w, err, customErrors.ResolveHTTPStatus(err))
+		return
+	}
+
+	utils.WriteJson(w, http.StatusOK, utils.JSONResponse{Error: false, Data: wallets})
+}
+
+func (ah *AdminHandler) WalletHistory(w http.ResponseWriter, r *http.Request) {
+	walletID, err := idParam(r)
+	if err != nil {
+		utils.ErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	history, err := ah.adminSvc.WalletHistory(r.Context(), walletID)
+	if err != nil {
+		utils.ErrorJSON(w, err, customErrors.ResolveHTTPStatus(err))
+		return
+	}
+
+	utils.WriteJson(w, http.StatusOK, utils.JSONResponse{Error: false, Data: history})
+}
+
+func (ah *AdminHandler) WalletAuditLogs(w http.ResponseWriter, r *http.Request) {
+	walletID, err := idParam(r)
+	if err != nil {
+		utils.ErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+	limit, offset := pageParams(r)
+
+	logs, err := ah.adminSvc.WalletAuditLogs(r.Context(), walletID, limit, offset)
+	if err != nil {
+		utils.ErrorJSON(w, err, customErrors.ResolveHTTPStatus(err))
+		return
+	}
+
+	utils.WriteJson(w, http.StatusOK, utils.JSONResponse{Error: false, Data: logs})
+}
+
+func (ah *AdminHandler) ChangeWalletStatus(w http.ResponseWriter, r *http.Request) {
+	claims := r.Context().Value("user_claims").(*jwt.JwtClaims)
+
+	walletID, err := idParam(r)
+	if err != nil {
+		utils.ErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	var req models.WalletStatusRequest
+	if err := utils.ReadJSON(w, r, &req); err != nil {
+		utils.ErrorJSON(w, customErrors.ErrInvalidPayload, http.StatusBadRequest)
+		return
+	}
+
+	event, err := ah.svc.ChangeWalletStatus(r.Context(), walletID.String(), claims.ID, req)
+	if err != nil {
+		utils.ErrorJSON(w, err, customErrors.ResolveHTTPStatus(err))
+		return
+	}
+	ah.audit.Record(r.Context(), newAuditLog(r, walletID.String(), "admin_wallet_status", fmt.Sprintf("%s -> %s (%s)", event.FromStatus, event.ToStatus, event.ReasonCode)))
+
+	utils.WriteJson(w, http.StatusOK, utils.JSONResponse{Error: false, Message: "wallet status updated", Data: event})
+}
+
+func (ah *AdminHandler) WalletStatusEvents(w http.ResponseWriter, r *http.Request) {
+	walletID, err := idParam(r)
+	if err != nil {
+		utils.ErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	events, err := ah.svc.GetWalletStatusEvents(r.Context(), walletID.String())
+	if err != nil {
+		utils.ErrorJSON(w, customErrors.ErrInternalServer, http.StatusInternalServerError)
+		return
+	}
+
+	utils.WriteJson(w, http.StatusOK, utils.JSONResponse{Error: false, Data: events})
+}
+
+func (ah *AdminHandler) GetApprovalPolicy(w http.ResponseWriter, r *http.Request) {
+	walletID, err := idParam(r)
+	if err != nil {
+		utils.ErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	policy, err := ah.svc.GetApprovalPolicy(r.Context(), walletID.String())
+	if err != nil {
+		utils.ErrorJSON(w, customErrors.ErrRecordNotFound, http.StatusNotFound)
+		return
+	}
+
+	utils.WriteJson(w, http.StatusOK, utils.JSONResponse{Error: false, Data: policy})
+}
+
+func (ah *AdminHandler) SetApprovalPolicy(w http.ResponseWriter, r *http.Request) {
+	walletID, err := idParam(r)
+	if err != nil {
+		utils.ErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	var req models.ApprovalPolicy
+	if err := utils.ReadJSON(w, r, &req); err != nil {
+		utils.ErrorJSON(w, customErrors.ErrInvalidPayload, http.StatusBadRequest)
+		return
+	}
+
+	policy, err := ah.svc.SetApprovalPolicy(r.Context(), walletID.String(), req)
+	if err != nil {
+		utils.ErrorJSON(w, err, customErrors.ResolveHTTPStatus(err))
+		return
+	}
+	ah.audit.Record(r.Context(), newAuditLog(r, walletID.String(), "admin_set_approvers", fmt.Sprintf("%d approvers over %.2f USDx", len(policy.ApproverIDs), policy.ThresholdUSD)))
+
+	utils.WriteJson(w, http.StatusOK, utils.JSONResponse{Error: false, Message: "approval policy updated", Data: policy})
+}
+
+func (ah *AdminHandler) GetTransaction(w http.ResponseWriter, r *http.Request) {
+	id, err := idParam(r)
+	if err != nil {
+		utils.ErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	tx, err := ah.svc.GetTransactionReversals(r.Context(), id.String())
+	if err != nil {
+		utils.ErrorJSON(w, err, customErrors.ResolveHTTPStatus(err))
+		return
+	}
+
+	utils.WriteJson(w, http.StatusOK, utils.JSONResponse{Error: false, Data: tx})
+}
+
+func (ah *AdminHandler) ReverseTransaction(w http.ResponseWriter, r *http.Request) {
+	id, err := idParam(r)
+	if err != nil {
+		utils.ErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	var req models.ReversalRequest
+	if err := utils.ReadJSON(w, r, &req); err != nil {
+		utils.ErrorJSON(w, customErrors.ErrInvalidPayload, http.StatusBadRequest)
+		return
+	}
+
+	tx, err := ah.svc.ReverseTransaction(r.Context(), id.String(), req)
+	if err != nil {
+		utils.ErrorJSON(w, err, customErrors.ResolveHTTPStatus(err))
+		return
+	}
+	reversal := tx.Reversals[len(tx.Reversals)-1]
+	ah.audit.Record(r.Context(), newAuditLog(r, tx.Original.WalletID, "admin_reverse_transaction",
+		fmt.Sprintf("%s: %.4f %s refunded (%s)", tx.Original.ID, *reversal.ConvertedAmount, *reversal.ToCurrency, req.Reason)))
+
+	utils.WriteJson(w, http.StatusOK, utils.JSONResponse{Error: false, Message: "transaction reversed", Data: tx})
+}
+
+func (ah *AdminHandler) FreezeUser(w http.ResponseWriter, r *http.Request) {
+	claims := r.Context().Value("user_claims").(*jwt.JwtClaims)
+
+	userID, err := idParam(r)
+	if err != nil {
+		utils.ErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	var req dtos.FreezeUser
+	if err := utils.ReadJSON(w, r, &req); err != nil {
+		utils.ErrorJSON(w, customErrors.ErrInvalidPayload, http.StatusBadRequest)
+		return
+	}
+
+	if err := ah.adminSvc.FreezeUser(r.Context(), claims.ID, userID, req.Reason); err != nil {
+		utils.ErrorJSON(w, err, customErrors.ResolveHTTPStatus(err))
+		return
+	}
+	ah.audit.Record(r.Context(), newAuditLog(r, "", "admin_freeze_user", userID.String()+": "+req.Reason))
+
+	utils.WriteJson(w, http.StatusOK, utils.JSONResponse{Error: false, Message: "user frozen"})
+}
+
+func (ah *AdminHandler) UnfreezeUser(w http.ResponseWriter, r *http.Request) {
+	userID, err := idParam(r)
+	if err != nil {
+		utils.ErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	if err := ah.adminSvc.UnfreezeUser(r.Context(), userID); err != nil {
+		utils.ErrorJSON(w, err, customErrors.ResolveHTTPStatus(err))
+		return
+	}
+	ah.audit.Record(r.Context(), newAuditLog(r, "", "admin_unfreeze_user", userID.String()))
+
+	utils.WriteJson(w, http.StatusOK, utils.JSONResponse{Error: false, Message: "user unfrozen"})
+}
+
+func (ah *AdminHandler) SetRole(w http.ResponseWriter, r *http.Request) {
+	claims := r.Context().Value("user_claims").(*jwt.JwtClaims)
+
+	userID, err := idParam(r)
+	if err != nil {
+		utils.ErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	var req dtos.SetRole
+	if err := utils.ReadJSON(w, r, &req); err != nil {
+		utils.ErrorJSON(w, customErrors.ErrInvalidPayload, http.StatusBadRequest)
+		return
+	}
+
+	if err := ah.adminSvc.SetRole(r.Context(), claims.ID, userID, req.Role); err != nil {
+		utils.ErrorJSON(w, err, customErrors.ResolveHTTPStatus(err))
+		return
+	}
+	ah.audit.Record(r.Context(), newAuditLog(r, "", "admin_set_role", userID.String()+": "+req.Role))
+
+	utils.WriteJson(w, http.StatusOK, utils.JSONResponse{Error: false, Message: "role updated"})
+}
+
+func (ah *AdminHandler) UnlockUser(w http.ResponseWriter, r *http.Request) {
+	userID, err := idParam(r)
+	if err != nil {
+		utils.ErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	if err := ah.userSvc.UnlockUser(r.Context(), userID); err != nil {
+		utils.ErrorJSON(w, err, customErrors.ResolveHTTPStatus(err))
+		return
+	}
+	ah.audit.Record(r.Context(), newAuditLog(r, "", "admin_unlock_user", userID.String()))
+
+	response := utils.JSONResponse{
+		Error:   false,
+		Message: "user unlocked",
+	}
+
+	utils.WriteJson(w, http.StatusOK, response)
+}
+
+func (ah *AdminHandler) ListCases(w http.ResponseWriter, r *http.Request) {
+	limit, offset := pageParams(r)
+
+	cases, err := ah.svc.ListCases(r.Context(), r.URL.Query().Get("status"), limit, offset)
+	if err != nil {
+		utils.ErrorJSON(w, customErrors.ErrInternalServer, http.StatusInternalServerError)
+		return
+	}
+
+	utils.WriteJson(w, http.StatusOK, utils.JSONResponse{Error: false, Data: cases})
+}
+
+func (ah *AdminHandler) GetCase(w http.ResponseWriter, r *http.Request) {
+	caseID, err := idParam(r)
+	if err != nil {
+		utils.ErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	c, err := ah.svc.GetCase(r.Context(), caseID)
+	if err != nil {
+		utils.ErrorJSON(w, err, customErrors.ResolveHTTPStatus(err))
+		return
+	}
+
+	utils.WriteJson(w, http.StatusOK, utils.JSONResponse{Error: false, Data: c})
+}
+
+func (ah *AdminHandler) ApproveCase(w http.ResponseWriter, r *http.Request) {
+	claims := r.Context().Value("user_claims").(*jwt.JwtClaims)
+
+	caseID, err := idParam(r)
+	if err != nil {
+		utils.ErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	var req dtos.ResolveCase
+	if err := utils.ReadJSON(w, r, &req); err != nil {
+		utils.ErrorJSON(w, customErrors.ErrInvalidPayload, http.StatusBadRequest)
+		return
+	}
+
+	c, err := ah.svc.ApproveCase(r.Context(), claims.ID, caseID, req.Note)
+	if err != nil {
+		utils.ErrorJSON(w, err, customErrors.ResolveHTTPStatus(err))
+		return
+	}
+	ah.audit.Record(r.Context(), newAuditLog(r, c.WalletID, "admin_approve_case", caseID.String()))
+
+	utils.WriteJson(w, http.StatusOK, utils.JSONResponse{Error: false, Message: "case approved", Data: c})
+}
+
+func (ah *AdminHandler) RejectCase(w http.ResponseWriter, r *http.Request) {
+	claims := r.Context().Value("user_claims").(*jwt.JwtClaims)
+
+	caseID, err := idParam(r)
+	if err != nil {
+		utils.ErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	var req dtos.ResolveCase
+	if err := utils.ReadJSON(w, r, &req); err != nil {
+		utils.ErrorJSON(w, customErrors.ErrInvalidPayload, http.StatusBadRequest)
+		return
+	}
+
+	c, err := ah.svc.RejectCase(r.Context(), claims.ID, caseID, req.Note)
+	if err != nil {
+		utils.ErrorJSON(w, err, customErrors.ResolveHTTPStatus(err))
+		return
+	}
+	ah.audit.Record(r.Context(), newAuditLog(r, c.WalletID, "admin_reject_case", caseID.String()))
+
+	utils.WriteJson(w, http.StatusOK, utils.JSONResponse{Error: false, Message: "case rejected", Data: c})
+}
@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"net/http"
+
+	customErrors "github.com/toluhikay/fx-exchange/internal/errors"
+	"github.com/toluhikay/fx-exchange/internal/models"
+	"github.com/toluhikay/fx-exchange/internal/services"
+	"github.com/toluhikay/fx-exchange/pkg/jwt"
+	"github.com/toluhikay/fx-exchange/pkg/utils"
+)
+
+type AlertHandler struct {
+	alertSvc *services.AlertService
+}
+
+func NewAlertHandler(alertSvc *services.AlertService) *AlertHandler {
+	return &AlertHandler{alertSvc: alertSvc}
+}
+
+func (ah *AlertHandler) Create(w http.ResponseWriter, r *http.Request) {
+	claims := r.Context().Value("user_claims").(*jwt.JwtClaims)
+
+	var req models.RateAlertRequest
+	if err := utils.ReadJSON(w, r, &req); err != nil {
+		utils.ErrorJSON(w, customErrors.ErrInvalidPayload, http.StatusBadRequest)
+		return
+	}
+
+	alert, err := ah.alertSvc.CreateAlert(r.Context(), claims.ID, req)
+	if err != nil {
+		utils.ErrorJSON(w, err, customErrors.ResolveHTTPStatus(err))
+		return
+	}
+
+	utils.WriteJson(w, http.StatusCreated, utils.JSONResponse{Error: false, Message: "rate alert created", Data: alert})
+}
+
+// List returns the caller's alerts; ?status= narrows them to one status.
+func (ah *AlertHandler) List(w http.ResponseWriter, r *http.Request) {
+	claims := r.Context().Value("user_claims").(*jwt.JwtClaims)
+	limit, offset := pageParams(r)
+
+	alerts, err := ah.alertSvc.ListAlerts(r.Context(), claims.ID, r.URL.Query().Get("status"), limit, offset)
+	if err != nil {
+		utils.ErrorJSON(w, customErrors.ErrInternalServer, http.StatusInternalServerError)
+		return
+	}
+
+	utils.WriteJson(w, http.StatusOK, utils.JSONResponse{Error: false, Data: alerts})
+}
+
+func (ah *AlertHandler) Get(w http.ResponseWriter, r *http.Request) {
+	claims := r.Context().Value("user_claims").(*jwt.JwtClaims)
+
+	id, err := idParam(r)
+	if err != nil {
+		utils.ErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	alert, err := ah.alertSvc.GetAlert(r.Context(), claims.ID, id)
+	if err != nil {
+		utils.ErrorJSON(w, err, customErrors.ResolveHTTPStatus(err))
+		return
+	}
+
+	utils.WriteJson(w, http.StatusOK, utils.JSONResponse{Error: false, Data: alert})
+}
+
+func (ah *AlertHandler) Cancel(w http.ResponseWriter, r *http.Request) {
+	claims := r.Context().Value("user_claims").(*jwt.JwtClaims)
+
+	id, err := idParam(r)
+	if err != nil {
+		utils.ErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	alert, err := ah.alertSvc.CancelAlert(r.Context(), claims.ID, id)
+	if err != nil {
+		utils.ErrorJSON(w, err, customErrors.ResolveHTTPStatus(err))
+		return
+	}
+
+	utils.WriteJson(w, http.StatusOK, utils.JSONResponse{Error: false, Message: "rate alert cancelled", Data: alert})
+}
@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/toluhikay/fx-exchange/internal/dtos"
+	customErrors "github.com/toluhikay/fx-exchange/internal/errors"
+	"github.com/toluhikay/fx-exchange/internal/services"
+	"github.com/toluhikay/fx-exchange/pkg/jwt"
+	"github.com/toluhikay/fx-exchange/pkg/utils"
+)
+
+// ApprovalHandler serves the designated approvers of maker-checker wallets.
+type ApprovalHandler struct {
+	svc   *services.Service
+	audit *services.AuditService
+}
+
+func NewApprovalHandler(svc *services.Service, audit *services.AuditService) *ApprovalHandler {
+	return &ApprovalHandler{svc: svc, audit: audit}
+}
+
+func (ah *ApprovalHandler) ListPending(w http.ResponseWriter, r *http.Request) {
+	claims := r.Context().Value("user_claims").(*jwt.JwtClaims)
+	limit, offset := pageParams(r)
+
+	approvals, err := ah.svc.ListPendingApprovals(r.Context(), claims.ID, limit, offset)
+	if err != nil {
+		utils.ErrorJSON(w, customErrors.ErrInternalServer, http.StatusInternalServerError)
+		return
+	}
+
+	utils.WriteJson(w, http.StatusOK, utils.JSONResponse{Error: false, Data: approvals})
+}
+
+func (ah *ApprovalHandler) Approve(w http.ResponseWriter, r *http.Request) {
+	claims := r.Context().Value("user_claims").(*jwt.JwtClaims)
+
+	id, err := idParam(r)
+	if err != nil {
+		utils.ErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	approval, err := ah.svc.ApproveTransfer(r.Context(), claims.ID, id)
+	if err != nil {
+		utils.ErrorJSON(w, err, customErrors.ResolveHTTPStatus(err))
+		return
+	}
+	ah.audit.Record(r.Context(), newAuditLog(r, approval.WalletID, "approve_transfer",
+		fmt.Sprintf("%s %s %.4f to %s", id, approval.Currency, approval.Amount, approval.ReceiverWalletID)))
+
+	utils.WriteJson(w, http.StatusOK, utils.JSONResponse{Error: false, Message: "transfer approved", Data: approval})
+}
+
+func (ah *ApprovalHandler) Reject(w http.ResponseWriter, r *http.Request) {
+	claims := r.Context().Value("user_claims").(*jwt.JwtClaims)
+
+	id, err := idParam(r)
+	if err != nil {
+		utils.ErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	var req dtos.ResolveCase
+	if err := utils.ReadJSON(w, r, &req); err != nil {
+		utils.ErrorJSON(w, customErrors.ErrInvalidPayload, http.StatusBadRequest)
+		return
+	}
+
+	approval, err := ah.svc.RejectTransfer(r.Context(), claims.ID, id, req.Note)
+	if err != nil {
+		utils.ErrorJSON(w, err, customErrors.ResolveHTTPStatus(err))
+		return
+	}
+	ah.audit.Record(r.Context(), newAuditLog(r, approval.WalletID, "reject_transfer", id.String()))
+
+	utils.WriteJson(w, http.StatusOK, utils.JSONResponse{Error: false, Message: "transfer rejected", Data: approval})
+}
@@ -0,0 +1,28 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/toluhikay/fx-exchange/internal/models"
+	"github.com/toluhikay/fx-exchange/pkg/jwt"
+	"github.com/toluhikay/fx-exchange/pkg/utils"
+)
+
+// newAuditLog captures who did what from where for the audit trail.
+func newAuditLog(r *http.Request, walletID, operation, body string) models.AuditLog {
+	l := models.AuditLog{
+		Operation:     operation,
+		ClientIP:      utils.ClientIP(r),
+		UserAgent:     r.UserAgent(),
+		RequestMethod: r.Method,
+		RequestPath:   r.URL.Path,
+		RequestBody:   body,
+	}
+	if walletID != "" {
+		l.WalletID = &walletID
+	}
+	if claims, ok := r.Context().Value("user_claims").(*jwt.JwtClaims); ok {
+		l.UserID = &claims.ID
+	}
+	return l
+}
@@ -0,0 +1,251 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	customErrors "github.com/toluhikay/fx-exchange/internal/errors"
+	"github.com/toluhikay/fx-exchange/internal/models"
+	"github.com/toluhikay/fx-exchange/internal/services"
+	"github.com/toluhikay/fx-exchange/pkg/jwt"
+	"github.com/toluhikay/fx-exchange/pkg/utils"
+)
+
+// DisputeHandler serves both the parties to a disputed transfer and the
+// staff who decide it.
+type DisputeHandler struct {
+	svc        *services.Service
+	disputeSvc *services.DisputeService
+	audit      *services.AuditService
+}
+
+func NewDisputeHandler(svc *services.Service, disputeSvc *services.DisputeService, audit *services.AuditService) *DisputeHandler {
+	return &DisputeHandler{svc: svc, disputeSvc: disputeSvc, audit: audit}
+}
+
+func (dh *DisputeHandler) Open(w http.ResponseWriter, r *http.Request) {
+	claims := r.Context().Value("user_claims").(*jwt.JwtClaims)
+	wallet, err := dh.svc.GetWalletByUserId(r.Context(), claims.ID)
+	if err != nil {
+		utils.ErrorJSON(w, customErrors.ErrRecordNotFound, http.StatusBadRequest)
+		return
+	}
+
+	var req models.OpenDisputeRequest
+	if err := utils.ReadJSON(w, r, &req); err != nil {
+		utils.ErrorJSON(w, customErrors.ErrInvalidPayload, http.StatusBadRequest)
+		return
+	}
+
+	dispute, err := dh.disputeSvc.OpenDispute(r.Context(), claims.ID, wallet.ID, req)
+	if err != nil {
+		utils.ErrorJSON(w, err, customErrors.ResolveHTTPStatus(err))
+		return
+	}
+	dh.audit.Record(r.Context(), newAuditLog(r, wallet.ID, "dispute_opened", fmt.Sprintf("%s on %s (%s)", dispute.ID, dispute.TransactionID, dispute.Reason)))
+
+	utils.WriteJson(w, http.StatusCreated, utils.JSONResponse{Error: false, Message: "dispute opened", Data: dispute})
+}
+
+func (dh *DisputeHandler) ListMine(w http.ResponseWriter, r *http.Request) {
+	claims := r.Context().Value("user_claims").(*jwt.JwtClaims)
+	wallet, err := dh.svc.GetWalletByUserId(r.Context(), claims.ID)
+	if err != nil {
+		utils.ErrorJSON(w, customErrors.ErrRecordNotFound, http.StatusBadRequest)
+		return
+	}
+	limit, offset := pageParams(r)
+
+	disputes, err := dh.disputeSvc.ListWalletDisputes(r.Context(), wallet.ID, limit, offset)
+	if err != nil {
+		utils.ErrorJSON(w, customErrors.ErrInternalServer, http.StatusInternalServerError)
+		return
+	}
+
+	utils.WriteJson(w, http.StatusOK, utils.JSONResponse{Error: false, Data: disputes})
+}
+
+func (dh *DisputeHandler) GetMine(w http.ResponseWriter, r *http.Request) {
+	claims := r.Context().Value("user_claims").(*jwt.JwtClaims)
+	wallet, err := dh.svc.GetWalletByUserId(r.Context(), claims.ID)
+	if err != nil {
+		utils.ErrorJSON(w, customErrors.ErrRecordNotFound, http.StatusBadRequest)
+		return
+	}
+	id, err := idParam(r)
+	if err != nil {
+		utils.ErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	dispute, err := dh.disputeSvc.GetWalletDispute(r.Context(), wallet.ID, id)
+	if err != nil {
+		utils.ErrorJSON(w, err, customErrors.ResolveHTTPStatus(err))
+		return
+	}
+
+	utils.WriteJson(w, http.StatusOK, utils.JSONResponse{Error: false, Data: dispute})
+}
+
+// AddEvidence takes a multipart form with a "file" field.
+func (dh *DisputeHandler) AddEvidence(w http.ResponseWriter, r *http.Request) {
+	claims := r.Context().Value("user_claims").(*jwt.JwtClaims)
+	wallet, err := dh.svc.GetWalletByUserId(r.Context(), claims.ID)
+	if err != nil {
+		utils.ErrorJSON(w, customErrors.ErrRecordNotFound, http.StatusBadRequest)
+		return
+	}
+	id, err := idParam(r)
+	if err != nil {
+		utils.ErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, services.MaxDisputeEvidenceSize+1<<20)
+	if err := r.ParseMultipartForm(services.MaxDisputeEvidenceSize); err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			utils.ErrorJSON(w, customErrors.ErrFileTooLarge, http.StatusRequestEntityTooLarge)
+			return
+		}
+		utils.ErrorJSON(w, customErrors.ErrInvalidPayload, http.StatusBadRequest)
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		utils.ErrorJSON(w, customErrors.ErrInvalidPayload, http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+
+	// trust the file contents rather than the client's content type
+	head := make([]byte, 512)
+	n, err := io.ReadFull(file, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		utils.ErrorJSON(w, customErrors.ErrInvalidPayload, http.StatusBadRequest)
+		return
+	}
+	head = head[:n]
+
+	evidence, err := dh.disputeSvc.AddEvidence(
+		r.Context(),
+		claims.ID,
+		wallet.ID,
+		id,
+		filepath.Base(header.Filename),
+		http.DetectContentType(head),
+		io.MultiReader(bytes.NewReader(head), file),
+	)
+	if err != nil {
+		utils.ErrorJSON(w, err, customErrors.ResolveHTTPStatus(err))
+		return
+	}
+	dh.audit.Record(r.Context(), newAuditLog(r, wallet.ID, "dispute_evidence_added", fmt.Sprintf("%s on %s", evidence.ID, id)))
+
+	utils.WriteJson(w, http.StatusCreated, utils.JSONResponse{Error: false, Message: "evidence added", Data: evidence})
+}
+
+func (dh *DisputeHandler) List(w http.ResponseWriter, r *http.Request) {
+	limit, offset := pageParams(r)
+
+	disputes, err := dh.disputeSvc.ListDisputes(r.Context(), r.URL.Query().Get("status"), limit, offset)
+	if err != nil {
+		utils.ErrorJSON(w, customErrors.ErrInternalServer, http.StatusInternalServerError)
+		return
+	}
+
+	utils.WriteJson(w, http.StatusOK, utils.JSONResponse{Error: false, Data: disputes})
+}
+
+func (dh *DisputeHandler) Get(w http.ResponseWriter, r *http.Request) {
+	id, err := idParam(r)
+	if err != nil {
+		utils.ErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	dispute, err := dh.disputeSvc.GetDispute(r.Context(), id)
+	if err != nil {
+		utils.ErrorJSON(w, err, customErrors.ResolveHTTPStatus(err))
+		return
+	}
+
+	utils.WriteJson(w, http.StatusOK, utils.JSONResponse{Error: false, Data: dispute})
+}
+
+func (dh *DisputeHandler) DownloadEvidence(w http.ResponseWriter, r *http.Request) {
+	id, err := idParam(r)
+	if err != nil {
+		utils.ErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+	evidenceID, err := uuid.Parse(chi.URLParam(r, "evidenceID"))
+	if err != nil {
+		utils.ErrorJSON(w, customErrors.ErrInvalidPayload, http.StatusBadRequest)
+		return
+	}
+
+	evidence, file, err := dh.disputeSvc.OpenEvidence(r.Context(), id, evidenceID)
+	if err != nil {
+		utils.ErrorJSON(w, err, customErrors.ResolveHTTPStatus(err))
+		return
+	}
+	defer file.Close()
+	dh.audit.Record(r.Context(), newAuditLog(r, "", "dispute_evidence_viewed", evidence.ID.String()))
+
+	w.Header().Set("Content-Type", evidence.ContentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", evidence.FileName))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(http.StatusOK)
+	io.Copy(w, file)
+}
+
+func (dh *DisputeHandler) StartReview(w http.ResponseWriter, r *http.Request) {
+	id, err := idParam(r)
+	if err != nil {
+		utils.ErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	dispute, err := dh.disputeSvc.StartReview(r.Context(), id)
+	if err != nil {
+		utils.ErrorJSON(w, err, customErrors.ResolveHTTPStatus(err))
+		return
+	}
+	dh.audit.Record(r.Context(), newAuditLog(r, dispute.SenderWalletID, "admin_dispute_review", id.String()))
+
+	utils.WriteJson(w, http.StatusOK, utils.JSONResponse{Error: false, Message: "dispute under review", Data: dispute})
+}
+
+func (dh *DisputeHandler) Resolve(w http.ResponseWriter, r *http.Request) {
+	claims := r.Context().Value("user_claims").(*jwt.JwtClaims)
+
+	id, err := idParam(r)
+	if err != nil {
+		utils.ErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	var req models.ResolveDisputeRequest
+	if err := utils.ReadJSON(w, r, &req); err != nil {
+		utils.ErrorJSON(w, customErrors.ErrInvalidPayload, http.StatusBadRequest)
+		return
+	}
+
+	dispute, err := dh.disputeSvc.ResolveDispute(r.Context(), claims.ID, id, req)
+	if err != nil {
+		utils.ErrorJSON(w, err, customErrors.ResolveHTTPStatus(err))
+		return
+	}
+	dh.audit.Record(r.Context(), newAuditLog(r, dispute.SenderWalletID, "admin_dispute_resolved", fmt.Sprintf("%s: %s", id, dispute.Status)))
+
+	utils.WriteJson(w, http.StatusOK, utils.JSONResponse{Error: false, Message: "dispute resolved", Data: dispute})
+}
@@ -0,0 +1,154 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+
+	"github.com/toluhikay/fx-exchange/internal/dtos"
+	customErrors "github.com/toluhikay/fx-exchange/internal/errors"
+	"github.com/toluhikay/fx-exchange/internal/services"
+	"github.com/toluhikay/fx-exchange/pkg/jwt"
+	"github.com/toluhikay/fx-exchange/pkg/utils"
+)
+
+type KycHandler struct {
+	kycSvc *services.KycService
+	audit  *services.AuditService
+}
+
+func NewKycHandler(kycSvc *services.KycService, audit *services.AuditService) *KycHandler {
+	return &KycHandler{kycSvc: kycSvc, audit: audit}
+}
+
+// SubmitDocument takes a multipart form with "level", "document_type" and a
+// "file" field.
+func (kh *KycHandler) SubmitDocument(w http.ResponseWriter, r *http.Request) {
+	claims := r.Context().Value("user_claims").(*jwt.JwtClaims)
+
+	r.Body = http.MaxBytesReader(w, r.Body, services.MaxKycDocumentSize+1<<20)
+	if err := r.ParseMultipartForm(services.MaxKycDocumentSize); err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			utils.ErrorJSON(w, customErrors.ErrFileTooLarge, http.StatusRequestEntityTooLarge)
+			return
+		}
+		utils.ErrorJSON(w, customErrors.ErrInvalidPayload, http.StatusBadRequest)
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		utils.ErrorJSON(w, customErrors.ErrInvalidPayload, http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+
+	// trust the file contents rather than the client's content type
+	head := make([]byte, 512)
+	n, err := io.ReadFull(file, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		utils.ErrorJSON(w, customErrors.ErrInvalidPayload, http.StatusBadRequest)
+		return
+	}
+	head = head[:n]
+	contentType := http.DetectContentType(head)
+
+	doc, err := kh.kycSvc.SubmitDocument(
+		r.Context(),
+		claims.ID,
+		r.FormValue("level"),
+		r.FormValue("document_type"),
+		filepath.Base(header.Filename),
+		contentType,
+		io.MultiReader(bytes.NewReader(head), file),
+	)
+	if err != nil {
+		utils.ErrorJSON(w, err, customErrors.ResolveHTTPStatus(err))
+		return
+	}
+	kh.audit.Record(r.Context(), newAuditLog(r, "", "kyc_document_submitted", doc.ID.String()))
+
+	response := utils.JSONResponse{
+		Error:   false,
+		Message: "document submitted for review",
+		Data:    doc,
+	}
+
+	utils.WriteJson(w, http.StatusCreated, response)
+}
+
+func (kh *KycHandler) GetStatus(w http.ResponseWriter, r *http.Request) {
+	claims := r.Context().Value("user_claims").(*jwt.JwtClaims)
+
+	status, err := kh.kycSvc.GetStatus(r.Context(), claims.ID)
+	if err != nil {
+		utils.ErrorJSON(w, err, customErrors.ResolveHTTPStatus(err))
+		return
+	}
+
+	utils.WriteJson(w, http.StatusOK, utils.JSONResponse{Error: false, Data: status})
+}
+
+func (kh *KycHandler) ListDocuments(w http.ResponseWriter, r *http.Request) {
+	limit, offset := pageParams(r)
+
+	docs, err := kh.kycSvc.ListDocuments(r.Context(), r.URL.Query().Get("status"), limit, offset)
+	if err != nil {
+		utils.ErrorJSON(w, err, customErrors.ResolveHTTPStatus(err))
+		return
+	}
+
+	utils.WriteJson(w, http.StatusOK, utils.JSONResponse{Error: false, Data: docs})
+}
+
+func (kh *KycHandler) DownloadDocument(w http.ResponseWriter, r *http.Request) {
+	docID, err := idParam(r)
+	if err != nil {
+		utils.ErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	doc, file, err := kh.kycSvc.OpenDocument(r.Context(), docID)
+	if err != nil {
+		utils.ErrorJSON(w, err, customErrors.ResolveHTTPStatus(err))
+		return
+	}
+	defer file.Close()
+	kh.audit.Record(r.Context(), newAuditLog(r, "", "kyc_document_viewed", doc.ID.String()))
+
+	w.Header().Set("Content-Type", doc.ContentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", doc.FileName))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(http.StatusOK)
+	io.Copy(w, file)
+}
+
+func (kh *KycHandler) ReviewDocument(w http.ResponseWriter, r *http.Request) {
+	claims := r.Context().Value("user_claims").(*jwt.JwtClaims)
+
+	docID, err := idParam(r)
+	if err != nil {
+		utils.ErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	var req dtos.ReviewKycDocument
+	if err := utils.ReadJSON(w, r, &req); err != nil {
+		utils.ErrorJSON(w, customErrors.ErrInvalidPayload, http.StatusBadRequest)
+		return
+	}
+
+	doc, err := kh.kycSvc.ReviewDocument(r.Context(), claims.ID, docID, req)
+	if err != nil {
+		utils.ErrorJSON(w, err, customErrors.ResolveHTTPStatus(err))
+		return
+	}
+	kh.audit.Record(r.Context(), newAuditLog(r, "", "kyc_document_reviewed", fmt.Sprintf("%s %s", doc.ID, doc.Status)))
+
+	utils.WriteJson(w, http.StatusOK, utils.JSONResponse{Error: false, Message: "document " + doc.Status, Data: doc})
+}
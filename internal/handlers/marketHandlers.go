@@ -0,0 +1,174 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	customErrors "github.com/toluhikay/fx-exchange/internal/errors"
+	"github.com/toluhikay/fx-exchange/internal/models"
+	"github.com/toluhikay/fx-exchange/internal/services"
+	"github.com/toluhikay/fx-exchange/pkg/jwt"
+	"github.com/toluhikay/fx-exchange/pkg/utils"
+)
+
+// MarketHandler serves the peer-to-peer order book: the public market data
+// and the orders a wallet places on it.
+type MarketHandler struct {
+	wallets   *Handler
+	marketSvc *services.MarketService
+}
+
+func NewMarketHandler(wallets *Handler, marketSvc *services.MarketService) *MarketHandler {
+	return &MarketHandler{wallets: wallets, marketSvc: marketSvc}
+}
+
+// marketParam reads a market from its {base}/{quote} path segments.
+func marketParam(r *http.Request) string {
+	return chi.URLParam(r, "base") + "/" + chi.URLParam(r, "quote")
+}
+
+func (mh *MarketHandler) ListMarkets(w http.ResponseWriter, r *http.Request) {
+	utils.WriteJson(w, http.StatusOK, utils.JSONResponse{Error: false, Data: mh.marketSvc.Markets(), Message: "success"})
+}
+
+func (mh *MarketHandler) Depth(w http.ResponseWriter, r *http.Request) {
+	levels, err := strconv.Atoi(r.URL.Query().Get("levels"))
+	if err != nil || levels <= 0 || levels > services.DepthLevels {
+		levels = services.DepthLevels
+	}
+	depth, err := mh.marketSvc.Depth(marketParam(r), levels)
+	if err != nil {
+		utils.ErrorJSON(w, err, customErrors.ResolveHTTPStatus(err))
+		return
+	}
+
+	utils.WriteJson(w, http.StatusOK, utils.JSONResponse{Error: false, Data: depth, Message: "success"})
+}
+
+func (mh *MarketHandler) Trades(w http.ResponseWriter, r *http.Request) {
+	limit, offset := pageParams(r)
+	trades, err := mh.marketSvc.ListTrades(r.Context(), marketParam(r), limit, offset)
+	if err != nil {
+		utils.ErrorJSON(w, err, customErrors.ResolveHTTPStatus(err))
+		return
+	}
+
+	utils.WriteJson(w, http.StatusOK, utils.JSONResponse{Error: false, Data: trades, Message: "success"})
+}
+
+// PlaceOrder puts a bid or ask on the book, matching what it can straight
+// away and reserving the rest.
+func (mh *MarketHandler) PlaceOrder(w http.ResponseWriter, r *http.Request) {
+	userClaims := r.Context().Value("user_claims").(*jwt.JwtClaims)
+	wallet, err := mh.wallets.svc.GetWalletByUserId(r.Context(), userClaims.ID)
+	if err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+	walletID := wallet.ID
+	var req models.P2POrderRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+	if !mh.wallets.verifyPin(w, r, walletID, req.Pin) {
+		return
+	}
+	order, err := mh.marketSvc.PlaceOrder(r.Context(), userClaims.ID, walletID, req)
+	if err != nil {
+		utils.ErrorJSON(w, err, customErrors.ResolveHTTPStatus(err))
+		return
+	}
+	mh.wallets.logAudit(r, walletID, "p2p_order_placed", fmt.Sprintf("%s %s %s %.4f at %.8f, filled %.4f", order.ID, order.Side, order.Market, order.Amount, order.Price, order.Filled))
+
+	message := "order placed"
+	if order.Status == models.OrderFilled {
+		message = "order filled"
+	}
+
+	utils.WriteJson(w, http.StatusCreated, utils.JSONResponse{Error: false, Data: order, Message: message})
+}
+
+func (mh *MarketHandler) ListOrders(w http.ResponseWriter, r *http.Request) {
+	userClaims := r.Context().Value("user_claims").(*jwt.JwtClaims)
+	wallet, err := mh.wallets.svc.GetWalletByUserId(r.Context(), userClaims.ID)
+	if err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+	limit, offset := pageParams(r)
+	orders, err := mh.marketSvc.ListOrders(r.Context(), wallet.ID, r.URL.Query().Get("status"), limit, offset)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	utils.WriteJson(w, http.StatusOK, utils.JSONResponse{Error: false, Data: orders, Message: "success"})
+}
+
+func (mh *MarketHandler) GetOrder(w http.ResponseWriter, r *http.Request) {
+	userClaims := r.Context().Value("user_claims").(*jwt.JwtClaims)
+	wallet, err := mh.wallets.svc.GetWalletByUserId(r.Context(), userClaims.ID)
+	if err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+	id, err := idParam(r)
+	if err != nil {
+		utils.ErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+	order, err := mh.marketSvc.GetOrder(r.Context(), wallet.ID, id)
+	if err != nil {
+		utils.ErrorJSON(w, err, customErrors.ResolveHTTPStatus(err))
+		return
+	}
+
+	utils.WriteJson(w, http.StatusOK, utils.JSONResponse{Error: false, Data: order, Message: "success"})
+}
+
+func (mh *MarketHandler) ListOrderTrades(w http.ResponseWriter, r *http.Request) {
+	userClaims := r.Context().Value("user_claims").(*jwt.JwtClaims)
+	wallet, err := mh.wallets.svc.GetWalletByUserId(r.Context(), userClaims.ID)
+	if err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+	id, err := idParam(r)
+	if err != nil {
+		utils.ErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+	trades, err := mh.marketSvc.ListOrderTrades(r.Context(), wallet.ID, id)
+	if err != nil {
+		utils.ErrorJSON(w, err, customErrors.ResolveHTTPStatus(err))
+		return
+	}
+
+	utils.WriteJson(w, http.StatusOK, utils.JSONResponse{Error: false, Data: trades, Message: "success"})
+}
+
+func (mh *MarketHandler) CancelOrder(w http.ResponseWriter, r *http.Request) {
+	userClaims := r.Context().Value("user_claims").(*jwt.JwtClaims)
+	wallet, err := mh.wallets.svc.GetWalletByUserId(r.Context(), userClaims.ID)
+	if err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+	id, err := idParam(r)
+	if err != nil {
+		utils.ErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+	order, err := mh.marketSvc.CancelOrder(r.Context(), wallet.ID, id)
+	if err != nil {
+		utils.ErrorJSON(w, err, customErrors.ResolveHTTPStatus(err))
+		return
+	}
+	mh.wallets.logAudit(r, wallet.ID, "p2p_order_cancelled", order.ID.String())
+
+	utils.WriteJson(w, http.StatusOK, utils.JSONResponse{Error: false, Data: order, Message: "order cancelled"})
+}
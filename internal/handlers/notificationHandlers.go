@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"net/http"
+
+	customErrors "github.com/toluhikay/fx-exchange/internal/errors"
+	"github.com/toluhikay/fx-exchange/internal/services"
+	"github.com/toluhikay/fx-exchange/pkg/jwt"
+	"github.com/toluhikay/fx-exchange/pkg/utils"
+)
+
+type NotificationHandler struct {
+	notificationSvc *services.NotificationService
+}
+
+func NewNotificationHandler(notificationSvc *services.NotificationService) *NotificationHandler {
+	return &NotificationHandler{notificationSvc: notificationSvc}
+}
+
+// List returns the caller's inbox; ?unread=true leaves out what was read.
+func (nh *NotificationHandler) List(w http.ResponseWriter, r *http.Request) {
+	claims := r.Context().Value("user_claims").(*jwt.JwtClaims)
+	limit, offset := pageParams(r)
+
+	notifications, err := nh.notificationSvc.List(r.Context(), claims.ID, r.URL.Query().Get("unread") == "true", limit, offset)
+	if err != nil {
+		utils.ErrorJSON(w, customErrors.ErrInternalServer, http.StatusInternalServerError)
+		return
+	}
+
+	utils.WriteJson(w, http.StatusOK, utils.JSONResponse{Error: false, Data: notifications})
+}
+
+func (nh *NotificationHandler) MarkRead(w http.ResponseWriter, r *http.Request) {
+	claims := r.Context().Value("user_claims").(*jwt.JwtClaims)
+
+	id, err := idParam(r)
+	if err != nil {
+		utils.ErrorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	if err := nh.notificationSvc.MarkRead(r.Context(), claims.ID, id); err != nil {
+		utils.ErrorJSON(w, err, customErrors.ResolveHTTPStatus(err))
+		return
+	}
+
+	utils.WriteJson(w, http.StatusOK, utils.JSONResponse{Error: false, Message: "notification marked as read"})
+}
+
+func (nh *NotificationHandler) MarkAllRead(w http.ResponseWriter, r *http.Request) {
+	claims := r.Context().Value("user_claims").(*jwt.JwtClaims)
+
+	if err := nh.notificationSvc.MarkAllRead(r.Context(), claims.ID); err != nil {
+		utils.ErrorJSON(w, customErrors.ErrInternalServer, http.StatusInternalServerError)
+		return
+	}
+
+	utils.WriteJson(w, http.StatusOK, utils.JSONResponse{Error: false, Message: "notifications marked as read"})
+}
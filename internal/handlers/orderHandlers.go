@@ -0,0 +1,130 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	customErrors "github.com/toluhikay/fx-exchange/internal/errors"
+	"github.com/toluhikay/fx-exchange/internal/models"
+	"github.com/toluhikay/fx-exchange/pkg/jwt"
+	"github.com/toluhikay/fx-exchange/pkg/utils"
+)
+
+// PlaceSwapOrder opens a limit or stop order, reserving the amount until the
+// order fills, is cancelled or expires.
+func (h *Handler) PlaceSwapOrder(w http.ResponseWriter, r *http.Request) {
+	userClaims := r.Context().Value("user_claims").(*jwt.JwtClaims)
+	wallet, err := h.svc.GetWalletByUserId(r.Context(), userClaims.ID)
+	if err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+	walletID := wallet.ID
+	var req models.SwapOrderRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+	if !h.verifyPin(w, r, walletID, req.Pin) {
+		return
+	}
+	order, err := h.svc.PlaceSwapOrder(r.Context(), userClaims.ID, walletID, req)
+	if err != nil {
+		utils.ErrorJSON(w, err, customErrors.ResolveHTTPStatus(err))
+		return
+	}
+	h.logAudit(r, walletID, "swap_order_placed", fmt.Sprintf("%s %s %s->%s %.4f at %.8f", order.ID, order.Type, order.FromCurrency, order.ToCurrency, order.Amount, order.TriggerRate))
+
+	message := "order placed"
+	if order.Status == models.OrderFilled {
+		message = "order filled"
+	}
+
+	jsonResponse := utils.JSONResponse{
+		Error:   false,
+		Data:    order,
+		Message: message,
+	}
+
+	utils.WriteJson(w, http.StatusCreated, jsonResponse)
+}
+
+func (h *Handler) ListSwapOrders(w http.ResponseWriter, r *http.Request) {
+	userClaims := r.Context().Value("user_claims").(*jwt.JwtClaims)
+	wallet, err := h.svc.GetWalletByUserId(r.Context(), userClaims.ID)
+	if err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+	limit, offset := pageParams(r)
+	orders, err := h.svc.ListSwapOrders(r.Context(), wallet.ID, r.URL.Query().Get("status"), limit, offset)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	jsonResponse := utils.JSONResponse{
+		Error:   false,
+		Data:    orders,
+		Message: "success",
+	}
+
+	utils.WriteJson(w, http.StatusOK, jsonResponse)
+}
+
+func (h *Handler) GetSwapOrder(w http.ResponseWriter, r *http.Request) {
+	userClaims := r.Context().Value("user_claims").(*jwt.JwtClaims)
+	wallet, err := h.svc.GetWalletByUserId(r.Context(), userClaims.ID)
+	if err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+	id, err := idParam(r)
+	if err != nil {
+		utils.ErrorJSON(w, err, customErrors.ResolveHTTPStatus(err))
+		return
+	}
+	order, err := h.svc.GetSwapOrder(r.Context(), wallet.ID, id)
+	if err != nil {
+		utils.ErrorJSON(w, err, customErrors.ResolveHTTPStatus(err))
+		return
+	}
+
+	jsonResponse := utils.JSONResponse{
+		Error:   false,
+		Data:    order,
+		Message: "success",
+	}
+
+	utils.WriteJson(w, http.StatusOK, jsonResponse)
+}
+
+func (h *Handler) CancelSwapOrder(w http.ResponseWriter, r *http.Request) {
+	userClaims := r.Context().Value("user_claims").(*jwt.JwtClaims)
+	wallet, err := h.svc.GetWalletByUserId(r.Context(), userClaims.ID)
+	if err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+	walletID := wallet.ID
+	id, err := idParam(r)
+	if err != nil {
+		utils.ErrorJSON(w, err, customErrors.ResolveHTTPStatus(err))
+		return
+	}
+	order, err := h.svc.CancelSwapOrder(r.Context(), walletID, id)
+	if err != nil {
+		utils.ErrorJSON(w, err, customErrors.ResolveHTTPStatus(err))
+		return
+	}
+	h.logAudit(r, walletID, "swap_order_cancelled", order.ID.String())
+
+	jsonResponse := utils.JSONResponse{
+		Error:   false,
+		Data:    order,
+		Message: "order cancelled",
+	}
+
+	utils.WriteJson(w, http.StatusOK, jsonResponse)
+}
@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	customErrors "github.com/toluhikay/fx-exchange/internal/errors"
+	"github.com/toluhikay/fx-exchange/internal/services"
+	"github.com/toluhikay/fx-exchange/pkg/utils"
+)
+
+// RateHandler serves FX rates over REST for clients that do not hold a
+// WebSocket open.
+type RateHandler struct {
+	rateSvc *services.RateService
+}
+
+func NewRateHandler(rateSvc *services.RateService) *RateHandler {
+	return &RateHandler{rateSvc: rateSvc}
+}
+
+func (rh *RateHandler) Current(w http.ResponseWriter, r *http.Request) {
+	rates, err := rh.rateSvc.CurrentRates(r.Context())
+	if err != nil {
+		utils.ErrorJSON(w, customErrors.ErrInternalServer, http.StatusInternalServerError)
+		return
+	}
+
+	utils.WriteJson(w, http.StatusOK, utils.JSONResponse{Error: false, Data: rates})
+}
+
+// History returns candles and ticks for ?pair=FROM/TO, bucketed by
+// ?interval=, between the RFC 3339 times ?from= and ?to=.
+func (rh *RateHandler) History(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	var from, to time.Time
+	for _, param := range []struct {
+		name string
+		dst  *time.Time
+	}{{"from", &from}, {"to", &to}} {
+		value := query.Get(param.name)
+		if value == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			utils.ErrorJSON(w, customErrors.ErrInvalidRateQuery, http.StatusBadRequest)
+			return
+		}
+		*param.dst = t
+	}
+
+	history, err := rh.rateSvc.History(r.Context(), query.Get("pair"), query.Get("interval"), from, to)
+	if err != nil {
+		utils.ErrorJSON(w, err, customErrors.ResolveHTTPStatus(err))
+		return
+	}
+
+	utils.WriteJson(w, http.StatusOK, utils.JSONResponse{Error: false, Data: history})
+}
@@ -0,0 +1,180 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/google/uuid"
+	customErrors "github.com/toluhikay/fx-exchange/internal/errors"
+	"github.com/toluhikay/fx-exchange/internal/models"
+	"github.com/toluhikay/fx-exchange/pkg/jwt"
+	"github.com/toluhikay/fx-exchange/pkg/utils"
+)
+
+// ScheduleTransfer sets up a one-off or recurring transfer. The pin, and a
+// second factor for large amounts, are checked once here since nobody is
+// around to answer them when the transfer runs.
+func (h *Handler) ScheduleTransfer(w http.ResponseWriter, r *http.Request) {
+	userClaims := r.Context().Value("user_claims").(*jwt.JwtClaims)
+	wallet, err := h.svc.GetWalletByUserId(r.Context(), userClaims.ID)
+	if err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+	walletID := wallet.ID
+	var req models.ScheduleTransferRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+
+	if !h.verifyStepUp(w, r, req.Currency, req.Amount, req.MfaCode) {
+		return
+	}
+	if !h.verifyPin(w, r, walletID, req.Pin) {
+		return
+	}
+	st, err := h.svc.ScheduleTransfer(r.Context(), userClaims.ID, walletID, req)
+	if err != nil {
+		utils.ErrorJSON(w, err, customErrors.ResolveHTTPStatus(err))
+		return
+	}
+	h.logAudit(r, walletID, "transfer_scheduled", fmt.Sprintf("%s %s %.4f to %s (%s)", st.ID, st.Currency, st.Amount, st.ReceiverWalletID, st.Frequency))
+
+	jsonResponse := utils.JSONResponse{
+		Error:   false,
+		Data:    st,
+		Message: "transfer scheduled",
+	}
+
+	utils.WriteJson(w, http.StatusCreated, jsonResponse)
+}
+
+func (h *Handler) ListScheduledTransfers(w http.ResponseWriter, r *http.Request) {
+	userClaims := r.Context().Value("user_claims").(*jwt.JwtClaims)
+	wallet, err := h.svc.GetWalletByUserId(r.Context(), userClaims.ID)
+	if err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+	limit, offset := pageParams(r)
+	schedules, err := h.svc.ListScheduledTransfers(r.Context(), wallet.ID, limit, offset)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	jsonResponse := utils.JSONResponse{
+		Error:   false,
+		Data:    schedules,
+		Message: "success",
+	}
+
+	utils.WriteJson(w, http.StatusOK, jsonResponse)
+}
+
+func (h *Handler) GetScheduledTransfer(w http.ResponseWriter, r *http.Request) {
+	userClaims := r.Context().Value("user_claims").(*jwt.JwtClaims)
+	wallet, err := h.svc.GetWalletByUserId(r.Context(), userClaims.ID)
+	if err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+	id, err := idParam(r)
+	if err != nil {
+		utils.ErrorJSON(w, err, customErrors.ResolveHTTPStatus(err))
+		return
+	}
+	st, err := h.svc.GetScheduledTransfer(r.Context(), wallet.ID, id)
+	if err != nil {
+		utils.ErrorJSON(w, err, customErrors.ResolveHTTPStatus(err))
+		return
+	}
+
+	jsonResponse := utils.JSONResponse{
+		Error:   false,
+		Data:    st,
+		Message: "success",
+	}
+
+	utils.WriteJson(w, http.StatusOK, jsonResponse)
+}
+
+// ListScheduledTransferRuns returns the execution history of a schedule.
+func (h *Handler) ListScheduledTransferRuns(w http.ResponseWriter, r *http.Request) {
+	userClaims := r.Context().Value("user_claims").(*jwt.JwtClaims)
+	wallet, err := h.svc.GetWalletByUserId(r.Context(), userClaims.ID)
+	if err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+	id, err := idParam(r)
+	if err != nil {
+		utils.ErrorJSON(w, err, customErrors.ResolveHTTPStatus(err))
+		return
+	}
+	limit, offset := pageParams(r)
+	runs, err := h.svc.ListScheduledTransferRuns(r.Context(), wallet.ID, id, limit, offset)
+	if err != nil {
+		utils.ErrorJSON(w, err, customErrors.ResolveHTTPStatus(err))
+		return
+	}
+
+	jsonResponse := utils.JSONResponse{
+		Error:   false,
+		Data:    runs,
+		Message: "success",
+	}
+
+	utils.WriteJson(w, http.StatusOK, jsonResponse)
+}
+
+func (h *Handler) SkipScheduledTransfer(w http.ResponseWriter, r *http.Request) {
+	h.changeScheduledTransfer(w, r, "scheduled_transfer_skipped", "next run skipped", h.svc.SkipScheduledTransfer)
+}
+
+func (h *Handler) PauseScheduledTransfer(w http.ResponseWriter, r *http.Request) {
+	h.changeScheduledTransfer(w, r, "scheduled_transfer_paused", "scheduled transfer paused", h.svc.PauseScheduledTransfer)
+}
+
+func (h *Handler) ResumeScheduledTransfer(w http.ResponseWriter, r *http.Request) {
+	h.changeScheduledTransfer(w, r, "scheduled_transfer_resumed", "scheduled transfer resumed", h.svc.ResumeScheduledTransfer)
+}
+
+func (h *Handler) CancelScheduledTransfer(w http.ResponseWriter, r *http.Request) {
+	h.changeScheduledTransfer(w, r, "scheduled_transfer_cancelled", "scheduled transfer cancelled", h.svc.CancelScheduledTransfer)
+}
+
+// changeScheduledTransfer runs one of the schedule actions on the caller's
+// wallet and audits it.
+func (h *Handler) changeScheduledTransfer(w http.ResponseWriter, r *http.Request, operation, message string,
+	change func(ctx context.Context, walletID string, id uuid.UUID) (*models.ScheduledTransfer, error)) {
+	userClaims := r.Context().Value("user_claims").(*jwt.JwtClaims)
+	wallet, err := h.svc.GetWalletByUserId(r.Context(), userClaims.ID)
+	if err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+	walletID := wallet.ID
+	id, err := idParam(r)
+	if err != nil {
+		utils.ErrorJSON(w, err, customErrors.ResolveHTTPStatus(err))
+		return
+	}
+	st, err := change(r.Context(), walletID, id)
+	if err != nil {
+		utils.ErrorJSON(w, err, customErrors.ResolveHTTPStatus(err))
+		return
+	}
+	h.logAudit(r, walletID, operation, st.ID.String())
+
+	jsonResponse := utils.JSONResponse{
+		Error:   false,
+		Data:    st,
+		Message: message,
+	}
+
+	utils.WriteJson(w, http.StatusOK, jsonResponse)
+}
@@ -0,0 +1,200 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/google/uuid"
+	customErrors "github.com/toluhikay/fx-exchange/internal/errors"
+	"github.com/toluhikay/fx-exchange/internal/models"
+	"github.com/toluhikay/fx-exchange/pkg/jwt"
+	"github.com/toluhikay/fx-exchange/pkg/utils"
+)
+
+// CreateSwapPlan sets up a recurring swap. The pin is checked once here since
+// nobody is around to enter it when the plan runs.
+func (h *Handler) CreateSwapPlan(w http.ResponseWriter, r *http.Request) {
+	userClaims := r.Context().Value("user_claims").(*jwt.JwtClaims)
+	wallet, err := h.svc.GetWalletByUserId(r.Context(), userClaims.ID)
+	if err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+	walletID := wallet.ID
+	var req models.SwapPlanRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+
+	if !h.verifyPin(w, r, walletID, req.Pin) {
+		return
+	}
+	plan, err := h.svc.CreateSwapPlan(r.Context(), userClaims.ID, walletID, req)
+	if err != nil {
+		utils.ErrorJSON(w, err, customErrors.ResolveHTTPStatus(err))
+		return
+	}
+	h.logAudit(r, walletID, "swap_plan_created", fmt.Sprintf("%s %s %.4f to %s (%s)", plan.ID, plan.FromCurrency, plan.Amount, plan.ToCurrency, plan.Frequency))
+
+	jsonResponse := utils.JSONResponse{
+		Error:   false,
+		Data:    plan,
+		Message: "swap plan created",
+	}
+
+	utils.WriteJson(w, http.StatusCreated, jsonResponse)
+}
+
+func (h *Handler) ListSwapPlans(w http.ResponseWriter, r *http.Request) {
+	userClaims := r.Context().Value("user_claims").(*jwt.JwtClaims)
+	wallet, err := h.svc.GetWalletByUserId(r.Context(), userClaims.ID)
+	if err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+	limit, offset := pageParams(r)
+	plans, err := h.svc.ListSwapPlans(r.Context(), wallet.ID, limit, offset)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	jsonResponse := utils.JSONResponse{
+		Error:   false,
+		Data:    plans,
+		Message: "success",
+	}
+
+	utils.WriteJson(w, http.StatusOK, jsonResponse)
+}
+
+func (h *Handler) GetSwapPlan(w http.ResponseWriter, r *http.Request) {
+	userClaims := r.Context().Value("user_claims").(*jwt.JwtClaims)
+	wallet, err := h.svc.GetWalletByUserId(r.Context(), userClaims.ID)
+	if err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+	id, err := idParam(r)
+	if err != nil {
+		utils.ErrorJSON(w, err, customErrors.ResolveHTTPStatus(err))
+		return
+	}
+	plan, err := h.svc.GetSwapPlan(r.Context(), wallet.ID, id)
+	if err != nil {
+		utils.ErrorJSON(w, err, customErrors.ResolveHTTPStatus(err))
+		return
+	}
+
+	jsonResponse := utils.JSONResponse{
+		Error:   false,
+		Data:    plan,
+		Message: "success",
+	}
+
+	utils.WriteJson(w, http.StatusOK, jsonResponse)
+}
+
+// ListSwapPlanRuns returns the execution report of each run of a plan.
+func (h *Handler) ListSwapPlanRuns(w http.ResponseWriter, r *http.Request) {
+	userClaims := r.Context().Value("user_claims").(*jwt.JwtClaims)
+	wallet, err := h.svc.GetWalletByUserId(r.Context(), userClaims.ID)
+	if err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+	id, err := idParam(r)
+	if err != nil {
+		utils.ErrorJSON(w, err, customErrors.ResolveHTTPStatus(err))
+		return
+	}
+	limit, offset := pageParams(r)
+	runs, err := h.svc.ListSwapPlanRuns(r.Context(), wallet.ID, id, limit, offset)
+	if err != nil {
+		utils.ErrorJSON(w, err, customErrors.ResolveHTTPStatus(err))
+		return
+	}
+
+	jsonResponse := utils.JSONResponse{
+		Error:   false,
+		Data:    runs,
+		Message: "success",
+	}
+
+	utils.WriteJson(w, http.StatusOK, jsonResponse)
+}
+
+// GetSwapPlanSummary returns the totals and average rate of a plan.
+func (h *Handler) GetSwapPlanSummary(w http.ResponseWriter, r *http.Request) {
+	userClaims := r.Context().Value("user_claims").(*jwt.JwtClaims)
+	wallet, err := h.svc.GetWalletByUserId(r.Context(), userClaims.ID)
+	if err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+	id, err := idParam(r)
+	if err != nil {
+		utils.ErrorJSON(w, err, customErrors.ResolveHTTPStatus(err))
+		return
+	}
+	summary, err := h.svc.GetSwapPlanSummary(r.Context(), wallet.ID, id)
+	if err != nil {
+		utils.ErrorJSON(w, err, customErrors.ResolveHTTPStatus(err))
+		return
+	}
+
+	jsonResponse := utils.JSONResponse{
+		Error:   false,
+		Data:    summary,
+		Message: "success",
+	}
+
+	utils.WriteJson(w, http.StatusOK, jsonResponse)
+}
+
+func (h *Handler) PauseSwapPlan(w http.ResponseWriter, r *http.Request) {
+	h.changeSwapPlan(w, r, "swap_plan_paused", "swap plan paused", h.svc.PauseSwapPlan)
+}
+
+func (h *Handler) ResumeSwapPlan(w http.ResponseWriter, r *http.Request) {
+	h.changeSwapPlan(w, r, "swap_plan_resumed", "swap plan resumed", h.svc.ResumeSwapPlan)
+}
+
+func (h *Handler) CancelSwapPlan(w http.ResponseWriter, r *http.Request) {
+	h.changeSwapPlan(w, r, "swap_plan_cancelled", "swap plan cancelled", h.svc.CancelSwapPlan)
+}
+
+// changeSwapPlan runs one of the plan actions on the caller's wallet and
+// audits it.
+func (h *Handler) changeSwapPlan(w http.ResponseWriter, r *http.Request, operation, message string,
+	change func(ctx context.Context, walletID string, id uuid.UUID) (*models.SwapPlan, error)) {
+	userClaims := r.Context().Value("user_claims").(*jwt.JwtClaims)
+	wallet, err := h.svc.GetWalletByUserId(r.Context(), userClaims.ID)
+	if err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+	walletID := wallet.ID
+	id, err := idParam(r)
+	if err != nil {
+		utils.ErrorJSON(w, err, customErrors.ResolveHTTPStatus(err))
+		return
+	}
+	plan, err := change(r.Context(), walletID, id)
+	if err != nil {
+		utils.ErrorJSON(w, err, customErrors.ResolveHTTPStatus(err))
+		return
+	}
+	h.logAudit(r, walletID, operation, plan.ID.String())
+
+	jsonResponse := utils.JSONResponse{
+		Error:   false,
+		Data:    plan,
+		Message: message,
+	}
+
+	utils.WriteJson(w, http.StatusOK, jsonResponse)
+}
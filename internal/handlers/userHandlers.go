@@ -3,8 +3,12 @@ package handlers
 import (
 	"errors"
 	"fmt"
+	"math"
 	"net/http"
+	"strconv"
 
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
 	"github.com/toluhikay/fx-exchange/internal/dtos"
 	customErrors "github.com/toluhikay/fx-exchange/internal/errors"
 	"github.com/toluhikay/fx-exchange/internal/models"
@@ -16,12 +20,14 @@ import (
 type UserHandler struct {
 	userService services.UserServiceImpl
 	auth        jwt.Auth
+	audit       *services.AuditService
 }
 
-func NewUserHandler(service services.UserServiceImpl, auth jwt.Auth) *UserHandler {
+func NewUserHandler(service services.UserServiceImpl, auth jwt.Auth, audit *services.AuditService) *UserHandler {
 	return &UserHandler{
 		userService: service,
 		auth:        auth,
+		audit:       audit,
 	}
 }
 
@@ -33,22 +39,70 @@ func (uh *UserHandler) CreateUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	user, err := uh.userService.CreateUser(r.Context(), req)
+	user, token, err := uh.userService.CreateUser(r.Context(), req)
 	if err != nil {
-		utils.ErrorJSON(w, err, http.StatusBadRequest)
+		utils.ErrorJSON(w, err, customErrors.ResolveHTTPStatus(err))
 		return
 	}
 
 	response := utils.JSONResponse{
 		Error:   false,
-		Message: "sign up successful",
-		Data:    user,
+		Message: "sign up successful, check your email for the verification code",
+		Data: struct {
+			VerificationToken string       `json:"verification_token"`
+			Data              *models.User `json:"data"`
+		}{
+			VerificationToken: token,
+			Data:              user,
+		},
 	}
 
 	utils.WriteJson(w, http.StatusCreated, response)
 
 }
 
+func (uh *UserHandler) VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	var req dtos.VerifyEmail
+	if err := utils.ReadJSON(w, r, &req); err != nil {
+		utils.ErrorJSON(w, errors.Join(customErrors.ErrInvalidPayload, err))
+		return
+	}
+
+	user, err := uh.userService.VerifyEmail(r.Context(), req)
+	if err != nil {
+		utils.ErrorJSON(w, err, customErrors.ResolveHTTPStatus(err))
+		return
+	}
+
+	response := utils.JSONResponse{
+		Error:   false,
+		Message: "email verified successfully",
+		Data:    user,
+	}
+
+	utils.WriteJson(w, http.StatusOK, response)
+}
+
+func (uh *UserHandler) ResendVerificationOtp(w http.ResponseWriter, r *http.Request) {
+	var req dtos.ResendOtp
+	if err := utils.ReadJSON(w, r, &req); err != nil {
+		utils.ErrorJSON(w, errors.Join(customErrors.ErrInvalidPayload, err))
+		return
+	}
+
+	if err := uh.userService.ResendVerificationOtp(r.Context(), req); err != nil {
+		utils.ErrorJSON(w, err, customErrors.ResolveHTTPStatus(err))
+		return
+	}
+
+	response := utils.JSONResponse{
+		Error:   false,
+		Message: "if the email is registered and unverified, a new verification code has been sent",
+	}
+
+	utils.WriteJson(w, http.StatusOK, response)
+}
+
 func (uh *UserHandler) GetUserById(w http.ResponseWriter, r *http.Request) {
 	claims := r.Context().Value("user_claims").(*jwt.JwtClaims)
 
@@ -84,18 +138,68 @@ func (uh *UserHandler) UserLogin(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	user, err := uh.userService.GetUserByEmail(r.Context(), req)
+	user, err := uh.userService.UserLogin(r.Context(), req.Email, req.Password, utils.ClientIP(r))
 	if err != nil {
 		fmt.Println(err, "at log - 01")
+		var retryErr *customErrors.RetryAfterError
+		if errors.As(err, &retryErr) {
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryErr.RetryAfter.Seconds()))))
+		}
+		utils.ErrorJSON(w, err, customErrors.ResolveHTTPStatus(err))
+		return
+	}
+
+	// users with two-factor enabled get a challenge token instead of a session
+	if user.IsMfaEnabled() {
+		mfaToken, err := uh.auth.GenerateMfaChallengeToken(*user)
+		if err != nil {
+			utils.ErrorJSON(w, customErrors.ErrInternalServer, http.StatusInternalServerError)
+			return
+		}
+
+		response := utils.JSONResponse{
+			Error:   false,
+			Message: "two-factor authentication required",
+			Data: map[string]any{
+				"mfa_required": true,
+				"mfa_token":    mfaToken,
+			},
+		}
+
+		utils.WriteJson(w, http.StatusAccepted, response)
+		return
+	}
+
+	uh.writeLoginResponse(w, user)
+}
+
+func (uh *UserHandler) MfaLogin(w http.ResponseWriter, r *http.Request) {
+	var req dtos.MfaLogin
+	if err := utils.ReadJSON(w, r, &req); err != nil {
+		utils.ErrorJSON(w, errors.Join(customErrors.ErrInvalidPayload, err))
+		return
+	}
+
+	claims, err := uh.auth.VerifyMfaChallengeToken(req.MfaToken)
+	if err != nil {
+		utils.ErrorJSON(w, err, http.StatusUnauthorized)
+		return
+	}
+
+	user, err := uh.userService.CompleteMfaLogin(r.Context(), claims.ID, claims.SessionVersion, req.Code)
+	if err != nil {
 		utils.ErrorJSON(w, err, customErrors.ResolveHTTPStatus(err))
 		return
 	}
 
-	fmt.Println(user)
+	uh.writeLoginResponse(w, user)
+}
 
+func (uh *UserHandler) writeLoginResponse(w http.ResponseWriter, user *models.User) {
 	tokenPairs, err := uh.auth.GenerateTokens(*user)
 	if err != nil {
-		utils.ErrorJSON(w, customErrors.ErrInternalServer)
+		utils.ErrorJSON(w, customErrors.ErrInternalServer, http.StatusInternalServerError)
+		return
 	}
 
 	response := utils.JSONResponse{
@@ -115,3 +219,251 @@ func (uh *UserHandler) UserLogin(w http.ResponseWriter, r *http.Request) {
 	utils.WriteJson(w, http.StatusOK, response)
 
 }
+
+func (uh *UserHandler) ForgotPassword(w http.ResponseWriter, r *http.Request) {
+	var req dtos.ForgotPassword
+	if err := utils.ReadJSON(w, r, &req); err != nil {
+		utils.ErrorJSON(w, errors.Join(customErrors.ErrInvalidPayload, err))
+		return
+	}
+
+	if err := uh.userService.ForgotPassword(r.Context(), req); err != nil {
+		utils.ErrorJSON(w, err, customErrors.ResolveHTTPStatus(err))
+		return
+	}
+
+	response := utils.JSONResponse{
+		Error:   false,
+		Message: "if the email is registered, a password reset link has been sent",
+	}
+
+	utils.WriteJson(w, http.StatusOK, response)
+}
+
+func (uh *UserHandler) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	var req dtos.ResetPassword
+	if err := utils.ReadJSON(w, r, &req); err != nil {
+		utils.ErrorJSON(w, errors.Join(customErrors.ErrInvalidPayload, err))
+		return
+	}
+
+	if err := uh.userService.ResetPassword(r.Context(), req); err != nil {
+		utils.ErrorJSON(w, err, customErrors.ResolveHTTPStatus(err))
+		return
+	}
+
+	response := utils.JSONResponse{
+		Error:   false,
+		Message: "password reset successful, please sign in",
+	}
+
+	utils.WriteJson(w, http.StatusOK, response)
+}
+
+func (uh *UserHandler) ChangePassword(w http.ResponseWriter, r *http.Request) {
+	claims := r.Context().Value("user_claims").(*jwt.JwtClaims)
+
+	var req dtos.ChangePassword
+	if err := utils.ReadJSON(w, r, &req); err != nil {
+		utils.ErrorJSON(w, errors.Join(customErrors.ErrInvalidPayload, err))
+		return
+	}
+
+	user, err := uh.userService.ChangePassword(r.Context(), claims.ID, req)
+	if err != nil {
+		utils.ErrorJSON(w, err, customErrors.ResolveHTTPStatus(err))
+		return
+	}
+
+	// every earlier session was revoked, hand the caller a fresh pair
+	tokenPairs, err := uh.auth.GenerateTokens(*user)
+	if err != nil {
+		utils.ErrorJSON(w, customErrors.ErrInternalServer, http.StatusInternalServerError)
+		return
+	}
+
+	response := utils.JSONResponse{
+		Error:   false,
+		Message: "password changed successfully",
+		Data:    tokenPairs,
+	}
+
+	utils.WriteJson(w, http.StatusOK, response)
+}
+
+func (uh *UserHandler) EnrollMfa(w http.ResponseWriter, r *http.Request) {
+	claims := r.Context().Value("user_claims").(*jwt.JwtClaims)
+
+	enrolment, err := uh.userService.EnrollMfa(r.Context(), claims.ID)
+	if err != nil {
+		utils.ErrorJSON(w, err, customErrors.ResolveHTTPStatus(err))
+		return
+	}
+
+	response := utils.JSONResponse{
+		Error:   false,
+		Message: "scan the provisioning uri and confirm with a code to enable two-factor authentication",
+		Data:    enrolment,
+	}
+
+	utils.WriteJson(w, http.StatusOK, response)
+}
+
+func (uh *UserHandler) ActivateMfa(w http.ResponseWriter, r *http.Request) {
+	claims := r.Context().Value("user_claims").(*jwt.JwtClaims)
+
+	var req dtos.MfaCode
+	if err := utils.ReadJSON(w, r, &req); err != nil {
+		utils.ErrorJSON(w, errors.Join(customErrors.ErrInvalidPayload, err))
+		return
+	}
+
+	if err := uh.userService.ActivateMfa(r.Context(), claims.ID, req.Code); err != nil {
+		utils.ErrorJSON(w, err, customErrors.ResolveHTTPStatus(err))
+		return
+	}
+
+	response := utils.JSONResponse{
+		Error:   false,
+		Message: "two-factor authentication enabled",
+	}
+
+	utils.WriteJson(w, http.StatusOK, response)
+}
+
+func (uh *UserHandler) DisableMfa(w http.ResponseWriter, r *http.Request) {
+	claims := r.Context().Value("user_claims").(*jwt.JwtClaims)
+
+	var req dtos.DisableMfa
+	if err := utils.ReadJSON(w, r, &req); err != nil {
+		utils.ErrorJSON(w, errors.Join(customErrors.ErrInvalidPayload, err))
+		return
+	}
+
+	if err := uh.userService.DisableMfa(r.Context(), claims.ID, req); err != nil {
+		utils.ErrorJSON(w, err, customErrors.ResolveHTTPStatus(err))
+		return
+	}
+
+	response := utils.JSONResponse{
+		Error:   false,
+		Message: "two-factor authentication disabled",
+	}
+
+	utils.WriteJson(w, http.StatusOK, response)
+}
+
+func (uh *UserHandler) SetPin(w http.ResponseWriter, r *http.Request) {
+	claims := r.Context().Value("user_claims").(*jwt.JwtClaims)
+
+	var req dtos.SetPin
+	if err := utils.ReadJSON(w, r, &req); err != nil {
+		utils.ErrorJSON(w, errors.Join(customErrors.ErrInvalidPayload, err))
+		return
+	}
+
+	if err := uh.userService.SetPin(r.Context(), claims.ID, req); err != nil {
+		utils.ErrorJSON(w, err, customErrors.ResolveHTTPStatus(err))
+		return
+	}
+	uh.audit.Record(r.Context(), newAuditLog(r, "", "pin_set", ""))
+
+	response := utils.JSONResponse{
+		Error:   false,
+		Message: "transaction pin set successfully",
+	}
+
+	utils.WriteJson(w, http.StatusCreated, response)
+}
+
+func (uh *UserHandler) ChangePin(w http.ResponseWriter, r *http.Request) {
+	claims := r.Context().Value("user_claims").(*jwt.JwtClaims)
+
+	var req dtos.ChangePin
+	if err := utils.ReadJSON(w, r, &req); err != nil {
+		utils.ErrorJSON(w, errors.Join(customErrors.ErrInvalidPayload, err))
+		return
+	}
+
+	if err := uh.userService.ChangePin(r.Context(), claims.ID, req); err != nil {
+		if errors.Is(err, customErrors.ErrInvalidPin) || errors.Is(err, customErrors.ErrPinLocked) {
+			uh.audit.Record(r.Context(), newAuditLog(r, "", "pin_verification_failed", err.Error()))
+		}
+		utils.ErrorJSON(w, err, customErrors.ResolveHTTPStatus(err))
+		return
+	}
+	uh.audit.Record(r.Context(), newAuditLog(r, "", "pin_changed", ""))
+
+	response := utils.JSONResponse{
+		Error:   false,
+		Message: "transaction pin changed successfully",
+	}
+
+	utils.WriteJson(w, http.StatusOK, response)
+}
+
+func (uh *UserHandler) CreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	claims := r.Context().Value("user_claims").(*jwt.JwtClaims)
+
+	var req dtos.CreateAPIKey
+	if err := utils.ReadJSON(w, r, &req); err != nil {
+		utils.ErrorJSON(w, errors.Join(customErrors.ErrInvalidPayload, err))
+		return
+	}
+
+	created, err := uh.userService.CreateAPIKey(r.Context(), claims.ID, req)
+	if err != nil {
+		utils.ErrorJSON(w, err, customErrors.ResolveHTTPStatus(err))
+		return
+	}
+	uh.audit.Record(r.Context(), newAuditLog(r, "", "api_key_created", created.APIKey.ID.String()))
+
+	response := utils.JSONResponse{
+		Error:   false,
+		Message: "api key created, store it now as it will not be shown again",
+		Data:    created,
+	}
+
+	utils.WriteJson(w, http.StatusCreated, response)
+}
+
+func (uh *UserHandler) ListAPIKeys(w http.ResponseWriter, r *http.Request) {
+	claims := r.Context().Value("user_claims").(*jwt.JwtClaims)
+
+	keys, err := uh.userService.ListAPIKeys(r.Context(), claims.ID)
+	if err != nil {
+		utils.ErrorJSON(w, err, customErrors.ResolveHTTPStatus(err))
+		return
+	}
+
+	response := utils.JSONResponse{
+		Error:   false,
+		Message: "success",
+		Data:    keys,
+	}
+
+	utils.WriteJson(w, http.StatusOK, response)
+}
+
+func (uh *UserHandler) RevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	claims := r.Context().Value("user_claims").(*jwt.JwtClaims)
+
+	keyID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		utils.ErrorJSON(w, customErrors.ErrInvalidPayload, http.StatusBadRequest)
+		return
+	}
+
+	if err := uh.userService.RevokeAPIKey(r.Context(), claims.ID, keyID); err != nil {
+		utils.ErrorJSON(w, err, customErrors.ResolveHTTPStatus(err))
+		return
+	}
+	uh.audit.Record(r.Context(), newAuditLog(r, "", "api_key_revoked", keyID.String()))
+
+	response := utils.JSONResponse{
+		Error:   false,
+		Message: "api key revoked",
+	}
+
+	utils.WriteJson(w, http.StatusOK, response)
+}
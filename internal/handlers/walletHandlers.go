@@ -2,10 +2,12 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"strings"
 
+	customErrors "github.com/toluhikay/fx-exchange/internal/errors"
 	"github.com/toluhikay/fx-exchange/internal/models"
 	"github.com/toluhikay/fx-exchange/internal/services"
 	"github.com/toluhikay/fx-exchange/pkg/jwt"
@@ -15,10 +17,11 @@ import (
 type Handler struct {
 	svc     *services.Service
 	userSvc *services.UserServiceImpl
+	audit   *services.AuditService
 }
 
-func NewHandler(svc *services.Service, userSvc *services.UserServiceImpl) *Handler {
-	return &Handler{svc: svc, userSvc: userSvc}
+func NewHandler(svc *services.Service, userSvc *services.UserServiceImpl, audit *services.AuditService) *Handler {
+	return &Handler{svc: svc, userSvc: userSvc, audit: audit}
 }
 
 func (h *Handler) CreateWallet(w http.ResponseWriter, r *http.Request) {
@@ -81,7 +84,7 @@ func (h *Handler) Deposit(w http.ResponseWriter, r *http.Request) {
 	}
 	err = h.svc.Deposit(r.Context(), walletID, req.Currency, req.Amount)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		http.Error(w, err.Error(), customErrors.ResolveHTTPStatus(err))
 		return
 	}
 	jsonResponse := utils.JSONResponse{
@@ -89,7 +92,7 @@ func (h *Handler) Deposit(w http.ResponseWriter, r *http.Request) {
 		Error:   false,
 		Data:    nil,
 	}
-	h.logAudit(r, walletID)
+	h.logAudit(r, walletID, "deposit", "")
 	utils.WriteJson(w, http.StatusAccepted, jsonResponse)
 }
 
@@ -106,13 +109,16 @@ func (h *Handler) Swap(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Invalid request", http.StatusBadRequest)
 		return
 	}
+	if !h.verifyPin(w, r, walletID, req.Pin) {
+		return
+	}
 	convertedAmount, rate, err := h.svc.Swap(r.Context(), walletID, req.FromCurrency, req.ToCurrency, req.Amount)
 	if err != nil {
 		fmt.Println(err)
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		utils.ErrorJSON(w, err, customErrors.ResolveHTTPStatus(err))
 		return
 	}
-	// h.logAudit(r, walletID)
+	h.logAudit(r, walletID, "swap", fmt.Sprintf("%s->%s %.4f", req.FromCurrency, req.ToCurrency, req.Amount))
 
 	data := map[string]any{
 		"converted_amount": convertedAmount,
@@ -141,12 +147,19 @@ func (h *Handler) Transfer(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Invalid request", http.StatusBadRequest)
 		return
 	}
+
+	if !h.verifyStepUp(w, r, req.Currency, req.Amount, req.MfaCode) {
+		return
+	}
+	if !h.verifyPin(w, r, walletID, req.Pin) {
+		return
+	}
 	convertedAmount, rate, err := h.svc.Transfer(r.Context(), walletID, req.ReceiverID, req.Currency, req.Amount)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		utils.ErrorJSON(w, err, customErrors.ResolveHTTPStatus(err))
 		return
 	}
-	// h.logAudit(r, walletID)
+	h.logAudit(r, walletID, "transfer", fmt.Sprintf("%s %.4f to %s", req.Currency, req.Amount, req.ReceiverID))
 
 	data := map[string]any{
 		"converted_amount": convertedAmount,
@@ -186,9 +199,330 @@ func (h *Handler) GetTransactionHistory(w http.ResponseWriter, r *http.Request)
 	utils.WriteJson(w, http.StatusAccepted, jsonResponse)
 }
 
-func (h *Handler) logAudit(r *http.Request, walletID string) {
-	// Simplified audit logging
-	// Add real implementation with IP, device info, etc.
+func (h *Handler) Withdraw(w http.ResponseWriter, r *http.Request) {
+	userClaims := r.Context().Value("user_claims").(*jwt.JwtClaims)
+	wallet, err := h.svc.GetWalletByUserId(r.Context(), userClaims.ID)
+	if err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+	walletID := wallet.ID
+	var req models.WithdrawRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+	if !h.verifyPin(w, r, walletID, req.Pin) {
+		return
+	}
+	err = h.svc.Withdraw(r.Context(), walletID, req.Currency, req.Destination, req.Amount)
+	if err != nil {
+		http.Error(w, err.Error(), customErrors.ResolveHTTPStatus(err))
+		return
+	}
+	h.logAudit(r, walletID, "withdrawal", fmt.Sprintf("%s %.4f to %s", req.Currency, req.Amount, req.Destination))
+
+	jsonResponse := utils.JSONResponse{
+		Error:   false,
+		Data:    nil,
+		Message: "withdrawal successful",
+	}
+
+	utils.WriteJson(w, http.StatusAccepted, jsonResponse)
+}
+
+// CloseWallet closes the caller's wallet for good, paying out any remaining
+// balance to the given destination.
+func (h *Handler) CloseWallet(w http.ResponseWriter, r *http.Request) {
+	userClaims := r.Context().Value("user_claims").(*jwt.JwtClaims)
+	wallet, err := h.svc.GetWalletByUserId(r.Context(), userClaims.ID)
+	if err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+	walletID := wallet.ID
+	var req models.CloseWalletRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+	if !h.verifyPin(w, r, walletID, req.Pin) {
+		return
+	}
+	event, err := h.svc.CloseWallet(r.Context(), walletID, userClaims.ID, req.PayoutDestination)
+	if err != nil {
+		http.Error(w, err.Error(), customErrors.ResolveHTTPStatus(err))
+		return
+	}
+	h.logAudit(r, walletID, "wallet_closed", req.PayoutDestination)
+
+	jsonResponse := utils.JSONResponse{
+		Error:   false,
+		Data:    event,
+		Message: "wallet closed",
+	}
+
+	utils.WriteJson(w, http.StatusAccepted, jsonResponse)
+}
+
+func (h *Handler) GetSpendingControls(w http.ResponseWriter, r *http.Request) {
+	userClaims := r.Context().Value("user_claims").(*jwt.JwtClaims)
+	wallet, err := h.svc.GetWalletByUserId(r.Context(), userClaims.ID)
+	if err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+	controls, err := h.svc.GetSpendingControls(r.Context(), wallet.ID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	jsonResponse := utils.JSONResponse{
+		Error:   false,
+		Data:    controls,
+		Message: "success",
+	}
+
+	utils.WriteJson(w, http.StatusOK, jsonResponse)
+}
+
+// SetSpendingControls replaces the caller's spending controls. The pin is
+// required so a stolen session cannot quietly lift them.
+func (h *Handler) SetSpendingControls(w http.ResponseWriter, r *http.Request) {
+	userClaims := r.Context().Value("user_claims").(*jwt.JwtClaims)
+	wallet, err := h.svc.GetWalletByUserId(r.Context(), userClaims.ID)
+	if err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+	walletID := wallet.ID
+	var req models.SpendingControlsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+	if !h.verifyPin(w, r, walletID, req.Pin) {
+		return
+	}
+	controls, err := h.svc.SetSpendingControls(r.Context(), walletID, req.SpendingControls)
+	if err != nil {
+		utils.ErrorJSON(w, err, customErrors.ResolveHTTPStatus(err))
+		return
+	}
+	body, _ := json.Marshal(controls)
+	h.logAudit(r, walletID, "spending_controls_updated", string(body))
+
+	jsonResponse := utils.JSONResponse{
+		Error:   false,
+		Data:    controls,
+		Message: "spending controls updated",
+	}
+
+	utils.WriteJson(w, http.StatusOK, jsonResponse)
+}
+
+// GetApprovals lists the wallet's transfers that needed sign-off.
+func (h *Handler) GetApprovals(w http.ResponseWriter, r *http.Request) {
+	userClaims := r.Context().Value("user_claims").(*jwt.JwtClaims)
+	wallet, err := h.svc.GetWalletByUserId(r.Context(), userClaims.ID)
+	if err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+	limit, offset := pageParams(r)
+	approvals, err := h.svc.ListWalletApprovals(r.Context(), wallet.ID, limit, offset)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	jsonResponse := utils.JSONResponse{
+		Error:   false,
+		Data:    approvals,
+		Message: "success",
+	}
+
+	utils.WriteJson(w, http.StatusOK, jsonResponse)
+}
+
+// ListHolds lists the wallet's holds, optionally filtered by ?status=.
+func (h *Handler) ListHolds(w http.ResponseWriter, r *http.Request) {
+	userClaims := r.Context().Value("user_claims").(*jwt.JwtClaims)
+	wallet, err := h.svc.GetWalletByUserId(r.Context(), userClaims.ID)
+	if err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+	limit, offset := pageParams(r)
+	holds, err := h.svc.ListHolds(r.Context(), wallet.ID, r.URL.Query().Get("status"), limit, offset)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	jsonResponse := utils.JSONResponse{
+		Error:   false,
+		Data:    holds,
+		Message: "success",
+	}
+
+	utils.WriteJson(w, http.StatusOK, jsonResponse)
+}
+
+// PlaceHold reserves funds for a merchant without moving them.
+func (h *Handler) PlaceHold(w http.ResponseWriter, r *http.Request) {
+	userClaims := r.Context().Value("user_claims").(*jwt.JwtClaims)
+	wallet, err := h.svc.GetWalletByUserId(r.Context(), userClaims.ID)
+	if err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+	walletID := wallet.ID
+	var req models.PlaceHoldRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+	if !h.verifyPin(w, r, walletID, req.Pin) {
+		return
+	}
+	hold, err := h.svc.PlaceHold(r.Context(), walletID, req)
+	if err != nil {
+		utils.ErrorJSON(w, err, customErrors.ResolveHTTPStatus(err))
+		return
+	}
+	h.logAudit(r, walletID, "hold_placed", fmt.Sprintf("%s %s %.4f", hold.ID, hold.Currency, hold.Amount))
+
+	jsonResponse := utils.JSONResponse{
+		Error:   false,
+		Data:    hold,
+		Message: "hold placed",
+	}
+
+	utils.WriteJson(w, http.StatusCreated, jsonResponse)
+}
+
+// CaptureHold settles part or all of a hold.
+func (h *Handler) CaptureHold(w http.ResponseWriter, r *http.Request) {
+	userClaims := r.Context().Value("user_claims").(*jwt.JwtClaims)
+	wallet, err := h.svc.GetWalletByUserId(r.Context(), userClaims.ID)
+	if err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+	walletID := wallet.ID
+	id, err := idParam(r)
+	if err != nil {
+		utils.ErrorJSON(w, err, customErrors.ResolveHTTPStatus(err))
+		return
+	}
+	var req models.CaptureHoldRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+	held, err := h.svc.GetHold(r.Context(), walletID, id)
+	if err != nil {
+		utils.ErrorJSON(w, err, customErrors.ResolveHTTPStatus(err))
+		return
+	}
+	amount := req.Amount
+	if amount == 0 {
+		amount = held.Remaining()
+	}
+	if !h.verifyStepUp(w, r, held.Currency, amount, req.MfaCode) {
+		return
+	}
+	if !h.verifyPin(w, r, walletID, req.Pin) {
+		return
+	}
+	hold, err := h.svc.CaptureHold(r.Context(), walletID, id, req)
+	if err != nil {
+		utils.ErrorJSON(w, err, customErrors.ResolveHTTPStatus(err))
+		return
+	}
+	h.logAudit(r, walletID, "hold_captured", fmt.Sprintf("%s %s %.4f to %s", hold.ID, hold.Currency, hold.Captured, req.Destination))
+
+	jsonResponse := utils.JSONResponse{
+		Error:   false,
+		Data:    hold,
+		Message: "hold captured",
+	}
+
+	utils.WriteJson(w, http.StatusOK, jsonResponse)
+}
+
+// ReleaseHold gives the remaining funds of a hold back to the available
+// balance.
+func (h *Handler) ReleaseHold(w http.ResponseWriter, r *http.Request) {
+	userClaims := r.Context().Value("user_claims").(*jwt.JwtClaims)
+	wallet, err := h.svc.GetWalletByUserId(r.Context(), userClaims.ID)
+	if err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+	walletID := wallet.ID
+	id, err := idParam(r)
+	if err != nil {
+		utils.ErrorJSON(w, err, customErrors.ResolveHTTPStatus(err))
+		return
+	}
+	hold, err := h.svc.ReleaseHold(r.Context(), walletID, id)
+	if err != nil {
+		utils.ErrorJSON(w, err, customErrors.ResolveHTTPStatus(err))
+		return
+	}
+	h.logAudit(r, walletID, "hold_released", hold.ID.String())
+
+	jsonResponse := utils.JSONResponse{
+		Error:   false,
+		Data:    hold,
+		Message: "hold released",
+	}
+
+	utils.WriteJson(w, http.StatusOK, jsonResponse)
+}
+
+// verifyStepUp writes the error response when an amount needs a second factor
+// the request does not carry. Large amounts need a fresh code from users who
+// enrolled; api keys are a separate credential that cannot answer a totp
+// challenge, so they are capped at the threshold instead.
+func (h *Handler) verifyStepUp(w http.ResponseWriter, r *http.Request, currency string, amount float64, code string) bool {
+	userClaims := r.Context().Value("user_claims").(*jwt.JwtClaims)
+	amountUSD, err := h.svc.USDValue(r.Context(), currency, amount)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return false
+	}
+	if _, viaAPIKey := r.Context().Value("api_key").(*models.APIKey); viaAPIKey {
+		err = h.userSvc.CapAPIKeyStepUp(amountUSD)
+	} else {
+		err = h.userSvc.VerifyStepUp(r.Context(), userClaims.ID, amountUSD, code)
+	}
+	if err != nil {
+		utils.ErrorJSON(w, err, customErrors.ResolveHTTPStatus(err))
+		return false
+	}
+	return true
+}
+
+// verifyPin writes the error response and audits the failure when the pin
+// does not authorise the request.
+func (h *Handler) verifyPin(w http.ResponseWriter, r *http.Request, walletID, pin string) bool {
+	userClaims := r.Context().Value("user_claims").(*jwt.JwtClaims)
+	if err := h.userSvc.VerifyPin(r.Context(), userClaims.ID, pin); err != nil {
+		if errors.Is(err, customErrors.ErrInvalidPin) || errors.Is(err, customErrors.ErrPinLocked) {
+			h.logAudit(r, walletID, "pin_verification_failed", err.Error())
+		}
+		utils.ErrorJSON(w, err, customErrors.ResolveHTTPStatus(err))
+		return false
+	}
+	return true
+}
+
+func (h *Handler) logAudit(r *http.Request, walletID, operation, body string) {
+	h.audit.Record(r.Context(), newAuditLog(r, walletID, operation, body))
 }
 
 func (h *Handler) GetBalances(w http.ResponseWriter, r *http.Request) {
@@ -6,7 +6,11 @@ import (
 	"net/http"
 
 	"github.com/gorilla/websocket"
+	customErrors "github.com/toluhikay/fx-exchange/internal/errors"
 	"github.com/toluhikay/fx-exchange/internal/fx"
+	"github.com/toluhikay/fx-exchange/internal/services"
+	"github.com/toluhikay/fx-exchange/pkg/jwt"
+	"github.com/toluhikay/fx-exchange/pkg/utils"
 )
 
 var upgrader = websocket.Upgrader{
@@ -19,10 +23,12 @@ var upgrader = websocket.Upgrader{
 
 type WebSocketHandler struct {
 	fxProvider fx.FXProvider
+	marketSvc  *services.MarketService
+	alertSvc   *services.AlertService
 }
 
-func NewWebSocketHandler(fxProvider fx.FXProvider) *WebSocketHandler {
-	return &WebSocketHandler{fxProvider: fxProvider}
+func NewWebSocketHandler(fxProvider fx.FXProvider, marketSvc *services.MarketService, alertSvc *services.AlertService) *WebSocketHandler {
+	return &WebSocketHandler{fxProvider: fxProvider, marketSvc: marketSvc, alertSvc: alertSvc}
 }
 
 func (h *WebSocketHandler) HandleFXRates(w http.ResponseWriter, r *http.Request) {
@@ -50,3 +56,99 @@ func (h *WebSocketHandler) HandleFXRates(w http.ResponseWriter, r *http.Request)
 		}
 	}
 }
+
+// HandleOrderBook streams the depth of the market in the market query
+// parameter, e.g. ?market=USDx/cNGN. It sends the current depth first and
+// then a fresh snapshot every time the book changes.
+func (h *WebSocketHandler) HandleOrderBook(w http.ResponseWriter, r *http.Request) {
+	market := r.URL.Query().Get("market")
+	depth, err := h.marketSvc.Depth(market, services.DepthLevels)
+	if err != nil {
+		utils.ErrorJSON(w, err, customErrors.ResolveHTTPStatus(err))
+		return
+	}
+	updates, unsubscribe, err := h.marketSvc.SubscribeDepth(market)
+	if err != nil {
+		utils.ErrorJSON(w, err, customErrors.ResolveHTTPStatus(err))
+		return
+	}
+	defer unsubscribe()
+
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		log.Printf("Failed to upgrade to WebSocket: %v", err)
+		return
+	}
+	defer conn.Close()
+
+	// the client never sends anything, but reading is how a closed
+	// connection is noticed
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			if _, _, err := conn.NextReader(); err != nil {
+				return
+			}
+		}
+	}()
+
+	if err := conn.WriteJSON(depth); err != nil {
+		log.Printf("Failed to send depth: %v", err)
+		return
+	}
+	for {
+		select {
+		case <-closed:
+			return
+		case <-r.Context().Done():
+			return
+		case depth := <-updates:
+			if err := conn.WriteJSON(depth); err != nil {
+				log.Printf("Failed to send depth: %v", err)
+				return
+			}
+		}
+	}
+}
+
+// HandleRateAlerts streams the caller's rate alerts as they fire. It sits
+// behind WebSocketAuth, so the token can come from the Authorization header
+// or the access_token query parameter.
+func (h *WebSocketHandler) HandleRateAlerts(w http.ResponseWriter, r *http.Request) {
+	claims := r.Context().Value("user_claims").(*jwt.JwtClaims)
+
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		log.Printf("Failed to upgrade to WebSocket: %v", err)
+		return
+	}
+	defer conn.Close()
+
+	events, unsubscribe := h.alertSvc.Subscribe(claims.ID)
+	defer unsubscribe()
+
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			if _, _, err := conn.NextReader(); err != nil {
+				return
+			}
+		}
+	}()
+
+	for {
+		select {
+		case <-closed:
+			return
+		case <-r.Context().Done():
+			return
+		case event := <-events:
+			if err := conn.WriteJSON(event); err != nil {
+				log.Printf("Failed to send rate alert: %v", err)
+				return
+			}
+		}
+	}
+}
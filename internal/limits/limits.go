@@ -0,0 +1,86 @@
+package limits
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	customError "github.com/toluhikay/fx-exchange/internal/errors"
+	"github.com/toluhikay/fx-exchange/internal/models"
+)
+
+// Cap bounds how much of one currency a wallet may move. A zero field means
+// that window is not capped.
+type Cap struct {
+	Single  float64 `json:"single"`
+	Daily   float64 `json:"daily"`
+	Monthly float64 `json:"monthly"`
+}
+
+// Table maps a kyc level to its caps per currency. Currencies missing from a
+// level are not capped.
+type Table map[string]map[string]Cap
+
+// Usage is what a wallet has already moved in the current day and month.
+type Usage struct {
+	Daily   float64
+	Monthly float64
+}
+
+func DefaultTable() Table {
+	return Table{
+		models.KycUnverified: {
+			"USDx": {Single: 100, Daily: 200, Monthly: 500},
+			"EURx": {Single: 100, Daily: 200, Monthly: 500},
+			"cNGN": {Single: 150000, Daily: 300000, Monthly: 750000},
+			"cXAF": {Single: 60000, Daily: 120000, Monthly: 300000},
+		},
+		models.KycBasic: {
+			"USDx": {Single: 2000, Daily: 5000, Monthly: 20000},
+			"EURx": {Single: 2000, Daily: 5000, Monthly: 20000},
+			"cNGN": {Single: 3000000, Daily: 7500000, Monthly: 30000000},
+			"cXAF": {Single: 1200000, Daily: 3000000, Monthly: 12000000},
+		},
+		models.KycFull: {
+			"USDx": {Single: 50000, Daily: 100000, Monthly: 1000000},
+			"EURx": {Single: 50000, Daily: 100000, Monthly: 1000000},
+			"cNGN": {Single: 75000000, Daily: 150000000, Monthly: 1500000000},
+			"cXAF": {Single: 30000000, Daily: 60000000, Monthly: 600000000},
+		},
+	}
+}
+
+// LoadFile reads a table written as
+// {"basic": {"USDx": {"single": 2000, "daily": 5000, "monthly": 20000}}}.
+func LoadFile(path string) (Table, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read limits file: %w", err)
+	}
+
+	var table Table
+	if err := json.Unmarshal(data, &table); err != nil {
+		return nil, fmt.Errorf("failed to parse limits file: %w", err)
+	}
+	return table, nil
+}
+
+// Check reports whether moving amount of currency fits the caps of level
+// given what was already used.
+func (t Table) Check(level, currency string, amount float64, usage Usage) error {
+	c, ok := t[level][currency]
+	if !ok {
+		return nil
+	}
+
+	if c.Single > 0 && amount > c.Single {
+		return fmt.Errorf("%w: single transaction limit for %s accounts is %.2f %s", customError.ErrLimitExceeded, level, c.Single, currency)
+	}
+	if c.Daily > 0 && usage.Daily+amount > c.Daily {
+		return fmt.Errorf("%w: daily limit for %s accounts is %.2f %s, %.2f remaining", customError.ErrLimitExceeded, level, c.Daily, currency, max(c.Daily-usage.Daily, 0))
+	}
+	if c.Monthly > 0 && usage.Monthly+amount > c.Monthly {
+		return fmt.Errorf("%w: monthly limit for %s accounts is %.2f %s, %.2f remaining", customError.ErrLimitExceeded, level, c.Monthly, currency, max(c.Monthly-usage.Monthly, 0))
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	DriverStdout = "stdout"
+	DriverFile   = "file"
+)
+
+type Config struct {
+	Driver   string
+	From     string
+	FilePath string
+}
+
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer delivers outgoing emails. Real providers (SMTP, SES, ...) only need to
+// satisfy this interface to be plugged in.
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+func NewMailer(cfg Config) (Mailer, error) {
+	switch cfg.Driver {
+	case "", DriverStdout:
+		return NewWriterMailer(os.Stdout, cfg.From), nil
+	case DriverFile:
+		if cfg.FilePath == "" {
+			return nil, fmt.Errorf("mailer file path is required for the %q driver", DriverFile)
+		}
+		return NewFileMailer(cfg.FilePath, cfg.From), nil
+	default:
+		return nil, fmt.Errorf("unsupported mailer driver: %s", cfg.Driver)
+	}
+}
+
+// WriterMailer writes every message to an io.Writer, which is handy for local
+// development where no mail provider is configured.
+type WriterMailer struct {
+	w    io.Writer
+	from string
+	mu   sync.Mutex
+}
+
+func NewWriterMailer(w io.Writer, from string) *WriterMailer {
+	return &WriterMailer{w: w, from: from}
+}
+
+func (m *WriterMailer) Send(ctx context.Context, msg Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	_, err := io.WriteString(m.w, format(m.from, msg))
+	return err
+}
+
+// FileMailer appends every message to a file on disk.
+type FileMailer struct {
+	path string
+	from string
+	mu   sync.Mutex
+}
+
+func NewFileMailer(path, from string) *FileMailer {
+	return &FileMailer{path: path, from: from}
+}
+
+func (m *FileMailer) Send(ctx context.Context, msg Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	f, err := os.OpenFile(m.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to open mail file: %w", err)
+	}
+	defer f.Close()
+
+	if _, err := f.WriteString(format(m.from, msg)); err != nil {
+		return fmt.Errorf("failed to write mail: %w", err)
+	}
+	return nil
+}
+
+func format(from string, msg Message) string {
+	var b strings.Builder
+	b.WriteString("----- mail -----\n")
+	fmt.Fprintf(&b, "Date: %s\n", time.Now().UTC().Format(time.RFC1123Z))
+	fmt.Fprintf(&b, "From: %s\n", from)
+	fmt.Fprintf(&b, "To: %s\n", msg.To)
+	fmt.Fprintf(&b, "Subject: %s\n\n", msg.Subject)
+	b.WriteString(msg.Body)
+	b.WriteString("\n----------------\n")
+	return b.String()
+}
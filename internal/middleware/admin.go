@@ -0,0 +1,32 @@
+package middleware
+
+import (
+	"net/http"
+
+	customErrors "github.com/toluhikay/fx-exchange/internal/errors"
+	"github.com/toluhikay/fx-exchange/internal/models"
+	"github.com/toluhikay/fx-exchange/pkg/jwt"
+	"github.com/toluhikay/fx-exchange/pkg/utils"
+)
+
+// RequirePermission must run after AuthRequired and only lets through users
+// whose role grants permission. The role comes from the token; changing a
+// role bumps the session version, so AuthRequired rejects stale tokens.
+func RequirePermission(permission string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			claims, ok := r.Context().Value("user_claims").(*jwt.JwtClaims)
+			if !ok {
+				utils.ErrorJSON(w, customErrors.ErrUnauthorized, http.StatusUnauthorized)
+				return
+			}
+
+			if !models.RoleHasPermission(claims.Role, permission) {
+				utils.ErrorJSON(w, customErrors.ErrForbidden, http.StatusForbidden)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
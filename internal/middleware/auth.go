@@ -4,19 +4,38 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"strings"
 
+	"github.com/google/uuid"
+	customErrors "github.com/toluhikay/fx-exchange/internal/errors"
+	"github.com/toluhikay/fx-exchange/internal/models"
 	"github.com/toluhikay/fx-exchange/pkg/jwt"
 	"github.com/toluhikay/fx-exchange/pkg/utils"
 )
 
+// UserLookup loads the account behind a set of claims for the checks that
+// cannot be answered from the token alone.
+type UserLookup interface {
+	GetUserById(ctx context.Context, id uuid.UUID) (*models.User, error)
+	ValidateSession(ctx context.Context, id uuid.UUID, sessionVersion int) error
+	AuthenticateAPIKey(ctx context.Context, rawKey, clientIP string) (*models.APIKey, *models.User, error)
+}
+
 type AuthMiddleware struct {
-	auth *jwt.Auth
+	auth  *jwt.Auth
+	users UserLookup
 }
 
 func NewMiddleware(auth *jwt.Auth) AuthMiddleware {
 	return AuthMiddleware{auth: auth}
 }
 
+// WithUserLookup returns a copy of the middleware able to run account checks.
+func (mw AuthMiddleware) WithUserLookup(users UserLookup) AuthMiddleware {
+	mw.users = users
+	return mw
+}
+
 type ContextUserClaims any
 
 func (mw AuthMiddleware) AuthRequired(next http.Handler) http.Handler {
@@ -28,10 +47,127 @@ func (mw AuthMiddleware) AuthRequired(next http.Handler) http.Handler {
 			return
 		}
 
+		// tokens issued before a password change are no longer honoured
+		if mw.users != nil {
+			if err := mw.users.ValidateSession(r.Context(), claims.ID, claims.SessionVersion); err != nil {
+				utils.ErrorJSON(w, err, customErrors.ResolveHTTPStatus(err))
+				return
+			}
+		}
+
+		var ctxClaimsKey ContextUserClaims = "user_claims"
+
+		ctx := context.WithValue(r.Context(), ctxClaimsKey, claims)
+
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// WebSocketAuth is AuthRequired for WebSocket routes. Browsers cannot set
+// headers on a WebSocket handshake, so the token may also be sent as
+// ?access_token=<jwt>.
+func (mw AuthMiddleware) WebSocketAuth(next http.Handler) http.Handler {
+	auth := mw.AuthRequired(next)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if token := r.URL.Query().Get("access_token"); token != "" && r.Header.Get("Authorization") == "" {
+			r.Header.Set("Authorization", "Bearer "+token)
+		}
+		auth.ServeHTTP(w, r)
+	})
+}
+
+// AuthRequiredOrAPIKey accepts either a Bearer JWT or an api key sent as
+// "Authorization: ApiKey <key>" or "X-API-Key: <key>". Routes behind it must
+// declare what api keys may do with RequireScope or refuse them with
+// SessionOnly.
+func (mw AuthMiddleware) AuthRequiredOrAPIKey(next http.Handler) http.Handler {
+	jwtAuth := mw.AuthRequired(next)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rawKey := r.Header.Get("X-API-Key")
+		if rawKey == "" {
+			rawKey, _ = strings.CutPrefix(r.Header.Get("Authorization"), "ApiKey ")
+		}
+		if rawKey == "" {
+			jwtAuth.ServeHTTP(w, r)
+			return
+		}
+
+		key, user, err := mw.users.AuthenticateAPIKey(r.Context(), rawKey, utils.ClientIP(r))
+		if err != nil {
+			utils.ErrorJSON(w, err, customErrors.ResolveHTTPStatus(err))
+			return
+		}
+
+		claims := &jwt.JwtClaims{
+			ID:             user.ID,
+			Email:          user.Email,
+			SessionVersion: user.SessionVersion,
+			Role:           user.Role,
+		}
+
 		var ctxClaimsKey ContextUserClaims = "user_claims"
+		var ctxAPIKey ContextUserClaims = "api_key"
 
 		ctx := context.WithValue(r.Context(), ctxClaimsKey, claims)
+		ctx = context.WithValue(ctx, ctxAPIKey, key)
 
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
+
+// RequireScope lets api key requests through only when the key carries scope.
+// Requests authenticated with a JWT are not restricted.
+func (mw AuthMiddleware) RequireScope(scope string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if key, ok := r.Context().Value("api_key").(*models.APIKey); ok && !key.HasScope(scope) {
+				utils.ErrorJSON(w, customErrors.ErrInsufficientScope, http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// SessionOnly refuses api keys on routes meant for signed in users.
+func (mw AuthMiddleware) SessionOnly(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := r.Context().Value("api_key").(*models.APIKey); ok {
+			utils.ErrorJSON(w, customErrors.ErrInsufficientScope, http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// AccountActive must run after AuthRequired. It rejects users who have not
+// confirmed their email address yet, and lets frozen accounts read but not
+// change anything.
+func (mw AuthMiddleware) AccountActive(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		claims, ok := r.Context().Value("user_claims").(*jwt.JwtClaims)
+		if !ok {
+			utils.ErrorJSON(w, customErrors.ErrUnauthorized, http.StatusUnauthorized)
+			return
+		}
+
+		user, err := mw.users.GetUserById(r.Context(), claims.ID)
+		if err != nil {
+			utils.ErrorJSON(w, err, customErrors.ResolveHTTPStatus(err))
+			return
+		}
+
+		if !user.IsEmailVerified() {
+			utils.ErrorJSON(w, customErrors.ErrEmailNotVerified, http.StatusForbidden)
+			return
+		}
+
+		if user.IsFrozen() && r.Method != http.MethodGet {
+			utils.ErrorJSON(w, customErrors.ErrAccountFrozen, http.StatusForbidden)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,17 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/toluhikay/fx-exchange/internal/fraud"
+	"github.com/toluhikay/fx-exchange/pkg/utils"
+)
+
+// DeviceFingerprint tags the request with the device it came from so the risk
+// rules can tell new devices apart.
+func DeviceFingerprint(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := fraud.WithDevice(r.Context(), utils.DeviceFingerprint(r))
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
@@ -0,0 +1,53 @@
+package middleware
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	customErrors "github.com/toluhikay/fx-exchange/internal/errors"
+	"github.com/toluhikay/fx-exchange/internal/ratelimit"
+	"github.com/toluhikay/fx-exchange/pkg/jwt"
+	"github.com/toluhikay/fx-exchange/pkg/utils"
+)
+
+// RateLimit applies policy per authenticated user, or per client ip for
+// anonymous requests. Place it after AuthRequired on protected groups so the
+// user id is available.
+func RateLimit(store ratelimit.Store, policy ratelimit.Policy) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := policy.Name + ":ip:" + utils.ClientIP(r)
+			if claims, ok := r.Context().Value("user_claims").(*jwt.JwtClaims); ok {
+				key = policy.Name + ":user:" + claims.ID.String()
+			}
+
+			result, err := store.Take(r.Context(), key, policy)
+			if err != nil {
+				// fail open, an unavailable limiter should not take the api down
+				fmt.Println("rate limiter error: ", err)
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			w.Header().Set("RateLimit-Policy", fmt.Sprintf("%d;w=%d", policy.Limit, int(policy.Period.Seconds())))
+			w.Header().Set("RateLimit-Limit", strconv.Itoa(result.Limit))
+			w.Header().Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+			w.Header().Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(result.Reset)))
+
+			if !result.Allowed {
+				w.Header().Set("Retry-After", strconv.Itoa(ceilSeconds(result.RetryAfter)))
+				utils.ErrorJSON(w, customErrors.ErrRateLimited, http.StatusTooManyRequests)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,91 @@
+package middleware
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// RealIP replaces the request's RemoteAddr with the client address, so
+// utils.ClientIP and everything keyed on it (lockouts, rate limits, API key
+// allowlists, audit) sees the client rather than the load balancer. The proxy
+// headers are only read when the connection comes from one of trustedProxies,
+// given as CIDRs or single addresses, and X-Forwarded-For is walked from the
+// right to the first hop that is not a trusted proxy; anything to its left
+// could have been written by the client.
+func RealIP(trustedProxies []string) func(http.Handler) http.Handler {
+	var trusted []*net.IPNet
+	for _, proxy := range trustedProxies {
+		if !strings.Contains(proxy, "/") {
+			if ip := net.ParseIP(proxy); ip != nil && ip.To4() != nil {
+				proxy += "/32"
+			} else {
+				proxy += "/128"
+			}
+		}
+		_, network, err := net.ParseCIDR(proxy)
+		if err != nil {
+			fmt.Println("ignoring invalid trusted proxy: ", proxy)
+			continue
+		}
+		trusted = append(trusted, network)
+	}
+
+	isTrusted := func(ip net.IP) bool {
+		for _, network := range trusted {
+			if network.Contains(ip) {
+				return true
+			}
+		}
+		return false
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if client := clientAddr(r, isTrusted); client != "" {
+				r.RemoteAddr = client
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// clientAddr is the right-most untrusted address in the chain ending at the
+// socket peer, or "" when the peer is not a trusted proxy.
+func clientAddr(r *http.Request, isTrusted func(net.IP) bool) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	peer := net.ParseIP(host)
+	if peer == nil || !isTrusted(peer) {
+		return ""
+	}
+
+	var hops []string
+	for _, header := range r.Header.Values("X-Forwarded-For") {
+		hops = append(hops, strings.Split(header, ",")...)
+	}
+	if len(hops) == 0 {
+		if realIP := net.ParseIP(strings.TrimSpace(r.Header.Get("X-Real-IP"))); realIP != nil {
+			return realIP.String()
+		}
+		return ""
+	}
+
+	client := peer
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := net.ParseIP(strings.TrimSpace(hops[i]))
+		if hop == nil {
+			// a malformed hop was not written by a trusted proxy, so stop at
+			// the last address one of them vouched for
+			break
+		}
+		client = hop
+		if !isTrusted(hop) {
+			break
+		}
+	}
+	return client.String()
+}
@@ -0,0 +1,76 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Directions a rate alert watches for.
+const (
+	AlertAbove = "above"
+	AlertBelow = "below"
+)
+
+// Rate alert statuses. A one-shot alert is triggered the first time it fires;
+// a repeating alert stays active until it is cancelled.
+const (
+	AlertActive    = "active"
+	AlertTriggered = "triggered"
+	AlertCancelled = "cancelled"
+)
+
+// RateAlert tells a user when the rate of a pair such as USDx/cNGN, in quote
+// currency per unit of base currency, rises to or above a threshold or falls
+// to or below it. A repeating alert is disarmed when it fires and armed again
+// once the rate is back on the other side of the threshold, so it fires once
+// per crossing.
+type RateAlert struct {
+	ID              uuid.UUID  `json:"id"`
+	UserID          uuid.UUID  `json:"user_id"`
+	BaseCurrency    string     `json:"base_currency"`
+	QuoteCurrency   string     `json:"quote_currency"`
+	Direction       string     `json:"direction"`
+	Threshold       float64    `json:"threshold"`
+	Repeating       bool       `json:"repeating"`
+	Armed           bool       `json:"armed"`
+	Status          string     `json:"status"`
+	TriggerCount    int        `json:"trigger_count"`
+	LastRate        *float64   `json:"last_rate"`
+	LastTriggeredAt *time.Time `json:"last_triggered_at"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+}
+
+// Pair is the alert's pair written BASE/QUOTE.
+func (a *RateAlert) Pair() string {
+	return a.BaseCurrency + "/" + a.QuoteCurrency
+}
+
+// Crossed reports whether rate is at or past the threshold in the alert's
+// direction.
+func (a *RateAlert) Crossed(rate float64) bool {
+	if a.Direction == AlertBelow {
+		return rate <= a.Threshold
+	}
+	return rate >= a.Threshold
+}
+
+type RateAlertRequest struct {
+	BaseCurrency  string  `json:"base_currency"`
+	QuoteCurrency string  `json:"quote_currency"`
+	Direction     string  `json:"direction"`
+	Threshold     float64 `json:"threshold"`
+	Repeating     bool    `json:"repeating"`
+}
+
+// RateAlertEvent is sent to the user's alert WebSocket when an alert fires.
+type RateAlertEvent struct {
+	AlertID     uuid.UUID `json:"alert_id"`
+	Pair        string    `json:"pair"`
+	Direction   string    `json:"direction"`
+	Threshold   float64   `json:"threshold"`
+	Rate        float64   `json:"rate"`
+	Repeating   bool      `json:"repeating"`
+	TriggeredAt time.Time `json:"triggered_at"`
+}
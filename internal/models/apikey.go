@@ -0,0 +1,59 @@
+package models
+
+import (
+	"net"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	ScopeReadBalances = "balances:read"
+	ScopeReadHistory  = "history:read"
+	ScopeTransfer     = "transfers:write"
+	ScopeHolds        = "holds:write"
+)
+
+var APIKeyScopes = []string{ScopeReadBalances, ScopeReadHistory, ScopeTransfer, ScopeHolds}
+
+type APIKey struct {
+	ID         uuid.UUID  `json:"id"`
+	UserID     uuid.UUID  `json:"user_id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	KeyHash    string     `json:"-"`
+	Scopes     []string   `json:"scopes"`
+	AllowedIPs []string   `json:"allowed_ips"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+}
+
+func (k *APIKey) HasScope(scope string) bool {
+	return slices.Contains(k.Scopes, scope)
+}
+
+// AllowsIP reports whether ip matches the allow-list. Entries may be single
+// addresses or CIDR ranges; an empty list allows every address.
+func (k *APIKey) AllowsIP(ip string) bool {
+	if len(k.AllowedIPs) == 0 {
+		return true
+	}
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return false
+	}
+	for _, allowed := range k.AllowedIPs {
+		if _, network, err := net.ParseCIDR(allowed); err == nil {
+			if network.Contains(addr) {
+				return true
+			}
+			continue
+		}
+		if allowedIP := net.ParseIP(allowed); allowedIP != nil && allowedIP.Equal(addr) {
+			return true
+		}
+	}
+	return false
+}
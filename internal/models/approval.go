@@ -0,0 +1,45 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	ApprovalPending  = "pending"
+	ApprovalApproved = "approved"
+	ApprovalRejected = "rejected"
+	ApprovalExpired  = "expired"
+)
+
+// CodePendingApproval is returned when a transfer waits for a second person.
+const CodePendingApproval = "pending_approval"
+
+// ApprovalPolicy makes a wallet require maker-checker sign-off. Transfers
+// worth more than ThresholdUSD wait for one of the approvers; a zero
+// threshold falls back to the configured default.
+type ApprovalPolicy struct {
+	ApproverIDs  []uuid.UUID `json:"approver_ids"`
+	ThresholdUSD float64     `json:"threshold_usd"`
+}
+
+// TransferApproval is a transfer waiting for sign-off. The amount is reserved
+// by a hold on the sender's balance, captured on approval and released if the
+// request is rejected or expires.
+type TransferApproval struct {
+	ID               uuid.UUID  `json:"id"`
+	WalletID         string     `json:"wallet_id"`
+	ReceiverWalletID string     `json:"receiver_wallet_id"`
+	RequestedBy      uuid.UUID  `json:"requested_by"`
+	HoldID           uuid.UUID  `json:"hold_id"`
+	Currency         string     `json:"currency"`
+	Amount           float64    `json:"amount"`
+	AmountUSD        float64    `json:"amount_usd"`
+	Status           string     `json:"status"`
+	ApproverID       *uuid.UUID `json:"approver_id"`
+	Note             *string    `json:"note"`
+	CreatedAt        time.Time  `json:"created_at"`
+	ExpiresAt        time.Time  `json:"expires_at"`
+	ResolvedAt       *time.Time `json:"resolved_at"`
+}
@@ -0,0 +1,20 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type AuditLog struct {
+	ID            uuid.UUID  `json:"id"`
+	WalletID      *string    `json:"wallet_id"`
+	UserID        *uuid.UUID `json:"user_id"`
+	Operation     string     `json:"operation"`
+	ClientIP      string     `json:"client_ip"`
+	UserAgent     string     `json:"user_agent"`
+	RequestMethod string     `json:"request_method"`
+	RequestPath   string     `json:"request_path"`
+	RequestBody   string     `json:"request_body"`
+	Timestamp     time.Time  `json:"timestamp"`
+}
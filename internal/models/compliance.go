@@ -0,0 +1,83 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Operations screened by the risk rules and held for review.
+const (
+	OpDeposit  = "deposit"
+	OpSwap     = "swap"
+	OpTransfer = "transfer"
+	// OpWithdrawal is a payout out of the platform, including the payout of
+	// the remaining balance when a customer closes their wallet.
+	OpWithdrawal = "withdrawal"
+	// OpRegistration is a new account held by sanctions screening. Approving
+	// it lifts the freeze placed at sign up.
+	OpRegistration = "registration"
+)
+
+const (
+	CaseOpen     = "open"
+	CaseApproved = "approved"
+	CaseRejected = "rejected"
+	CaseBlocked  = "blocked"
+)
+
+// Where a case came from.
+const (
+	CaseSourceFraud     = "fraud"
+	CaseSourceSanctions = "sanctions"
+)
+
+// SanctionsFreezeReason is the freeze reason on accounts awaiting review of a
+// sanctions match found at registration.
+const SanctionsFreezeReason = "pending sanctions review"
+
+// Error codes returned when the risk rules stop an operation.
+const (
+	CodeHeldForReview = "held_for_review"
+	CodeBlockedByRisk = "blocked_by_risk_rules"
+)
+
+// CaseOperation is the request a case is holding, kept so it can be executed
+// as-is once approved. Registration cases only carry the type.
+type CaseOperation struct {
+	Type       string  `json:"type"`
+	WalletID   string  `json:"wallet_id,omitempty"`
+	ReceiverID string  `json:"receiver_id,omitempty"`
+	Currency   string  `json:"currency,omitempty"`
+	ToCurrency string  `json:"to_currency,omitempty"`
+	Amount     float64 `json:"amount,omitempty"`
+	// Destination is where a withdrawal is paid out to.
+	Destination string `json:"destination,omitempty"`
+	// HoldID is set on withdrawals that capture a merchant hold.
+	HoldID string `json:"hold_id,omitempty"`
+}
+
+type RuleHit struct {
+	Rule   string `json:"rule"`
+	Action string `json:"action"`
+	Reason string `json:"reason"`
+	// Subject and ListEntry are set on sanctions hits: the user screened and
+	// the id of the listed entry they resemble.
+	Subject   string `json:"subject,omitempty"`
+	ListEntry string `json:"list_entry,omitempty"`
+}
+
+type ComplianceCase struct {
+	ID         uuid.UUID     `json:"id"`
+	UserID     uuid.UUID     `json:"user_id"`
+	WalletID   string        `json:"wallet_id,omitempty"`
+	Source     string        `json:"source"`
+	Action     string        `json:"action"`
+	Status     string        `json:"status"`
+	Operation  CaseOperation `json:"operation"`
+	Hits       []RuleHit     `json:"hits"`
+	ReviewerID *uuid.UUID    `json:"reviewer_id"`
+	ReviewNote *string       `json:"review_note"`
+	CreatedAt  time.Time     `json:"created_at"`
+	ResolvedAt *time.Time    `json:"resolved_at"`
+}
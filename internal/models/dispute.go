@@ -0,0 +1,84 @@
+package models
+
+import (
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Dispute states. A dispute is resolved for the sender when the transfer is
+// reversed and for the receiver when it stands.
+const (
+	DisputeOpened           = "opened"
+	DisputeUnderReview      = "under_review"
+	DisputeResolvedSender   = "resolved_sender"
+	DisputeResolvedReceiver = "resolved_receiver"
+)
+
+const (
+	DisputeWrongRecipient = "wrong_recipient"
+	DisputeWrongAmount    = "wrong_amount"
+	DisputeUnauthorised   = "unauthorised"
+	DisputeFraud          = "fraud"
+	DisputeOther          = "other"
+)
+
+var DisputeReasons = []string{DisputeWrongRecipient, DisputeWrongAmount, DisputeUnauthorised, DisputeFraud, DisputeOther}
+
+// HoldKindDispute holds back disputed funds in the receiver's wallet.
+const HoldKindDispute = "dispute"
+
+// DisputeIsOpen reports whether a dispute still waits for a decision.
+func DisputeIsOpen(status string) bool {
+	return slices.Contains([]string{DisputeOpened, DisputeUnderReview}, status)
+}
+
+// Dispute is raised by the sender of a transfer. Amount is in the currency
+// sent; HeldAmount is what could still be held of it in the receiver's
+// wallet, in Currency.
+type Dispute struct {
+	ID               uuid.UUID         `json:"id"`
+	TransactionID    string            `json:"transaction_id"`
+	SenderWalletID   string            `json:"sender_wallet_id"`
+	ReceiverWalletID string            `json:"receiver_wallet_id"`
+	OpenedBy         uuid.UUID         `json:"opened_by"`
+	Reason           string            `json:"reason"`
+	Description      string            `json:"description"`
+	Currency         string            `json:"currency"`
+	Amount           float64           `json:"amount"`
+	HeldAmount       float64           `json:"held_amount"`
+	HoldID           *uuid.UUID        `json:"hold_id"`
+	Status           string            `json:"status"`
+	ResolvedBy       *uuid.UUID        `json:"resolved_by"`
+	ResolutionNote   *string           `json:"resolution_note"`
+	CreatedAt        time.Time         `json:"created_at"`
+	UpdatedAt        time.Time         `json:"updated_at"`
+	ResolvedAt       *time.Time        `json:"resolved_at"`
+	Evidence         []DisputeEvidence `json:"evidence,omitempty"`
+}
+
+type DisputeEvidence struct {
+	ID          uuid.UUID `json:"id"`
+	DisputeID   uuid.UUID `json:"dispute_id"`
+	UploadedBy  uuid.UUID `json:"uploaded_by"`
+	FileKey     string    `json:"-"`
+	FileName    string    `json:"file_name"`
+	ContentType string    `json:"content_type"`
+	Size        int64     `json:"size"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+type OpenDisputeRequest struct {
+	TransactionID string `json:"transaction_id" validate:"required"`
+	Reason        string `json:"reason" validate:"required"`
+	Description   string `json:"description" validate:"required"`
+}
+
+// ResolveDisputeRequest decides a dispute. Force is passed on to the
+// reversal when the outcome favours the sender.
+type ResolveDisputeRequest struct {
+	Outcome string `json:"outcome" validate:"required"`
+	Note    string `json:"note"`
+	Force   bool   `json:"force"`
+}
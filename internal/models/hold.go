@@ -0,0 +1,62 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// A hold reserves part of a balance. Active holds lower the available balance
+// but leave the ledger balance alone until they are captured.
+const (
+	HoldActive   = "active"
+	HoldCaptured = "captured"
+	HoldReleased = "released"
+	HoldExpired  = "expired"
+)
+
+// What a hold is for. Merchant holds are placed and settled through the api;
+// approval holds belong to a pending transfer approval and order holds to an
+// open swap order.
+const (
+	HoldKindMerchant = "merchant"
+	HoldKindApproval = "approval"
+	HoldKindOrder    = "order"
+)
+
+type BalanceHold struct {
+	ID         uuid.UUID  `json:"id"`
+	WalletID   string     `json:"wallet_id"`
+	Kind       string     `json:"kind"`
+	Currency   string     `json:"currency"`
+	Amount     float64    `json:"amount"`
+	Captured   float64    `json:"captured"`
+	Status     string     `json:"status"`
+	Reference  *string    `json:"reference"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ResolvedAt *time.Time `json:"resolved_at"`
+}
+
+// Remaining is what the hold still reserves.
+func (h *BalanceHold) Remaining() float64 {
+	return h.Amount - h.Captured
+}
+
+type PlaceHoldRequest struct {
+	Currency  string  `json:"currency"`
+	Amount    float64 `json:"amount"`
+	Reference string  `json:"reference"`
+	// ExpiresIn is a duration such as "72h"; empty uses the default.
+	ExpiresIn string `json:"expires_in"`
+	Pin       string `json:"pin"`
+}
+
+// CaptureHoldRequest settles part or all of a hold. A zero amount captures
+// whatever is left.
+type CaptureHoldRequest struct {
+	Amount      float64 `json:"amount"`
+	Destination string  `json:"destination"`
+	Pin         string  `json:"pin"`
+	MfaCode     string  `json:"mfa_code,omitempty"`
+}
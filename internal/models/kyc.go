@@ -0,0 +1,75 @@
+package models
+
+import (
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Kyc levels in ascending order of verification.
+const (
+	KycUnverified = "unverified"
+	KycBasic      = "basic"
+	KycFull       = "full"
+)
+
+var KycLevels = []string{KycUnverified, KycBasic, KycFull}
+
+// KycRank orders levels so upgrades can be compared; unknown levels rank lowest.
+func KycRank(level string) int {
+	return slices.Index(KycLevels, level)
+}
+
+const (
+	DocPassport       = "passport"
+	DocNationalID     = "national_id"
+	DocDriversLicence = "drivers_licence"
+	DocProofOfAddress = "proof_of_address"
+	DocSelfie         = "selfie"
+)
+
+var KycDocumentTypes = []string{DocPassport, DocNationalID, DocDriversLicence, DocProofOfAddress, DocSelfie}
+
+// KycRequirements lists the documents a user needs approved to reach each
+// level. Every entry must be met, by any one of its document types.
+var KycRequirements = map[string][][]string{
+	KycBasic: {{DocPassport, DocNationalID, DocDriversLicence}},
+	KycFull:  {{DocPassport, DocNationalID, DocDriversLicence}, {DocProofOfAddress}, {DocSelfie}},
+}
+
+// KycLevelFor returns the highest level whose requirements, and those of every
+// level below it, the approved document types meet.
+func KycLevelFor(approved []string) string {
+	level := KycUnverified
+	for _, next := range KycLevels[1:] {
+		for _, anyOf := range KycRequirements[next] {
+			if !slices.ContainsFunc(anyOf, func(doc string) bool { return slices.Contains(approved, doc) }) {
+				return level
+			}
+		}
+		level = next
+	}
+	return level
+}
+
+const (
+	KycPending  = "pending"
+	KycApproved = "approved"
+	KycRejected = "rejected"
+)
+
+type KycDocument struct {
+	ID             uuid.UUID  `json:"id"`
+	UserID         uuid.UUID  `json:"user_id"`
+	RequestedLevel string     `json:"requested_level"`
+	DocumentType   string     `json:"document_type"`
+	FileKey        string     `json:"-"`
+	FileName       string     `json:"file_name"`
+	ContentType    string     `json:"content_type"`
+	Status         string     `json:"status"`
+	ReviewerID     *uuid.UUID `json:"reviewer_id"`
+	ReviewNote     *string    `json:"review_note"`
+	CreatedAt      time.Time  `json:"created_at"`
+	ReviewedAt     *time.Time `json:"reviewed_at"`
+}
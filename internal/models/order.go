@@ -0,0 +1,78 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Order types. Rates are quoted as swaps are, in ToCurrency per FromCurrency:
+// a limit order fills once the rate rises to TriggerRate or above, and a stop
+// order once it falls to TriggerRate or below.
+const (
+	OrderLimit = "limit"
+	OrderStop  = "stop"
+)
+
+// How long an order stays open. Good-till-cancelled orders wait until they
+// fill or are cancelled; good-till-date orders also expire at ExpiresAt.
+const (
+	OrderGoodTillCancelled = "gtc"
+	OrderGoodTillDate      = "gtd"
+)
+
+const (
+	OrderOpen      = "open"
+	OrderFilled    = "filled"
+	OrderCancelled = "cancelled"
+	OrderExpired   = "expired"
+	OrderFailed    = "failed"
+)
+
+// SwapOrder is a swap waiting for the rate to reach TriggerRate. The amount is
+// reserved by a hold on the wallet, captured when the order fills and released
+// when it is cancelled, expires or fails.
+type SwapOrder struct {
+	ID              uuid.UUID  `json:"id"`
+	WalletID        string     `json:"wallet_id"`
+	CreatedBy       uuid.UUID  `json:"created_by"`
+	Type            string     `json:"type"`
+	FromCurrency    string     `json:"from_currency"`
+	ToCurrency      string     `json:"to_currency"`
+	Amount          float64    `json:"amount"`
+	TriggerRate     float64    `json:"trigger_rate"`
+	TimeInForce     string     `json:"time_in_force"`
+	ExpiresAt       *time.Time `json:"expires_at"`
+	Status          string     `json:"status"`
+	HoldID          uuid.UUID  `json:"hold_id"`
+	Rate            *float64   `json:"rate"`
+	ConvertedAmount *float64   `json:"converted_amount"`
+	TransactionID   *uuid.UUID `json:"transaction_id"`
+	Error           *string    `json:"error"`
+	CreatedAt       time.Time  `json:"created_at"`
+	ResolvedAt      *time.Time `json:"resolved_at"`
+}
+
+// Triggered reports whether the order fills at rate.
+func (o *SwapOrder) Triggered(rate float64) bool {
+	switch o.Type {
+	case OrderLimit:
+		return rate >= o.TriggerRate
+	case OrderStop:
+		return rate <= o.TriggerRate
+	}
+	return false
+}
+
+// SwapOrderRequest places an order. TimeInForce defaults to good-till-cancelled
+// and ExpiresAt is required for good-till-date orders.
+type SwapOrderRequest struct {
+	Type         string     `json:"type"`
+	FromCurrency string     `json:"from_currency"`
+	ToCurrency   string     `json:"to_currency"`
+	Amount       float64    `json:"amount"`
+	TriggerRate  float64    `json:"trigger_rate"`
+	TimeInForce  string     `json:"time_in_force"`
+	ExpiresAt    *time.Time `json:"expires_at"`
+	Pin          string     `json:"pin"`
+}
@@ -0,0 +1,66 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Sides of a peer-to-peer order. A bid buys the base currency of the market
+// with the quote currency and an ask sells it.
+const (
+	SideBid = "bid"
+	SideAsk = "ask"
+)
+
+// P2POrder is a bid or ask on a market such as USDx/cNGN, priced in the quote
+// currency per unit of the base currency. Amount is in the base currency.
+// What the order can spend is reserved by a hold until it fills or is
+// cancelled: the quote amount for a bid, the base amount for an ask.
+type P2POrder struct {
+	ID            uuid.UUID  `json:"id"`
+	WalletID      string     `json:"wallet_id"`
+	CreatedBy     uuid.UUID  `json:"created_by"`
+	Market        string     `json:"market"`
+	BaseCurrency  string     `json:"base_currency"`
+	QuoteCurrency string     `json:"quote_currency"`
+	Side          string     `json:"side"`
+	Price         float64    `json:"price"`
+	Amount        float64    `json:"amount"`
+	Filled        float64    `json:"filled"`
+	Status        string     `json:"status"`
+	HoldID        uuid.UUID  `json:"hold_id"`
+	Error         *string    `json:"error"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+	ResolvedAt    *time.Time `json:"resolved_at"`
+}
+
+// Remaining is what the order still wants to trade, in the base currency.
+func (o *P2POrder) Remaining() float64 {
+	return o.Amount - o.Filled
+}
+
+// P2PTrade is one match between a bid and an ask, at the price of the order
+// that was resting on the book.
+type P2PTrade struct {
+	ID             uuid.UUID `json:"id"`
+	Market         string    `json:"market"`
+	Price          float64   `json:"price"`
+	Amount         float64   `json:"amount"`
+	QuoteAmount    float64   `json:"quote_amount"`
+	BidOrderID     uuid.UUID `json:"bid_order_id"`
+	AskOrderID     uuid.UUID `json:"ask_order_id"`
+	BuyerWalletID  string    `json:"buyer_wallet_id"`
+	SellerWalletID string    `json:"seller_wallet_id"`
+	TakerSide      string    `json:"taker_side"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+type P2POrderRequest struct {
+	Market string  `json:"market"`
+	Side   string  `json:"side"`
+	Price  float64 `json:"price"`
+	Amount float64 `json:"amount"`
+	Pin    string  `json:"pin"`
+}
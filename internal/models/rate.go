@@ -0,0 +1,41 @@
+package models
+
+import "time"
+
+// Resolutions fx_rates ticks are rolled up into. Hour candles are built from
+// minute candles and day candles from hour candles.
+const (
+	Resolution1m = "1m"
+	Resolution1h = "1h"
+	Resolution1d = "1d"
+)
+
+// Candle is the open, high, low and close of a pair over one interval
+// starting at Time. Ticks is how many rates were published in it.
+type Candle struct {
+	Time  time.Time `json:"time"`
+	Open  float64   `json:"open"`
+	High  float64   `json:"high"`
+	Low   float64   `json:"low"`
+	Close float64   `json:"close"`
+	Ticks int       `json:"ticks"`
+}
+
+// RateTick is one published rate.
+type RateTick struct {
+	Rate      float64   `json:"rate"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// RateHistory is a pair's rates between From and To, as candles of Interval
+// and as the raw ticks. TicksTruncated is set when there were more ticks in
+// the range than are returned; the earliest ones are kept.
+type RateHistory struct {
+	Pair           string     `json:"pair"`
+	Interval       string     `json:"interval"`
+	From           time.Time  `json:"from"`
+	To             time.Time  `json:"to"`
+	Candles        []Candle   `json:"candles"`
+	Ticks          []RateTick `json:"ticks"`
+	TicksTruncated bool       `json:"ticks_truncated"`
+}
@@ -0,0 +1,31 @@
+package models
+
+// Transaction statuses. Swaps and transfers move to partially_reversed or
+// reversed as compensating entries are written against them.
+const (
+	TxCompleted         = "completed"
+	TxPartiallyReversed = "partially_reversed"
+	TxReversed          = "reversed"
+)
+
+// TxReversal is the type of the compensating entry a reversal writes.
+const TxReversal = "reversal"
+
+// CodeFundsSpent is returned when the money to take back has already been
+// spent and the reversal was not forced.
+const CodeFundsSpent = "funds_spent"
+
+// ReversalRequest undoes all or part of a swap or transfer. Amount is in the
+// currency originally sent; zero reverses whatever is left. Force takes the
+// money back even when that leaves the paying wallet below zero.
+type ReversalRequest struct {
+	Amount float64 `json:"amount"`
+	Reason string  `json:"reason" validate:"required"`
+	Force  bool    `json:"force"`
+}
+
+// ReversalResponse shows the original transaction next to its reversals.
+type ReversalResponse struct {
+	Original  *Transaction  `json:"original"`
+	Reversals []Transaction `json:"reversals"`
+}
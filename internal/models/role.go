@@ -0,0 +1,43 @@
+package models
+
+import "slices"
+
+const (
+	RoleCustomer   = "customer"
+	RoleSupport    = "support"
+	RoleCompliance = "compliance"
+	RoleAdmin      = "admin"
+)
+
+var Roles = []string{RoleCustomer, RoleSupport, RoleCompliance, RoleAdmin}
+
+const (
+	PermUsersRead        = "users:read"
+	PermUsersUnlock      = "users:unlock"
+	PermUsersFreeze      = "users:freeze"
+	PermUsersRole        = "users:role"
+	PermWalletsRead      = "wallets:read"
+	PermWalletsStatus    = "wallets:status"
+	PermAuditRead        = "audit:read"
+	PermKycReview        = "kyc:review"
+	PermCasesReview      = "cases:review"
+	PermWalletsApprovers = "wallets:approvers"
+	PermTxReverse        = "transactions:reverse"
+	PermDisputesReview   = "disputes:review"
+)
+
+// rolePermissions lists what each staff role may do through the admin api.
+// Customers have no admin permissions.
+var rolePermissions = map[string][]string{
+	RoleSupport:    {PermUsersRead, PermUsersUnlock, PermWalletsRead, PermAuditRead, PermTxReverse, PermDisputesReview},
+	RoleCompliance: {PermUsersRead, PermUsersFreeze, PermWalletsRead, PermWalletsStatus, PermAuditRead, PermKycReview, PermCasesReview, PermDisputesReview},
+	RoleAdmin:      {PermUsersRead, PermUsersUnlock, PermUsersFreeze, PermUsersRole, PermWalletsRead, PermWalletsStatus, PermAuditRead, PermKycReview, PermCasesReview, PermWalletsApprovers, PermTxReverse, PermDisputesReview},
+}
+
+func IsValidRole(role string) bool {
+	return slices.Contains(Roles, role)
+}
+
+func RoleHasPermission(role, permission string) bool {
+	return slices.Contains(rolePermissions[role], permission)
+}
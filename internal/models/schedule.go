@@ -0,0 +1,91 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// How often a scheduled transfer runs. Daily, weekly and monthly repeat from
+// the start time; monthly runs fall on the last day of shorter months. Cron
+// schedules follow a five field expression evaluated in UTC.
+const (
+	FrequencyOnce    = "once"
+	FrequencyDaily   = "daily"
+	FrequencyWeekly  = "weekly"
+	FrequencyMonthly = "monthly"
+	FrequencyCron    = "cron"
+)
+
+var ScheduleFrequencies = []string{FrequencyOnce, FrequencyDaily, FrequencyWeekly, FrequencyMonthly, FrequencyCron}
+
+// Scheduled transfer states. Cancelled and completed are final.
+const (
+	ScheduleActive    = "active"
+	SchedulePaused    = "paused"
+	ScheduleCancelled = "cancelled"
+	ScheduleCompleted = "completed"
+)
+
+// Outcomes recorded for each run. A retrying run failed for lack of funds and
+// will be tried again; held runs were parked for approval or compliance
+// review and go through once that is settled.
+const (
+	RunSucceeded       = "succeeded"
+	RunFailed          = "failed"
+	RunRetrying        = "retrying"
+	RunSkipped         = "skipped"
+	RunPendingApproval = "pending_approval"
+	RunHeldForReview   = "held_for_review"
+)
+
+// ScheduledTransfer is a one-off or recurring transfer the scheduler makes on
+// the owner's behalf. Device is the fingerprint of the request that created
+// it; every run is judged by the risk rules as coming from that device.
+type ScheduledTransfer struct {
+	ID               uuid.UUID  `json:"id"`
+	WalletID         string     `json:"wallet_id"`
+	ReceiverWalletID string     `json:"receiver_wallet_id"`
+	CreatedBy        uuid.UUID  `json:"created_by"`
+	Currency         string     `json:"currency"`
+	Amount           float64    `json:"amount"`
+	Frequency        string     `json:"frequency"`
+	CronExpr         *string    `json:"cron,omitempty"`
+	Note             *string    `json:"note"`
+	Status           string     `json:"status"`
+	StartAt          time.Time  `json:"start_at"`
+	EndAt            *time.Time `json:"end_at"`
+	NextRunAt        *time.Time `json:"next_run_at"`
+	LastRunAt        *time.Time `json:"last_run_at"`
+	RetryCount       int        `json:"retry_count"`
+	Device           string     `json:"-"`
+	CreatedAt        time.Time  `json:"created_at"`
+	UpdatedAt        time.Time  `json:"updated_at"`
+}
+
+type ScheduledTransferRun struct {
+	ID              uuid.UUID `json:"id"`
+	ScheduleID      uuid.UUID `json:"schedule_id"`
+	ScheduledFor    time.Time `json:"scheduled_for"`
+	Attempt         int       `json:"attempt"`
+	Status          string    `json:"status"`
+	ConvertedAmount *float64  `json:"converted_amount"`
+	Rate            *float64  `json:"rate"`
+	Error           *string   `json:"error"`
+	CreatedAt       time.Time `json:"created_at"`
+}
+
+// ScheduleTransferRequest sets up a transfer to run later. StartAt defaults
+// to now, and a transfer without EndAt repeats until it is cancelled.
+type ScheduleTransferRequest struct {
+	ReceiverID string     `json:"receiver_id"`
+	Currency   string     `json:"currency"`
+	Amount     float64    `json:"amount"`
+	Frequency  string     `json:"frequency"`
+	Cron       string     `json:"cron"`
+	StartAt    *time.Time `json:"start_at"`
+	EndAt      *time.Time `json:"end_at"`
+	Note       string     `json:"note"`
+	Pin        string     `json:"pin"`
+	MfaCode    string     `json:"mfa_code,omitempty"`
+}
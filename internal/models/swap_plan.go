@@ -0,0 +1,81 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// RunRateExceeded is recorded for a swap plan run that did not go out because
+// the price was above the plan's maximum.
+const RunRateExceeded = "rate_exceeded"
+
+// SwapPlan converts a fixed amount of one currency into another on a
+// schedule, dollar-cost averaging into the target currency. MaxRate is the
+// most FromCurrency the owner will pay for one unit of ToCurrency; runs priced
+// above it are passed over.
+type SwapPlan struct {
+	ID           uuid.UUID  `json:"id"`
+	WalletID     string     `json:"wallet_id"`
+	CreatedBy    uuid.UUID  `json:"created_by"`
+	FromCurrency string     `json:"from_currency"`
+	ToCurrency   string     `json:"to_currency"`
+	Amount       float64    `json:"amount"`
+	MaxRate      *float64   `json:"max_rate"`
+	Frequency    string     `json:"frequency"`
+	CronExpr     *string    `json:"cron,omitempty"`
+	Status       string     `json:"status"`
+	StartAt      time.Time  `json:"start_at"`
+	EndAt        *time.Time `json:"end_at"`
+	NextRunAt    *time.Time `json:"next_run_at"`
+	LastRunAt    *time.Time `json:"last_run_at"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
+
+// SwapPlanRun is the execution report of one run. Rate is quoted as swaps
+// are, in ToCurrency per FromCurrency.
+type SwapPlanRun struct {
+	ID              uuid.UUID `json:"id"`
+	PlanID          uuid.UUID `json:"plan_id"`
+	ScheduledFor    time.Time `json:"scheduled_for"`
+	Status          string    `json:"status"`
+	Amount          float64   `json:"amount"`
+	ConvertedAmount *float64  `json:"converted_amount"`
+	Rate            *float64  `json:"rate"`
+	Error           *string   `json:"error"`
+	CreatedAt       time.Time `json:"created_at"`
+}
+
+// SwapPlanSummary adds up the runs of a plan. AverageRate is what was
+// received per unit spent across every successful run, and AveragePrice is
+// its inverse, comparable with the plan's MaxRate.
+type SwapPlanSummary struct {
+	PlanID        uuid.UUID  `json:"plan_id"`
+	FromCurrency  string     `json:"from_currency"`
+	ToCurrency    string     `json:"to_currency"`
+	Runs          int        `json:"runs"`
+	Succeeded     int        `json:"succeeded"`
+	TotalSpent    float64    `json:"total_spent"`
+	TotalReceived float64    `json:"total_received"`
+	AverageRate   float64    `json:"average_rate"`
+	AveragePrice  float64    `json:"average_price"`
+	BestRate      *float64   `json:"best_rate"`
+	WorstRate     *float64   `json:"worst_rate"`
+	FirstRunAt    *time.Time `json:"first_run_at"`
+	LastRunAt     *time.Time `json:"last_run_at"`
+}
+
+// SwapPlanRequest sets up a plan. StartAt defaults to now, and a plan without
+// EndAt runs until it is cancelled.
+type SwapPlanRequest struct {
+	FromCurrency string     `json:"from_currency"`
+	ToCurrency   string     `json:"to_currency"`
+	Amount       float64    `json:"amount"`
+	MaxRate      float64    `json:"max_rate"`
+	Frequency    string     `json:"frequency"`
+	Cron         string     `json:"cron"`
+	StartAt      *time.Time `json:"start_at"`
+	EndAt        *time.Time `json:"end_at"`
+	Pin          string     `json:"pin"`
+}
@@ -10,13 +10,85 @@ import (
 )
 
 type User struct {
-	ID        uuid.UUID  `json:"id" validate:"required"`
-	Name      string     `json:"name" validate:"required"`
-	Email     string     `json:"email" validate:"required"`
-	Password  string     `json:"-" validate:"required"`
-	CreatedAt time.Time  `json:"created_at"`
-	UpdatedAt time.Time  `json:"updated_at"`
-	DeletedAt *time.Time `json:"deleted_at"`
+	ID              uuid.UUID  `json:"id" validate:"required"`
+	Name            string     `json:"name" validate:"required"`
+	Email           string     `json:"email" validate:"required"`
+	Password        string     `json:"-" validate:"required"`
+	Role            string     `json:"role"`
+	KycLevel        string     `json:"kyc_level"`
+	EmailVerifiedAt *time.Time `json:"email_verified_at"`
+	SessionVersion  int        `json:"-"`
+	MfaSecret       *string    `json:"-"`
+	MfaEnabledAt    *time.Time `json:"mfa_enabled_at"`
+	MfaLastStep     int64      `json:"-"`
+	TransactionPin  *string    `json:"-"`
+	PinAttempts     int        `json:"-"`
+	PinLockedUntil  *time.Time `json:"-"`
+	FrozenAt        *time.Time `json:"frozen_at"`
+	FrozenReason    *string    `json:"frozen_reason"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+	DeletedAt       *time.Time `json:"deleted_at"`
+}
+
+type EmailVerification struct {
+	ID         uuid.UUID
+	UserID     uuid.UUID
+	OtpHash    string
+	Attempts   int
+	ExpiresAt  time.Time
+	ConsumedAt *time.Time
+	CreatedAt  time.Time
+}
+
+type PasswordReset struct {
+	ID        uuid.UUID
+	UserID    uuid.UUID
+	TokenHash string
+	ExpiresAt time.Time
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
+
+type LoginThrottle struct {
+	Key           string
+	Failures      int
+	LastFailureAt time.Time
+	LockedUntil   *time.Time
+}
+
+func (u *User) IsEmailVerified() bool {
+	return u.EmailVerifiedAt != nil
+}
+
+func (u *User) IsMfaEnabled() bool {
+	return u.MfaEnabledAt != nil
+}
+
+func (u *User) IsDeleted() bool {
+	return u.DeletedAt != nil
+}
+
+func (u *User) IsFrozen() bool {
+	return u.FrozenAt != nil
+}
+
+func (u *User) HasPin() bool {
+	return u.TransactionPin != nil
+}
+
+func (u *User) CompareHashedPin(pin string) (bool, error) {
+	if u.TransactionPin == nil {
+		return false, nil
+	}
+	err := bcrypt.CompareHashAndPassword([]byte(*u.TransactionPin), []byte(pin))
+	if err != nil {
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
 }
 
 func (u *User) HashPassword(password string) (string, error) {
@@ -1,15 +1,105 @@
 package models
 
-import "time"
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
 
 type Wallet struct {
 	ID            string             `json:"id"`
 	EmailOrMobile string             `json:"email"`
 	UserId        string             `json:"user_id"`
 	Balances      map[string]float64 `json:"balances"`
+	Status        string             `json:"status"`
 	CreatedAt     time.Time          `json:"created_at"`
 }
 
+// Wallet states. frozen-debit still accepts incoming funds, frozen-all
+// accepts nothing, and closed is final.
+const (
+	WalletActive      = "active"
+	WalletFrozenDebit = "frozen-debit"
+	WalletFrozenAll   = "frozen-all"
+	WalletClosed      = "closed"
+)
+
+var WalletStatuses = []string{WalletActive, WalletFrozenDebit, WalletFrozenAll, WalletClosed}
+
+// Reason codes recorded with every wallet state change.
+const (
+	ReasonCustomerRequest    = "customer_request"
+	ReasonAccountCompromised = "account_compromised"
+	ReasonSuspectedFraud     = "suspected_fraud"
+	ReasonComplianceReview   = "compliance_review"
+	ReasonLegalOrder         = "legal_order"
+	ReasonReviewCleared      = "review_cleared"
+	ReasonDormant            = "dormant"
+)
+
+var WalletReasonCodes = []string{
+	ReasonCustomerRequest,
+	ReasonAccountCompromised,
+	ReasonSuspectedFraud,
+	ReasonComplianceReview,
+	ReasonLegalOrder,
+	ReasonReviewCleared,
+	ReasonDormant,
+}
+
+func WalletCanDebit(status string) bool {
+	return status == WalletActive
+}
+
+func WalletCanCredit(status string) bool {
+	return status == WalletActive || status == WalletFrozenDebit
+}
+
+type WalletStatusRequest struct {
+	Status            string `json:"status"`
+	ReasonCode        string `json:"reason_code"`
+	Note              string `json:"note"`
+	PayoutDestination string `json:"payout_destination"`
+}
+
+type CloseWalletRequest struct {
+	PayoutDestination string `json:"payout_destination"`
+	Pin               string `json:"pin"`
+}
+
+// SpendingControls are limits owners put on their own wallet on top of the
+// kyc caps. Zero values and empty lists mean no restriction.
+type SpendingControls struct {
+	MaxTransferUSD        float64  `json:"max_transfer_usd"`
+	DailyOutgoingUSD      float64  `json:"daily_outgoing_usd"`
+	AllowedCounterparties []string `json:"allowed_counterparties"`
+	AllowedCurrencies     []string `json:"allowed_currencies"`
+}
+
+type SpendingControlsRequest struct {
+	SpendingControls
+	Pin string `json:"pin"`
+}
+
+// Error codes returned when a spending control blocks an operation.
+const (
+	CodeMaxTransferExceeded    = "max_transfer_exceeded"
+	CodeDailyOutgoingExceeded  = "daily_outgoing_exceeded"
+	CodeCounterpartyNotAllowed = "counterparty_not_allowed"
+	CodeCurrencyNotAllowed     = "currency_not_allowed"
+)
+
+type WalletStatusEvent struct {
+	ID         string     `json:"id"`
+	WalletID   string     `json:"wallet_id"`
+	FromStatus string     `json:"from_status"`
+	ToStatus   string     `json:"to_status"`
+	ReasonCode string     `json:"reason_code"`
+	Note       *string    `json:"note"`
+	ActorID    *uuid.UUID `json:"actor_id"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
 type DepositRequest struct {
 	Currency string  `json:"currency"`
 	Amount   float64 `json:"amount"`
@@ -19,27 +109,46 @@ type SwapRequest struct {
 	FromCurrency string  `json:"from_currency"`
 	ToCurrency   string  `json:"to_currency"`
 	Amount       float64 `json:"amount"`
+	Pin          string  `json:"pin"`
 }
 
 type TransferRequest struct {
 	ReceiverID string  `json:"receiver_id"`
 	Currency   string  `json:"currency"`
 	Amount     float64 `json:"amount"`
+	Pin        string  `json:"pin"`
+	MfaCode    string  `json:"mfa_code,omitempty"`
+}
+
+type WithdrawRequest struct {
+	Currency    string  `json:"currency"`
+	Amount      float64 `json:"amount"`
+	Destination string  `json:"destination"`
+	Pin         string  `json:"pin"`
 }
 
 type Transaction struct {
-	ID              string    `json:"id"`
-	WalletID        string    `json:"wallet_id"`
-	Type            string    `json:"type"`
-	FromCurrency    *string   `json:"from_currency"`
-	ToCurrency      *string   `json:"to_currency"`
-	Amount          *float64  `json:"amount"`
-	ConvertedAmount *float64  `json:"converted_amount"`
-	Rate            *float64  `json:"rate"`
-	Timestamp       time.Time `json:"timestamp"`
+	ID               string    `json:"id"`
+	WalletID         string    `json:"wallet_id"`
+	Type             string    `json:"type"`
+	FromCurrency     *string   `json:"from_currency"`
+	ToCurrency       *string   `json:"to_currency"`
+	Amount           *float64  `json:"amount"`
+	ConvertedAmount  *float64  `json:"converted_amount"`
+	Rate             *float64  `json:"rate"`
+	Reference        *string   `json:"reference"`
+	ReceiverWalletID *string   `json:"receiver_wallet_id"`
+	Status           string    `json:"status"`
+	ReversedAmount   float64   `json:"reversed_amount"`
+	ReversalOf       *string   `json:"reversal_of"`
+	Timestamp        time.Time `json:"timestamp"`
 }
 
+// BalanceResponse reports the ledger balance per currency and what is
+// available once active holds are taken off.
 type BalanceResponse struct {
-	Balances map[string]float64 `json:"balances"`
-	TotalUSD float64            `json:"total_usd"`
+	Balances     map[string]float64 `json:"balances"`
+	Available    map[string]float64 `json:"available"`
+	TotalUSD     float64            `json:"total_usd"`
+	AvailableUSD float64            `json:"available_usd"`
 }
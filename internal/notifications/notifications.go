@@ -0,0 +1,77 @@
+package notifications
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/toluhikay/fx-exchange/internal/mailer"
+)
+
+type Notification struct {
+	UserID  uuid.UUID
+	Email   string
+	Subject string
+	Body    string
+}
+
+// Notifier tells a user that something happened on their account. Channels
+// beyond email can be added by implementing this interface.
+type Notifier interface {
+	Notify(ctx context.Context, n Notification) error
+}
+
+type MailNotifier struct {
+	mailer mailer.Mailer
+}
+
+func NewMailNotifier(m mailer.Mailer) *MailNotifier {
+	return &MailNotifier{mailer: m}
+}
+
+func (n *MailNotifier) Notify(ctx context.Context, notification Notification) error {
+	return n.mailer.Send(ctx, mailer.Message{
+		To:      notification.Email,
+		Subject: notification.Subject,
+		Body:    notification.Body,
+	})
+}
+
+// Inbox persists notifications for the in-app inbox.
+type Inbox interface {
+	CreateNotification(ctx context.Context, userID uuid.UUID, subject, body string) error
+}
+
+// InAppNotifier keeps a copy of every notification so users can read them in
+// the app.
+type InAppNotifier struct {
+	inbox Inbox
+}
+
+func NewInAppNotifier(inbox Inbox) *InAppNotifier {
+	return &InAppNotifier{inbox: inbox}
+}
+
+func (n *InAppNotifier) Notify(ctx context.Context, notification Notification) error {
+	return n.inbox.CreateNotification(ctx, notification.UserID, notification.Subject, notification.Body)
+}
+
+// MultiNotifier sends every notification on each of its channels. A failing
+// channel does not stop the others.
+type MultiNotifier struct {
+	notifiers []Notifier
+}
+
+func NewMultiNotifier(notifiers ...Notifier) *MultiNotifier {
+	return &MultiNotifier{notifiers: notifiers}
+}
+
+func (n *MultiNotifier) Notify(ctx context.Context, notification Notification) error {
+	var errs []error
+	for _, notifier := range n.notifiers {
+		if err := notifier.Notify(ctx, notification); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
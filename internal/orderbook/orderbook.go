@@ -0,0 +1,186 @@
+// Package orderbook keeps the resting orders of one market in price-time
+// priority. It only tracks what is open; settling trades is up to the caller,
+// which also serialises access to a book.
+package orderbook
+
+import (
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	Bid = "bid"
+	Ask = "ask"
+)
+
+// dust is the smallest remaining amount an order keeps its place for.
+// Amounts are stored with four decimals, so anything below that is filled.
+const dust = 0.00005
+
+// Order is a resting order. Price is in the quote currency per unit of the
+// base currency and Remaining is in the base currency.
+type Order struct {
+	ID        uuid.UUID
+	WalletID  string
+	Side      string
+	Price     float64
+	Remaining float64
+	CreatedAt time.Time
+}
+
+type level struct {
+	price  float64
+	orders []*Order
+}
+
+// Book is the order book of one market. It is not safe for concurrent use.
+type Book struct {
+	market string
+	bids   []*level // highest price first
+	asks   []*level // lowest price first
+	orders map[uuid.UUID]*Order
+}
+
+func New(market string) *Book {
+	return &Book{market: market, orders: make(map[uuid.UUID]*Order)}
+}
+
+func (b *Book) Market() string {
+	return b.market
+}
+
+// Add rests an order behind the orders already at its price.
+func (b *Book) Add(o Order) {
+	if o.Remaining < dust {
+		return
+	}
+	if _, ok := b.orders[o.ID]; ok {
+		return
+	}
+
+	entry := &o
+	b.orders[o.ID] = entry
+
+	levels := &b.asks
+	better := func(p float64) bool { return p < o.Price }
+	if o.Side == Bid {
+		levels = &b.bids
+		better = func(p float64) bool { return p > o.Price }
+	}
+
+	i := sort.Search(len(*levels), func(i int) bool { return !better((*levels)[i].price) })
+	if i < len(*levels) && (*levels)[i].price == o.Price {
+		(*levels)[i].orders = append((*levels)[i].orders, entry)
+		return
+	}
+	*levels = append(*levels, nil)
+	copy((*levels)[i+1:], (*levels)[i:])
+	(*levels)[i] = &level{price: o.Price, orders: []*Order{entry}}
+}
+
+// Remove takes an order off the book. It reports whether the order was there.
+func (b *Book) Remove(id uuid.UUID) bool {
+	o, ok := b.orders[id]
+	if !ok {
+		return false
+	}
+	delete(b.orders, id)
+
+	levels := &b.asks
+	if o.Side == Bid {
+		levels = &b.bids
+	}
+	for i, l := range *levels {
+		if l.price != o.Price {
+			continue
+		}
+		for j, entry := range l.orders {
+			if entry.ID == id {
+				l.orders = append(l.orders[:j], l.orders[j+1:]...)
+				break
+			}
+		}
+		if len(l.orders) == 0 {
+			*levels = append((*levels)[:i], (*levels)[i+1:]...)
+		}
+		break
+	}
+	return true
+}
+
+// Reduce takes a fill of amount off a resting order, removing it once what
+// is left is dust.
+func (b *Book) Reduce(id uuid.UUID, amount float64) {
+	o, ok := b.orders[id]
+	if !ok {
+		return
+	}
+	o.Remaining -= amount
+	if o.Remaining < dust {
+		b.Remove(id)
+	}
+}
+
+// Crossing returns the resting orders an incoming order on side at price
+// would trade with, in the order they should be filled.
+func (b *Book) Crossing(side string, price float64) []Order {
+	levels := b.bids
+	crosses := func(p float64) bool { return p >= price }
+	if side == Bid {
+		levels = b.asks
+		crosses = func(p float64) bool { return p <= price }
+	}
+
+	var orders []Order
+	for _, l := range levels {
+		if !crosses(l.price) {
+			break
+		}
+		for _, o := range l.orders {
+			orders = append(orders, *o)
+		}
+	}
+	return orders
+}
+
+// Level is the total resting at one price.
+type Level struct {
+	Price  float64 `json:"price"`
+	Amount float64 `json:"amount"`
+	Orders int     `json:"orders"`
+}
+
+// Depth is a snapshot of the best levels on both sides of a book.
+type Depth struct {
+	Market    string    `json:"market"`
+	Bids      []Level   `json:"bids"`
+	Asks      []Level   `json:"asks"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// Depth returns up to levels price levels a side.
+func (b *Book) Depth(levels int) Depth {
+	return Depth{
+		Market:    b.market,
+		Bids:      aggregate(b.bids, levels),
+		Asks:      aggregate(b.asks, levels),
+		Timestamp: time.Now(),
+	}
+}
+
+func aggregate(levels []*level, n int) []Level {
+	out := []Level{}
+	for _, l := range levels {
+		if len(out) == n {
+			break
+		}
+		total := Level{Price: l.price, Orders: len(l.orders)}
+		for _, o := range l.orders {
+			total.Amount += o.Remaining
+		}
+		out = append(out, total)
+	}
+	return out
+}
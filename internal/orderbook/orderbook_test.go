@@ -0,0 +1,129 @@
+package orderbook
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// restingBook builds a book from orders given oldest first.
+func restingBook(orders ...Order) *Book {
+	b := New("USDx/cNGN")
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i, o := range orders {
+		o.CreatedAt = start.Add(time.Duration(i) * time.Second)
+		b.Add(o)
+	}
+	return b
+}
+
+func order(side string, price, remaining float64) Order {
+	return Order{ID: uuid.New(), Side: side, Price: price, Remaining: remaining}
+}
+
+func TestCrossingPriceTimePriority(t *testing.T) {
+	ask105a := order(Ask, 105, 1)
+	ask100 := order(Ask, 100, 1)
+	ask105b := order(Ask, 105, 1)
+	ask120 := order(Ask, 120, 1)
+	bid100a := order(Bid, 100, 1)
+	bid98 := order(Bid, 98, 1)
+	bid100b := order(Bid, 100, 1)
+	bid90 := order(Bid, 90, 1)
+	b := restingBook(ask105a, ask100, ask105b, ask120, bid100a, bid98, bid100b, bid90)
+
+	tests := []struct {
+		name  string
+		side  string
+		price float64
+		want  []Order
+	}{
+		{"bid takes the cheapest asks first, oldest first at a price", Bid, 110, []Order{ask100, ask105a, ask105b}},
+		{"bid at a level price includes the level", Bid, 105, []Order{ask100, ask105a, ask105b}},
+		{"bid below the best ask crosses nothing", Bid, 99, nil},
+		{"ask takes the highest bids first, oldest first at a price", Ask, 95, []Order{bid100a, bid100b, bid98}},
+		{"ask at a level price includes the level", Ask, 98, []Order{bid100a, bid100b, bid98}},
+		{"ask above the best bid crosses nothing", Ask, 101, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := b.Crossing(tt.side, tt.price)
+			if len(got) != len(tt.want) {
+				t.Fatalf("Crossing(%s, %v) returned %d orders, want %d", tt.side, tt.price, len(got), len(tt.want))
+			}
+			for i := range got {
+				if got[i].ID != tt.want[i].ID {
+					t.Errorf("order %d is %v at %v, want %v at %v", i, got[i].ID, got[i].Price, tt.want[i].ID, tt.want[i].Price)
+				}
+			}
+		})
+	}
+}
+
+func TestReduceDust(t *testing.T) {
+	tests := []struct {
+		name          string
+		remaining     float64
+		fill          float64
+		wantResting   bool
+		wantRemaining float64
+	}{
+		{"partial fill keeps its place", 1, 0.4, true, 0.6},
+		{"fill down to the smallest stored amount keeps its place", 1, 0.9999, true, 0.0001},
+		{"fill leaving dust removes the order", 1, 0.99996, false, 0},
+		{"full fill removes the order", 1, 1, false, 0},
+		{"overfill removes the order", 1, 1.5, false, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			first := order(Ask, 100, tt.remaining)
+			second := order(Ask, 100, 1)
+			b := restingBook(first, second)
+
+			b.Reduce(first.ID, tt.fill)
+
+			crossing := b.Crossing(Bid, 100)
+			if !tt.wantResting {
+				if len(crossing) != 1 || crossing[0].ID != second.ID {
+					t.Fatalf("book still holds the filled order: %+v", crossing)
+				}
+				if depth := b.Depth(1); len(depth.Asks) != 1 || depth.Asks[0].Orders != 1 {
+					t.Errorf("depth = %+v, want one order at the level", depth.Asks)
+				}
+				return
+			}
+			if len(crossing) != 2 || crossing[0].ID != first.ID {
+				t.Fatalf("reduced order lost its place: %+v", crossing)
+			}
+			if diff := crossing[0].Remaining - tt.wantRemaining; diff > 1e-9 || diff < -1e-9 {
+				t.Errorf("remaining = %v, want %v", crossing[0].Remaining, tt.wantRemaining)
+			}
+		})
+	}
+}
+
+func TestAddDust(t *testing.T) {
+	b := restingBook(order(Bid, 100, 0.00004))
+	if got := b.Crossing(Ask, 100); len(got) != 0 {
+		t.Errorf("dust order rests on the book: %+v", got)
+	}
+}
+
+func TestRemoveEmptiesLevel(t *testing.T) {
+	only := order(Bid, 100, 1)
+	b := restingBook(only, order(Bid, 99, 1))
+
+	if !b.Remove(only.ID) {
+		t.Fatal("Remove reported the order missing")
+	}
+	if b.Remove(only.ID) {
+		t.Error("Remove reported a removed order present")
+	}
+	depth := b.Depth(5)
+	if len(depth.Bids) != 1 || depth.Bids[0].Price != 99 {
+		t.Errorf("bids = %+v, want only the 99 level", depth.Bids)
+	}
+}
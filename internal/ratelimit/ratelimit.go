@@ -0,0 +1,133 @@
+// Package ratelimit implements token bucket rate limiting behind a Store
+// interface so the in-memory buckets can be swapped for a shared store when the
+// API runs on more than one instance.
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Policy allows Limit requests per Period, refilled continuously.
+type Policy struct {
+	Name   string
+	Limit  int
+	Period time.Duration
+}
+
+// ParsePolicy reads policies written as "<limit>/<period>", e.g. "10/1m".
+func ParsePolicy(name, spec string) (Policy, error) {
+	limitStr, periodStr, ok := strings.Cut(spec, "/")
+	if !ok {
+		return Policy{}, fmt.Errorf("invalid rate limit %q, expected <limit>/<period>", spec)
+	}
+	limit, err := strconv.Atoi(strings.TrimSpace(limitStr))
+	if err != nil || limit <= 0 {
+		return Policy{}, fmt.Errorf("invalid rate limit %q: limit must be a positive number", spec)
+	}
+	period, err := time.ParseDuration(strings.TrimSpace(periodStr))
+	if err != nil || period <= 0 {
+		return Policy{}, fmt.Errorf("invalid rate limit %q: period must be a positive duration", spec)
+	}
+	return Policy{Name: name, Limit: limit, Period: period}, nil
+}
+
+func (p Policy) ratePerSecond() float64 {
+	return float64(p.Limit) / p.Period.Seconds()
+}
+
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// Reset is how long until the bucket is full again.
+	Reset time.Duration
+	// RetryAfter is how long until the next request would be allowed.
+	RetryAfter time.Duration
+}
+
+type Store interface {
+	Take(ctx context.Context, key string, policy Policy) (Result, error)
+}
+
+type bucket struct {
+	tokens   float64
+	last     time.Time
+	period   time.Duration
+	policyID string
+}
+
+type MemoryStore struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	now     func() time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		buckets: map[string]*bucket{},
+		now:     time.Now,
+	}
+}
+
+func (s *MemoryStore) Take(ctx context.Context, key string, policy Policy) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	capacity := float64(policy.Limit)
+	rate := policy.ratePerSecond()
+	policyID := fmt.Sprintf("%d/%s", policy.Limit, policy.Period)
+
+	b, ok := s.buckets[key]
+	if !ok || b.policyID != policyID {
+		b = &bucket{tokens: capacity, last: now, period: policy.Period, policyID: policyID}
+		s.buckets[key] = b
+	}
+
+	b.tokens = math.Min(capacity, b.tokens+now.Sub(b.last).Seconds()*rate)
+	b.last = now
+
+	result := Result{Limit: policy.Limit}
+	if b.tokens >= 1 {
+		b.tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = seconds((1 - b.tokens) / rate)
+	}
+	result.Remaining = int(math.Floor(b.tokens))
+	result.Reset = seconds((capacity - b.tokens) / rate)
+
+	return result, nil
+}
+
+// StartCleanup drops buckets that have refilled completely, since they carry no
+// state worth keeping.
+func (s *MemoryStore) StartCleanup(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.mu.Lock()
+			now := s.now()
+			for key, b := range s.buckets {
+				if now.Sub(b.last) >= b.period {
+					delete(s.buckets, key)
+				}
+			}
+			s.mu.Unlock()
+		}
+	}
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
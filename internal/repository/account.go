@@ -0,0 +1,139 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	customError "github.com/toluhikay/fx-exchange/internal/errors"
+	"github.com/toluhikay/fx-exchange/internal/models"
+)
+
+// softDeleteUser marks the account deleted, signs it out everywhere and
+// revokes its api keys. Personal data stays until AnonymiseUser runs.
+func softDeleteUser(ctx context.Context, tx *sql.Tx, userID uuid.UUID) error {
+	now := time.Now()
+	query := `UPDATE users SET deleted_at = $1, session_version = session_version + 1, updated_at = $1
+			WHERE id = $2 AND deleted_at IS NULL`
+	if _, err := tx.ExecContext(ctx, query, now, userID); err != nil {
+		return fmt.Errorf("failed to delete user: %w", err)
+	}
+
+	query = `UPDATE api_keys SET revoked_at = $1 WHERE user_id = $2 AND revoked_at IS NULL`
+	if _, err := tx.ExecContext(ctx, query, now, userID); err != nil {
+		return fmt.Errorf("failed to revoke api keys: %w", err)
+	}
+	return nil
+}
+
+// CloseAccount closes the user's wallet, when event is not nil, and
+// soft-deletes the account in one transaction, so a failure leaves neither
+// done. The user row is locked first and the account refused while it is
+// deleted, frozen or has an open compliance case, which a freeze landing
+// between the caller's checks and this transaction cannot get around.
+func (r *Repository) CloseAccount(ctx context.Context, userID uuid.UUID, event *models.WalletStatusEvent, payoutDestination string, payout map[string]float64) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var frozen, deleted bool
+	query := `SELECT frozen_at IS NOT NULL, deleted_at IS NOT NULL FROM users WHERE id = $1 FOR UPDATE`
+	if err := tx.QueryRowContext(ctx, query, userID).Scan(&frozen, &deleted); err != nil {
+		return fmt.Errorf("failed to lock user: %w", err)
+	}
+	switch {
+	case deleted:
+		return customError.ErrUnauthorized
+	case frozen:
+		return customError.ErrAccountFrozen
+	}
+
+	var openCases bool
+	query = `SELECT EXISTS (SELECT 1 FROM compliance_cases WHERE user_id = $1 AND status = $2)`
+	if err := tx.QueryRowContext(ctx, query, userID, models.CaseOpen).Scan(&openCases); err != nil {
+		return fmt.Errorf("failed to check compliance cases: %w", err)
+	}
+	if openCases {
+		return customError.ErrOpenComplianceCase
+	}
+
+	if event != nil {
+		if err := changeWalletStatus(ctx, tx, event, payoutDestination, payout); err != nil {
+			return err
+		}
+	}
+	if err := softDeleteUser(ctx, tx, userID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// ListUsersDueForAnonymisation returns deleted accounts whose retention period
+// ended before cutoff.
+func (m *UserDbRepo) ListUsersDueForAnonymisation(ctx context.Context, cutoff time.Time, limit int) ([]uuid.UUID, error) {
+	query := `SELECT id FROM users
+			WHERE deleted_at IS NOT NULL AND deleted_at < $1 AND anonymised_at IS NULL
+			ORDER BY deleted_at LIMIT $2`
+	rows, err := m.DB.QueryContext(ctx, query, cutoff, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list deleted users: %w", err)
+	}
+	defer rows.Close()
+
+	ids := []uuid.UUID{}
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan user id: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// AnonymiseUser scrubs personal data from a deleted account. Transactions are
+// left untouched so the ledger still balances; the user and wallet rows stay
+// as anonymous placeholders for them to reference.
+func (m *UserDbRepo) AnonymiseUser(ctx context.Context, userID uuid.UUID, throttleKey string) error {
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var id uuid.UUID
+	query := `SELECT id FROM users WHERE id = $1 AND deleted_at IS NOT NULL AND anonymised_at IS NULL FOR UPDATE`
+	if err := tx.QueryRowContext(ctx, query, userID).Scan(&id); err != nil {
+		return fmt.Errorf("failed to lock user: %w", err)
+	}
+
+	placeholder := fmt.Sprintf("deleted-%s@anonymised.invalid", userID)
+	now := time.Now()
+
+	statements := []struct {
+		query string
+		args  []any
+	}{
+		{`UPDATE users SET name = 'Deleted user', email = $1, password = '', mfa_secret = NULL, mfa_enabled_at = NULL,
+			transaction_pin = NULL, frozen_reason = NULL, anonymised_at = $2, updated_at = $2 WHERE id = $3`, []any{placeholder, now, userID}},
+		{`UPDATE wallets SET email = $1 WHERE user_id = $2`, []any{placeholder, userID}},
+		{`UPDATE audit_logs SET client_ip = '', user_agent = NULL, request_body = NULL WHERE user_id = $1`, []any{userID}},
+		{`DELETE FROM email_verifications WHERE user_id = $1`, []any{userID}},
+		{`DELETE FROM password_resets WHERE user_id = $1`, []any{userID}},
+		{`DELETE FROM mfa_recovery_codes WHERE user_id = $1`, []any{userID}},
+		{`DELETE FROM kyc_documents WHERE user_id = $1`, []any{userID}},
+		{`DELETE FROM login_throttles WHERE key = $1`, []any{throttleKey}},
+	}
+	for _, stmt := range statements {
+		if _, err := tx.ExecContext(ctx, stmt.query, stmt.args...); err != nil {
+			return fmt.Errorf("failed to anonymise user: %w", err)
+		}
+	}
+
+	return tx.Commit()
+}
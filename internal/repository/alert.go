@@ -0,0 +1,139 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/toluhikay/fx-exchange/internal/models"
+)
+
+const rateAlertColumns = `id, user_id, base_currency, quote_currency, direction, threshold, repeating, armed, status,
+             trigger_count, last_rate, last_triggered_at, created_at, updated_at`
+
+func scanRateAlert(row rowScanner) (*models.RateAlert, error) {
+	var a models.RateAlert
+	if err := row.Scan(
+		&a.ID,
+		&a.UserID,
+		&a.BaseCurrency,
+		&a.QuoteCurrency,
+		&a.Direction,
+		&a.Threshold,
+		&a.Repeating,
+		&a.Armed,
+		&a.Status,
+		&a.TriggerCount,
+		&a.LastRate,
+		&a.LastTriggeredAt,
+		&a.CreatedAt,
+		&a.UpdatedAt,
+	); err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+func scanRateAlerts(rows *sql.Rows) ([]models.RateAlert, error) {
+	alerts := []models.RateAlert{}
+	for rows.Next() {
+		a, err := scanRateAlert(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan rate alert: %w", err)
+		}
+		alerts = append(alerts, *a)
+	}
+	return alerts, rows.Err()
+}
+
+func (r *Repository) CreateRateAlert(ctx context.Context, a models.RateAlert) (*models.RateAlert, error) {
+	now := time.Now()
+	query := `INSERT INTO rate_alerts (id, user_id, base_currency, quote_currency, direction, threshold, repeating, armed, status,
+             created_at, updated_at)
+             VALUES ($1, $2, $3, $4, $5, $6, $7, TRUE, $8, $9, $9)
+             RETURNING ` + rateAlertColumns
+	alert, err := scanRateAlert(r.db.QueryRowContext(ctx, query, uuid.New(), a.UserID, a.BaseCurrency, a.QuoteCurrency,
+		a.Direction, a.Threshold, a.Repeating, models.AlertActive, now))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create rate alert: %w", err)
+	}
+	return alert, nil
+}
+
+func (r *Repository) GetRateAlert(ctx context.Context, id uuid.UUID) (*models.RateAlert, error) {
+	query := `SELECT ` + rateAlertColumns + ` FROM rate_alerts WHERE id = $1`
+	return scanRateAlert(r.db.QueryRowContext(ctx, query, id))
+}
+
+// ListRateAlerts returns the user's alerts, newest first. An empty status
+// lists every alert.
+func (r *Repository) ListRateAlerts(ctx context.Context, userID uuid.UUID, status string, limit, offset int) ([]models.RateAlert, error) {
+	query := `SELECT ` + rateAlertColumns + ` FROM rate_alerts
+             WHERE user_id = $1 AND ($2 = '' OR status = $2)
+             ORDER BY created_at DESC LIMIT $3 OFFSET $4`
+	rows, err := r.db.QueryContext(ctx, query, userID, status, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list rate alerts: %w", err)
+	}
+	defer rows.Close()
+
+	return scanRateAlerts(rows)
+}
+
+func (r *Repository) CountActiveRateAlerts(ctx context.Context, userID uuid.UUID) (int, error) {
+	var count int
+	query := `SELECT COUNT(*) FROM rate_alerts WHERE user_id = $1 AND status = 'active'`
+	if err := r.db.QueryRowContext(ctx, query, userID).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count rate alerts: %w", err)
+	}
+	return count, nil
+}
+
+// ListActiveRateAlerts returns every active alert, oldest first, to check
+// against a rate update.
+func (r *Repository) ListActiveRateAlerts(ctx context.Context) ([]models.RateAlert, error) {
+	query := `SELECT ` + rateAlertColumns + ` FROM rate_alerts WHERE status = 'active' ORDER BY created_at`
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list active rate alerts: %w", err)
+	}
+	defer rows.Close()
+
+	return scanRateAlerts(rows)
+}
+
+// TriggerRateAlert records that an armed alert fired at rate. A repeating
+// alert stays active but disarmed; any other alert becomes triggered. It
+// returns sql.ErrNoRows when the alert is no longer armed and active, so an
+// alert fires at most once per crossing.
+func (r *Repository) TriggerRateAlert(ctx context.Context, id uuid.UUID, rate float64) (*models.RateAlert, error) {
+	query := `UPDATE rate_alerts
+             SET armed = FALSE,
+                 status = CASE WHEN repeating THEN status ELSE $1 END,
+                 trigger_count = trigger_count + 1, last_rate = $2, last_triggered_at = $3, updated_at = $3
+             WHERE id = $4 AND status = 'active' AND armed
+             RETURNING ` + rateAlertColumns
+	return scanRateAlert(r.db.QueryRowContext(ctx, query, models.AlertTriggered, rate, time.Now(), id))
+}
+
+// RearmRateAlert arms a repeating alert again once the rate is back on the
+// other side of its threshold.
+func (r *Repository) RearmRateAlert(ctx context.Context, id uuid.UUID, rate float64) error {
+	query := `UPDATE rate_alerts SET armed = TRUE, last_rate = $1, updated_at = $2
+             WHERE id = $3 AND status = 'active' AND NOT armed`
+	if _, err := r.db.ExecContext(ctx, query, rate, time.Now(), id); err != nil {
+		return fmt.Errorf("failed to rearm rate alert: %w", err)
+	}
+	return nil
+}
+
+// CancelRateAlert returns sql.ErrNoRows when the user has no active alert
+// with that id.
+func (r *Repository) CancelRateAlert(ctx context.Context, userID, id uuid.UUID) (*models.RateAlert, error) {
+	query := `UPDATE rate_alerts SET status = $1, updated_at = $2
+             WHERE id = $3 AND user_id = $4 AND status = 'active'
+             RETURNING ` + rateAlertColumns
+	return scanRateAlert(r.db.QueryRowContext(ctx, query, models.AlertCancelled, time.Now(), id, userID))
+}
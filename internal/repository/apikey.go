@@ -0,0 +1,107 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/toluhikay/fx-exchange/internal/models"
+)
+
+func (m *UserDbRepo) CreateAPIKey(ctx context.Context, k models.APIKey) error {
+	scopesJSON, err := json.Marshal(k.Scopes)
+	if err != nil {
+		return fmt.Errorf("failed to marshal scopes: %w", err)
+	}
+	allowedIPsJSON, err := json.Marshal(k.AllowedIPs)
+	if err != nil {
+		return fmt.Errorf("failed to marshal allowed ips: %w", err)
+	}
+
+	query := `INSERT INTO api_keys (id, user_id, name, prefix, key_hash, scopes, allowed_ips, created_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`
+	_, err = m.DB.ExecContext(ctx, query, k.ID, k.UserID, k.Name, k.Prefix, k.KeyHash, scopesJSON, allowedIPsJSON, k.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create api key: %w", err)
+	}
+	return nil
+}
+
+func (m *UserDbRepo) GetAPIKeyByPrefix(ctx context.Context, prefix string) (*models.APIKey, error) {
+	query := `SELECT id, user_id, name, prefix, key_hash, scopes, allowed_ips, created_at, last_used_at, revoked_at
+			FROM api_keys WHERE prefix = $1`
+	return scanAPIKey(m.DB.QueryRowContext(ctx, query, prefix))
+}
+
+func (m *UserDbRepo) ListAPIKeys(ctx context.Context, userID uuid.UUID) ([]models.APIKey, error) {
+	query := `SELECT id, user_id, name, prefix, key_hash, scopes, allowed_ips, created_at, last_used_at, revoked_at
+			FROM api_keys WHERE user_id = $1 ORDER BY created_at DESC`
+	rows, err := m.DB.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list api keys: %w", err)
+	}
+	defer rows.Close()
+
+	keys := []models.APIKey{}
+	for rows.Next() {
+		k, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, *k)
+	}
+	return keys, rows.Err()
+}
+
+func (m *UserDbRepo) RevokeAPIKey(ctx context.Context, userID, keyID uuid.UUID) error {
+	query := `UPDATE api_keys SET revoked_at = $1 WHERE id = $2 AND user_id = $3 AND revoked_at IS NULL`
+	res, err := m.DB.ExecContext(ctx, query, time.Now(), keyID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to revoke api key: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func (m *UserDbRepo) TouchAPIKey(ctx context.Context, keyID uuid.UUID) error {
+	query := `UPDATE api_keys SET last_used_at = $1 WHERE id = $2`
+	if _, err := m.DB.ExecContext(ctx, query, time.Now(), keyID); err != nil {
+		return fmt.Errorf("failed to update api key usage: %w", err)
+	}
+	return nil
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanAPIKey(row rowScanner) (*models.APIKey, error) {
+	var k models.APIKey
+	var scopesJSON, allowedIPsJSON []byte
+	if err := row.Scan(
+		&k.ID,
+		&k.UserID,
+		&k.Name,
+		&k.Prefix,
+		&k.KeyHash,
+		&scopesJSON,
+		&allowedIPsJSON,
+		&k.CreatedAt,
+		&k.LastUsedAt,
+		&k.RevokedAt,
+	); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(scopesJSON, &k.Scopes); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal scopes: %w", err)
+	}
+	if err := json.Unmarshal(allowedIPsJSON, &k.AllowedIPs); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal allowed ips: %w", err)
+	}
+	return &k, nil
+}
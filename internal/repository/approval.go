@@ -0,0 +1,302 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	customError "github.com/toluhikay/fx-exchange/internal/errors"
+	"github.com/toluhikay/fx-exchange/internal/models"
+)
+
+// GetApprovalPolicy returns the wallet's approvers and threshold. Wallets
+// without approvers do not need sign-off.
+func (r *Repository) GetApprovalPolicy(ctx context.Context, walletID string) (*models.ApprovalPolicy, error) {
+	policy := models.ApprovalPolicy{ApproverIDs: []uuid.UUID{}}
+
+	query := `SELECT COALESCE(approval_threshold_usd, 0) FROM wallets WHERE id = $1`
+	if err := r.db.QueryRowContext(ctx, query, walletID).Scan(&policy.ThresholdUSD); err != nil {
+		return nil, fmt.Errorf("failed to get approval policy: %w", err)
+	}
+
+	query = `SELECT user_id FROM wallet_approvers WHERE wallet_id = $1 ORDER BY created_at`
+	rows, err := r.db.QueryContext(ctx, query, walletID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list wallet approvers: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan wallet approver: %w", err)
+		}
+		policy.ApproverIDs = append(policy.ApproverIDs, id)
+	}
+	return &policy, rows.Err()
+}
+
+// SetApprovalPolicy replaces the wallet's approvers and threshold.
+func (r *Repository) SetApprovalPolicy(ctx context.Context, walletID string, policy models.ApprovalPolicy) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `UPDATE wallets SET approval_threshold_usd = NULLIF($1, 0) WHERE id = $2`
+	if _, err := tx.ExecContext(ctx, query, policy.ThresholdUSD, walletID); err != nil {
+		return fmt.Errorf("failed to update approval threshold: %w", err)
+	}
+
+	query = `DELETE FROM wallet_approvers WHERE wallet_id = $1`
+	if _, err := tx.ExecContext(ctx, query, walletID); err != nil {
+		return fmt.Errorf("failed to clear wallet approvers: %w", err)
+	}
+
+	query = `INSERT INTO wallet_approvers (wallet_id, user_id, created_at) VALUES ($1, $2, $3)`
+	for _, id := range policy.ApproverIDs {
+		if _, err := tx.ExecContext(ctx, query, walletID, id, time.Now()); err != nil {
+			return fmt.Errorf("failed to add wallet approver: %w", err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+const transferApprovalColumns = `id, wallet_id, receiver_wallet_id, requested_by, currency, amount, amount_usd, status,
+             approver_id, note, hold_id, created_at, expires_at, resolved_at`
+
+func scanTransferApproval(row rowScanner) (*models.TransferApproval, error) {
+	var a models.TransferApproval
+	if err := row.Scan(
+		&a.ID,
+		&a.WalletID,
+		&a.ReceiverWalletID,
+		&a.RequestedBy,
+		&a.Currency,
+		&a.Amount,
+		&a.AmountUSD,
+		&a.Status,
+		&a.ApproverID,
+		&a.Note,
+		&a.HoldID,
+		&a.CreatedAt,
+		&a.ExpiresAt,
+		&a.ResolvedAt,
+	); err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+func scanTransferApprovals(rows *sql.Rows) ([]models.TransferApproval, error) {
+	approvals := []models.TransferApproval{}
+	for rows.Next() {
+		a, err := scanTransferApproval(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan transfer approval: %w", err)
+		}
+		approvals = append(approvals, *a)
+	}
+	return approvals, rows.Err()
+}
+
+// CreateTransferApproval reserves the amount with a hold on the sender's
+// balance and records the pending request.
+func (r *Repository) CreateTransferApproval(ctx context.Context, a models.TransferApproval) (*models.TransferApproval, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	balances, status, err := lockWallet(ctx, tx, a.WalletID)
+	if err != nil {
+		return nil, err
+	}
+	hold, err := insertHold(ctx, tx, models.BalanceHold{
+		WalletID:  a.WalletID,
+		Kind:      models.HoldKindApproval,
+		Currency:  a.Currency,
+		Amount:    a.Amount,
+		ExpiresAt: a.ExpiresAt,
+	}, balances, status)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `INSERT INTO transfer_approvals (id, wallet_id, receiver_wallet_id, requested_by, currency, amount, amount_usd, status, hold_id, created_at, expires_at)
+             VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+             RETURNING ` + transferApprovalColumns
+	created, err := scanTransferApproval(tx.QueryRowContext(ctx, query, uuid.New(), a.WalletID, a.ReceiverWalletID, a.RequestedBy,
+		a.Currency, a.Amount, a.AmountUSD, models.ApprovalPending, hold.ID, time.Now(), a.ExpiresAt))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create transfer approval: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+func (r *Repository) GetTransferApproval(ctx context.Context, id uuid.UUID) (*models.TransferApproval, error) {
+	query := `SELECT ` + transferApprovalColumns + ` FROM transfer_approvals WHERE id = $1`
+	return scanTransferApproval(r.db.QueryRowContext(ctx, query, id))
+}
+
+// ListApprovalsForApprover returns requests on the wallets the user approves
+// for, oldest first.
+func (r *Repository) ListApprovalsForApprover(ctx context.Context, approverID uuid.UUID, status string, limit, offset int) ([]models.TransferApproval, error) {
+	query := `SELECT ` + transferApprovalColumns + ` FROM transfer_approvals
+             WHERE status = $2 AND wallet_id IN (SELECT wallet_id FROM wallet_approvers WHERE user_id = $1)
+             ORDER BY created_at LIMIT $3 OFFSET $4`
+	rows, err := r.db.QueryContext(ctx, query, approverID, status, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list transfer approvals: %w", err)
+	}
+	defer rows.Close()
+
+	return scanTransferApprovals(rows)
+}
+
+func (r *Repository) ListWalletApprovals(ctx context.Context, walletID string, limit, offset int) ([]models.TransferApproval, error) {
+	query := `SELECT ` + transferApprovalColumns + ` FROM transfer_approvals WHERE wallet_id = $1
+             ORDER BY created_at DESC LIMIT $2 OFFSET $3`
+	rows, err := r.db.QueryContext(ctx, query, walletID, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list transfer approvals: %w", err)
+	}
+	defer rows.Close()
+
+	return scanTransferApprovals(rows)
+}
+
+// lockPendingApproval returns sql.ErrNoRows when the request is no longer
+// pending.
+func lockPendingApproval(ctx context.Context, tx *sql.Tx, id uuid.UUID) (*models.TransferApproval, error) {
+	query := `SELECT ` + transferApprovalColumns + ` FROM transfer_approvals WHERE id = $1 AND status = 'pending' FOR UPDATE`
+	return scanTransferApproval(tx.QueryRowContext(ctx, query, id))
+}
+
+// CompleteTransferApproval captures the request's hold on the sender, pays
+// the receiver and logs the transfer.
+func (r *Repository) CompleteTransferApproval(ctx context.Context, id, approverID uuid.UUID, toCurrency string, rate, convertedAmount float64) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	a, err := lockPendingApproval(ctx, tx, id)
+	if err != nil {
+		return err
+	}
+	if time.Now().After(a.ExpiresAt) {
+		return customError.ErrApprovalExpired
+	}
+
+	senderBalances, senderStatus, err := lockWallet(ctx, tx, a.WalletID)
+	if err != nil {
+		return err
+	}
+	if err := checkDebit(senderStatus); err != nil {
+		return err
+	}
+	hold, err := lockActiveHold(ctx, tx, a.HoldID)
+	if err != nil {
+		if errors.Is(err, customError.ErrHoldNotActive) {
+			return customError.ErrApprovalExpired
+		}
+		return err
+	}
+	if err := captureHold(ctx, tx, hold, hold.Remaining(), senderBalances); err != nil {
+		return err
+	}
+	if err := saveBalances(ctx, tx, a.WalletID, senderBalances); err != nil {
+		return err
+	}
+
+	balances, status, err := lockWallet(ctx, tx, a.ReceiverWalletID)
+	if err != nil {
+		return err
+	}
+	if err := checkCredit(status); err != nil {
+		return fmt.Errorf("receiver %w", err)
+	}
+	if _, ok := balances[toCurrency]; !ok {
+		return fmt.Errorf("unsupported receiver currency: %s", toCurrency)
+	}
+
+	balances[toCurrency] += convertedAmount
+	if err := saveBalances(ctx, tx, a.ReceiverWalletID, balances); err != nil {
+		return err
+	}
+
+	query := `INSERT INTO transactions (id, wallet_id, type, from_currency, to_currency, amount, converted_amount, rate, receiver_wallet_id, timestamp) 
+             VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`
+	_, err = tx.ExecContext(ctx, query, uuid.New().String(), a.WalletID, "transfer", a.Currency, toCurrency, a.Amount, convertedAmount, rate, a.ReceiverWalletID, time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to log transaction: %w", err)
+	}
+
+	query = `UPDATE transfer_approvals SET status = $1, approver_id = $2, resolved_at = $3 WHERE id = $4`
+	if _, err := tx.ExecContext(ctx, query, models.ApprovalApproved, approverID, time.Now(), id); err != nil {
+		return fmt.Errorf("failed to update transfer approval: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+// ReleaseTransferApproval frees the request's hold and closes it as rejected
+// or expired.
+func (r *Repository) ReleaseTransferApproval(ctx context.Context, id uuid.UUID, status string, approverID *uuid.UUID, note *string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	a, err := lockPendingApproval(ctx, tx, id)
+	if err != nil {
+		return err
+	}
+
+	holdStatus := models.HoldReleased
+	if status == models.ApprovalExpired {
+		holdStatus = models.HoldExpired
+	}
+	if err := releaseHold(ctx, tx, a.HoldID, holdStatus); err != nil {
+		return err
+	}
+
+	query := `UPDATE transfer_approvals SET status = $1, approver_id = $2, note = $3, resolved_at = $4 WHERE id = $5`
+	if _, err := tx.ExecContext(ctx, query, status, approverID, note, time.Now(), id); err != nil {
+		return fmt.Errorf("failed to update transfer approval: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+func (r *Repository) ListExpiredApprovals(ctx context.Context, now time.Time, limit int) ([]uuid.UUID, error) {
+	query := `SELECT id FROM transfer_approvals WHERE status = 'pending' AND expires_at <= $1 ORDER BY expires_at LIMIT $2`
+	rows, err := r.db.QueryContext(ctx, query, now, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list expired approvals: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan approval id: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
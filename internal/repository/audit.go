@@ -0,0 +1,82 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/toluhikay/fx-exchange/internal/models"
+)
+
+type AuditRepo struct {
+	db *sql.DB
+}
+
+func NewAuditRepo(db *sql.DB) *AuditRepo {
+	return &AuditRepo{db: db}
+}
+
+func (a *AuditRepo) CreateAuditLog(ctx context.Context, l models.AuditLog) error {
+	if l.ID == uuid.Nil {
+		l.ID = uuid.New()
+	}
+	if l.Timestamp.IsZero() {
+		l.Timestamp = time.Now()
+	}
+
+	query := `INSERT INTO audit_logs (id, wallet_id, user_id, operation, client_ip, user_agent, request_method, request_path, request_body, timestamp)
+             VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`
+	_, err := a.db.ExecContext(ctx, query, l.ID, l.WalletID, l.UserID, l.Operation, l.ClientIP, l.UserAgent, l.RequestMethod, l.RequestPath, l.RequestBody, l.Timestamp)
+	if err != nil {
+		return fmt.Errorf("failed to create audit log: %w", err)
+	}
+	return nil
+}
+
+// ListAuditLogs returns the newest entries first for either a wallet or a
+// user; the other id should be nil.
+func (a *AuditRepo) ListAuditLogs(ctx context.Context, walletID *string, userID *uuid.UUID, limit, offset int) ([]models.AuditLog, error) {
+	query := `SELECT id, wallet_id, user_id, operation, client_ip, user_agent, request_method, request_path, request_body, timestamp
+             FROM audit_logs
+             WHERE ($1::uuid IS NULL OR wallet_id = $1) AND ($2::uuid IS NULL OR user_id = $2)
+             ORDER BY timestamp DESC LIMIT $3 OFFSET $4`
+	rows, err := a.db.QueryContext(ctx, query, walletID, userID, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list audit logs: %w", err)
+	}
+	defer rows.Close()
+
+	return scanAuditLogs(rows)
+}
+
+// ExportAuditLogs returns every entry made by the user or against their wallet.
+func (a *AuditRepo) ExportAuditLogs(ctx context.Context, userID uuid.UUID, walletID *string) ([]models.AuditLog, error) {
+	query := `SELECT id, wallet_id, user_id, operation, client_ip, user_agent, request_method, request_path, request_body, timestamp
+             FROM audit_logs
+             WHERE user_id = $1 OR ($2::uuid IS NOT NULL AND wallet_id = $2)
+             ORDER BY timestamp DESC`
+	rows, err := a.db.QueryContext(ctx, query, userID, walletID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to export audit logs: %w", err)
+	}
+	defer rows.Close()
+
+	return scanAuditLogs(rows)
+}
+
+func scanAuditLogs(rows *sql.Rows) ([]models.AuditLog, error) {
+	logs := []models.AuditLog{}
+	for rows.Next() {
+		var l models.AuditLog
+		var userAgent, method, path, body sql.NullString
+		err := rows.Scan(&l.ID, &l.WalletID, &l.UserID, &l.Operation, &l.ClientIP, &userAgent, &method, &path, &body, &l.Timestamp)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan audit log: %w", err)
+		}
+		l.UserAgent, l.RequestMethod, l.RequestPath, l.RequestBody = userAgent.String, method.String, path.String, body.String
+		logs = append(logs, l)
+	}
+	return logs, rows.Err()
+}
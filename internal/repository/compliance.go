@@ -0,0 +1,274 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/toluhikay/fx-exchange/internal/models"
+)
+
+// IncomingSince sums what the wallet received in currency since the given
+// time: deposits, transfers from other wallets and swaps into the currency.
+func (r *Repository) IncomingSince(ctx context.Context, walletID, currency string, since time.Time) (float64, error) {
+	query := `SELECT COALESCE(SUM(CASE WHEN type = 'deposit' THEN amount ELSE converted_amount END), 0)
+             FROM transactions
+             WHERE timestamp >= $3 AND to_currency = $2 AND (
+                 (wallet_id = $1 AND type IN ('deposit', 'swap'))
+                 OR (receiver_wallet_id = $1 AND type = 'transfer'))`
+	var total float64
+	if err := r.db.QueryRowContext(ctx, query, walletID, currency, since).Scan(&total); err != nil {
+		return 0, fmt.Errorf("failed to sum incoming funds: %w", err)
+	}
+	return total, nil
+}
+
+// CountAtLeast counts the wallet's transactions in currency of at least
+// minAmount since the given time.
+func (r *Repository) CountAtLeast(ctx context.Context, walletID, currency string, minAmount float64, since time.Time) (int, error) {
+	query := `SELECT COUNT(*) FROM transactions
+             WHERE wallet_id = $1 AND timestamp >= $4 AND amount >= $3
+             AND ((type = 'deposit' AND to_currency = $2) OR (type <> 'deposit' AND from_currency = $2))`
+	var count int
+	if err := r.db.QueryRowContext(ctx, query, walletID, currency, minAmount, since).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count transactions: %w", err)
+	}
+	return count, nil
+}
+
+// RecipientsSince lists the distinct wallets the wallet sent transfers to.
+func (r *Repository) RecipientsSince(ctx context.Context, walletID string, since time.Time) ([]string, error) {
+	query := `SELECT DISTINCT receiver_wallet_id FROM transactions
+             WHERE wallet_id = $1 AND type = 'transfer' AND receiver_wallet_id IS NOT NULL AND timestamp >= $2`
+	rows, err := r.db.QueryContext(ctx, query, walletID, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list recipients: %w", err)
+	}
+	defer rows.Close()
+
+	var recipients []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan recipient: %w", err)
+		}
+		recipients = append(recipients, id)
+	}
+	return recipients, rows.Err()
+}
+
+// DeviceFirstSeen returns nil for a device the user has not acted from before.
+func (r *Repository) DeviceFirstSeen(ctx context.Context, userID, device string) (*time.Time, error) {
+	query := `SELECT first_seen_at FROM user_devices WHERE user_id = $1 AND fingerprint = $2`
+	var firstSeen time.Time
+	err := r.db.QueryRowContext(ctx, query, userID, device).Scan(&firstSeen)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get device: %w", err)
+	}
+	return &firstSeen, nil
+}
+
+func (r *Repository) TouchDevice(ctx context.Context, userID, device string) error {
+	query := `INSERT INTO user_devices (user_id, fingerprint, first_seen_at, last_seen_at) VALUES ($1, $2, $3, $3)
+             ON CONFLICT (user_id, fingerprint) DO UPDATE SET last_seen_at = EXCLUDED.last_seen_at`
+	if _, err := r.db.ExecContext(ctx, query, userID, device, time.Now()); err != nil {
+		return fmt.Errorf("failed to record device: %w", err)
+	}
+	return nil
+}
+
+const complianceCaseColumns = `id, user_id, COALESCE(wallet_id::text, ''), source, action, status, operation, hits, reviewer_id, review_note, created_at, resolved_at`
+
+func scanComplianceCase(row rowScanner) (*models.ComplianceCase, error) {
+	var c models.ComplianceCase
+	var operationJSON, hitsJSON []byte
+	if err := row.Scan(
+		&c.ID,
+		&c.UserID,
+		&c.WalletID,
+		&c.Source,
+		&c.Action,
+		&c.Status,
+		&operationJSON,
+		&hitsJSON,
+		&c.ReviewerID,
+		&c.ReviewNote,
+		&c.CreatedAt,
+		&c.ResolvedAt,
+	); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(operationJSON, &c.Operation); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal case operation: %w", err)
+	}
+	if err := json.Unmarshal(hitsJSON, &c.Hits); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal case hits: %w", err)
+	}
+	return &c, nil
+}
+
+func (r *Repository) CreateComplianceCase(ctx context.Context, c models.ComplianceCase) (*models.ComplianceCase, error) {
+	return createComplianceCase(ctx, r.db, c)
+}
+
+func createComplianceCase(ctx context.Context, q queryer, c models.ComplianceCase) (*models.ComplianceCase, error) {
+	operationJSON, err := json.Marshal(c.Operation)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal case operation: %w", err)
+	}
+	hitsJSON, err := json.Marshal(c.Hits)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal case hits: %w", err)
+	}
+
+	query := `INSERT INTO compliance_cases (id, user_id, wallet_id, source, action, status, operation, hits, created_at)
+             VALUES ($1, $2, NULLIF($3, '')::uuid, $4, $5, $6, $7, $8, $9)
+             RETURNING ` + complianceCaseColumns
+	created, err := scanComplianceCase(q.QueryRowContext(ctx, query, uuid.New(), c.UserID, c.WalletID, c.Source, c.Action, c.Status, operationJSON, hitsJSON, time.Now()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create compliance case: %w", err)
+	}
+	return created, nil
+}
+
+func (r *Repository) GetComplianceCase(ctx context.Context, id uuid.UUID) (*models.ComplianceCase, error) {
+	query := `SELECT ` + complianceCaseColumns + ` FROM compliance_cases WHERE id = $1`
+	return scanComplianceCase(r.db.QueryRowContext(ctx, query, id))
+}
+
+// ListComplianceCases returns the queue for a status, oldest first.
+func (r *Repository) ListComplianceCases(ctx context.Context, status string, limit, offset int) ([]models.ComplianceCase, error) {
+	query := `SELECT ` + complianceCaseColumns + ` FROM compliance_cases WHERE status = $1 ORDER BY created_at LIMIT $2 OFFSET $3`
+	rows, err := r.db.QueryContext(ctx, query, status, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list compliance cases: %w", err)
+	}
+	defer rows.Close()
+
+	cases := []models.ComplianceCase{}
+	for rows.Next() {
+		c, err := scanComplianceCase(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan compliance case: %w", err)
+		}
+		cases = append(cases, *c)
+	}
+	return cases, rows.Err()
+}
+
+// ResolveComplianceCase closes an open case. It returns sql.ErrNoRows when the
+// case is not open.
+func (r *Repository) ResolveComplianceCase(ctx context.Context, id, reviewerID uuid.UUID, status, note string) error {
+	query := `UPDATE compliance_cases SET status = $1, reviewer_id = $2, review_note = NULLIF($3, ''), resolved_at = $4
+             WHERE id = $5 AND status = 'open'`
+	result, err := r.db.ExecContext(ctx, query, status, reviewerID, note, time.Now(), id)
+	if err != nil {
+		return fmt.Errorf("failed to resolve compliance case: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// ReopenComplianceCase puts an approved case back in the queue, used when the
+// approved operation could not be executed.
+func (r *Repository) ReopenComplianceCase(ctx context.Context, id uuid.UUID) error {
+	query := `UPDATE compliance_cases SET status = 'open', reviewer_id = NULL, review_note = NULL, resolved_at = NULL
+             WHERE id = $1 AND status = 'approved'`
+	if _, err := r.db.ExecContext(ctx, query, id); err != nil {
+		return fmt.Errorf("failed to reopen compliance case: %w", err)
+	}
+	return nil
+}
+
+// GetWalletHolder returns the owner of a wallet and their name.
+func (r *Repository) GetWalletHolder(ctx context.Context, walletID string) (uuid.UUID, string, error) {
+	query := `SELECT u.id, u.name FROM wallets w JOIN users u ON u.id = w.user_id WHERE w.id = $1`
+	var userID uuid.UUID
+	var name string
+	if err := r.db.QueryRowContext(ctx, query, walletID).Scan(&userID, &name); err != nil {
+		return uuid.Nil, "", err
+	}
+	return userID, name, nil
+}
+
+// CreateUserForReview creates a new account frozen pending sanctions review
+// together with the case that reviews it, so the account never exists without
+// its case.
+func (r *Repository) CreateUserForReview(ctx context.Context, u models.User, c models.ComplianceCase) (*models.User, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	reason := models.SanctionsFreezeReason
+	u.FrozenAt, u.FrozenReason = &now, &reason
+	created, err := createUser(ctx, tx, u)
+	if err != nil {
+		return nil, err
+	}
+	c.UserID = created.ID
+	if _, err := createComplianceCase(ctx, tx, c); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+// ReleaseScreeningHold lifts the freeze set by CreateUserForReview. Freezes
+// placed by staff for other reasons are left alone.
+func (r *Repository) ReleaseScreeningHold(ctx context.Context, userID uuid.UUID) error {
+	query := `UPDATE users SET frozen_at = NULL, frozen_reason = NULL, updated_at = $1 WHERE id = $2 AND frozen_reason = $3`
+	if _, err := r.db.ExecContext(ctx, query, time.Now(), userID, models.SanctionsFreezeReason); err != nil {
+		return fmt.Errorf("failed to release screening hold: %w", err)
+	}
+	return nil
+}
+
+func (r *Repository) ListSanctionsClearances(ctx context.Context, userID uuid.UUID) ([]string, error) {
+	query := `SELECT entry_id FROM sanctions_clearances WHERE user_id = $1`
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sanctions clearances: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []string
+	for rows.Next() {
+		var entry string
+		if err := rows.Scan(&entry); err != nil {
+			return nil, fmt.Errorf("failed to scan sanctions clearance: %w", err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
+
+// ClearSanctionsHits records that the sanctions hits of an approved case were
+// false positives.
+func (r *Repository) ClearSanctionsHits(ctx context.Context, caseID uuid.UUID, hits []models.RuleHit) error {
+	query := `INSERT INTO sanctions_clearances (user_id, entry_id, case_id, cleared_at) VALUES ($1, $2, $3, $4)
+             ON CONFLICT (user_id, entry_id) DO NOTHING`
+	for _, hit := range hits {
+		if hit.Subject == "" || hit.ListEntry == "" {
+			continue
+		}
+		if _, err := r.db.ExecContext(ctx, query, hit.Subject, hit.ListEntry, caseID, time.Now()); err != nil {
+			return fmt.Errorf("failed to clear sanctions hit: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,258 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+	customError "github.com/toluhikay/fx-exchange/internal/errors"
+	"github.com/toluhikay/fx-exchange/internal/models"
+)
+
+const disputeColumns = `id, transaction_id, sender_wallet_id, receiver_wallet_id, opened_by, reason, description, currency, amount,
+             held_amount, hold_id, status, resolved_by, resolution_note, created_at, updated_at, resolved_at`
+
+func scanDispute(row rowScanner) (*models.Dispute, error) {
+	var d models.Dispute
+	if err := row.Scan(
+		&d.ID,
+		&d.TransactionID,
+		&d.SenderWalletID,
+		&d.ReceiverWalletID,
+		&d.OpenedBy,
+		&d.Reason,
+		&d.Description,
+		&d.Currency,
+		&d.Amount,
+		&d.HeldAmount,
+		&d.HoldID,
+		&d.Status,
+		&d.ResolvedBy,
+		&d.ResolutionNote,
+		&d.CreatedAt,
+		&d.UpdatedAt,
+		&d.ResolvedAt,
+	); err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+func scanDisputes(rows *sql.Rows) ([]models.Dispute, error) {
+	disputes := []models.Dispute{}
+	for rows.Next() {
+		d, err := scanDispute(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan dispute: %w", err)
+		}
+		disputes = append(disputes, *d)
+	}
+	return disputes, rows.Err()
+}
+
+// CreateDispute opens a dispute on the unreversed part of a transfer and
+// holds as much of it as the receiver still has available. holdExpiry bounds
+// how long the funds stay held if nobody decides the dispute.
+func (r *Repository) CreateDispute(ctx context.Context, d models.Dispute, holdExpiry time.Time) (*models.Dispute, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `SELECT ` + transactionColumns + ` FROM transactions WHERE id = $1 FOR UPDATE`
+	original, err := scanTransaction(tx.QueryRowContext(ctx, query, d.TransactionID))
+	if err != nil {
+		return nil, err
+	}
+	if original.Type != "transfer" || original.WalletID != d.SenderWalletID || original.ReceiverWalletID == nil ||
+		original.Amount == nil || original.ConvertedAmount == nil || original.ToCurrency == nil {
+		return nil, customError.ErrNotDisputable
+	}
+	if original.Status == models.TxReversed {
+		return nil, customError.ErrAlreadyReversed
+	}
+
+	d.ID = uuid.New()
+	d.ReceiverWalletID = *original.ReceiverWalletID
+	d.Currency = *original.ToCurrency
+	d.Amount = *original.Amount - original.ReversedAmount
+	received := *original.ConvertedAmount * d.Amount / *original.Amount
+
+	balances, _, err := lockWallet(ctx, tx, d.ReceiverWalletID)
+	if err != nil {
+		return nil, err
+	}
+	held, err := heldAmount(ctx, tx, d.ReceiverWalletID, d.Currency)
+	if err != nil {
+		return nil, err
+	}
+	d.HeldAmount = math.Min(received, math.Max(balances[d.Currency]-held, 0))
+
+	if d.HeldAmount > 0 {
+		reference := "dispute " + d.ID.String()
+		hold, err := createHold(ctx, tx, models.BalanceHold{
+			WalletID:  d.ReceiverWalletID,
+			Kind:      models.HoldKindDispute,
+			Currency:  d.Currency,
+			Amount:    d.HeldAmount,
+			Reference: &reference,
+			ExpiresAt: holdExpiry,
+		})
+		if err != nil {
+			return nil, err
+		}
+		d.HoldID = &hold.ID
+	}
+
+	now := time.Now()
+	query = `INSERT INTO disputes (id, transaction_id, sender_wallet_id, receiver_wallet_id, opened_by, reason, description, currency, amount,
+                 held_amount, hold_id, status, created_at, updated_at)
+             VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+             RETURNING ` + disputeColumns
+	created, err := scanDispute(tx.QueryRowContext(ctx, query, d.ID, d.TransactionID, d.SenderWalletID, d.ReceiverWalletID, d.OpenedBy,
+		d.Reason, d.Description, d.Currency, d.Amount, d.HeldAmount, d.HoldID, models.DisputeOpened, now, now))
+	if err != nil {
+		if customError.ErrorCode(err) == customError.UniqueViolation {
+			return nil, customError.ErrDisputeExists
+		}
+		return nil, fmt.Errorf("failed to create dispute: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+func (r *Repository) GetDispute(ctx context.Context, id uuid.UUID) (*models.Dispute, error) {
+	query := `SELECT ` + disputeColumns + ` FROM disputes WHERE id = $1`
+	return scanDispute(r.db.QueryRowContext(ctx, query, id))
+}
+
+// ListWalletDisputes returns disputes the wallet raised or is named in,
+// newest first.
+func (r *Repository) ListWalletDisputes(ctx context.Context, walletID string, limit, offset int) ([]models.Dispute, error) {
+	query := `SELECT ` + disputeColumns + ` FROM disputes WHERE sender_wallet_id = $1 OR receiver_wallet_id = $1
+             ORDER BY created_at DESC LIMIT $2 OFFSET $3`
+	rows, err := r.db.QueryContext(ctx, query, walletID, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list disputes: %w", err)
+	}
+	defer rows.Close()
+
+	return scanDisputes(rows)
+}
+
+// ListDisputes returns the review queue, oldest first.
+func (r *Repository) ListDisputes(ctx context.Context, status string, limit, offset int) ([]models.Dispute, error) {
+	query := `SELECT ` + disputeColumns + ` FROM disputes WHERE status = $1 ORDER BY created_at LIMIT $2 OFFSET $3`
+	rows, err := r.db.QueryContext(ctx, query, status, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list disputes: %w", err)
+	}
+	defer rows.Close()
+
+	return scanDisputes(rows)
+}
+
+// MarkDisputeUnderReview returns sql.ErrNoRows unless the dispute was opened
+// and not yet picked up.
+func (r *Repository) MarkDisputeUnderReview(ctx context.Context, id uuid.UUID) error {
+	query := `UPDATE disputes SET status = $1, updated_at = $2 WHERE id = $3 AND status = $4`
+	result, err := r.db.ExecContext(ctx, query, models.DisputeUnderReview, time.Now(), id, models.DisputeOpened)
+	if err != nil {
+		return fmt.Errorf("failed to update dispute: %w", err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// ResolveDispute releases the receiver's hold and, when the sender wins,
+// reverses what is left of the transfer in the same transaction. It returns
+// sql.ErrNoRows when the dispute is already decided.
+func (r *Repository) ResolveDispute(ctx context.Context, id, resolverID uuid.UUID, status string, note *string, force bool) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `SELECT ` + disputeColumns + ` FROM disputes WHERE id = $1 AND status IN ($2, $3) FOR UPDATE`
+	d, err := scanDispute(tx.QueryRowContext(ctx, query, id, models.DisputeOpened, models.DisputeUnderReview))
+	if err != nil {
+		return err
+	}
+
+	if d.HoldID != nil {
+		if err := releaseHold(ctx, tx, *d.HoldID, models.HoldReleased); err != nil {
+			return err
+		}
+	}
+
+	if status == models.DisputeResolvedSender {
+		// staff may already have reversed the transfer by hand
+		_, err := reverseTransaction(ctx, tx, d.TransactionID, 0, "dispute "+d.ID.String(), force)
+		if err != nil && !errors.Is(err, customError.ErrAlreadyReversed) {
+			return err
+		}
+	}
+
+	now := time.Now()
+	query = `UPDATE disputes SET status = $1, resolved_by = $2, resolution_note = $3, updated_at = $4, resolved_at = $4 WHERE id = $5`
+	if _, err := tx.ExecContext(ctx, query, status, resolverID, note, now, id); err != nil {
+		return fmt.Errorf("failed to update dispute: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+func (r *Repository) AddDisputeEvidence(ctx context.Context, e models.DisputeEvidence) error {
+	query := `INSERT INTO dispute_evidence (id, dispute_id, uploaded_by, file_key, file_name, content_type, size, created_at)
+             VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`
+	_, err := r.db.ExecContext(ctx, query, e.ID, e.DisputeID, e.UploadedBy, e.FileKey, e.FileName, e.ContentType, e.Size, e.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to add dispute evidence: %w", err)
+	}
+	return nil
+}
+
+const disputeEvidenceColumns = `id, dispute_id, uploaded_by, file_key, file_name, content_type, size, created_at`
+
+func scanDisputeEvidence(row rowScanner) (*models.DisputeEvidence, error) {
+	var e models.DisputeEvidence
+	if err := row.Scan(&e.ID, &e.DisputeID, &e.UploadedBy, &e.FileKey, &e.FileName, &e.ContentType, &e.Size, &e.CreatedAt); err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+func (r *Repository) ListDisputeEvidence(ctx context.Context, disputeID uuid.UUID) ([]models.DisputeEvidence, error) {
+	query := `SELECT ` + disputeEvidenceColumns + ` FROM dispute_evidence WHERE dispute_id = $1 ORDER BY created_at`
+	rows, err := r.db.QueryContext(ctx, query, disputeID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list dispute evidence: %w", err)
+	}
+	defer rows.Close()
+
+	evidence := []models.DisputeEvidence{}
+	for rows.Next() {
+		e, err := scanDisputeEvidence(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan dispute evidence: %w", err)
+		}
+		evidence = append(evidence, *e)
+	}
+	return evidence, rows.Err()
+}
+
+func (r *Repository) GetDisputeEvidence(ctx context.Context, disputeID, id uuid.UUID) (*models.DisputeEvidence, error) {
+	query := `SELECT ` + disputeEvidenceColumns + ` FROM dispute_evidence WHERE id = $1 AND dispute_id = $2`
+	return scanDisputeEvidence(r.db.QueryRowContext(ctx, query, id, disputeID))
+}
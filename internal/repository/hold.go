@@ -0,0 +1,313 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	customError "github.com/toluhikay/fx-exchange/internal/errors"
+	"github.com/toluhikay/fx-exchange/internal/models"
+)
+
+// lockWallet reads a wallet's balances and status with the row locked for the
+// rest of tx, and runs the wallet's checks from ctx.
+func lockWallet(ctx context.Context, tx *sql.Tx, walletID string) (map[string]float64, string, error) {
+	query := `SELECT balances, status FROM wallets WHERE id = $1 FOR UPDATE`
+	var balancesJSON []byte
+	var status string
+	if err := tx.QueryRowContext(ctx, query, walletID).Scan(&balancesJSON, &status); err != nil {
+		return nil, "", fmt.Errorf("failed to lock wallet: %w", err)
+	}
+	if err := runWalletChecks(ctx, tx, walletID); err != nil {
+		return nil, "", err
+	}
+
+	var balances map[string]float64
+	if err := json.Unmarshal(balancesJSON, &balances); err != nil {
+		return nil, "", fmt.Errorf("failed to unmarshal balances: %w", err)
+	}
+	return balances, status, nil
+}
+
+func saveBalances(ctx context.Context, tx *sql.Tx, walletID string, balances map[string]float64) error {
+	balancesJSON, err := json.Marshal(balances)
+	if err != nil {
+		return fmt.Errorf("failed to marshal balances: %w", err)
+	}
+
+	query := `UPDATE wallets SET balances = $1 WHERE id = $2`
+	if _, err := tx.ExecContext(ctx, query, balancesJSON, walletID); err != nil {
+		return fmt.Errorf("failed to update wallet: %w", err)
+	}
+	return nil
+}
+
+// heldAmount sums what active holds reserve in currency. Holds past their
+// expiry stop counting even before the expiry job marks them.
+func heldAmount(ctx context.Context, tx *sql.Tx, walletID, currency string) (float64, error) {
+	query := `SELECT COALESCE(SUM(amount - captured), 0) FROM balance_holds
+             WHERE wallet_id = $1 AND currency = $2 AND status = 'active' AND expires_at > $3`
+	var held float64
+	if err := tx.QueryRowContext(ctx, query, walletID, currency, time.Now()).Scan(&held); err != nil {
+		return 0, fmt.Errorf("failed to sum holds: %w", err)
+	}
+	return held, nil
+}
+
+// checkAvailable is called with the wallet locked before any debit.
+func checkAvailable(ctx context.Context, tx *sql.Tx, walletID, currency string, balances map[string]float64, amount float64) error {
+	held, err := heldAmount(ctx, tx, walletID, currency)
+	if err != nil {
+		return err
+	}
+	if amount <= 0 || balances[currency]-held < amount {
+		return customError.ErrInsufficientBalance
+	}
+	return nil
+}
+
+const balanceHoldColumns = `id, wallet_id, kind, currency, amount, captured, status, reference, expires_at, created_at, resolved_at`
+
+func scanBalanceHold(row rowScanner) (*models.BalanceHold, error) {
+	var h models.BalanceHold
+	if err := row.Scan(
+		&h.ID,
+		&h.WalletID,
+		&h.Kind,
+		&h.Currency,
+		&h.Amount,
+		&h.Captured,
+		&h.Status,
+		&h.Reference,
+		&h.ExpiresAt,
+		&h.CreatedAt,
+		&h.ResolvedAt,
+	); err != nil {
+		return nil, err
+	}
+	return &h, nil
+}
+
+// insertHold reserves funds on a wallet already locked by tx.
+func insertHold(ctx context.Context, tx *sql.Tx, h models.BalanceHold, balances map[string]float64, status string) (*models.BalanceHold, error) {
+	if err := checkDebit(status); err != nil {
+		return nil, err
+	}
+	if _, ok := balances[h.Currency]; !ok {
+		return nil, fmt.Errorf("unsupported currency: %s", h.Currency)
+	}
+	if err := checkAvailable(ctx, tx, h.WalletID, h.Currency, balances, h.Amount); err != nil {
+		return nil, err
+	}
+
+	return createHold(ctx, tx, h)
+}
+
+// createHold writes an active hold without checking the balance.
+func createHold(ctx context.Context, tx *sql.Tx, h models.BalanceHold) (*models.BalanceHold, error) {
+	query := `INSERT INTO balance_holds (id, wallet_id, kind, currency, amount, status, reference, expires_at, created_at)
+             VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+             RETURNING ` + balanceHoldColumns
+	created, err := scanBalanceHold(tx.QueryRowContext(ctx, query, uuid.New(), h.WalletID, h.Kind, h.Currency, h.Amount,
+		models.HoldActive, h.Reference, h.ExpiresAt, time.Now()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create hold: %w", err)
+	}
+	return created, nil
+}
+
+// lockActiveHold returns customError.ErrHoldNotActive when the hold was
+// already settled or has run out.
+func lockActiveHold(ctx context.Context, tx *sql.Tx, id uuid.UUID) (*models.BalanceHold, error) {
+	query := `SELECT ` + balanceHoldColumns + ` FROM balance_holds WHERE id = $1 FOR UPDATE`
+	h, err := scanBalanceHold(tx.QueryRowContext(ctx, query, id))
+	if err != nil {
+		return nil, err
+	}
+	if h.Status != models.HoldActive || !time.Now().Before(h.ExpiresAt) {
+		return nil, customError.ErrHoldNotActive
+	}
+	return h, nil
+}
+
+// captureHold takes amount of a locked hold out of the ledger balance. The
+// hold is done once nothing remains.
+func captureHold(ctx context.Context, tx *sql.Tx, h *models.BalanceHold, amount float64, balances map[string]float64) error {
+	if amount <= 0 || amount > h.Remaining() {
+		return fmt.Errorf("%w: at most %.4f %s can be captured", customError.ErrInvalidCapture, h.Remaining(), h.Currency)
+	}
+
+	balances[h.Currency] -= amount
+	h.Captured += amount
+
+	var resolvedAt *time.Time
+	if h.Remaining() <= 0 {
+		now := time.Now()
+		h.Status = models.HoldCaptured
+		resolvedAt = &now
+	}
+
+	query := `UPDATE balance_holds SET captured = $1, status = $2, resolved_at = $3 WHERE id = $4`
+	if _, err := tx.ExecContext(ctx, query, h.Captured, h.Status, resolvedAt, h.ID); err != nil {
+		return fmt.Errorf("failed to update hold: %w", err)
+	}
+	h.ResolvedAt = resolvedAt
+	return nil
+}
+
+func releaseHold(ctx context.Context, tx *sql.Tx, id uuid.UUID, status string) error {
+	query := `UPDATE balance_holds SET status = $1, resolved_at = $2 WHERE id = $3 AND status = 'active'`
+	if _, err := tx.ExecContext(ctx, query, status, time.Now(), id); err != nil {
+		return fmt.Errorf("failed to release hold: %w", err)
+	}
+	return nil
+}
+
+func (r *Repository) PlaceHold(ctx context.Context, h models.BalanceHold) (*models.BalanceHold, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	balances, status, err := lockWallet(ctx, tx, h.WalletID)
+	if err != nil {
+		return nil, err
+	}
+	created, err := insertHold(ctx, tx, h, balances, status)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+func (r *Repository) GetHold(ctx context.Context, id uuid.UUID) (*models.BalanceHold, error) {
+	query := `SELECT ` + balanceHoldColumns + ` FROM balance_holds WHERE id = $1`
+	return scanBalanceHold(r.db.QueryRowContext(ctx, query, id))
+}
+
+// ListHolds returns the wallet's holds, newest first. An empty status lists
+// every hold.
+func (r *Repository) ListHolds(ctx context.Context, walletID, status string, limit, offset int) ([]models.BalanceHold, error) {
+	query := `SELECT ` + balanceHoldColumns + ` FROM balance_holds
+             WHERE wallet_id = $1 AND ($2 = '' OR status = $2)
+             ORDER BY created_at DESC LIMIT $3 OFFSET $4`
+	rows, err := r.db.QueryContext(ctx, query, walletID, status, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list holds: %w", err)
+	}
+	defer rows.Close()
+
+	holds := []models.BalanceHold{}
+	for rows.Next() {
+		h, err := scanBalanceHold(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan hold: %w", err)
+		}
+		holds = append(holds, *h)
+	}
+	return holds, rows.Err()
+}
+
+// CaptureHold settles amount of a hold as a withdrawal to destination. A zero
+// amount captures whatever the hold has left.
+func (r *Repository) CaptureHold(ctx context.Context, id uuid.UUID, walletID string, amount float64, destination string) (*models.BalanceHold, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	balances, status, err := lockWallet(ctx, tx, walletID)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkDebit(status); err != nil {
+		return nil, err
+	}
+	h, err := lockActiveHold(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+	if h.WalletID != walletID {
+		return nil, sql.ErrNoRows
+	}
+	if amount == 0 {
+		amount = h.Remaining()
+	}
+	if err := captureHold(ctx, tx, h, amount, balances); err != nil {
+		return nil, err
+	}
+	if err := saveBalances(ctx, tx, walletID, balances); err != nil {
+		return nil, err
+	}
+
+	query := `INSERT INTO transactions (id, wallet_id, type, from_currency, amount, reference, timestamp) 
+             VALUES ($1, $2, $3, $4, $5, $6, $7)`
+	_, err = tx.ExecContext(ctx, query, uuid.New().String(), walletID, "withdrawal", h.Currency, amount, destination, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("failed to log transaction: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+func (r *Repository) ReleaseHold(ctx context.Context, id uuid.UUID) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := lockActiveHold(ctx, tx, id); err != nil {
+		return err
+	}
+	if err := releaseHold(ctx, tx, id, models.HoldReleased); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// ExpireHolds marks holds past their expiry. The ledger is untouched, so this
+// only tidies up statuses.
+func (r *Repository) ExpireHolds(ctx context.Context, now time.Time) (int64, error) {
+	query := `UPDATE balance_holds SET status = 'expired', resolved_at = $1 WHERE status = 'active' AND expires_at <= $1`
+	result, err := r.db.ExecContext(ctx, query, now)
+	if err != nil {
+		return 0, fmt.Errorf("failed to expire holds: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+// heldByCurrency sums the active holds of a wallet per currency.
+func (r *Repository) heldByCurrency(ctx context.Context, walletID string) (map[string]float64, error) {
+	query := `SELECT currency, SUM(amount - captured) FROM balance_holds
+             WHERE wallet_id = $1 AND status = 'active' AND expires_at > $2
+             GROUP BY currency`
+	rows, err := r.db.QueryContext(ctx, query, walletID, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("failed to sum holds: %w", err)
+	}
+	defer rows.Close()
+
+	held := make(map[string]float64)
+	for rows.Next() {
+		var currency string
+		var amount float64
+		if err := rows.Scan(&currency, &amount); err != nil {
+			return nil, fmt.Errorf("failed to scan hold total: %w", err)
+		}
+		held[currency] = amount
+	}
+	return held, rows.Err()
+}
@@ -0,0 +1,151 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/toluhikay/fx-exchange/internal/models"
+)
+
+const kycDocumentColumns = `id, user_id, requested_level, document_type, file_key, file_name, content_type, status,
+			reviewer_id, review_note, created_at, reviewed_at`
+
+func scanKycDocument(row rowScanner) (*models.KycDocument, error) {
+	var d models.KycDocument
+	if err := row.Scan(
+		&d.ID,
+		&d.UserID,
+		&d.RequestedLevel,
+		&d.DocumentType,
+		&d.FileKey,
+		&d.FileName,
+		&d.ContentType,
+		&d.Status,
+		&d.ReviewerID,
+		&d.ReviewNote,
+		&d.CreatedAt,
+		&d.ReviewedAt,
+	); err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+func scanKycDocuments(rows *sql.Rows) ([]models.KycDocument, error) {
+	docs := []models.KycDocument{}
+	for rows.Next() {
+		d, err := scanKycDocument(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan kyc document: %w", err)
+		}
+		docs = append(docs, *d)
+	}
+	return docs, rows.Err()
+}
+
+func (m *UserDbRepo) CreateKycDocument(ctx context.Context, d models.KycDocument) error {
+	query := `INSERT INTO kyc_documents (id, user_id, requested_level, document_type, file_key, file_name, content_type, status, created_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`
+	_, err := m.DB.ExecContext(ctx, query, d.ID, d.UserID, d.RequestedLevel, d.DocumentType, d.FileKey, d.FileName, d.ContentType, d.Status, d.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create kyc document: %w", err)
+	}
+	return nil
+}
+
+func (m *UserDbRepo) GetKycDocument(ctx context.Context, id uuid.UUID) (*models.KycDocument, error) {
+	query := `SELECT ` + kycDocumentColumns + ` FROM kyc_documents WHERE id = $1`
+	return scanKycDocument(m.DB.QueryRowContext(ctx, query, id))
+}
+
+func (m *UserDbRepo) ListKycDocuments(ctx context.Context, userID uuid.UUID) ([]models.KycDocument, error) {
+	query := `SELECT ` + kycDocumentColumns + ` FROM kyc_documents WHERE user_id = $1 ORDER BY created_at DESC`
+	rows, err := m.DB.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list kyc documents: %w", err)
+	}
+	defer rows.Close()
+
+	return scanKycDocuments(rows)
+}
+
+// ListKycDocumentsByStatus returns the review queue, oldest first.
+func (m *UserDbRepo) ListKycDocumentsByStatus(ctx context.Context, status string, limit, offset int) ([]models.KycDocument, error) {
+	query := `SELECT ` + kycDocumentColumns + ` FROM kyc_documents WHERE status = $1 ORDER BY created_at LIMIT $2 OFFSET $3`
+	rows, err := m.DB.QueryContext(ctx, query, status, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list kyc documents: %w", err)
+	}
+	defer rows.Close()
+
+	return scanKycDocuments(rows)
+}
+
+// ReviewKycDocument records the decision on a pending document. Approving
+// raises the owner's kyc level to the highest one every document it requires
+// has been approved for, unless the owner is already at or above it. It
+// returns sql.ErrNoRows when the document is not pending.
+func (m *UserDbRepo) ReviewKycDocument(ctx context.Context, id, reviewerID uuid.UUID, status string, note *string) (*models.KycDocument, error) {
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `UPDATE kyc_documents SET status = $1, reviewer_id = $2, review_note = $3, reviewed_at = $4
+			WHERE id = $5 AND status = 'pending'
+			RETURNING ` + kycDocumentColumns
+	doc, err := scanKycDocument(tx.QueryRowContext(ctx, query, status, reviewerID, note, time.Now(), id))
+	if err != nil {
+		return nil, err
+	}
+
+	if status == models.KycApproved {
+		var level string
+		query = `SELECT kyc_level FROM users WHERE id = $1 FOR UPDATE`
+		if err := tx.QueryRowContext(ctx, query, doc.UserID).Scan(&level); err != nil {
+			return nil, fmt.Errorf("failed to lock user: %w", err)
+		}
+
+		approved, err := approvedKycDocumentTypes(ctx, tx, doc.UserID)
+		if err != nil {
+			return nil, err
+		}
+
+		if verified := models.KycLevelFor(approved); models.KycRank(verified) > models.KycRank(level) {
+			query = `UPDATE users SET kyc_level = $1, updated_at = $2 WHERE id = $3`
+			if _, err := tx.ExecContext(ctx, query, verified, time.Now(), doc.UserID); err != nil {
+				return nil, fmt.Errorf("failed to update kyc level: %w", err)
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// approvedKycDocumentTypes lists the document types approved for the user,
+// whatever level they were submitted for.
+func approvedKycDocumentTypes(ctx context.Context, tx *sql.Tx, userID uuid.UUID) ([]string, error) {
+	query := `SELECT DISTINCT document_type FROM kyc_documents WHERE user_id = $1 AND status = $2`
+	rows, err := tx.QueryContext(ctx, query, userID, models.KycApproved)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list approved kyc documents: %w", err)
+	}
+	defer rows.Close()
+
+	var types []string
+	for rows.Next() {
+		var docType string
+		if err := rows.Scan(&docType); err != nil {
+			return nil, fmt.Errorf("failed to scan kyc document type: %w", err)
+		}
+		types = append(types, docType)
+	}
+	return types, rows.Err()
+}
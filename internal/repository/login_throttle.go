@@ -0,0 +1,56 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/toluhikay/fx-exchange/internal/models"
+)
+
+func (m *UserDbRepo) GetLoginThrottle(ctx context.Context, key string) (*models.LoginThrottle, error) {
+	query := `SELECT key, failures, last_failure_at, locked_until FROM login_throttles WHERE key = $1`
+	var t models.LoginThrottle
+	if err := m.DB.QueryRowContext(ctx, query, key).Scan(
+		&t.Key,
+		&t.Failures,
+		&t.LastFailureAt,
+		&t.LockedUntil,
+	); err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// RecordLoginFailure counts a failure for key. Counters that have been quiet for
+// longer than window start again from one.
+func (m *UserDbRepo) RecordLoginFailure(ctx context.Context, key string, window time.Duration) (int, error) {
+	now := time.Now()
+	query := `INSERT INTO login_throttles (key, failures, last_failure_at)
+			VALUES ($1, 1, $2)
+			ON CONFLICT (key) DO UPDATE SET
+				failures = CASE WHEN login_throttles.last_failure_at < $3 THEN 1 ELSE login_throttles.failures + 1 END,
+				last_failure_at = $2
+			RETURNING failures`
+	var failures int
+	if err := m.DB.QueryRowContext(ctx, query, key, now, now.Add(-window)).Scan(&failures); err != nil {
+		return 0, fmt.Errorf("failed to record login failure: %w", err)
+	}
+	return failures, nil
+}
+
+func (m *UserDbRepo) LockLogin(ctx context.Context, key string, until time.Time) error {
+	query := `UPDATE login_throttles SET locked_until = $1 WHERE key = $2`
+	if _, err := m.DB.ExecContext(ctx, query, until, key); err != nil {
+		return fmt.Errorf("failed to lock login: %w", err)
+	}
+	return nil
+}
+
+func (m *UserDbRepo) ClearLoginThrottle(ctx context.Context, key string) error {
+	query := `DELETE FROM login_throttles WHERE key = $1`
+	if _, err := m.DB.ExecContext(ctx, query, key); err != nil {
+		return fmt.Errorf("failed to clear login throttle: %w", err)
+	}
+	return nil
+}
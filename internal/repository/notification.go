@@ -0,0 +1,63 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/toluhikay/fx-exchange/internal/models"
+)
+
+func (r *Repository) CreateNotification(ctx context.Context, userID uuid.UUID, subject, body string) error {
+	query := `INSERT INTO notifications (id, user_id, subject, body, created_at) VALUES ($1, $2, $3, $4, $5)`
+	if _, err := r.db.ExecContext(ctx, query, uuid.New(), userID, subject, body, time.Now()); err != nil {
+		return fmt.Errorf("failed to create notification: %w", err)
+	}
+	return nil
+}
+
+// ListNotifications returns the user's inbox, newest first.
+func (r *Repository) ListNotifications(ctx context.Context, userID uuid.UUID, unreadOnly bool, limit, offset int) ([]models.Notification, error) {
+	query := `SELECT id, user_id, subject, body, read_at, created_at FROM notifications
+             WHERE user_id = $1 AND (NOT $2 OR read_at IS NULL)
+             ORDER BY created_at DESC LIMIT $3 OFFSET $4`
+	rows, err := r.db.QueryContext(ctx, query, userID, unreadOnly, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list notifications: %w", err)
+	}
+	defer rows.Close()
+
+	notifications := []models.Notification{}
+	for rows.Next() {
+		var n models.Notification
+		if err := rows.Scan(&n.ID, &n.UserID, &n.Subject, &n.Body, &n.ReadAt, &n.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan notification: %w", err)
+		}
+		notifications = append(notifications, n)
+	}
+	return notifications, rows.Err()
+}
+
+// MarkNotificationRead returns sql.ErrNoRows when the notification is not
+// in the user's inbox.
+func (r *Repository) MarkNotificationRead(ctx context.Context, userID, id uuid.UUID) error {
+	query := `UPDATE notifications SET read_at = COALESCE(read_at, $1) WHERE id = $2 AND user_id = $3`
+	result, err := r.db.ExecContext(ctx, query, time.Now(), id, userID)
+	if err != nil {
+		return fmt.Errorf("failed to mark notification read: %w", err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func (r *Repository) MarkAllNotificationsRead(ctx context.Context, userID uuid.UUID) error {
+	query := `UPDATE notifications SET read_at = $1 WHERE user_id = $2 AND read_at IS NULL`
+	if _, err := r.db.ExecContext(ctx, query, time.Now(), userID); err != nil {
+		return fmt.Errorf("failed to mark notifications read: %w", err)
+	}
+	return nil
+}
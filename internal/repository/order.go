@@ -0,0 +1,252 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	customError "github.com/toluhikay/fx-exchange/internal/errors"
+	"github.com/toluhikay/fx-exchange/internal/models"
+)
+
+const swapOrderColumns = `id, wallet_id, created_by, type, from_currency, to_currency, amount, trigger_rate, time_in_force, expires_at,
+             status, hold_id, rate, converted_amount, transaction_id, error, created_at, resolved_at`
+
+func scanSwapOrder(row rowScanner) (*models.SwapOrder, error) {
+	var o models.SwapOrder
+	if err := row.Scan(
+		&o.ID,
+		&o.WalletID,
+		&o.CreatedBy,
+		&o.Type,
+		&o.FromCurrency,
+		&o.ToCurrency,
+		&o.Amount,
+		&o.TriggerRate,
+		&o.TimeInForce,
+		&o.ExpiresAt,
+		&o.Status,
+		&o.HoldID,
+		&o.Rate,
+		&o.ConvertedAmount,
+		&o.TransactionID,
+		&o.Error,
+		&o.CreatedAt,
+		&o.ResolvedAt,
+	); err != nil {
+		return nil, err
+	}
+	return &o, nil
+}
+
+func scanSwapOrders(rows *sql.Rows) ([]models.SwapOrder, error) {
+	orders := []models.SwapOrder{}
+	for rows.Next() {
+		o, err := scanSwapOrder(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan swap order: %w", err)
+		}
+		orders = append(orders, *o)
+	}
+	return orders, rows.Err()
+}
+
+// CreateSwapOrder reserves the amount with a hold lasting until holdExpiry and
+// records the open order.
+func (r *Repository) CreateSwapOrder(ctx context.Context, o models.SwapOrder, holdExpiry time.Time) (*models.SwapOrder, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	balances, status, err := lockWallet(ctx, tx, o.WalletID)
+	if err != nil {
+		return nil, err
+	}
+	hold, err := insertHold(ctx, tx, models.BalanceHold{
+		WalletID:  o.WalletID,
+		Kind:      models.HoldKindOrder,
+		Currency:  o.FromCurrency,
+		Amount:    o.Amount,
+		ExpiresAt: holdExpiry,
+	}, balances, status)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `INSERT INTO swap_orders (id, wallet_id, created_by, type, from_currency, to_currency, amount, trigger_rate, time_in_force,
+             expires_at, status, hold_id, created_at)
+             VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+             RETURNING ` + swapOrderColumns
+	created, err := scanSwapOrder(tx.QueryRowContext(ctx, query, uuid.New(), o.WalletID, o.CreatedBy, o.Type, o.FromCurrency,
+		o.ToCurrency, o.Amount, o.TriggerRate, o.TimeInForce, o.ExpiresAt, models.OrderOpen, hold.ID, time.Now()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create swap order: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+func (r *Repository) GetSwapOrder(ctx context.Context, id uuid.UUID) (*models.SwapOrder, error) {
+	query := `SELECT ` + swapOrderColumns + ` FROM swap_orders WHERE id = $1`
+	return scanSwapOrder(r.db.QueryRowContext(ctx, query, id))
+}
+
+// ListSwapOrders returns the wallet's orders, newest first. An empty status
+// lists every order.
+func (r *Repository) ListSwapOrders(ctx context.Context, walletID, status string, limit, offset int) ([]models.SwapOrder, error) {
+	query := `SELECT ` + swapOrderColumns + ` FROM swap_orders
+             WHERE wallet_id = $1 AND ($2 = '' OR status = $2)
+             ORDER BY created_at DESC LIMIT $3 OFFSET $4`
+	rows, err := r.db.QueryContext(ctx, query, walletID, status, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list swap orders: %w", err)
+	}
+	defer rows.Close()
+
+	return scanSwapOrders(rows)
+}
+
+// ListTriggeredOrders returns the open, unexpired orders on a pair that fill
+// at rate, oldest first.
+func (r *Repository) ListTriggeredOrders(ctx context.Context, fromCurrency, toCurrency string, rate float64, now time.Time, limit int) ([]models.SwapOrder, error) {
+	query := `SELECT ` + swapOrderColumns + ` FROM swap_orders
+             WHERE status = 'open' AND from_currency = $1 AND to_currency = $2
+             AND ((type = 'limit' AND trigger_rate <= $3) OR (type = 'stop' AND trigger_rate >= $3))
+             AND (expires_at IS NULL OR expires_at > $4)
+             ORDER BY created_at LIMIT $5`
+	rows, err := r.db.QueryContext(ctx, query, fromCurrency, toCurrency, rate, now, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list triggered swap orders: %w", err)
+	}
+	defer rows.Close()
+
+	return scanSwapOrders(rows)
+}
+
+// lockOpenOrder returns sql.ErrNoRows when the order is no longer open.
+func lockOpenOrder(ctx context.Context, tx *sql.Tx, id uuid.UUID) (*models.SwapOrder, error) {
+	query := `SELECT ` + swapOrderColumns + ` FROM swap_orders WHERE id = $1 AND status = 'open' FOR UPDATE`
+	return scanSwapOrder(tx.QueryRowContext(ctx, query, id))
+}
+
+// FillSwapOrder captures the order's hold, credits the converted amount at
+// rate and logs the fill as a swap.
+func (r *Repository) FillSwapOrder(ctx context.Context, id uuid.UUID, rate float64) (*models.SwapOrder, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	o, err := lockOpenOrder(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	if o.ExpiresAt != nil && !now.Before(*o.ExpiresAt) {
+		return nil, customError.ErrOrderNotOpen
+	}
+
+	balances, status, err := lockWallet(ctx, tx, o.WalletID)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkDebit(status); err != nil {
+		return nil, err
+	}
+	if _, ok := balances[o.ToCurrency]; !ok {
+		return nil, fmt.Errorf("unsupported currency: %s", o.ToCurrency)
+	}
+	hold, err := lockActiveHold(ctx, tx, o.HoldID)
+	if err != nil {
+		if errors.Is(err, customError.ErrHoldNotActive) {
+			return nil, customError.ErrOrderNotOpen
+		}
+		return nil, err
+	}
+	if err := captureHold(ctx, tx, hold, hold.Remaining(), balances); err != nil {
+		return nil, err
+	}
+	convertedAmount := o.Amount * rate
+	balances[o.ToCurrency] += convertedAmount
+	if err := saveBalances(ctx, tx, o.WalletID, balances); err != nil {
+		return nil, err
+	}
+
+	transactionID := uuid.New()
+	query := `INSERT INTO transactions (id, wallet_id, type, from_currency, to_currency, amount, converted_amount, rate, timestamp)
+             VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`
+	_, err = tx.ExecContext(ctx, query, transactionID.String(), o.WalletID, "swap", o.FromCurrency, o.ToCurrency, o.Amount, convertedAmount, rate, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to log transaction: %w", err)
+	}
+
+	query = `UPDATE swap_orders SET status = $1, rate = $2, converted_amount = $3, transaction_id = $4, resolved_at = $5
+             WHERE id = $6 RETURNING ` + swapOrderColumns
+	filled, err := scanSwapOrder(tx.QueryRowContext(ctx, query, models.OrderFilled, rate, convertedAmount, transactionID, now, id))
+	if err != nil {
+		return nil, fmt.Errorf("failed to update swap order: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return filled, nil
+}
+
+// CloseSwapOrder frees the order's hold and closes it as cancelled, expired
+// or failed, with reason when there is one.
+func (r *Repository) CloseSwapOrder(ctx context.Context, id uuid.UUID, status string, reason *string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	o, err := lockOpenOrder(ctx, tx, id)
+	if err != nil {
+		return err
+	}
+
+	holdStatus := models.HoldReleased
+	if status == models.OrderExpired {
+		holdStatus = models.HoldExpired
+	}
+	if err := releaseHold(ctx, tx, o.HoldID, holdStatus); err != nil {
+		return err
+	}
+
+	query := `UPDATE swap_orders SET status = $1, error = $2, resolved_at = $3 WHERE id = $4`
+	if _, err := tx.ExecContext(ctx, query, status, reason, time.Now(), id); err != nil {
+		return fmt.Errorf("failed to update swap order: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+func (r *Repository) ListExpiredOrders(ctx context.Context, now time.Time, limit int) ([]uuid.UUID, error) {
+	query := `SELECT id FROM swap_orders WHERE status = 'open' AND expires_at <= $1 ORDER BY expires_at LIMIT $2`
+	rows, err := r.db.QueryContext(ctx, query, now, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list expired swap orders: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan swap order id: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
@@ -0,0 +1,395 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+	customError "github.com/toluhikay/fx-exchange/internal/errors"
+	"github.com/toluhikay/fx-exchange/internal/models"
+)
+
+const p2pOrderColumns = `id, wallet_id, created_by, market, base_currency, quote_currency, side, price, amount, filled, status,
+             hold_id, error, created_at, updated_at, resolved_at`
+
+func scanP2POrder(row rowScanner) (*models.P2POrder, error) {
+	var o models.P2POrder
+	if err := row.Scan(
+		&o.ID,
+		&o.WalletID,
+		&o.CreatedBy,
+		&o.Market,
+		&o.BaseCurrency,
+		&o.QuoteCurrency,
+		&o.Side,
+		&o.Price,
+		&o.Amount,
+		&o.Filled,
+		&o.Status,
+		&o.HoldID,
+		&o.Error,
+		&o.CreatedAt,
+		&o.UpdatedAt,
+		&o.ResolvedAt,
+	); err != nil {
+		return nil, err
+	}
+	return &o, nil
+}
+
+func scanP2POrders(rows *sql.Rows) ([]models.P2POrder, error) {
+	orders := []models.P2POrder{}
+	for rows.Next() {
+		o, err := scanP2POrder(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan p2p order: %w", err)
+		}
+		orders = append(orders, *o)
+	}
+	return orders, rows.Err()
+}
+
+const p2pTradeColumns = `id, market, price, amount, quote_amount, bid_order_id, ask_order_id, buyer_wallet_id, seller_wallet_id,
+             taker_side, created_at`
+
+func scanP2PTrades(rows *sql.Rows) ([]models.P2PTrade, error) {
+	trades := []models.P2PTrade{}
+	for rows.Next() {
+		var t models.P2PTrade
+		if err := rows.Scan(
+			&t.ID,
+			&t.Market,
+			&t.Price,
+			&t.Amount,
+			&t.QuoteAmount,
+			&t.BidOrderID,
+			&t.AskOrderID,
+			&t.BuyerWalletID,
+			&t.SellerWalletID,
+			&t.TakerSide,
+			&t.CreatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan p2p trade: %w", err)
+		}
+		trades = append(trades, t)
+	}
+	return trades, rows.Err()
+}
+
+// QuoteAmount is what amount of the base currency costs at price, rounded to
+// the four decimals balances are kept in.
+func QuoteAmount(price, amount float64) float64 {
+	return math.Round(price*amount*1e4) / 1e4
+}
+
+// p2pHoldExpiry keeps order holds for as long as the order is open.
+func p2pHoldExpiry() time.Time {
+	return time.Now().AddDate(100, 0, 0)
+}
+
+// CreateP2POrder reserves what the order can spend and records it as open.
+func (r *Repository) CreateP2POrder(ctx context.Context, o models.P2POrder) (*models.P2POrder, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	hold := models.BalanceHold{
+		WalletID:  o.WalletID,
+		Kind:      models.HoldKindOrder,
+		Currency:  o.BaseCurrency,
+		Amount:    o.Amount,
+		ExpiresAt: p2pHoldExpiry(),
+	}
+	if o.Side == models.SideBid {
+		hold.Currency = o.QuoteCurrency
+		hold.Amount = QuoteAmount(o.Price, o.Amount)
+	}
+
+	balances, status, err := lockWallet(ctx, tx, o.WalletID)
+	if err != nil {
+		return nil, err
+	}
+	created, err := insertHold(ctx, tx, hold, balances, status)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	query := `INSERT INTO p2p_orders (id, wallet_id, created_by, market, base_currency, quote_currency, side, price, amount, status,
+             hold_id, created_at, updated_at)
+             VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $12)
+             RETURNING ` + p2pOrderColumns
+	order, err := scanP2POrder(tx.QueryRowContext(ctx, query, uuid.New(), o.WalletID, o.CreatedBy, o.Market, o.BaseCurrency,
+		o.QuoteCurrency, o.Side, o.Price, o.Amount, models.OrderOpen, created.ID, now))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create p2p order: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return order, nil
+}
+
+func (r *Repository) GetP2POrder(ctx context.Context, id uuid.UUID) (*models.P2POrder, error) {
+	query := `SELECT ` + p2pOrderColumns + ` FROM p2p_orders WHERE id = $1`
+	return scanP2POrder(r.db.QueryRowContext(ctx, query, id))
+}
+
+// ListP2POrders returns the wallet's orders, newest first. An empty status
+// lists every order.
+func (r *Repository) ListP2POrders(ctx context.Context, walletID, status string, limit, offset int) ([]models.P2POrder, error) {
+	query := `SELECT ` + p2pOrderColumns + ` FROM p2p_orders
+             WHERE wallet_id = $1 AND ($2 = '' OR status = $2)
+             ORDER BY created_at DESC LIMIT $3 OFFSET $4`
+	rows, err := r.db.QueryContext(ctx, query, walletID, status, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list p2p orders: %w", err)
+	}
+	defer rows.Close()
+
+	return scanP2POrders(rows)
+}
+
+// ListOpenP2POrders returns every open order, oldest first, for rebuilding
+// the order books.
+func (r *Repository) ListOpenP2POrders(ctx context.Context) ([]models.P2POrder, error) {
+	query := `SELECT ` + p2pOrderColumns + ` FROM p2p_orders WHERE status = 'open' ORDER BY created_at, id`
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list open p2p orders: %w", err)
+	}
+	defer rows.Close()
+
+	return scanP2POrders(rows)
+}
+
+// ListP2PTrades returns a market's trades, newest first.
+func (r *Repository) ListP2PTrades(ctx context.Context, market string, limit, offset int) ([]models.P2PTrade, error) {
+	query := `SELECT ` + p2pTradeColumns + ` FROM p2p_trades WHERE market = $1
+             ORDER BY created_at DESC LIMIT $2 OFFSET $3`
+	rows, err := r.db.QueryContext(ctx, query, market, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list p2p trades: %w", err)
+	}
+	defer rows.Close()
+
+	return scanP2PTrades(rows)
+}
+
+// ListP2POrderTrades returns the fills of an order, oldest first.
+func (r *Repository) ListP2POrderTrades(ctx context.Context, orderID uuid.UUID) ([]models.P2PTrade, error) {
+	query := `SELECT ` + p2pTradeColumns + ` FROM p2p_trades WHERE bid_order_id = $1 OR ask_order_id = $1
+             ORDER BY created_at`
+	rows, err := r.db.QueryContext(ctx, query, orderID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list p2p order trades: %w", err)
+	}
+	defer rows.Close()
+
+	return scanP2PTrades(rows)
+}
+
+// lockOpenP2POrder returns sql.ErrNoRows when the order is no longer open.
+func lockOpenP2POrder(ctx context.Context, tx *sql.Tx, id uuid.UUID) (*models.P2POrder, error) {
+	query := `SELECT ` + p2pOrderColumns + ` FROM p2p_orders WHERE id = $1 AND status = 'open' FOR UPDATE`
+	return scanP2POrder(tx.QueryRowContext(ctx, query, id))
+}
+
+// SettleP2PTrade fills amount of a bid against an ask at price in one
+// transaction: both holds are captured for their share, the buyer is credited
+// the base currency, the seller the quote currency, each exactly what the other
+// side paid, and the trade is logged on both wallets. Failures that belong to
+// one side come back as a customError.SettlementError naming that order.
+func (r *Repository) SettleP2PTrade(ctx context.Context, bidID, askID uuid.UUID, price, amount float64, takerSide string) (*models.P2PTrade, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// lock in a fixed order so two settlements on the same orders cannot
+	// deadlock
+	ids := []uuid.UUID{bidID, askID}
+	if askID.String() < bidID.String() {
+		ids = []uuid.UUID{askID, bidID}
+	}
+	locked := make(map[uuid.UUID]*models.P2POrder, 2)
+	for _, id := range ids {
+		o, err := lockOpenP2POrder(ctx, tx, id)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return nil, &customError.SettlementError{OrderID: id.String(), Err: customError.ErrOrderNotOpen}
+			}
+			return nil, err
+		}
+		locked[id] = o
+	}
+	bid, ask := locked[bidID], locked[askID]
+	if bid.WalletID == ask.WalletID {
+		return nil, fmt.Errorf("orders %s and %s belong to the same wallet", bidID, askID)
+	}
+	for _, o := range []*models.P2POrder{bid, ask} {
+		if amount <= 0 || amount > o.Remaining()+1e-9 {
+			return nil, &customError.SettlementError{OrderID: o.ID.String(), Err: customError.ErrInsufficientBalance}
+		}
+	}
+	quoteAmount := QuoteAmount(price, amount)
+
+	walletOrders := []*models.P2POrder{bid, ask}
+	if ask.WalletID < bid.WalletID {
+		walletOrders = []*models.P2POrder{ask, bid}
+	}
+	balances := make(map[string]map[string]float64, 2)
+	for _, o := range walletOrders {
+		b, status, err := lockWallet(ctx, tx, o.WalletID)
+		if err != nil {
+			var checkErr *walletCheckError
+			if errors.As(err, &checkErr) {
+				return nil, &customError.SettlementError{OrderID: o.ID.String(), Err: checkErr.err}
+			}
+			return nil, err
+		}
+		if err := checkDebit(status); err != nil {
+			return nil, &customError.SettlementError{OrderID: o.ID.String(), Err: err}
+		}
+		if err := checkCredit(status); err != nil {
+			return nil, &customError.SettlementError{OrderID: o.ID.String(), Err: err}
+		}
+		balances[o.WalletID] = b
+	}
+
+	// the buyer pays the quote amount out of the bid's hold and the seller
+	// hands over the base amount out of the ask's hold
+	legs := []struct {
+		order   *models.P2POrder
+		hold    *models.BalanceHold
+		spend   float64
+		receive string
+		credit  float64
+	}{
+		{order: bid, spend: quoteAmount, receive: bid.BaseCurrency},
+		{order: ask, spend: amount, receive: ask.QuoteCurrency},
+	}
+	for i := range legs {
+		o := legs[i].order
+		hold, err := lockActiveHold(ctx, tx, o.HoldID)
+		if err != nil {
+			if errors.Is(err, customError.ErrHoldNotActive) {
+				return nil, &customError.SettlementError{OrderID: o.ID.String(), Err: customError.ErrOrderNotOpen}
+			}
+			return nil, err
+		}
+		// rounding can leave the last fill a fraction over what is held
+		legs[i].hold = hold
+		legs[i].spend = min(legs[i].spend, hold.Remaining())
+	}
+	// each side is credited exactly what the other side paid
+	legs[0].credit, legs[1].credit = legs[1].spend, legs[0].spend
+
+	now := time.Now()
+	for _, leg := range legs {
+		o, hold, spend := leg.order, leg.hold, leg.spend
+		if err := captureHold(ctx, tx, hold, spend, balances[o.WalletID]); err != nil {
+			return nil, &customError.SettlementError{OrderID: o.ID.String(), Err: err}
+		}
+		if _, ok := balances[o.WalletID][leg.receive]; !ok {
+			return nil, &customError.SettlementError{OrderID: o.ID.String(), Err: fmt.Errorf("unsupported currency: %s", leg.receive)}
+		}
+		balances[o.WalletID][leg.receive] += leg.credit
+
+		o.Filled += amount
+		status := models.OrderOpen
+		var resolvedAt *time.Time
+		if o.Remaining() < 0.00005 {
+			status = models.OrderFilled
+			resolvedAt = &now
+			// a bid that bought below its price keeps the difference
+			if hold.Status == models.HoldActive {
+				if err := releaseHold(ctx, tx, hold.ID, models.HoldReleased); err != nil {
+					return nil, err
+				}
+			}
+		}
+		query := `UPDATE p2p_orders SET filled = $1, status = $2, updated_at = $3, resolved_at = $4 WHERE id = $5`
+		if _, err := tx.ExecContext(ctx, query, o.Filled, status, now, resolvedAt, o.ID); err != nil {
+			return nil, fmt.Errorf("failed to update p2p order: %w", err)
+		}
+
+		fromCurrency := o.BaseCurrency
+		if o.Side == models.SideBid {
+			fromCurrency = o.QuoteCurrency
+		}
+		query = `INSERT INTO transactions (id, wallet_id, type, from_currency, to_currency, amount, converted_amount, rate, timestamp)
+             VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`
+		_, err = tx.ExecContext(ctx, query, uuid.New().String(), o.WalletID, "trade", fromCurrency, leg.receive, spend, leg.credit,
+			leg.credit/spend, now)
+		if err != nil {
+			return nil, fmt.Errorf("failed to log transaction: %w", err)
+		}
+	}
+	for walletID, b := range balances {
+		if err := saveBalances(ctx, tx, walletID, b); err != nil {
+			return nil, err
+		}
+	}
+
+	trade := models.P2PTrade{
+		ID:             uuid.New(),
+		Market:         bid.Market,
+		Price:          price,
+		Amount:         legs[1].spend,
+		QuoteAmount:    legs[0].spend,
+		BidOrderID:     bid.ID,
+		AskOrderID:     ask.ID,
+		BuyerWalletID:  bid.WalletID,
+		SellerWalletID: ask.WalletID,
+		TakerSide:      takerSide,
+		CreatedAt:      now,
+	}
+	query := `INSERT INTO p2p_trades (id, market, price, amount, quote_amount, bid_order_id, ask_order_id, buyer_wallet_id,
+             seller_wallet_id, taker_side, created_at)
+             VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)`
+	_, err = tx.ExecContext(ctx, query, trade.ID, trade.Market, trade.Price, trade.Amount, trade.QuoteAmount, trade.BidOrderID,
+		trade.AskOrderID, trade.BuyerWalletID, trade.SellerWalletID, trade.TakerSide, trade.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to log p2p trade: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return &trade, nil
+}
+
+// CloseP2POrder releases what the order still holds and closes it as
+// cancelled or failed, with reason when there is one.
+func (r *Repository) CloseP2POrder(ctx context.Context, id uuid.UUID, status string, reason *string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	o, err := lockOpenP2POrder(ctx, tx, id)
+	if err != nil {
+		return err
+	}
+	if err := releaseHold(ctx, tx, o.HoldID, models.HoldReleased); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	query := `UPDATE p2p_orders SET status = $1, error = $2, updated_at = $3, resolved_at = $3 WHERE id = $4`
+	if _, err := tx.ExecContext(ctx, query, status, reason, now, id); err != nil {
+		return fmt.Errorf("failed to update p2p order: %w", err)
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,156 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/toluhikay/fx-exchange/internal/models"
+)
+
+// ratePoints are the sources candles are built from, each as rows of
+// (from_currency, to_currency, time, open, high, low, close, ticks): the raw
+// ticks under "" and the stored candles under their resolution.
+var ratePoints = map[string]string{
+	"": `SELECT from_currency, to_currency, timestamp AS time, rate AS open, rate AS high, rate AS low, rate AS close, 1 AS ticks
+             FROM fx_rates`,
+	models.Resolution1m: candlePoints(models.Resolution1m),
+	models.Resolution1h: candlePoints(models.Resolution1h),
+	models.Resolution1d: candlePoints(models.Resolution1d),
+}
+
+func candlePoints(resolution string) string {
+	return `SELECT from_currency, to_currency, bucket AS time, open, high, low, close, ticks
+             FROM fx_rate_candles WHERE resolution = '` + resolution + `'`
+}
+
+// bucketStart truncates the time column to the start of its bucket of step
+// seconds, given as the numbered parameter. Buckets are aligned to the Unix
+// epoch, so a 1h bucket always starts on the hour.
+func bucketStart(step int) string {
+	return fmt.Sprintf("to_timestamp(floor(extract(epoch FROM time)::float8 / $%[1]d::float8) * $%[1]d::float8) AT TIME ZONE 'UTC'", step)
+}
+
+// candleAggregates folds the points of a bucket into one candle.
+const candleAggregates = `(array_agg(open ORDER BY time))[1], MAX(high), MIN(low), (array_agg(close ORDER BY time DESC))[1], SUM(ticks)`
+
+// ListRateCandles buckets a pair's rates in [from, to) into candles of
+// interval, oldest first, built from the stored candles of resolution and,
+// past the last of those, from the raw ticks. Intervals without rates are
+// left out.
+func (r *Repository) ListRateCandles(ctx context.Context, fromCurrency, toCurrency string, from, to time.Time, interval time.Duration, resolution string, resolutionStep time.Duration) ([]models.Candle, error) {
+	query := `WITH rolled AS (
+                 SELECT MAX(bucket) AS last FROM fx_rate_candles
+                 WHERE from_currency = $1 AND to_currency = $2 AND resolution = $6
+             ), points AS (
+                 SELECT bucket AS time, open, high, low, close, ticks FROM fx_rate_candles
+                 WHERE from_currency = $1 AND to_currency = $2 AND resolution = $6 AND bucket >= $3 AND bucket < $4
+                 UNION ALL
+                 SELECT timestamp, rate, rate, rate, rate, 1 FROM fx_rates, rolled
+                 WHERE from_currency = $1 AND to_currency = $2 AND timestamp >= $3 AND timestamp < $4
+                   AND (rolled.last IS NULL OR timestamp >= rolled.last + make_interval(secs => $7))
+             )
+             SELECT ` + bucketStart(5) + ` AS bucket, ` + candleAggregates + `
+             FROM points
+             GROUP BY bucket
+             ORDER BY bucket`
+	rows, err := r.db.QueryContext(ctx, query, fromCurrency, toCurrency, from, to, interval.Seconds(), resolution, resolutionStep.Seconds())
+	if err != nil {
+		return nil, fmt.Errorf("failed to list rate candles: %w", err)
+	}
+	defer rows.Close()
+
+	candles := []models.Candle{}
+	for rows.Next() {
+		var c models.Candle
+		if err := rows.Scan(&c.Time, &c.Open, &c.High, &c.Low, &c.Close, &c.Ticks); err != nil {
+			return nil, fmt.Errorf("failed to scan rate candle: %w", err)
+		}
+		candles = append(candles, c)
+	}
+	return candles, rows.Err()
+}
+
+// ListRateTicks returns up to limit of a pair's rates in [from, to), oldest
+// first.
+func (r *Repository) ListRateTicks(ctx context.Context, fromCurrency, toCurrency string, from, to time.Time, limit int) ([]models.RateTick, error) {
+	query := `SELECT rate, timestamp FROM fx_rates
+             WHERE from_currency = $1 AND to_currency = $2 AND timestamp >= $3 AND timestamp < $4
+             ORDER BY timestamp LIMIT $5`
+	rows, err := r.db.QueryContext(ctx, query, fromCurrency, toCurrency, from, to, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list rate ticks: %w", err)
+	}
+	defer rows.Close()
+
+	ticks := []models.RateTick{}
+	for rows.Next() {
+		var t models.RateTick
+		if err := rows.Scan(&t.Rate, &t.Timestamp); err != nil {
+			return nil, fmt.Errorf("failed to scan rate tick: %w", err)
+		}
+		ticks = append(ticks, t)
+	}
+	return ticks, rows.Err()
+}
+
+// RollupRateCandles stores candles of resolution for every pair with points
+// from source, "" for the raw ticks or a finer resolution, in [from, to).
+// Candles already stored for those buckets are replaced, so a range can be
+// rolled up again safely.
+func (r *Repository) RollupRateCandles(ctx context.Context, resolution, source string, step time.Duration, from, to time.Time) error {
+	points, ok := ratePoints[source]
+	if !ok {
+		return fmt.Errorf("unknown rate candle source: %q", source)
+	}
+	query := `INSERT INTO fx_rate_candles (from_currency, to_currency, resolution, bucket, open, high, low, close, ticks)
+             SELECT from_currency, to_currency, $1, ` + bucketStart(2) + ` AS bucket, ` + candleAggregates + `
+             FROM (` + points + `) points
+             WHERE time >= $3 AND time < $4
+             GROUP BY from_currency, to_currency, bucket
+             ON CONFLICT (from_currency, to_currency, resolution, bucket) DO UPDATE
+             SET open = EXCLUDED.open, high = EXCLUDED.high, low = EXCLUDED.low, close = EXCLUDED.close, ticks = EXCLUDED.ticks`
+	if _, err := r.db.ExecContext(ctx, query, resolution, step.Seconds(), from, to); err != nil {
+		return fmt.Errorf("failed to roll up %s rate candles: %w", resolution, err)
+	}
+	return nil
+}
+
+// LastRateCandle returns the start of the latest stored candle of
+// resolution, or nil when there is none yet.
+func (r *Repository) LastRateCandle(ctx context.Context, resolution string) (*time.Time, error) {
+	var last *time.Time
+	query := `SELECT MAX(bucket) FROM fx_rate_candles WHERE resolution = $1`
+	if err := r.db.QueryRowContext(ctx, query, resolution).Scan(&last); err != nil {
+		return nil, fmt.Errorf("failed to get last rate candle: %w", err)
+	}
+	return last, nil
+}
+
+// FirstRatePoint returns the time of the earliest point in source, "" for
+// the raw ticks or a resolution, or nil when it is empty.
+func (r *Repository) FirstRatePoint(ctx context.Context, source string) (*time.Time, error) {
+	points, ok := ratePoints[source]
+	if !ok {
+		return nil, fmt.Errorf("unknown rate candle source: %q", source)
+	}
+	var first *time.Time
+	query := `SELECT MIN(time) FROM (` + points + `) points`
+	if err := r.db.QueryRowContext(ctx, query).Scan(&first); err != nil {
+		return nil, fmt.Errorf("failed to get first rate point: %w", err)
+	}
+	return first, nil
+}
+
+// PruneRateTicks deletes up to limit raw ticks from before before and
+// returns how many it deleted.
+func (r *Repository) PruneRateTicks(ctx context.Context, before time.Time, limit int) (int64, error) {
+	query := `DELETE FROM fx_rates WHERE id IN (
+                 SELECT id FROM fx_rates WHERE timestamp < $1 LIMIT $2
+             )`
+	result, err := r.db.ExecContext(ctx, query, before, limit)
+	if err != nil {
+		return 0, fmt.Errorf("failed to prune rate ticks: %w", err)
+	}
+	return result.RowsAffected()
+}
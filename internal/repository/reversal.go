@@ -0,0 +1,162 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	customError "github.com/toluhikay/fx-exchange/internal/errors"
+	"github.com/toluhikay/fx-exchange/internal/models"
+)
+
+func (r *Repository) GetTransaction(ctx context.Context, id string) (*models.Transaction, error) {
+	query := `SELECT ` + transactionColumns + ` FROM transactions WHERE id = $1`
+	return scanTransaction(r.db.QueryRowContext(ctx, query, id))
+}
+
+// ListReversals returns the compensating entries written against a
+// transaction, oldest first.
+func (r *Repository) ListReversals(ctx context.Context, id string) ([]models.Transaction, error) {
+	query := `SELECT ` + transactionColumns + ` FROM transactions WHERE reversal_of = $1 ORDER BY timestamp`
+	rows, err := r.db.QueryContext(ctx, query, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list reversals: %w", err)
+	}
+	defer rows.Close()
+
+	reversals := []models.Transaction{}
+	for rows.Next() {
+		t, err := scanTransaction(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan reversal: %w", err)
+		}
+		reversals = append(reversals, *t)
+	}
+	return reversals, rows.Err()
+}
+
+// ReverseTransaction writes a compensating entry for amount of a swap or
+// transfer, in the currency originally sent, at the original rate. The
+// converted share is taken back from the wallet that received it and the
+// sent amount is refunded. Reversals are a staff correction, so freezes do
+// not stop them, but closed wallets can no longer be touched.
+//
+// When the paying wallet no longer has the money available the reversal is
+// refused unless force is set, in which case its balance may go below zero.
+func (r *Repository) ReverseTransaction(ctx context.Context, id string, amount float64, reason string, force bool) (*models.Transaction, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	reversal, err := reverseTransaction(ctx, tx, id, amount, reason, force)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return reversal, nil
+}
+
+func reverseTransaction(ctx context.Context, tx *sql.Tx, id string, amount float64, reason string, force bool) (*models.Transaction, error) {
+	query := `SELECT ` + transactionColumns + ` FROM transactions WHERE id = $1 FOR UPDATE`
+	original, err := scanTransaction(tx.QueryRowContext(ctx, query, id))
+	if err != nil {
+		return nil, err
+	}
+	if original.Type != "swap" && original.Type != "transfer" {
+		return nil, customError.ErrNotReversible
+	}
+	if original.Status == models.TxReversed {
+		return nil, customError.ErrAlreadyReversed
+	}
+	if original.FromCurrency == nil || original.ToCurrency == nil || original.Amount == nil || original.ConvertedAmount == nil {
+		return nil, customError.ErrNotReversible
+	}
+
+	remaining := *original.Amount - original.ReversedAmount
+	if amount == 0 {
+		amount = remaining
+	}
+	if amount <= 0 || amount > remaining {
+		return nil, fmt.Errorf("%w: at most %.4f %s can be reversed", customError.ErrInvalidReversal, remaining, *original.FromCurrency)
+	}
+	takeBack := *original.ConvertedAmount * amount / *original.Amount
+
+	// refunded is the wallet that sent the money, payer the one that got it
+	refundedID, payerID := original.WalletID, original.WalletID
+	if original.Type == "transfer" {
+		if original.ReceiverWalletID == nil {
+			return nil, customError.ErrNotReversible
+		}
+		payerID = *original.ReceiverWalletID
+	}
+
+	// lock in a fixed order so two reversals between the same wallets cannot
+	// deadlock
+	walletIDs := []string{refundedID}
+	if payerID != refundedID {
+		walletIDs = append(walletIDs, payerID)
+	}
+	sort.Strings(walletIDs)
+	balances := make(map[string]map[string]float64, len(walletIDs))
+	for _, walletID := range walletIDs {
+		b, status, err := lockWallet(ctx, tx, walletID)
+		if err != nil {
+			return nil, err
+		}
+		if status == models.WalletClosed {
+			return nil, customError.ErrWalletClosed
+		}
+		balances[walletID] = b
+	}
+
+	held, err := heldAmount(ctx, tx, payerID, *original.ToCurrency)
+	if err != nil {
+		return nil, err
+	}
+	available := balances[payerID][*original.ToCurrency] - held
+	if available < takeBack && !force {
+		return nil, customError.NewCodedError(models.CodeFundsSpent,
+			fmt.Errorf("%w: %.4f %s is needed but only %.4f is available", customError.ErrFundsSpent, takeBack, *original.ToCurrency, math.Max(available, 0)))
+	}
+
+	balances[payerID][*original.ToCurrency] -= takeBack
+	balances[refundedID][*original.FromCurrency] += amount
+	for _, walletID := range walletIDs {
+		if err := saveBalances(ctx, tx, walletID, balances[walletID]); err != nil {
+			return nil, err
+		}
+	}
+
+	rate := 0.0
+	if takeBack > 0 {
+		rate = amount / takeBack
+	}
+	query = `INSERT INTO transactions (id, wallet_id, type, from_currency, to_currency, amount, converted_amount, rate, reference, receiver_wallet_id, reversal_of, timestamp)
+             VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+             RETURNING ` + transactionColumns
+	reversal, err := scanTransaction(tx.QueryRowContext(ctx, query, uuid.New().String(), original.WalletID, models.TxReversal,
+		*original.ToCurrency, *original.FromCurrency, takeBack, amount, rate, reason, original.ReceiverWalletID, original.ID, time.Now()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to log reversal: %w", err)
+	}
+
+	status := models.TxPartiallyReversed
+	// amounts are stored to four decimal places
+	if remaining-amount < 0.00005 {
+		status = models.TxReversed
+	}
+	query = `UPDATE transactions SET reversed_amount = reversed_amount + $1, status = $2 WHERE id = $3`
+	if _, err := tx.ExecContext(ctx, query, amount, status, original.ID); err != nil {
+		return nil, fmt.Errorf("failed to update transaction: %w", err)
+	}
+	return reversal, nil
+}
@@ -0,0 +1,238 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/toluhikay/fx-exchange/internal/models"
+)
+
+const scheduledTransferColumns = `id, wallet_id, receiver_wallet_id, created_by, currency, amount, frequency, cron_expr, note, status,
+             start_at, end_at, next_run_at, last_run_at, retry_count, device, created_at, updated_at`
+
+func scanScheduledTransfer(row rowScanner) (*models.ScheduledTransfer, error) {
+	var st models.ScheduledTransfer
+	if err := row.Scan(
+		&st.ID,
+		&st.WalletID,
+		&st.ReceiverWalletID,
+		&st.CreatedBy,
+		&st.Currency,
+		&st.Amount,
+		&st.Frequency,
+		&st.CronExpr,
+		&st.Note,
+		&st.Status,
+		&st.StartAt,
+		&st.EndAt,
+		&st.NextRunAt,
+		&st.LastRunAt,
+		&st.RetryCount,
+		&st.Device,
+		&st.CreatedAt,
+		&st.UpdatedAt,
+	); err != nil {
+		return nil, err
+	}
+	return &st, nil
+}
+
+func scanScheduledTransfers(rows *sql.Rows) ([]models.ScheduledTransfer, error) {
+	schedules := []models.ScheduledTransfer{}
+	for rows.Next() {
+		st, err := scanScheduledTransfer(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan scheduled transfer: %w", err)
+		}
+		schedules = append(schedules, *st)
+	}
+	return schedules, rows.Err()
+}
+
+func (r *Repository) CreateScheduledTransfer(ctx context.Context, st models.ScheduledTransfer) (*models.ScheduledTransfer, error) {
+	now := time.Now()
+	query := `INSERT INTO scheduled_transfers (id, wallet_id, receiver_wallet_id, created_by, currency, amount, frequency, cron_expr, note, status,
+             start_at, end_at, next_run_at, device, created_at, updated_at)
+             VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $15)
+             RETURNING ` + scheduledTransferColumns
+	created, err := scanScheduledTransfer(r.db.QueryRowContext(ctx, query, uuid.New(), st.WalletID, st.ReceiverWalletID, st.CreatedBy,
+		st.Currency, st.Amount, st.Frequency, st.CronExpr, st.Note, models.ScheduleActive, st.StartAt, st.EndAt, st.NextRunAt, st.Device, now))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create scheduled transfer: %w", err)
+	}
+	return created, nil
+}
+
+func (r *Repository) GetScheduledTransfer(ctx context.Context, id uuid.UUID) (*models.ScheduledTransfer, error) {
+	query := `SELECT ` + scheduledTransferColumns + ` FROM scheduled_transfers WHERE id = $1`
+	return scanScheduledTransfer(r.db.QueryRowContext(ctx, query, id))
+}
+
+func (r *Repository) ListScheduledTransfers(ctx context.Context, walletID string, limit, offset int) ([]models.ScheduledTransfer, error) {
+	query := `SELECT ` + scheduledTransferColumns + ` FROM scheduled_transfers WHERE wallet_id = $1
+             ORDER BY created_at DESC LIMIT $2 OFFSET $3`
+	rows, err := r.db.QueryContext(ctx, query, walletID, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list scheduled transfers: %w", err)
+	}
+	defer rows.Close()
+
+	return scanScheduledTransfers(rows)
+}
+
+// ListDueScheduledTransfers returns active schedules whose next run is at or
+// before now, most overdue first.
+func (r *Repository) ListDueScheduledTransfers(ctx context.Context, now time.Time, limit int) ([]models.ScheduledTransfer, error) {
+	query := `SELECT ` + scheduledTransferColumns + ` FROM scheduled_transfers
+             WHERE status = 'active' AND next_run_at <= $1 ORDER BY next_run_at LIMIT $2`
+	rows, err := r.db.QueryContext(ctx, query, now, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list due scheduled transfers: %w", err)
+	}
+	defer rows.Close()
+
+	return scanScheduledTransfers(rows)
+}
+
+// ClaimScheduledTransfer pushes a due run out to leaseUntil so no other
+// runner picks it up while it executes. It reports false when the schedule
+// was changed or claimed since it was listed.
+func (r *Repository) ClaimScheduledTransfer(ctx context.Context, id uuid.UUID, due, leaseUntil time.Time) (bool, error) {
+	query := `UPDATE scheduled_transfers SET next_run_at = $1, updated_at = $2
+             WHERE id = $3 AND status = 'active' AND next_run_at = $4`
+	res, err := r.db.ExecContext(ctx, query, leaseUntil, time.Now(), id, due)
+	if err != nil {
+		return false, fmt.Errorf("failed to claim scheduled transfer: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n == 1, nil
+}
+
+func insertScheduledRun(ctx context.Context, tx *sql.Tx, run models.ScheduledTransferRun) error {
+	query := `INSERT INTO scheduled_transfer_runs (id, schedule_id, scheduled_for, attempt, status, converted_amount, rate, error, created_at)
+             VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`
+	_, err := tx.ExecContext(ctx, query, uuid.New(), run.ScheduleID, run.ScheduledFor, run.Attempt, run.Status,
+		run.ConvertedAmount, run.Rate, run.Error, run.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to log scheduled transfer run: %w", err)
+	}
+	return nil
+}
+
+// RecordScheduledRun logs a run and moves the schedule on to next. A nil next
+// completes the schedule. Schedules cancelled while the run was executing
+// stay cancelled.
+func (r *Repository) RecordScheduledRun(ctx context.Context, run models.ScheduledTransferRun, next *time.Time, retryCount int) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := insertScheduledRun(ctx, tx, run); err != nil {
+		return err
+	}
+
+	query := `UPDATE scheduled_transfers SET
+             next_run_at = CASE WHEN status = 'cancelled' THEN NULL ELSE $1::timestamp END,
+             status = CASE WHEN $1::timestamp IS NULL AND status IN ('active', 'paused') THEN 'completed' ELSE status END,
+             retry_count = $2, last_run_at = $3, updated_at = $3
+             WHERE id = $4`
+	if _, err := tx.ExecContext(ctx, query, next, retryCount, run.CreatedAt, run.ScheduleID); err != nil {
+		return fmt.Errorf("failed to update scheduled transfer: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+// SkipScheduledRun logs the run due at due as skipped and moves the schedule
+// on to next. It returns sql.ErrNoRows when the run is no longer the one due,
+// for instance because it is already executing.
+func (r *Repository) SkipScheduledRun(ctx context.Context, run models.ScheduledTransferRun, next *time.Time) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `UPDATE scheduled_transfers SET
+             next_run_at = $1::timestamp,
+             status = CASE WHEN $1::timestamp IS NULL THEN 'completed' ELSE status END,
+             retry_count = 0, updated_at = $2
+             WHERE id = $3 AND status IN ('active', 'paused') AND next_run_at = $4`
+	res, err := tx.ExecContext(ctx, query, next, run.CreatedAt, run.ScheduleID, run.ScheduledFor)
+	if err != nil {
+		return fmt.Errorf("failed to update scheduled transfer: %w", err)
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return sql.ErrNoRows
+	}
+
+	if err := insertScheduledRun(ctx, tx, run); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// SetScheduledTransferStatus moves a schedule from one of the from states to
+// status with the given next run. It returns sql.ErrNoRows when the schedule
+// is in none of them.
+func (r *Repository) SetScheduledTransferStatus(ctx context.Context, id uuid.UUID, from []string, status string, next *time.Time) error {
+	query := `UPDATE scheduled_transfers SET status = $1, next_run_at = $2, retry_count = 0, updated_at = $3
+             WHERE id = $4 AND status = ANY($5)`
+	res, err := r.db.ExecContext(ctx, query, status, next, time.Now(), id, from)
+	if err != nil {
+		return fmt.Errorf("failed to update scheduled transfer: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+const scheduledRunColumns = `id, schedule_id, scheduled_for, attempt, status, converted_amount, rate, error, created_at`
+
+// ListScheduledTransferRuns returns a schedule's execution history, newest
+// first.
+func (r *Repository) ListScheduledTransferRuns(ctx context.Context, scheduleID uuid.UUID, limit, offset int) ([]models.ScheduledTransferRun, error) {
+	query := `SELECT ` + scheduledRunColumns + ` FROM scheduled_transfer_runs WHERE schedule_id = $1
+             ORDER BY created_at DESC LIMIT $2 OFFSET $3`
+	rows, err := r.db.QueryContext(ctx, query, scheduleID, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list scheduled transfer runs: %w", err)
+	}
+	defer rows.Close()
+
+	runs := []models.ScheduledTransferRun{}
+	for rows.Next() {
+		var run models.ScheduledTransferRun
+		if err := rows.Scan(
+			&run.ID,
+			&run.ScheduleID,
+			&run.ScheduledFor,
+			&run.Attempt,
+			&run.Status,
+			&run.ConvertedAmount,
+			&run.Rate,
+			&run.Error,
+			&run.CreatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan scheduled transfer run: %w", err)
+		}
+		runs = append(runs, run)
+	}
+	return runs, rows.Err()
+}
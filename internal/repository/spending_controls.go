@@ -0,0 +1,66 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/toluhikay/fx-exchange/internal/models"
+)
+
+func (r *Repository) GetSpendingControls(ctx context.Context, walletID string) (*models.SpendingControls, error) {
+	query := `SELECT spending_controls FROM wallets WHERE id = $1`
+	var controlsJSON []byte
+	if err := r.db.QueryRowContext(ctx, query, walletID).Scan(&controlsJSON); err != nil {
+		return nil, fmt.Errorf("failed to get spending controls: %w", err)
+	}
+
+	var controls models.SpendingControls
+	if err := json.Unmarshal(controlsJSON, &controls); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal spending controls: %w", err)
+	}
+	return &controls, nil
+}
+
+func (r *Repository) SetSpendingControls(ctx context.Context, walletID string, controls models.SpendingControls) error {
+	controlsJSON, err := json.Marshal(controls)
+	if err != nil {
+		return fmt.Errorf("failed to marshal spending controls: %w", err)
+	}
+
+	query := `UPDATE wallets SET spending_controls = $1 WHERE id = $2`
+	if _, err := r.db.ExecContext(ctx, query, controlsJSON, walletID); err != nil {
+		return fmt.Errorf("failed to update spending controls: %w", err)
+	}
+	return nil
+}
+
+// GetOutgoingByCurrency sums what the wallet sent since the given time, per
+// currency: the swaps, trades, transfers and withdrawals GetCurrencyUsage
+// counts against the currency sent.
+func (r *Repository) GetOutgoingByCurrency(ctx context.Context, walletID string, since time.Time) (map[string]float64, error) {
+	return outgoingByCurrency(ctx, r.db, walletID, since)
+}
+
+func outgoingByCurrency(ctx context.Context, q queryer, walletID string, since time.Time) (map[string]float64, error) {
+	query := `SELECT from_currency, SUM(amount) FROM transactions
+             WHERE wallet_id = $1 AND timestamp >= $2 AND type IN ('swap', 'trade', 'transfer', 'withdrawal')
+             GROUP BY from_currency`
+	rows, err := q.QueryContext(ctx, query, walletID, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get outgoing totals: %w", err)
+	}
+	defer rows.Close()
+
+	totals := make(map[string]float64)
+	for rows.Next() {
+		var currency string
+		var amount float64
+		if err := rows.Scan(&currency, &amount); err != nil {
+			return nil, fmt.Errorf("failed to scan outgoing total: %w", err)
+		}
+		totals[currency] = amount
+	}
+	return totals, rows.Err()
+}
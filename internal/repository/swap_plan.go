@@ -0,0 +1,220 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/toluhikay/fx-exchange/internal/models"
+)
+
+const swapPlanColumns = `id, wallet_id, created_by, from_currency, to_currency, amount, max_rate, frequency, cron_expr, status,
+             start_at, end_at, next_run_at, last_run_at, created_at, updated_at`
+
+func scanSwapPlan(row rowScanner) (*models.SwapPlan, error) {
+	var p models.SwapPlan
+	if err := row.Scan(
+		&p.ID,
+		&p.WalletID,
+		&p.CreatedBy,
+		&p.FromCurrency,
+		&p.ToCurrency,
+		&p.Amount,
+		&p.MaxRate,
+		&p.Frequency,
+		&p.CronExpr,
+		&p.Status,
+		&p.StartAt,
+		&p.EndAt,
+		&p.NextRunAt,
+		&p.LastRunAt,
+		&p.CreatedAt,
+		&p.UpdatedAt,
+	); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+func scanSwapPlans(rows *sql.Rows) ([]models.SwapPlan, error) {
+	plans := []models.SwapPlan{}
+	for rows.Next() {
+		p, err := scanSwapPlan(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan swap plan: %w", err)
+		}
+		plans = append(plans, *p)
+	}
+	return plans, rows.Err()
+}
+
+func (r *Repository) CreateSwapPlan(ctx context.Context, p models.SwapPlan) (*models.SwapPlan, error) {
+	query := `INSERT INTO swap_plans (id, wallet_id, created_by, from_currency, to_currency, amount, max_rate, frequency, cron_expr, status,
+             start_at, end_at, next_run_at, created_at, updated_at)
+             VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $14)
+             RETURNING ` + swapPlanColumns
+	created, err := scanSwapPlan(r.db.QueryRowContext(ctx, query, uuid.New(), p.WalletID, p.CreatedBy, p.FromCurrency, p.ToCurrency,
+		p.Amount, p.MaxRate, p.Frequency, p.CronExpr, models.ScheduleActive, p.StartAt, p.EndAt, p.NextRunAt, time.Now()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create swap plan: %w", err)
+	}
+	return created, nil
+}
+
+func (r *Repository) GetSwapPlan(ctx context.Context, id uuid.UUID) (*models.SwapPlan, error) {
+	query := `SELECT ` + swapPlanColumns + ` FROM swap_plans WHERE id = $1`
+	return scanSwapPlan(r.db.QueryRowContext(ctx, query, id))
+}
+
+func (r *Repository) ListSwapPlans(ctx context.Context, walletID string, limit, offset int) ([]models.SwapPlan, error) {
+	query := `SELECT ` + swapPlanColumns + ` FROM swap_plans WHERE wallet_id = $1
+             ORDER BY created_at DESC LIMIT $2 OFFSET $3`
+	rows, err := r.db.QueryContext(ctx, query, walletID, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list swap plans: %w", err)
+	}
+	defer rows.Close()
+
+	return scanSwapPlans(rows)
+}
+
+// ListDueSwapPlans returns active plans whose next run is at or before now,
+// most overdue first.
+func (r *Repository) ListDueSwapPlans(ctx context.Context, now time.Time, limit int) ([]models.SwapPlan, error) {
+	query := `SELECT ` + swapPlanColumns + ` FROM swap_plans
+             WHERE status = 'active' AND next_run_at <= $1 ORDER BY next_run_at LIMIT $2`
+	rows, err := r.db.QueryContext(ctx, query, now, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list due swap plans: %w", err)
+	}
+	defer rows.Close()
+
+	return scanSwapPlans(rows)
+}
+
+// ClaimSwapPlan works like ClaimScheduledTransfer.
+func (r *Repository) ClaimSwapPlan(ctx context.Context, id uuid.UUID, due, leaseUntil time.Time) (bool, error) {
+	query := `UPDATE swap_plans SET next_run_at = $1, updated_at = $2
+             WHERE id = $3 AND status = 'active' AND next_run_at = $4`
+	res, err := r.db.ExecContext(ctx, query, leaseUntil, time.Now(), id, due)
+	if err != nil {
+		return false, fmt.Errorf("failed to claim swap plan: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n == 1, nil
+}
+
+// RecordSwapPlanRun logs a run and moves the plan on to next. A nil next
+// completes the plan. Plans cancelled while the run was executing stay
+// cancelled.
+func (r *Repository) RecordSwapPlanRun(ctx context.Context, run models.SwapPlanRun, next *time.Time) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `INSERT INTO swap_plan_runs (id, plan_id, scheduled_for, status, amount, converted_amount, rate, error, created_at)
+             VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`
+	_, err = tx.ExecContext(ctx, query, uuid.New(), run.PlanID, run.ScheduledFor, run.Status, run.Amount,
+		run.ConvertedAmount, run.Rate, run.Error, run.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to log swap plan run: %w", err)
+	}
+
+	query = `UPDATE swap_plans SET
+             next_run_at = CASE WHEN status = 'cancelled' THEN NULL ELSE $1::timestamp END,
+             status = CASE WHEN $1::timestamp IS NULL AND status IN ('active', 'paused') THEN 'completed' ELSE status END,
+             last_run_at = $2, updated_at = $2
+             WHERE id = $3`
+	if _, err := tx.ExecContext(ctx, query, next, run.CreatedAt, run.PlanID); err != nil {
+		return fmt.Errorf("failed to update swap plan: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+// SetSwapPlanStatus moves a plan from one of the from states to status with
+// the given next run. It returns sql.ErrNoRows when the plan is in none of
+// them.
+func (r *Repository) SetSwapPlanStatus(ctx context.Context, id uuid.UUID, from []string, status string, next *time.Time) error {
+	query := `UPDATE swap_plans SET status = $1, next_run_at = $2, updated_at = $3
+             WHERE id = $4 AND status = ANY($5)`
+	res, err := r.db.ExecContext(ctx, query, status, next, time.Now(), id, from)
+	if err != nil {
+		return fmt.Errorf("failed to update swap plan: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// ListSwapPlanRuns returns a plan's execution reports, newest first.
+func (r *Repository) ListSwapPlanRuns(ctx context.Context, planID uuid.UUID, limit, offset int) ([]models.SwapPlanRun, error) {
+	query := `SELECT id, plan_id, scheduled_for, status, amount, converted_amount, rate, error, created_at
+             FROM swap_plan_runs WHERE plan_id = $1 ORDER BY created_at DESC LIMIT $2 OFFSET $3`
+	rows, err := r.db.QueryContext(ctx, query, planID, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list swap plan runs: %w", err)
+	}
+	defer rows.Close()
+
+	runs := []models.SwapPlanRun{}
+	for rows.Next() {
+		var run models.SwapPlanRun
+		if err := rows.Scan(
+			&run.ID,
+			&run.PlanID,
+			&run.ScheduledFor,
+			&run.Status,
+			&run.Amount,
+			&run.ConvertedAmount,
+			&run.Rate,
+			&run.Error,
+			&run.CreatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan swap plan run: %w", err)
+		}
+		runs = append(runs, run)
+	}
+	return runs, rows.Err()
+}
+
+// GetSwapPlanSummary totals the plan's runs. The averages are left for the
+// caller to work out from the totals.
+func (r *Repository) GetSwapPlanSummary(ctx context.Context, planID uuid.UUID) (*models.SwapPlanSummary, error) {
+	query := `SELECT COUNT(*),
+             COUNT(*) FILTER (WHERE status = 'succeeded'),
+             COALESCE(SUM(amount) FILTER (WHERE status = 'succeeded'), 0),
+             COALESCE(SUM(converted_amount) FILTER (WHERE status = 'succeeded'), 0),
+             MAX(rate) FILTER (WHERE status = 'succeeded'),
+             MIN(rate) FILTER (WHERE status = 'succeeded'),
+             MIN(created_at) FILTER (WHERE status = 'succeeded'),
+             MAX(created_at) FILTER (WHERE status = 'succeeded')
+             FROM swap_plan_runs WHERE plan_id = $1`
+	summary := models.SwapPlanSummary{PlanID: planID}
+	err := r.db.QueryRowContext(ctx, query, planID).Scan(
+		&summary.Runs,
+		&summary.Succeeded,
+		&summary.TotalSpent,
+		&summary.TotalReceived,
+		&summary.BestRate,
+		&summary.WorstRate,
+		&summary.FirstRunAt,
+		&summary.LastRunAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to summarise swap plan: %w", err)
+	}
+	return &summary, nil
+}
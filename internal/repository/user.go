@@ -3,9 +3,12 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"time"
 
 	"github.com/google/uuid"
+	customError "github.com/toluhikay/fx-exchange/internal/errors"
 	"github.com/toluhikay/fx-exchange/internal/models"
 )
 
@@ -24,19 +27,30 @@ func NewUserRepo(db *sql.DB) *UserDbRepo {
 }
 
 func (m *UserDbRepo) CreateUser(ctx context.Context, u models.User) (*models.User, error) {
-	stmt := `INSERT INTO users (name, email, password)
-			VALUES ($1, $2, $3) RETURNING id, name, email`
+	return createUser(ctx, m.DB, u)
+}
+
+func createUser(ctx context.Context, q queryer, u models.User) (*models.User, error) {
+	stmt := `INSERT INTO users (id, name, email, password, frozen_at, frozen_reason)
+			VALUES ($1, $2, $3, $4, $5, $6) RETURNING id, name, email, role, kyc_level, frozen_at, frozen_reason`
 
 	var newUser models.User
 
-	err := m.DB.QueryRowContext(ctx, stmt,
+	err := q.QueryRowContext(ctx, stmt,
+		u.ID,
 		u.Name,
 		u.Email,
 		u.Password,
+		u.FrozenAt,
+		u.FrozenReason,
 	).Scan(
 		&newUser.ID,
 		&newUser.Name,
 		&newUser.Email,
+		&newUser.Role,
+		&newUser.KycLevel,
+		&newUser.FrozenAt,
+		&newUser.FrozenReason,
 	)
 
 	if err != nil {
@@ -47,49 +61,418 @@ func (m *UserDbRepo) CreateUser(ctx context.Context, u models.User) (*models.Use
 
 }
 
-func (m *UserDbRepo) GetUserById(ctx context.Context, u uuid.UUID) (*models.User, error) {
+// userColumns is the column list scanned by scanUser.
+const userColumns = `id, name, email, password, role, kyc_level, email_verified_at, session_version, mfa_secret, mfa_enabled_at, mfa_last_step,
+				transaction_pin, pin_failed_attempts, pin_locked_until, frozen_at, frozen_reason, created_at, updated_at, deleted_at`
 
-	query := `
-				SELECT * from users
-				WHERE id = $1
-				FOR UPDATE
-	`
+func scanUser(row rowScanner) (*models.User, error) {
 	var user models.User
-	if err := m.DB.QueryRowContext(ctx, query, u).Scan(
+	if err := row.Scan(
 		&user.ID,
 		&user.Name,
 		&user.Email,
 		&user.Password,
+		&user.Role,
+		&user.KycLevel,
+		&user.EmailVerifiedAt,
+		&user.SessionVersion,
+		&user.MfaSecret,
+		&user.MfaEnabledAt,
+		&user.MfaLastStep,
+		&user.TransactionPin,
+		&user.PinAttempts,
+		&user.PinLockedUntil,
+		&user.FrozenAt,
+		&user.FrozenReason,
 		&user.CreatedAt,
 		&user.UpdatedAt,
 		&user.DeletedAt,
 	); err != nil {
 		return nil, err
 	}
-
 	return &user, nil
 }
 
+func (m *UserDbRepo) GetUserById(ctx context.Context, u uuid.UUID) (*models.User, error) {
+
+	query := `
+				SELECT ` + userColumns + ` from users
+				WHERE id = $1
+	`
+	return scanUser(m.DB.QueryRowContext(ctx, query, u))
+}
+
 func (m *UserDbRepo) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
 
 	query := `
-				SELECT id, name, email, password,  created_at, updated_at from users
-				WHERE email = $1
-				FOR UPDATE
+				SELECT ` + userColumns + ` from users
+				WHERE email = $1 AND deleted_at IS NULL
 	`
-	var user models.User
-	if err := m.DB.QueryRowContext(ctx, query, email).Scan(
-		&user.ID,
-		&user.Name,
-		&user.Email,
-		&user.Password,
-		&user.CreatedAt,
-		&user.UpdatedAt,
-	); err != nil {
+	user, err := scanUser(m.DB.QueryRowContext(ctx, query, email))
+	if err != nil {
 		fmt.Println(err, email)
 		return nil, err
 	}
 
-	return &user, nil
+	return user, nil
 
 }
+
+// SearchUsers matches q against name, email and id. An empty q lists everyone.
+func (m *UserDbRepo) SearchUsers(ctx context.Context, q string, limit, offset int) ([]models.User, error) {
+	query := `
+				SELECT ` + userColumns + ` from users
+				WHERE $1 = '' OR name ILIKE '%' || $1 || '%' OR email ILIKE '%' || $1 || '%' OR id::text = $1
+				ORDER BY created_at DESC
+				LIMIT $2 OFFSET $3
+	`
+	rows, err := m.DB.QueryContext(ctx, query, q, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search users: %w", err)
+	}
+	defer rows.Close()
+
+	users := []models.User{}
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan user: %w", err)
+		}
+		users = append(users, *user)
+	}
+	return users, rows.Err()
+}
+
+// SetUserRole also bumps the session version so tokens carrying the old role
+// stop working.
+func (m *UserDbRepo) SetUserRole(ctx context.Context, userID uuid.UUID, role string) error {
+	query := `UPDATE users SET role = $1, session_version = session_version + 1, updated_at = $2 WHERE id = $3`
+	res, err := m.DB.ExecContext(ctx, query, role, time.Now(), userID)
+	if err != nil {
+		return fmt.Errorf("failed to set user role: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// SetUserFrozen freezes the account with reason, or unfreezes it when reason is nil.
+func (m *UserDbRepo) SetUserFrozen(ctx context.Context, userID uuid.UUID, reason *string) error {
+	var frozenAt *time.Time
+	if reason != nil {
+		now := time.Now()
+		frozenAt = &now
+	}
+
+	query := `UPDATE users SET frozen_at = $1, frozen_reason = $2, updated_at = $3 WHERE id = $4`
+	res, err := m.DB.ExecContext(ctx, query, frozenAt, reason, time.Now(), userID)
+	if err != nil {
+		return fmt.Errorf("failed to update account freeze: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// CreateEmailVerification stores a new otp for a user and invalidates the
+// previous ones. It fails with ErrTooManyOtpResends once maxCodes were already
+// created since since.
+func (m *UserDbRepo) CreateEmailVerification(ctx context.Context, v models.EmailVerification, since time.Time, maxCodes int) error {
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// lock the user so concurrent resends are counted one after the other
+	query := `SELECT id FROM users WHERE id = $1 FOR UPDATE`
+	if _, err := tx.ExecContext(ctx, query, v.UserID); err != nil {
+		return fmt.Errorf("failed to lock user: %w", err)
+	}
+
+	var created int
+	query = `SELECT COUNT(*) FROM email_verifications WHERE user_id = $1 AND created_at >= $2`
+	if err := tx.QueryRowContext(ctx, query, v.UserID, since).Scan(&created); err != nil {
+		return fmt.Errorf("failed to count otps: %w", err)
+	}
+	if created >= maxCodes {
+		return customError.ErrTooManyOtpResends
+	}
+
+	// only the most recent otp is ever valid
+	query = `UPDATE email_verifications SET consumed_at = $1 WHERE user_id = $2 AND consumed_at IS NULL`
+	if _, err := tx.ExecContext(ctx, query, time.Now(), v.UserID); err != nil {
+		return fmt.Errorf("failed to invalidate previous otps: %w", err)
+	}
+
+	query = `INSERT INTO email_verifications (id, user_id, otp_hash, expires_at, created_at)
+			VALUES ($1, $2, $3, $4, $5)`
+	if _, err := tx.ExecContext(ctx, query, v.ID, v.UserID, v.OtpHash, v.ExpiresAt, time.Now()); err != nil {
+		return fmt.Errorf("failed to create email verification: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+// CountEmailVerificationAttempts returns the wrong guesses made against every
+// otp of a user created since since, so a fresh code does not reset them.
+func (m *UserDbRepo) CountEmailVerificationAttempts(ctx context.Context, userID uuid.UUID, since time.Time) (int, error) {
+	query := `SELECT COALESCE(SUM(attempts), 0) FROM email_verifications WHERE user_id = $1 AND created_at >= $2`
+	var attempts int
+	if err := m.DB.QueryRowContext(ctx, query, userID, since).Scan(&attempts); err != nil {
+		return 0, fmt.Errorf("failed to count otp attempts: %w", err)
+	}
+	return attempts, nil
+}
+
+func (m *UserDbRepo) GetActiveEmailVerification(ctx context.Context, userID uuid.UUID) (*models.EmailVerification, error) {
+	query := `
+				SELECT id, user_id, otp_hash, attempts, expires_at, consumed_at, created_at FROM email_verifications
+				WHERE user_id = $1 AND consumed_at IS NULL
+				ORDER BY created_at DESC
+				LIMIT 1
+	`
+	var v models.EmailVerification
+	if err := m.DB.QueryRowContext(ctx, query, userID).Scan(
+		&v.ID,
+		&v.UserID,
+		&v.OtpHash,
+		&v.Attempts,
+		&v.ExpiresAt,
+		&v.ConsumedAt,
+		&v.CreatedAt,
+	); err != nil {
+		return nil, err
+	}
+
+	return &v, nil
+}
+
+func (m *UserDbRepo) IncrementEmailVerificationAttempts(ctx context.Context, id uuid.UUID) (int, error) {
+	query := `UPDATE email_verifications SET attempts = attempts + 1 WHERE id = $1 RETURNING attempts`
+	var attempts int
+	if err := m.DB.QueryRowContext(ctx, query, id).Scan(&attempts); err != nil {
+		return 0, fmt.Errorf("failed to record otp attempt: %w", err)
+	}
+	return attempts, nil
+}
+
+func (m *UserDbRepo) MarkEmailVerified(ctx context.Context, verificationID, userID uuid.UUID) error {
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	query := `UPDATE email_verifications SET consumed_at = $1 WHERE id = $2`
+	if _, err := tx.ExecContext(ctx, query, now, verificationID); err != nil {
+		return fmt.Errorf("failed to consume otp: %w", err)
+	}
+
+	query = `UPDATE users SET email_verified_at = $1, updated_at = $1 WHERE id = $2`
+	if _, err := tx.ExecContext(ctx, query, now, userID); err != nil {
+		return fmt.Errorf("failed to verify user email: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+func (m *UserDbRepo) CreatePasswordReset(ctx context.Context, pr models.PasswordReset) error {
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// a new request supersedes any reset link that is still outstanding
+	query := `UPDATE password_resets SET used_at = $1 WHERE user_id = $2 AND used_at IS NULL`
+	if _, err := tx.ExecContext(ctx, query, time.Now(), pr.UserID); err != nil {
+		return fmt.Errorf("failed to invalidate previous resets: %w", err)
+	}
+
+	query = `INSERT INTO password_resets (id, user_id, token_hash, expires_at, created_at)
+			VALUES ($1, $2, $3, $4, $5)`
+	if _, err := tx.ExecContext(ctx, query, pr.ID, pr.UserID, pr.TokenHash, pr.ExpiresAt, time.Now()); err != nil {
+		return fmt.Errorf("failed to create password reset: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+func (m *UserDbRepo) GetPasswordResetByTokenHash(ctx context.Context, tokenHash string) (*models.PasswordReset, error) {
+	query := `SELECT id, user_id, token_hash, expires_at, used_at, created_at FROM password_resets WHERE token_hash = $1`
+	var pr models.PasswordReset
+	if err := m.DB.QueryRowContext(ctx, query, tokenHash).Scan(
+		&pr.ID,
+		&pr.UserID,
+		&pr.TokenHash,
+		&pr.ExpiresAt,
+		&pr.UsedAt,
+		&pr.CreatedAt,
+	); err != nil {
+		return nil, err
+	}
+
+	return &pr, nil
+}
+
+// UpdatePassword stores the new hash and bumps the session version so that every
+// token issued before the change stops being accepted. When resetID is set the
+// reset token is consumed in the same transaction.
+func (m *UserDbRepo) UpdatePassword(ctx context.Context, userID uuid.UUID, passwordHash string, resetID *uuid.UUID) (int, error) {
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	if resetID != nil {
+		query := `UPDATE password_resets SET used_at = $1 WHERE id = $2 AND used_at IS NULL`
+		res, err := tx.ExecContext(ctx, query, now, *resetID)
+		if err != nil {
+			return 0, fmt.Errorf("failed to consume password reset: %w", err)
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			return 0, sql.ErrNoRows
+		}
+	}
+
+	query := `UPDATE users SET password = $1, password_changed_at = $2, updated_at = $2, session_version = session_version + 1
+			WHERE id = $3 RETURNING session_version`
+	var sessionVersion int
+	if err := tx.QueryRowContext(ctx, query, passwordHash, now, userID).Scan(&sessionVersion); err != nil {
+		return 0, fmt.Errorf("failed to update password: %w", err)
+	}
+
+	return sessionVersion, tx.Commit()
+}
+
+// SaveMfaEnrolment stores a pending secret and replaces the recovery codes. The
+// secret only becomes active once EnableMfa is called.
+func (m *UserDbRepo) SaveMfaEnrolment(ctx context.Context, userID uuid.UUID, encryptedSecret string, recoveryCodeHashes []string) error {
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `UPDATE users SET mfa_secret = $1, mfa_enabled_at = NULL, mfa_last_step = 0, updated_at = $2 WHERE id = $3`
+	if _, err := tx.ExecContext(ctx, query, encryptedSecret, time.Now(), userID); err != nil {
+		return fmt.Errorf("failed to save mfa secret: %w", err)
+	}
+
+	query = `DELETE FROM mfa_recovery_codes WHERE user_id = $1`
+	if _, err := tx.ExecContext(ctx, query, userID); err != nil {
+		return fmt.Errorf("failed to remove recovery codes: %w", err)
+	}
+
+	query = `INSERT INTO mfa_recovery_codes (id, user_id, code_hash, created_at) VALUES ($1, $2, $3, $4)`
+	for _, hash := range recoveryCodeHashes {
+		if _, err := tx.ExecContext(ctx, query, uuid.New(), userID, hash, time.Now()); err != nil {
+			return fmt.Errorf("failed to save recovery code: %w", err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (m *UserDbRepo) EnableMfa(ctx context.Context, userID uuid.UUID, step int64) error {
+	query := `UPDATE users SET mfa_enabled_at = $1, mfa_last_step = $2, updated_at = $1 WHERE id = $3`
+	if _, err := m.DB.ExecContext(ctx, query, time.Now(), step, userID); err != nil {
+		return fmt.Errorf("failed to enable mfa: %w", err)
+	}
+	return nil
+}
+
+func (m *UserDbRepo) DisableMfa(ctx context.Context, userID uuid.UUID) error {
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `UPDATE users SET mfa_secret = NULL, mfa_enabled_at = NULL, mfa_last_step = 0, updated_at = $1 WHERE id = $2`
+	if _, err := tx.ExecContext(ctx, query, time.Now(), userID); err != nil {
+		return fmt.Errorf("failed to disable mfa: %w", err)
+	}
+
+	query = `DELETE FROM mfa_recovery_codes WHERE user_id = $1`
+	if _, err := tx.ExecContext(ctx, query, userID); err != nil {
+		return fmt.Errorf("failed to remove recovery codes: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+// UpdateMfaLastStep only moves forward, so a code that was already used cannot
+// be replayed by a concurrent request.
+func (m *UserDbRepo) UpdateMfaLastStep(ctx context.Context, userID uuid.UUID, step int64) (bool, error) {
+	query := `UPDATE users SET mfa_last_step = $1 WHERE id = $2 AND mfa_last_step < $1`
+	res, err := m.DB.ExecContext(ctx, query, step, userID)
+	if err != nil {
+		return false, fmt.Errorf("failed to record mfa step: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n == 1, nil
+}
+
+func (m *UserDbRepo) UseRecoveryCode(ctx context.Context, userID uuid.UUID, codeHash string) (bool, error) {
+	query := `UPDATE mfa_recovery_codes SET used_at = $1 WHERE user_id = $2 AND code_hash = $3 AND used_at IS NULL`
+	res, err := m.DB.ExecContext(ctx, query, time.Now(), userID, codeHash)
+	if err != nil {
+		return false, fmt.Errorf("failed to use recovery code: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+// SetTransactionPin stores a new pin hash and clears any failed attempts.
+func (m *UserDbRepo) SetTransactionPin(ctx context.Context, userID uuid.UUID, pinHash string) error {
+	query := `UPDATE users SET transaction_pin = $1, pin_failed_attempts = 0, pin_locked_until = NULL, updated_at = $2 WHERE id = $3`
+	if _, err := m.DB.ExecContext(ctx, query, pinHash, time.Now(), userID); err != nil {
+		return fmt.Errorf("failed to set transaction pin: %w", err)
+	}
+	return nil
+}
+
+// ClaimPinAttempt counts a pin attempt before the pin is compared, so
+// concurrent guesses cannot all slip in under the limit. The attempt that
+// reaches maxAttempts locks the pin until lockUntil and resets the counter for
+// the next window; ResetPinFailures undoes both when the pin turns out right.
+// It returns sql.ErrNoRows while the pin is locked, and otherwise reports
+// whether this attempt applied the lock.
+func (m *UserDbRepo) ClaimPinAttempt(ctx context.Context, userID uuid.UUID, maxAttempts int, lockUntil time.Time) (bool, error) {
+	query := `UPDATE users SET
+				pin_failed_attempts = CASE WHEN pin_failed_attempts + 1 >= $1 THEN 0 ELSE pin_failed_attempts + 1 END,
+				pin_locked_until = CASE WHEN pin_failed_attempts + 1 >= $1 THEN $2 ELSE NULL END
+			WHERE id = $3 AND (pin_locked_until IS NULL OR pin_locked_until <= $4)
+			RETURNING pin_failed_attempts`
+	var attempts int
+	if err := m.DB.QueryRowContext(ctx, query, maxAttempts, lockUntil, userID, time.Now()).Scan(&attempts); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, err
+		}
+		return false, fmt.Errorf("failed to claim pin attempt: %w", err)
+	}
+	// the counter only drops back to zero when the lock was applied
+	return attempts == 0, nil
+}
+
+// ResetPinFailures clears the counter and any lock after a correct pin.
+func (m *UserDbRepo) ResetPinFailures(ctx context.Context, userID uuid.UUID) error {
+	query := `UPDATE users SET pin_failed_attempts = 0, pin_locked_until = NULL WHERE id = $1`
+	if _, err := m.DB.ExecContext(ctx, query, userID); err != nil {
+		return fmt.Errorf("failed to reset pin failures: %w", err)
+	}
+	return nil
+}
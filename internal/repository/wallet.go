@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/toluhikay/fx-exchange/internal/limits"
 	"github.com/toluhikay/fx-exchange/internal/models"
 )
 
@@ -17,9 +18,75 @@ type RepositoryImpl interface {
 	Deposit(ctx context.Context, walletID, currency string, amount float64) error
 	Swap(ctx context.Context, walletID, fromCurrency, toCurrency string, amount, rate, convertedAmount float64) error
 	Transfer(ctx context.Context, senderID, receiverID, fromCurrency, toCurrency string, amount, rate, convertedAmount float64) error
+	Withdraw(ctx context.Context, walletID, currency, destination string, amount float64) error
 	GetTransactionHistory(ctx context.Context, walletID string) ([]models.Transaction, error)
 	GetWalletByUserID(ctx context.Context, id uuid.UUID) (*models.Wallet, error)
 	GetBalancesWithUSD(ctx context.Context, walletID string) (*models.BalanceResponse, error)
+	ChangeWalletStatus(ctx context.Context, event models.WalletStatusEvent, payoutDestination string, payout map[string]float64) (*models.WalletStatusEvent, error)
+	GetWalletStatusEvents(ctx context.Context, walletID string) ([]models.WalletStatusEvent, error)
+	GetWalletKycLevel(ctx context.Context, walletID string) (string, error)
+	GetCurrencyUsage(ctx context.Context, walletID, currency string, dayStart, monthStart time.Time) (limits.Usage, error)
+	GetSpendingControls(ctx context.Context, walletID string) (*models.SpendingControls, error)
+	SetSpendingControls(ctx context.Context, walletID string, controls models.SpendingControls) error
+	GetOutgoingByCurrency(ctx context.Context, walletID string, since time.Time) (map[string]float64, error)
+	IncomingSince(ctx context.Context, walletID, currency string, since time.Time) (float64, error)
+	CountAtLeast(ctx context.Context, walletID, currency string, minAmount float64, since time.Time) (int, error)
+	RecipientsSince(ctx context.Context, walletID string, since time.Time) ([]string, error)
+	DeviceFirstSeen(ctx context.Context, userID, device string) (*time.Time, error)
+	TouchDevice(ctx context.Context, userID, device string) error
+	CreateComplianceCase(ctx context.Context, c models.ComplianceCase) (*models.ComplianceCase, error)
+	GetComplianceCase(ctx context.Context, id uuid.UUID) (*models.ComplianceCase, error)
+	ListComplianceCases(ctx context.Context, status string, limit, offset int) ([]models.ComplianceCase, error)
+	ResolveComplianceCase(ctx context.Context, id, reviewerID uuid.UUID, status, note string) error
+	ReopenComplianceCase(ctx context.Context, id uuid.UUID) error
+	GetWalletHolder(ctx context.Context, walletID string) (uuid.UUID, string, error)
+	CreateUserForReview(ctx context.Context, u models.User, c models.ComplianceCase) (*models.User, error)
+	ReleaseScreeningHold(ctx context.Context, userID uuid.UUID) error
+	ListSanctionsClearances(ctx context.Context, userID uuid.UUID) ([]string, error)
+	ClearSanctionsHits(ctx context.Context, caseID uuid.UUID, hits []models.RuleHit) error
+	GetApprovalPolicy(ctx context.Context, walletID string) (*models.ApprovalPolicy, error)
+	SetApprovalPolicy(ctx context.Context, walletID string, policy models.ApprovalPolicy) error
+	CreateTransferApproval(ctx context.Context, a models.TransferApproval) (*models.TransferApproval, error)
+	GetTransferApproval(ctx context.Context, id uuid.UUID) (*models.TransferApproval, error)
+	ListApprovalsForApprover(ctx context.Context, approverID uuid.UUID, status string, limit, offset int) ([]models.TransferApproval, error)
+	ListWalletApprovals(ctx context.Context, walletID string, limit, offset int) ([]models.TransferApproval, error)
+	CompleteTransferApproval(ctx context.Context, id, approverID uuid.UUID, toCurrency string, rate, convertedAmount float64) error
+	ReleaseTransferApproval(ctx context.Context, id uuid.UUID, status string, approverID *uuid.UUID, note *string) error
+	ListExpiredApprovals(ctx context.Context, now time.Time, limit int) ([]uuid.UUID, error)
+	PlaceHold(ctx context.Context, h models.BalanceHold) (*models.BalanceHold, error)
+	GetHold(ctx context.Context, id uuid.UUID) (*models.BalanceHold, error)
+	ListHolds(ctx context.Context, walletID, status string, limit, offset int) ([]models.BalanceHold, error)
+	CaptureHold(ctx context.Context, id uuid.UUID, walletID string, amount float64, destination string) (*models.BalanceHold, error)
+	ReleaseHold(ctx context.Context, id uuid.UUID) error
+	ExpireHolds(ctx context.Context, now time.Time) (int64, error)
+	GetTransaction(ctx context.Context, id string) (*models.Transaction, error)
+	ListReversals(ctx context.Context, id string) ([]models.Transaction, error)
+	ReverseTransaction(ctx context.Context, id string, amount float64, reason string, force bool) (*models.Transaction, error)
+	CreateScheduledTransfer(ctx context.Context, st models.ScheduledTransfer) (*models.ScheduledTransfer, error)
+	GetScheduledTransfer(ctx context.Context, id uuid.UUID) (*models.ScheduledTransfer, error)
+	ListScheduledTransfers(ctx context.Context, walletID string, limit, offset int) ([]models.ScheduledTransfer, error)
+	ListDueScheduledTransfers(ctx context.Context, now time.Time, limit int) ([]models.ScheduledTransfer, error)
+	ClaimScheduledTransfer(ctx context.Context, id uuid.UUID, due, leaseUntil time.Time) (bool, error)
+	RecordScheduledRun(ctx context.Context, run models.ScheduledTransferRun, next *time.Time, retryCount int) error
+	SkipScheduledRun(ctx context.Context, run models.ScheduledTransferRun, next *time.Time) error
+	SetScheduledTransferStatus(ctx context.Context, id uuid.UUID, from []string, status string, next *time.Time) error
+	ListScheduledTransferRuns(ctx context.Context, scheduleID uuid.UUID, limit, offset int) ([]models.ScheduledTransferRun, error)
+	CreateSwapPlan(ctx context.Context, p models.SwapPlan) (*models.SwapPlan, error)
+	GetSwapPlan(ctx context.Context, id uuid.UUID) (*models.SwapPlan, error)
+	ListSwapPlans(ctx context.Context, walletID string, limit, offset int) ([]models.SwapPlan, error)
+	ListDueSwapPlans(ctx context.Context, now time.Time, limit int) ([]models.SwapPlan, error)
+	ClaimSwapPlan(ctx context.Context, id uuid.UUID, due, leaseUntil time.Time) (bool, error)
+	RecordSwapPlanRun(ctx context.Context, run models.SwapPlanRun, next *time.Time) error
+	SetSwapPlanStatus(ctx context.Context, id uuid.UUID, from []string, status string, next *time.Time) error
+	ListSwapPlanRuns(ctx context.Context, planID uuid.UUID, limit, offset int) ([]models.SwapPlanRun, error)
+	GetSwapPlanSummary(ctx context.Context, planID uuid.UUID) (*models.SwapPlanSummary, error)
+	CreateSwapOrder(ctx context.Context, o models.SwapOrder, holdExpiry time.Time) (*models.SwapOrder, error)
+	GetSwapOrder(ctx context.Context, id uuid.UUID) (*models.SwapOrder, error)
+	ListSwapOrders(ctx context.Context, walletID, status string, limit, offset int) ([]models.SwapOrder, error)
+	ListTriggeredOrders(ctx context.Context, fromCurrency, toCurrency string, rate float64, now time.Time, limit int) ([]models.SwapOrder, error)
+	FillSwapOrder(ctx context.Context, id uuid.UUID, rate float64) (*models.SwapOrder, error)
+	CloseSwapOrder(ctx context.Context, id uuid.UUID, status string, reason *string) error
+	ListExpiredOrders(ctx context.Context, now time.Time, limit int) ([]uuid.UUID, error)
 }
 
 type Repository struct {
@@ -49,15 +116,16 @@ func (r *Repository) CreateWallet(ctx context.Context, email string, user_id uui
 		ID:            walletID,
 		EmailOrMobile: email,
 		Balances:      balances,
+		Status:        models.WalletActive,
 		CreatedAt:     createdAt,
 	}, nil
 }
 
 func (r *Repository) GetWallet(ctx context.Context, Id string) (*models.Wallet, error) {
-	query := `SELECT id, email, user_id, balances, created_at FROM wallets WHERE id = $1`
+	query := `SELECT id, email, user_id, balances, status, created_at FROM wallets WHERE id = $1`
 	var balancesJSON []byte
 	var wallet models.Wallet
-	err := r.db.QueryRowContext(ctx, query, Id).Scan(&wallet.ID, &wallet.EmailOrMobile, &wallet.UserId, &balancesJSON, &wallet.CreatedAt)
+	err := r.db.QueryRowContext(ctx, query, Id).Scan(&wallet.ID, &wallet.EmailOrMobile, &wallet.UserId, &balancesJSON, &wallet.Status, &wallet.CreatedAt)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get wallet: %w", err)
 	}
@@ -68,11 +136,11 @@ func (r *Repository) GetWallet(ctx context.Context, Id string) (*models.Wallet,
 }
 
 func (r *Repository) GetWalletByUserID(ctx context.Context, id uuid.UUID) (*models.Wallet, error) {
-	query := `SELECT id, email, balances, created_at FROM wallets WHERE user_id = $1`
+	query := `SELECT id, email, balances, status, created_at FROM wallets WHERE user_id = $1`
 	var balancesJSON []byte
 	var wallet models.Wallet
 	fmt.Println(id)
-	err := r.db.QueryRowContext(ctx, query, id).Scan(&wallet.ID, &wallet.EmailOrMobile, &balancesJSON, &wallet.CreatedAt)
+	err := r.db.QueryRowContext(ctx, query, id).Scan(&wallet.ID, &wallet.EmailOrMobile, &balancesJSON, &wallet.Status, &wallet.CreatedAt)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get wallet: %w", err)
 	}
@@ -82,6 +150,63 @@ func (r *Repository) GetWalletByUserID(ctx context.Context, id uuid.UUID) (*mode
 	return &wallet, nil
 }
 
+// SearchWallets matches q against the wallet id, owner id and email. An empty
+// q lists every wallet.
+func (r *Repository) SearchWallets(ctx context.Context, q string, limit, offset int) ([]models.Wallet, error) {
+	query := `SELECT id, email, user_id, balances, status, created_at FROM wallets
+             WHERE $1 = '' OR id::text = $1 OR user_id::text = $1 OR email ILIKE '%' || $1 || '%'
+             ORDER BY created_at DESC LIMIT $2 OFFSET $3`
+	rows, err := r.db.QueryContext(ctx, query, q, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search wallets: %w", err)
+	}
+	defer rows.Close()
+
+	wallets := []models.Wallet{}
+	for rows.Next() {
+		var balancesJSON []byte
+		var wallet models.Wallet
+		if err := rows.Scan(&wallet.ID, &wallet.EmailOrMobile, &wallet.UserId, &balancesJSON, &wallet.Status, &wallet.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan wallet: %w", err)
+		}
+		if err := json.Unmarshal(balancesJSON, &wallet.Balances); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal balances: %w", err)
+		}
+		wallets = append(wallets, wallet)
+	}
+	return wallets, rows.Err()
+}
+
+// GetWalletKycLevel returns the kyc level of the wallet's owner.
+func (r *Repository) GetWalletKycLevel(ctx context.Context, walletID string) (string, error) {
+	query := `SELECT u.kyc_level FROM wallets w JOIN users u ON u.id = w.user_id WHERE w.id = $1`
+	var level string
+	if err := r.db.QueryRowContext(ctx, query, walletID).Scan(&level); err != nil {
+		return "", fmt.Errorf("failed to get kyc level: %w", err)
+	}
+	return level, nil
+}
+
+// GetCurrencyUsage sums what the wallet moved in currency since dayStart and
+// monthStart. Deposits count against the currency received, swaps, trades,
+// transfers and withdrawals against the currency sent.
+func (r *Repository) GetCurrencyUsage(ctx context.Context, walletID, currency string, dayStart, monthStart time.Time) (limits.Usage, error) {
+	return currencyUsage(ctx, r.db, walletID, currency, dayStart, monthStart)
+}
+
+func currencyUsage(ctx context.Context, q queryer, walletID, currency string, dayStart, monthStart time.Time) (limits.Usage, error) {
+	query := `SELECT COALESCE(SUM(amount) FILTER (WHERE timestamp >= $3), 0), COALESCE(SUM(amount), 0)
+             FROM transactions
+             WHERE wallet_id = $1 AND timestamp >= $4 AND (
+                 (type = 'deposit' AND to_currency = $2) OR
+                 (type IN ('swap', 'trade', 'transfer', 'withdrawal') AND from_currency = $2))`
+	var usage limits.Usage
+	if err := q.QueryRowContext(ctx, query, walletID, currency, dayStart, monthStart).Scan(&usage.Daily, &usage.Monthly); err != nil {
+		return limits.Usage{}, fmt.Errorf("failed to get usage: %w", err)
+	}
+	return usage, nil
+}
+
 func (r *Repository) Deposit(ctx context.Context, walletID, currency string, amount float64) error {
 	tx, err := r.db.BeginTx(ctx, nil)
 	if err != nil {
@@ -89,12 +214,19 @@ func (r *Repository) Deposit(ctx context.Context, walletID, currency string, amo
 	}
 	defer tx.Rollback()
 
-	query := `SELECT balances FROM wallets WHERE id = $1 FOR UPDATE`
+	query := `SELECT balances, status FROM wallets WHERE id = $1 FOR UPDATE`
 	var balancesJSON []byte
-	err = tx.QueryRowContext(ctx, query, walletID).Scan(&balancesJSON)
+	var status string
+	err = tx.QueryRowContext(ctx, query, walletID).Scan(&balancesJSON, &status)
 	if err != nil {
 		return fmt.Errorf("failed to lock wallet: %w", err)
 	}
+	if err := checkCredit(status); err != nil {
+		return err
+	}
+	if err := runWalletChecks(ctx, tx, walletID); err != nil {
+		return err
+	}
 
 	var balances map[string]float64
 	if err := json.Unmarshal(balancesJSON, &balances); err != nil {
@@ -137,20 +269,27 @@ func (r *Repository) Swap(ctx context.Context, walletID, fromCurrency, toCurrenc
 	}
 	defer tx.Rollback()
 
-	query := `SELECT balances FROM wallets WHERE id = $1 FOR UPDATE`
+	query := `SELECT balances, status FROM wallets WHERE id = $1 FOR UPDATE`
 	var balancesJSON []byte
-	err = tx.QueryRowContext(ctx, query, walletID).Scan(&balancesJSON)
+	var status string
+	err = tx.QueryRowContext(ctx, query, walletID).Scan(&balancesJSON, &status)
 	if err != nil {
 		return fmt.Errorf("failed to lock wallet: %w", err)
 	}
+	if err := checkDebit(status); err != nil {
+		return err
+	}
+	if err := runWalletChecks(ctx, tx, walletID); err != nil {
+		return err
+	}
 
 	var balances map[string]float64
 	if err := json.Unmarshal(balancesJSON, &balances); err != nil {
 		return fmt.Errorf("failed to unmarshal balances: %w", err)
 	}
 
-	if amount <= 0 || balances[fromCurrency] < amount {
-		return fmt.Errorf("invalid amount or insufficient balance")
+	if err := checkAvailable(ctx, tx, walletID, fromCurrency, balances, amount); err != nil {
+		return err
 	}
 	if _, ok := balances[toCurrency]; !ok {
 		return fmt.Errorf("unsupported currency: %s", toCurrency)
@@ -186,7 +325,7 @@ func (r *Repository) Transfer(ctx context.Context, senderID, receiverID, fromCur
 	}
 	defer tx.Rollback()
 
-	query := `SELECT id, balances FROM wallets WHERE id IN ($1, $2) FOR UPDATE`
+	query := `SELECT id, balances, status FROM wallets WHERE id IN ($1, $2) FOR UPDATE`
 	rows, err := tx.QueryContext(ctx, query, senderID, receiverID)
 	if err != nil {
 		return fmt.Errorf("failed to lock wallets: %w", err)
@@ -196,9 +335,9 @@ func (r *Repository) Transfer(ctx context.Context, senderID, receiverID, fromCur
 	var senderWallet, receiverWallet models.Wallet
 	foundSender, foundReceiver := false, false
 	for rows.Next() {
-		var id string
+		var id, status string
 		var balancesJSON []byte
-		if err := rows.Scan(&id, &balancesJSON); err != nil {
+		if err := rows.Scan(&id, &balancesJSON, &status); err != nil {
 			return fmt.Errorf("failed to scan wallet: %w", err)
 		}
 		var balances map[string]float64
@@ -207,10 +346,10 @@ func (r *Repository) Transfer(ctx context.Context, senderID, receiverID, fromCur
 		}
 		switch id {
 		case senderID:
-			senderWallet = models.Wallet{Balances: balances}
+			senderWallet = models.Wallet{Balances: balances, Status: status}
 			foundSender = true
 		case receiverID:
-			receiverWallet = models.Wallet{Balances: balances}
+			receiverWallet = models.Wallet{Balances: balances, Status: status}
 			foundReceiver = true
 		}
 	}
@@ -220,9 +359,18 @@ func (r *Repository) Transfer(ctx context.Context, senderID, receiverID, fromCur
 	if !foundReceiver {
 		return fmt.Errorf("receiver wallet %s not found", receiverID)
 	}
+	if err := checkDebit(senderWallet.Status); err != nil {
+		return err
+	}
+	if err := runWalletChecks(ctx, tx, senderID); err != nil {
+		return err
+	}
+	if err := checkCredit(receiverWallet.Status); err != nil {
+		return fmt.Errorf("receiver %w", err)
+	}
 
-	if amount <= 0 || senderWallet.Balances[fromCurrency] < amount {
-		return fmt.Errorf("invalid amount or insufficient balance")
+	if err := checkAvailable(ctx, tx, senderID, fromCurrency, senderWallet.Balances, amount); err != nil {
+		return err
 	}
 	if _, ok := senderWallet.Balances[fromCurrency]; !ok {
 		return fmt.Errorf("unsupported sender currency: %s", fromCurrency)
@@ -253,9 +401,9 @@ func (r *Repository) Transfer(ctx context.Context, senderID, receiverID, fromCur
 		return fmt.Errorf("failed to update receiver wallet: %w", err)
 	}
 
-	query = `INSERT INTO transactions (id, wallet_id, type, from_currency, to_currency, amount, converted_amount, rate, timestamp) 
-             VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`
-	_, err = tx.ExecContext(ctx, query, uuid.New().String(), senderID, "transfer", fromCurrency, toCurrency, amount, convertedAmount, rate, time.Now())
+	query = `INSERT INTO transactions (id, wallet_id, type, from_currency, to_currency, amount, converted_amount, rate, receiver_wallet_id, timestamp) 
+             VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`
+	_, err = tx.ExecContext(ctx, query, uuid.New().String(), senderID, "transfer", fromCurrency, toCurrency, amount, convertedAmount, rate, receiverID, time.Now())
 	if err != nil {
 		return fmt.Errorf("failed to log transaction: %w", err)
 	}
@@ -263,9 +411,89 @@ func (r *Repository) Transfer(ctx context.Context, senderID, receiverID, fromCur
 	return tx.Commit()
 }
 
+func (r *Repository) Withdraw(ctx context.Context, walletID, currency, destination string, amount float64) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `SELECT balances, status FROM wallets WHERE id = $1 FOR UPDATE`
+	var balancesJSON []byte
+	var status string
+	err = tx.QueryRowContext(ctx, query, walletID).Scan(&balancesJSON, &status)
+	if err != nil {
+		return fmt.Errorf("failed to lock wallet: %w", err)
+	}
+	if err := checkDebit(status); err != nil {
+		return err
+	}
+	if err := runWalletChecks(ctx, tx, walletID); err != nil {
+		return err
+	}
+
+	var balances map[string]float64
+	if err := json.Unmarshal(balancesJSON, &balances); err != nil {
+		return fmt.Errorf("failed to unmarshal balances: %w", err)
+	}
+
+	if _, ok := balances[currency]; !ok {
+		return fmt.Errorf("unsupported currency: %s", currency)
+	}
+	if err := checkAvailable(ctx, tx, walletID, currency, balances, amount); err != nil {
+		return err
+	}
+
+	balances[currency] -= amount
+	balancesJSON, err = json.Marshal(balances)
+	if err != nil {
+		return fmt.Errorf("failed to marshal balances: %w", err)
+	}
+
+	query = `UPDATE wallets SET balances = $1 WHERE id = $2`
+	_, err = tx.ExecContext(ctx, query, balancesJSON, walletID)
+	if err != nil {
+		return fmt.Errorf("failed to update wallet: %w", err)
+	}
+
+	query = `INSERT INTO transactions (id, wallet_id, type, from_currency, amount, reference, timestamp) 
+             VALUES ($1, $2, $3, $4, $5, $6, $7)`
+	_, err = tx.ExecContext(ctx, query, uuid.New().String(), walletID, "withdrawal", currency, amount, destination, time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to log transaction: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+const transactionColumns = `id, wallet_id, type, from_currency, to_currency, amount, converted_amount, rate, reference,
+             receiver_wallet_id, status, reversed_amount, reversal_of, timestamp`
+
+func scanTransaction(row rowScanner) (*models.Transaction, error) {
+	var t models.Transaction
+	if err := row.Scan(
+		&t.ID,
+		&t.WalletID,
+		&t.Type,
+		&t.FromCurrency,
+		&t.ToCurrency,
+		&t.Amount,
+		&t.ConvertedAmount,
+		&t.Rate,
+		&t.Reference,
+		&t.ReceiverWalletID,
+		&t.Status,
+		&t.ReversedAmount,
+		&t.ReversalOf,
+		&t.Timestamp,
+	); err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
 func (r *Repository) GetTransactionHistory(ctx context.Context, walletID string) ([]models.Transaction, error) {
-	query := `SELECT id, wallet_id, type, from_currency, to_currency, amount, converted_amount, rate, timestamp 
-             FROM transactions WHERE wallet_id = $1 ORDER BY timestamp DESC`
+	query := `SELECT ` + transactionColumns + ` FROM transactions WHERE wallet_id = $1 ORDER BY timestamp DESC`
 	rows, err := r.db.QueryContext(ctx, query, walletID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get transaction history: %w", err)
@@ -274,12 +502,11 @@ func (r *Repository) GetTransactionHistory(ctx context.Context, walletID string)
 
 	var transactions []models.Transaction
 	for rows.Next() {
-		var t models.Transaction
-		err := rows.Scan(&t.ID, &t.WalletID, &t.Type, &t.FromCurrency, &t.ToCurrency, &t.Amount, &t.ConvertedAmount, &t.Rate, &t.Timestamp)
+		t, err := scanTransaction(rows)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan transaction: %w", err)
 		}
-		transactions = append(transactions, t)
+		transactions = append(transactions, *t)
 	}
 	return transactions, nil
 }
@@ -297,11 +524,21 @@ func (r *Repository) GetBalancesWithUSD(ctx context.Context, walletID string) (*
 		return nil, fmt.Errorf("failed to unmarshal balances: %w", err)
 	}
 
+	held, err := r.heldByCurrency(ctx, walletID)
+	if err != nil {
+		return nil, err
+	}
+	available := make(map[string]float64, len(balances))
+	for currency, amount := range balances {
+		available[currency] = amount - held[currency]
+	}
+
 	// Calculate total USD equivalent
-	totalUSD := 0.0
+	totalUSD, availableUSD := 0.0, 0.0
 	for currency, amount := range balances {
 		if currency == "USDx" {
 			totalUSD += amount // USDx is already in USD
+			availableUSD += available[currency]
 			continue
 		}
 		// Get latest rate to USDx
@@ -313,10 +550,13 @@ func (r *Repository) GetBalancesWithUSD(ctx context.Context, walletID string) (*
 			continue
 		}
 		totalUSD += amount * rate
+		availableUSD += available[currency] * rate
 	}
 
 	return &models.BalanceResponse{
-		Balances: balances,
-		TotalUSD: totalUSD,
+		Balances:     balances,
+		Available:    available,
+		TotalUSD:     totalUSD,
+		AvailableUSD: availableUSD,
 	}, nil
 }
@@ -0,0 +1,104 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	customError "github.com/toluhikay/fx-exchange/internal/errors"
+	"github.com/toluhikay/fx-exchange/internal/limits"
+)
+
+// LockedWallet reads a wallet's recent transactions inside the transaction
+// that holds its row lock. Every other balance change of the wallet waits on
+// that lock, so what it returns cannot move before the change commits.
+type LockedWallet struct {
+	ctx context.Context
+	tx  *sql.Tx
+	ID  string
+}
+
+// CurrencyUsage is GetCurrencyUsage under the lock.
+func (w *LockedWallet) CurrencyUsage(currency string, dayStart, monthStart time.Time) (limits.Usage, error) {
+	return currencyUsage(w.ctx, w.tx, w.ID, currency, dayStart, monthStart)
+}
+
+// OutgoingByCurrency is GetOutgoingByCurrency under the lock.
+func (w *LockedWallet) OutgoingByCurrency(since time.Time) (map[string]float64, error) {
+	return outgoingByCurrency(w.ctx, w.tx, w.ID, since)
+}
+
+// CheckHolder refuses a wallet whose owner is frozen or deleted. The user row
+// is share locked so a freeze waits for the change to commit, or the change
+// sees the freeze.
+func (w *LockedWallet) CheckHolder() error {
+	query := `SELECT u.frozen_at IS NOT NULL, u.deleted_at IS NOT NULL
+             FROM wallets w JOIN users u ON u.id = w.user_id
+             WHERE w.id = $1 FOR SHARE OF u`
+	var frozen, deleted bool
+	if err := w.tx.QueryRowContext(w.ctx, query, w.ID).Scan(&frozen, &deleted); err != nil {
+		return fmt.Errorf("failed to check wallet holder: %w", err)
+	}
+	switch {
+	case deleted:
+		return customError.ErrUnauthorized
+	case frozen:
+		return customError.ErrAccountFrozen
+	}
+	return nil
+}
+
+// WalletCheck vets a balance change once the wallet is locked, typically
+// against what the wallet already moved.
+type WalletCheck func(w *LockedWallet) error
+
+type walletChecksKey struct{}
+
+type walletCheck struct {
+	walletID string
+	check    WalletCheck
+}
+
+// WithWalletCheck returns a context under which the next balance change of
+// walletID runs check with the wallet row locked and is refused if it fails.
+// Checks added for the same wallet all run, in the order they were added.
+func WithWalletCheck(ctx context.Context, walletID string, check WalletCheck) context.Context {
+	checks, _ := ctx.Value(walletChecksKey{}).([]walletCheck)
+	checks = append(checks[:len(checks):len(checks)], walletCheck{walletID: walletID, check: check})
+	return context.WithValue(ctx, walletChecksKey{}, checks)
+}
+
+// walletCheckError is a failed WalletCheck, told apart from errors locking the
+// wallet so trades know which order it belongs to.
+type walletCheckError struct {
+	err error
+}
+
+func (e *walletCheckError) Error() string {
+	return e.err.Error()
+}
+
+func (e *walletCheckError) Unwrap() error {
+	return e.err
+}
+
+// runWalletChecks is called right after walletID is locked in tx.
+func runWalletChecks(ctx context.Context, tx *sql.Tx, walletID string) error {
+	checks, _ := ctx.Value(walletChecksKey{}).([]walletCheck)
+	for _, c := range checks {
+		if c.walletID != walletID {
+			continue
+		}
+		if err := c.check(&LockedWallet{ctx: ctx, tx: tx, ID: walletID}); err != nil {
+			return &walletCheckError{err: err}
+		}
+	}
+	return nil
+}
+
+// queryer is what the transaction reads need from *sql.DB and *sql.Tx.
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
@@ -0,0 +1,190 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	customError "github.com/toluhikay/fx-exchange/internal/errors"
+	"github.com/toluhikay/fx-exchange/internal/models"
+)
+
+// checkDebit and checkCredit are called with the wallet row locked so a status
+// change cannot race a balance update.
+func checkDebit(status string) error {
+	if models.WalletCanDebit(status) {
+		return nil
+	}
+	return walletStatusError(status)
+}
+
+func checkCredit(status string) error {
+	if models.WalletCanCredit(status) {
+		return nil
+	}
+	return walletStatusError(status)
+}
+
+func walletStatusError(status string) error {
+	if status == models.WalletClosed {
+		return customError.ErrWalletClosed
+	}
+	return customError.ErrWalletFrozen
+}
+
+// ChangeWalletStatus moves a wallet to event.ToStatus and records the event.
+// A wallet holding funds can only be closed with a payout destination; every
+// remaining balance is then paid out as a withdrawal in the same transaction.
+// Wallets with active holds cannot be closed. payout is the non-zero balances
+// the caller already ran through the withdrawal checks; when it is not nil the
+// close fails with ErrBalanceChanged if the wallet holds anything else. A
+// customer can only close a wallet that is active.
+func (r *Repository) ChangeWalletStatus(ctx context.Context, event models.WalletStatusEvent, payoutDestination string, payout map[string]float64) (*models.WalletStatusEvent, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := changeWalletStatus(ctx, tx, &event, payoutDestination, payout); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return &event, nil
+}
+
+// changeWalletStatus is ChangeWalletStatus inside tx. It fills in the
+// event's id, from status and time.
+func changeWalletStatus(ctx context.Context, tx *sql.Tx, event *models.WalletStatusEvent, payoutDestination string, payout map[string]float64) error {
+	query := `SELECT balances, status FROM wallets WHERE id = $1 FOR UPDATE`
+	var balancesJSON []byte
+	var status string
+	err := tx.QueryRowContext(ctx, query, event.WalletID).Scan(&balancesJSON, &status)
+	if err != nil {
+		return fmt.Errorf("failed to lock wallet: %w", err)
+	}
+	if err := runWalletChecks(ctx, tx, event.WalletID); err != nil {
+		return err
+	}
+
+	if status == models.WalletClosed || status == event.ToStatus {
+		return customError.ErrInvalidStatusChange
+	}
+	// a frozen wallet stays under staff control
+	if event.ReasonCode == models.ReasonCustomerRequest && status != models.WalletActive {
+		return customError.ErrInvalidStatusChange
+	}
+
+	if event.ToStatus == models.WalletClosed {
+		// funds promised to a merchant or a pending approval cannot be paid out
+		var activeHolds int
+		query = `SELECT COUNT(*) FROM balance_holds WHERE wallet_id = $1 AND status = 'active' AND expires_at > $2`
+		if err := tx.QueryRowContext(ctx, query, event.WalletID, time.Now()).Scan(&activeHolds); err != nil {
+			return fmt.Errorf("failed to count holds: %w", err)
+		}
+		if activeHolds > 0 {
+			return customError.ErrActiveHolds
+		}
+
+		var balances map[string]float64
+		if err := json.Unmarshal(balancesJSON, &balances); err != nil {
+			return fmt.Errorf("failed to unmarshal balances: %w", err)
+		}
+
+		currencies := make([]string, 0, len(balances))
+		for currency, amount := range balances {
+			if amount > 0 {
+				currencies = append(currencies, currency)
+			}
+		}
+		if len(currencies) > 0 && payoutDestination == "" {
+			return customError.ErrWalletNotEmpty
+		}
+		if payout != nil {
+			if len(currencies) != len(payout) {
+				return customError.ErrBalanceChanged
+			}
+			for _, currency := range currencies {
+				if balances[currency] != payout[currency] {
+					return customError.ErrBalanceChanged
+				}
+			}
+		}
+		sort.Strings(currencies)
+
+		query = `INSERT INTO transactions (id, wallet_id, type, from_currency, amount, reference, timestamp) 
+             VALUES ($1, $2, $3, $4, $5, $6, $7)`
+		for _, currency := range currencies {
+			_, err = tx.ExecContext(ctx, query, uuid.New().String(), event.WalletID, "withdrawal", currency, balances[currency], payoutDestination, time.Now())
+			if err != nil {
+				return fmt.Errorf("failed to log payout: %w", err)
+			}
+			balances[currency] = 0
+		}
+
+		balancesJSON, err = json.Marshal(balances)
+		if err != nil {
+			return fmt.Errorf("failed to marshal balances: %w", err)
+		}
+
+		query = `UPDATE scheduled_transfers SET status = $1, next_run_at = NULL, updated_at = $2
+             WHERE wallet_id = $3 AND status IN ($4, $5)`
+		_, err = tx.ExecContext(ctx, query, models.ScheduleCancelled, time.Now(), event.WalletID, models.ScheduleActive, models.SchedulePaused)
+		if err != nil {
+			return fmt.Errorf("failed to cancel scheduled transfers: %w", err)
+		}
+
+		query = `UPDATE swap_plans SET status = $1, next_run_at = NULL, updated_at = $2
+             WHERE wallet_id = $3 AND status IN ($4, $5)`
+		_, err = tx.ExecContext(ctx, query, models.ScheduleCancelled, time.Now(), event.WalletID, models.ScheduleActive, models.SchedulePaused)
+		if err != nil {
+			return fmt.Errorf("failed to cancel swap plans: %w", err)
+		}
+	}
+
+	query = `UPDATE wallets SET balances = $1, status = $2 WHERE id = $3`
+	_, err = tx.ExecContext(ctx, query, balancesJSON, event.ToStatus, event.WalletID)
+	if err != nil {
+		return fmt.Errorf("failed to update wallet status: %w", err)
+	}
+
+	event.ID = uuid.New().String()
+	event.FromStatus = status
+	event.CreatedAt = time.Now()
+
+	query = `INSERT INTO wallet_status_events (id, wallet_id, from_status, to_status, reason_code, note, actor_id, created_at)
+             VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`
+	_, err = tx.ExecContext(ctx, query, event.ID, event.WalletID, event.FromStatus, event.ToStatus, event.ReasonCode, event.Note, event.ActorID, event.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to log wallet status change: %w", err)
+	}
+
+	return nil
+}
+
+func (r *Repository) GetWalletStatusEvents(ctx context.Context, walletID string) ([]models.WalletStatusEvent, error) {
+	query := `SELECT id, wallet_id, from_status, to_status, reason_code, note, actor_id, created_at
+             FROM wallet_status_events WHERE wallet_id = $1 ORDER BY created_at DESC`
+	rows, err := r.db.QueryContext(ctx, query, walletID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get wallet status events: %w", err)
+	}
+	defer rows.Close()
+
+	events := []models.WalletStatusEvent{}
+	for rows.Next() {
+		var e models.WalletStatusEvent
+		if err := rows.Scan(&e.ID, &e.WalletID, &e.FromStatus, &e.ToStatus, &e.ReasonCode, &e.Note, &e.ActorID, &e.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan wallet status event: %w", err)
+		}
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
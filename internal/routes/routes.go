@@ -3,33 +3,47 @@ package routes
 import (
 	"context"
 	"database/sql"
+	"fmt"
 	"net/http"
-	"os"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/cors"
+	"github.com/toluhikay/fx-exchange/internal/config"
 	"github.com/toluhikay/fx-exchange/internal/fx"
 	"github.com/toluhikay/fx-exchange/internal/handlers"
+	"github.com/toluhikay/fx-exchange/internal/mailer"
 	fxMiddleware "github.com/toluhikay/fx-exchange/internal/middleware"
+	"github.com/toluhikay/fx-exchange/internal/models"
+	"github.com/toluhikay/fx-exchange/internal/notifications"
+	"github.com/toluhikay/fx-exchange/internal/ratelimit"
 	"github.com/toluhikay/fx-exchange/internal/repository"
+	"github.com/toluhikay/fx-exchange/internal/sanctions"
 	"github.com/toluhikay/fx-exchange/internal/services"
+	"github.com/toluhikay/fx-exchange/internal/storage"
 	"github.com/toluhikay/fx-exchange/pkg/jwt"
 )
 
 type RouteConfig struct {
 	db               *sql.DB
 	ctx              context.Context
+	cfg              config.Config
 	fxProvider       fx.FXProvider
+	mailer           mailer.Mailer
+	screener         *sanctions.Screener
 	customMiddleware fxMiddleware.AuthMiddleware
 	auth             jwt.Auth
 }
 
-func NewRouteConfig(db *sql.DB, ctx context.Context, fxProvider fx.FXProvider, auth jwt.Auth, customMiddleWare fxMiddleware.AuthMiddleware) *RouteConfig {
+func NewRouteConfig(db *sql.DB, ctx context.Context, cfg config.Config, fxProvider fx.FXProvider, mailer mailer.Mailer, screener *sanctions.Screener, auth jwt.Auth, customMiddleWare fxMiddleware.AuthMiddleware) *RouteConfig {
 	return &RouteConfig{
 		db:               db,
 		ctx:              ctx,
+		cfg:              cfg,
 		fxProvider:       fxProvider,
+		mailer:           mailer,
+		screener:         screener,
 		auth:             auth,
 		customMiddleware: customMiddleWare,
 	}
@@ -39,28 +53,63 @@ func (r *RouteConfig) SetUpRoutes() http.Handler {
 
 	repo := repository.NewRepository(r.db)
 	userRepo := repository.NewUserRepo(r.db)
-	if os.Getenv("USE_MOCK_FX") == "true" {
-		r.fxProvider = fx.NewMockFXProvider(true)
-	} else {
-		r.fxProvider = fx.NewFXClient()
-	}
+	auditRepo := repository.NewAuditRepo(r.db)
+
+	svc := services.NewService(repo, r.fxProvider, r.cfg.KycLimits, r.cfg.FraudRules, r.screener, r.cfg.Approvals, r.cfg.Holds, r.cfg.Schedules)
+	notifier := notifications.NewMultiNotifier(notifications.NewMailNotifier(r.mailer), notifications.NewInAppNotifier(repo))
+	userSvc := services.NewUserService(*userRepo, r.mailer, notifier, svc, r.cfg.User)
+	auditSvc := services.NewAuditService(auditRepo)
+	adminSvc := services.NewAdminService(*userRepo, repo, auditRepo, notifier)
+	uploadStore := storage.NewLocalStore(r.cfg.Storage)
+	kycSvc := services.NewKycService(*userRepo, uploadStore, notifier)
+	accountSvc := services.NewAccountService(*userRepo, repo, auditRepo, uploadStore, notifier, svc, r.cfg.User)
+	disputeSvc := services.NewDisputeService(repo, *userRepo, uploadStore, notifier, r.cfg.Disputes)
+	notificationSvc := services.NewNotificationService(repo)
+	marketSvc := services.NewMarketService(repo, svc, r.cfg.Markets)
+	rateSvc := services.NewRateService(repo, r.fxProvider, r.cfg.Rates)
+	alertSvc := services.NewAlertService(repo, *userRepo, r.fxProvider, notifier, r.cfg.Alerts)
+	authMiddleware := r.customMiddleware.WithUserLookup(userSvc)
 
-	svc := services.NewService(repo, r.fxProvider)
-	userSvc := services.NewUserService(*userRepo)
+	handler := handlers.NewHandler(svc, userSvc, auditSvc)
+	wsHandler := handlers.NewWebSocketHandler(r.fxProvider, marketSvc, alertSvc)
+	userHandlers := handlers.NewUserHandler(*userSvc, r.auth, auditSvc)
+	accountHandlers := handlers.NewAccountHandler(accountSvc, auditSvc)
+	kycHandlers := handlers.NewKycHandler(kycSvc, auditSvc)
+	adminHandlers := handlers.NewAdminHandler(svc, userSvc, adminSvc, auditSvc)
+	approvalHandlers := handlers.NewApprovalHandler(svc, auditSvc)
+	disputeHandlers := handlers.NewDisputeHandler(svc, disputeSvc, auditSvc)
+	notificationHandlers := handlers.NewNotificationHandler(notificationSvc)
+	marketHandlers := handlers.NewMarketHandler(handler, marketSvc)
+	alertHandlers := handlers.NewAlertHandler(alertSvc)
+	rateHandlers := handlers.NewRateHandler(rateSvc)
 
-	handler := handlers.NewHandler(svc, userSvc)
-	wsHandler := handlers.NewWebSocketHandler(r.fxProvider)
-	userHandlers := handlers.NewUserHandler(*userSvc, r.auth)
+	// in-memory buckets are per instance; swap the store for a shared one when
+	// running more than one replica
+	limiterStore := ratelimit.NewMemoryStore()
+	go limiterStore.StartCleanup(r.ctx, time.Minute)
+	go accountSvc.StartAnonymiser(r.ctx, r.cfg.User.AnonymiseInterval)
+	go svc.StartApprovalExpiry(r.ctx, r.cfg.Approvals.ExpiryInterval)
+	go svc.StartHoldExpiry(r.ctx, r.cfg.Holds.ExpiryInterval)
+	go svc.StartScheduler(r.ctx, r.cfg.Schedules.Interval)
+	go svc.StartOrderMatcher(r.ctx)
+	go svc.StartOrderExpiry(r.ctx, r.cfg.Orders.ExpiryInterval)
+	go alertSvc.StartRateAlerts(r.ctx)
+	go rateSvc.StartRollups(r.ctx, r.cfg.Rates.RollupInterval)
+	if err := marketSvc.LoadOrderBooks(r.ctx); err != nil {
+		fmt.Println("error loading order books: ", err)
+	}
 
 	mux := chi.NewRouter()
 
 	mux.Use(middleware.Recoverer)
+	mux.Use(fxMiddleware.RealIP(r.cfg.TrustedProxies))
 	mux.Use(middleware.Logger)
 
 	corsMiddleware := cors.New(cors.Options{
 		AllowedOrigins:   []string{"http://localhost:5173", "https://fx-exchange-front.vercel.app"},
 		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS", "PATCH"},
-		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token"},
+		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token", "X-API-Key", "X-Device-ID"},
+		ExposedHeaders:   []string{"RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset", "RateLimit-Policy", "Retry-After"},
 		AllowCredentials: true,
 		MaxAge:           300,
 	})
@@ -68,26 +117,185 @@ func (r *RouteConfig) SetUpRoutes() http.Handler {
 	mux.Use(corsMiddleware.Handler)
 
 	// register handlers here
-	mux.Post("/api/user/register", userHandlers.CreateUser)
-	mux.Post("/api/user/login", userHandlers.UserLogin)
-
 	mux.Route("/api/user", func(mux chi.Router) {
-		mux.Get("/api/user/", userHandlers.GetUserById)
+		mux.Group(func(mux chi.Router) {
+			mux.Use(fxMiddleware.RateLimit(limiterStore, r.cfg.RateLimit.Auth))
+			mux.Post("/register", userHandlers.CreateUser)
+			mux.Post("/login", userHandlers.UserLogin)
+			mux.Post("/login/mfa", userHandlers.MfaLogin)
+			mux.Post("/verify-email", userHandlers.VerifyEmail)
+			mux.Post("/verify-email/resend", userHandlers.ResendVerificationOtp)
+			mux.Post("/password/forgot", userHandlers.ForgotPassword)
+			mux.Post("/password/reset", userHandlers.ResetPassword)
+		})
+
+		mux.Group(func(mux chi.Router) {
+			mux.Use(authMiddleware.AuthRequired)
+			mux.Use(fxMiddleware.RateLimit(limiterStore, r.cfg.RateLimit.API))
+			mux.Get("/", userHandlers.GetUserById)
+			mux.Delete("/", accountHandlers.DeleteAccount)
+			mux.Get("/export", accountHandlers.ExportData)
+			mux.Put("/password", userHandlers.ChangePassword)
+			mux.Post("/mfa/enroll", userHandlers.EnrollMfa)
+			mux.Post("/mfa/activate", userHandlers.ActivateMfa)
+			mux.Post("/mfa/disable", userHandlers.DisableMfa)
+			mux.Post("/pin", userHandlers.SetPin)
+			mux.Put("/pin", userHandlers.ChangePin)
+			mux.Post("/api-keys", userHandlers.CreateAPIKey)
+			mux.Get("/api-keys", userHandlers.ListAPIKeys)
+			mux.Delete("/api-keys/{id}", userHandlers.RevokeAPIKey)
+			mux.Get("/kyc", kycHandlers.GetStatus)
+			mux.Post("/kyc/documents", kycHandlers.SubmitDocument)
+		})
 	})
 
 	mux.Route("/api/wallets", func(mux chi.Router) {
-		mux.Use(r.customMiddleware.AuthRequired)
-		// todo - add rate limiter middleware
-		mux.Post("/", handler.CreateWallet)
-		mux.Get("/", handler.GetWallet)
-		mux.Post("/deposit", handler.Deposit)
-		mux.Get("/balances", handler.GetBalances)
-		mux.Post("/swap", handler.Swap)
-		mux.Post("/transfer", handler.Transfer)
-		mux.Get("/history", handler.GetTransactionHistory)
+		mux.Use(authMiddleware.AuthRequiredOrAPIKey)
+		mux.Use(fxMiddleware.RateLimit(limiterStore, r.cfg.RateLimit.API))
+		mux.Use(authMiddleware.AccountActive)
+		mux.Use(fxMiddleware.DeviceFingerprint)
+
+		// routes api keys may call, limited by scope
+		mux.With(authMiddleware.RequireScope(models.ScopeReadBalances)).Get("/", handler.GetWallet)
+		mux.With(authMiddleware.RequireScope(models.ScopeReadBalances)).Get("/balances", handler.GetBalances)
+		mux.With(authMiddleware.RequireScope(models.ScopeReadHistory)).Get("/history", handler.GetTransactionHistory)
+		mux.With(authMiddleware.RequireScope(models.ScopeTransfer)).Post("/transfer", handler.Transfer)
+		mux.With(authMiddleware.RequireScope(models.ScopeReadBalances)).Get("/holds", handler.ListHolds)
+		mux.With(authMiddleware.RequireScope(models.ScopeHolds)).Post("/holds", handler.PlaceHold)
+		mux.With(authMiddleware.RequireScope(models.ScopeHolds)).Post("/holds/{id}/capture", handler.CaptureHold)
+		mux.With(authMiddleware.RequireScope(models.ScopeHolds)).Post("/holds/{id}/release", handler.ReleaseHold)
+
+		mux.Group(func(mux chi.Router) {
+			mux.Use(authMiddleware.SessionOnly)
+			mux.Post("/", handler.CreateWallet)
+			mux.Post("/deposit", handler.Deposit)
+			mux.Post("/swap", handler.Swap)
+			mux.Post("/withdraw", handler.Withdraw)
+			mux.Post("/close", handler.CloseWallet)
+			mux.Get("/controls", handler.GetSpendingControls)
+			mux.Put("/controls", handler.SetSpendingControls)
+			mux.Get("/approvals", handler.GetApprovals)
+			mux.Post("/scheduled-transfers", handler.ScheduleTransfer)
+			mux.Get("/scheduled-transfers", handler.ListScheduledTransfers)
+			mux.Get("/scheduled-transfers/{id}", handler.GetScheduledTransfer)
+			mux.Get("/scheduled-transfers/{id}/runs", handler.ListScheduledTransferRuns)
+			mux.Post("/scheduled-transfers/{id}/skip", handler.SkipScheduledTransfer)
+			mux.Post("/scheduled-transfers/{id}/pause", handler.PauseScheduledTransfer)
+			mux.Post("/scheduled-transfers/{id}/resume", handler.ResumeScheduledTransfer)
+			mux.Post("/scheduled-transfers/{id}/cancel", handler.CancelScheduledTransfer)
+			mux.Post("/swap-plans", handler.CreateSwapPlan)
+			mux.Get("/swap-plans", handler.ListSwapPlans)
+			mux.Get("/swap-plans/{id}", handler.GetSwapPlan)
+			mux.Get("/swap-plans/{id}/runs", handler.ListSwapPlanRuns)
+			mux.Get("/swap-plans/{id}/summary", handler.GetSwapPlanSummary)
+			mux.Post("/swap-plans/{id}/pause", handler.PauseSwapPlan)
+			mux.Post("/swap-plans/{id}/resume", handler.ResumeSwapPlan)
+			mux.Post("/swap-plans/{id}/cancel", handler.CancelSwapPlan)
+			mux.Post("/orders", handler.PlaceSwapOrder)
+			mux.Get("/orders", handler.ListSwapOrders)
+			mux.Get("/orders/{id}", handler.GetSwapOrder)
+			mux.Post("/orders/{id}/cancel", handler.CancelSwapOrder)
+			mux.Post("/p2p/orders", marketHandlers.PlaceOrder)
+			mux.Get("/p2p/orders", marketHandlers.ListOrders)
+			mux.Get("/p2p/orders/{id}", marketHandlers.GetOrder)
+			mux.Get("/p2p/orders/{id}/trades", marketHandlers.ListOrderTrades)
+			mux.Post("/p2p/orders/{id}/cancel", marketHandlers.CancelOrder)
+			mux.Post("/disputes", disputeHandlers.Open)
+			mux.Get("/disputes", disputeHandlers.ListMine)
+			mux.Get("/disputes/{id}", disputeHandlers.GetMine)
+			mux.Post("/disputes/{id}/evidence", disputeHandlers.AddEvidence)
+		})
+	})
+
+	// approvers act on other people's wallets, so these sit outside /api/wallets
+	mux.Route("/api/approvals", func(mux chi.Router) {
+		mux.Use(authMiddleware.AuthRequired)
+		mux.Use(fxMiddleware.RateLimit(limiterStore, r.cfg.RateLimit.API))
+		mux.Use(authMiddleware.AccountActive)
+
+		mux.Get("/", approvalHandlers.ListPending)
+		mux.Post("/{id}/approve", approvalHandlers.Approve)
+		mux.Post("/{id}/reject", approvalHandlers.Reject)
+	})
+
+	mux.Route("/api/alerts", func(mux chi.Router) {
+		mux.Use(authMiddleware.AuthRequired)
+		mux.Use(fxMiddleware.RateLimit(limiterStore, r.cfg.RateLimit.API))
+		mux.Use(authMiddleware.AccountActive)
+
+		mux.Post("/", alertHandlers.Create)
+		mux.Get("/", alertHandlers.List)
+		mux.Get("/{id}", alertHandlers.Get)
+		mux.Post("/{id}/cancel", alertHandlers.Cancel)
+	})
+
+	mux.Route("/api/fx", func(mux chi.Router) {
+		mux.Use(fxMiddleware.RateLimit(limiterStore, r.cfg.RateLimit.Public))
+
+		mux.Get("/rates", rateHandlers.Current)
+		mux.Get("/rates/history", rateHandlers.History)
+	})
+
+	mux.Route("/api/markets", func(mux chi.Router) {
+		mux.Use(fxMiddleware.RateLimit(limiterStore, r.cfg.RateLimit.Public))
+
+		mux.Get("/", marketHandlers.ListMarkets)
+		mux.Get("/{base}/{quote}/depth", marketHandlers.Depth)
+		mux.Get("/{base}/{quote}/trades", marketHandlers.Trades)
+	})
+
+	mux.Route("/api/notifications", func(mux chi.Router) {
+		mux.Use(authMiddleware.AuthRequired)
+		mux.Use(fxMiddleware.RateLimit(limiterStore, r.cfg.RateLimit.API))
+
+		mux.Get("/", notificationHandlers.List)
+		mux.Post("/read", notificationHandlers.MarkAllRead)
+		mux.Post("/{id}/read", notificationHandlers.MarkRead)
+	})
+
+	mux.Route("/api/admin", func(mux chi.Router) {
+		mux.Use(authMiddleware.AuthRequired)
+		mux.Use(authMiddleware.AccountActive)
+		mux.Use(fxMiddleware.RateLimit(limiterStore, r.cfg.RateLimit.Admin))
+
+		mux.With(fxMiddleware.RequirePermission(models.PermUsersRead)).Get("/users", adminHandlers.SearchUsers)
+		mux.With(fxMiddleware.RequirePermission(models.PermUsersRead)).Get("/users/{id}", adminHandlers.GetUser)
+		mux.With(fxMiddleware.RequirePermission(models.PermAuditRead)).Get("/users/{id}/audit", adminHandlers.UserAuditLogs)
+		mux.With(fxMiddleware.RequirePermission(models.PermUsersUnlock)).Post("/users/{id}/unlock", adminHandlers.UnlockUser)
+		mux.With(fxMiddleware.RequirePermission(models.PermUsersFreeze)).Post("/users/{id}/freeze", adminHandlers.FreezeUser)
+		mux.With(fxMiddleware.RequirePermission(models.PermUsersFreeze)).Post("/users/{id}/unfreeze", adminHandlers.UnfreezeUser)
+		mux.With(fxMiddleware.RequirePermission(models.PermUsersRole)).Put("/users/{id}/role", adminHandlers.SetRole)
+
+		mux.With(fxMiddleware.RequirePermission(models.PermWalletsRead)).Get("/wallets", adminHandlers.SearchWallets)
+		mux.With(fxMiddleware.RequirePermission(models.PermWalletsRead)).Get("/wallets/{id}/history", adminHandlers.WalletHistory)
+		mux.With(fxMiddleware.RequirePermission(models.PermAuditRead)).Get("/wallets/{id}/audit", adminHandlers.WalletAuditLogs)
+		mux.With(fxMiddleware.RequirePermission(models.PermKycReview)).Get("/kyc/documents", kycHandlers.ListDocuments)
+		mux.With(fxMiddleware.RequirePermission(models.PermKycReview)).Get("/kyc/documents/{id}/file", kycHandlers.DownloadDocument)
+		mux.With(fxMiddleware.RequirePermission(models.PermKycReview)).Post("/kyc/documents/{id}/review", kycHandlers.ReviewDocument)
+
+		mux.With(fxMiddleware.RequirePermission(models.PermWalletsRead)).Get("/wallets/{id}/status-events", adminHandlers.WalletStatusEvents)
+		mux.With(fxMiddleware.RequirePermission(models.PermWalletsStatus)).Put("/wallets/{id}/status", adminHandlers.ChangeWalletStatus)
+		mux.With(fxMiddleware.RequirePermission(models.PermWalletsRead)).Get("/wallets/{id}/approvers", adminHandlers.GetApprovalPolicy)
+		mux.With(fxMiddleware.RequirePermission(models.PermWalletsApprovers)).Put("/wallets/{id}/approvers", adminHandlers.SetApprovalPolicy)
+
+		mux.With(fxMiddleware.RequirePermission(models.PermWalletsRead)).Get("/transactions/{id}", adminHandlers.GetTransaction)
+		mux.With(fxMiddleware.RequirePermission(models.PermTxReverse)).Post("/transactions/{id}/reverse", adminHandlers.ReverseTransaction)
+
+		mux.With(fxMiddleware.RequirePermission(models.PermDisputesReview)).Get("/disputes", disputeHandlers.List)
+		mux.With(fxMiddleware.RequirePermission(models.PermDisputesReview)).Get("/disputes/{id}", disputeHandlers.Get)
+		mux.With(fxMiddleware.RequirePermission(models.PermDisputesReview)).Get("/disputes/{id}/evidence/{evidenceID}/file", disputeHandlers.DownloadEvidence)
+		mux.With(fxMiddleware.RequirePermission(models.PermDisputesReview)).Post("/disputes/{id}/review", disputeHandlers.StartReview)
+		mux.With(fxMiddleware.RequirePermission(models.PermDisputesReview)).Post("/disputes/{id}/resolve", disputeHandlers.Resolve)
+
+		mux.With(fxMiddleware.RequirePermission(models.PermCasesReview)).Get("/cases", adminHandlers.ListCases)
+		mux.With(fxMiddleware.RequirePermission(models.PermCasesReview)).Get("/cases/{id}", adminHandlers.GetCase)
+		mux.With(fxMiddleware.RequirePermission(models.PermCasesReview)).Post("/cases/{id}/approve", adminHandlers.ApproveCase)
+		mux.With(fxMiddleware.RequirePermission(models.PermCasesReview)).Post("/cases/{id}/reject", adminHandlers.RejectCase)
 	})
 
-	mux.Get("/ws/fx-rates", wsHandler.HandleFXRates)
+	mux.With(fxMiddleware.RateLimit(limiterStore, r.cfg.RateLimit.Public)).Get("/ws/fx-rates", wsHandler.HandleFXRates)
+	mux.With(fxMiddleware.RateLimit(limiterStore, r.cfg.RateLimit.Public)).Get("/ws/order-book", wsHandler.HandleOrderBook)
+	mux.With(authMiddleware.WebSocketAuth, fxMiddleware.RateLimit(limiterStore, r.cfg.RateLimit.API)).Get("/ws/alerts", wsHandler.HandleRateAlerts)
 
 	return mux
 }
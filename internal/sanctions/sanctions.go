@@ -0,0 +1,255 @@
+package sanctions
+
+import (
+	"encoding/csv"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// Config points at the local copy of the sanctions list. Threshold is the
+// lowest similarity, between 0 and 1, that counts as a match.
+type Config struct {
+	File      string
+	Threshold float64
+}
+
+// Entry is one listed person or organisation.
+type Entry struct {
+	ID      string   `json:"id"`
+	Name    string   `json:"name"`
+	Aliases []string `json:"aliases,omitempty"`
+	Program string   `json:"program,omitempty"`
+}
+
+type Match struct {
+	EntryID    string  `json:"entry_id"`
+	ListedName string  `json:"listed_name"`
+	Program    string  `json:"program,omitempty"`
+	Score      float64 `json:"score"`
+}
+
+type Screener struct {
+	entries   []Entry
+	threshold float64
+}
+
+// NewScreener loads the list named in cfg. Without a file every name passes.
+// A configured list that cannot be loaded, or holds no entries, is an error
+// rather than a reason to let every name through.
+func NewScreener(cfg Config) (*Screener, error) {
+	s := &Screener{threshold: cfg.Threshold}
+	if cfg.File == "" {
+		fmt.Println("no sanctions list configured, screening is disabled")
+		return s, nil
+	}
+
+	entries, err := LoadFile(cfg.File)
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("sanctions list %s has no entries", cfg.File)
+	}
+	s.entries = entries
+	return s, nil
+}
+
+// Screen returns the best match per listed entry whose name or alias is at
+// least as similar to name as the threshold.
+func (s *Screener) Screen(name string) []Match {
+	candidate := normalise(name)
+	if candidate == "" {
+		return nil
+	}
+
+	var matches []Match
+	for _, entry := range s.entries {
+		best := Match{EntryID: entry.ID, Program: entry.Program}
+		for _, listed := range append([]string{entry.Name}, entry.Aliases...) {
+			if score := similarity(candidate, normalise(listed)); score > best.Score {
+				best.Score = score
+				best.ListedName = listed
+			}
+		}
+		if best.Score >= s.threshold {
+			matches = append(matches, best)
+		}
+	}
+
+	slices.SortFunc(matches, func(a, b Match) int {
+		switch {
+		case a.Score > b.Score:
+			return -1
+		case a.Score < b.Score:
+			return 1
+		}
+		return 0
+	})
+	return matches
+}
+
+// LoadFile reads a csv or xml list, chosen by the file extension.
+//
+// CSV files have a header row of id,name,aliases,program with aliases
+// separated by semicolons. XML files look like
+// <sanctions><entry id="..."><name>...</name><alias>...</alias><program>...</program></entry></sanctions>.
+func LoadFile(path string) ([]Entry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open sanctions list: %w", err)
+	}
+	defer f.Close()
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv":
+		return readCSV(f)
+	case ".xml":
+		return readXML(f)
+	}
+	return nil, fmt.Errorf("unsupported sanctions list format %q", filepath.Ext(path))
+}
+
+func readCSV(r io.Reader) ([]Entry, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read sanctions list header: %w", err)
+	}
+	columns := map[string]int{}
+	for i, column := range header {
+		columns[strings.ToLower(strings.TrimSpace(column))] = i
+	}
+	if _, ok := columns["name"]; !ok {
+		return nil, errors.New("sanctions list has no name column")
+	}
+	field := func(record []string, column string) string {
+		i, ok := columns[column]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	var entries []Entry
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read sanctions list: %w", err)
+		}
+
+		entry := Entry{ID: field(record, "id"), Name: field(record, "name"), Program: field(record, "program")}
+		for _, alias := range strings.Split(field(record, "aliases"), ";") {
+			if alias = strings.TrimSpace(alias); alias != "" {
+				entry.Aliases = append(entry.Aliases, alias)
+			}
+		}
+		if entry.Name != "" {
+			entries = append(entries, entry)
+		}
+	}
+	return entries, nil
+}
+
+func readXML(r io.Reader) ([]Entry, error) {
+	var doc struct {
+		Entries []struct {
+			ID      string   `xml:"id,attr"`
+			Name    string   `xml:"name"`
+			Aliases []string `xml:"alias"`
+			Program string   `xml:"program"`
+		} `xml:"entry"`
+	}
+	if err := xml.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("failed to parse sanctions list: %w", err)
+	}
+
+	var entries []Entry
+	for _, e := range doc.Entries {
+		entry := Entry{ID: strings.TrimSpace(e.ID), Name: strings.TrimSpace(e.Name), Program: strings.TrimSpace(e.Program)}
+		for _, alias := range e.Aliases {
+			if alias = strings.TrimSpace(alias); alias != "" {
+				entry.Aliases = append(entry.Aliases, alias)
+			}
+		}
+		if entry.Name != "" {
+			entries = append(entries, entry)
+		}
+	}
+	return entries, nil
+}
+
+// normalise lowercases a name, drops punctuation and sorts the words so
+// "Doe, John" and "john doe" compare equal.
+func normalise(name string) string {
+	words := strings.FieldsFunc(strings.ToLower(name), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	slices.Sort(words)
+	return strings.Join(words, " ")
+}
+
+// similarity is the Jaro-Winkler similarity of two strings, 1 for identical.
+func similarity(a, b string) float64 {
+	if a == b {
+		return 1
+	}
+	s1, s2 := []rune(a), []rune(b)
+	if len(s1) == 0 || len(s2) == 0 {
+		return 0
+	}
+
+	window := max(len(s1), len(s2))/2 - 1
+	window = max(window, 0)
+	matched1 := make([]bool, len(s1))
+	matched2 := make([]bool, len(s2))
+
+	matches := 0
+	for i := range s1 {
+		for j := max(0, i-window); j < min(len(s2), i+window+1); j++ {
+			if matched2[j] || s1[i] != s2[j] {
+				continue
+			}
+			matched1[i], matched2[j] = true, true
+			matches++
+			break
+		}
+	}
+	if matches == 0 {
+		return 0
+	}
+
+	transpositions, k := 0, 0
+	for i := range s1 {
+		if !matched1[i] {
+			continue
+		}
+		for !matched2[k] {
+			k++
+		}
+		if s1[i] != s2[k] {
+			transpositions++
+		}
+		k++
+	}
+
+	m := float64(matches)
+	jaro := (m/float64(len(s1)) + m/float64(len(s2)) + (m-float64(transpositions)/2)/m) / 3
+
+	prefix := 0
+	for prefix < min(4, len(s1), len(s2)) && s1[prefix] == s2[prefix] {
+		prefix++
+	}
+	return jaro + float64(prefix)*0.1*(1-jaro)
+}
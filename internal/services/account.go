@@ -0,0 +1,228 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/toluhikay/fx-exchange/internal/config"
+	"github.com/toluhikay/fx-exchange/internal/dtos"
+	customError "github.com/toluhikay/fx-exchange/internal/errors"
+	"github.com/toluhikay/fx-exchange/internal/models"
+	"github.com/toluhikay/fx-exchange/internal/notifications"
+	"github.com/toluhikay/fx-exchange/internal/repository"
+	"github.com/toluhikay/fx-exchange/internal/storage"
+)
+
+// anonymiseBatchSize caps how many accounts one anonymiser pass scrubs.
+const anonymiseBatchSize = 100
+
+// AccountService handles closing an account and exporting its data. It spans
+// users, wallets and audit logs, so it sits beside the user and wallet
+// services rather than inside either.
+type AccountService struct {
+	userRepo  repository.UserDbRepo
+	repo      *repository.Repository
+	auditRepo *repository.AuditRepo
+	store     storage.Store
+	notifier  notifications.Notifier
+	// wallets runs the payout of a closing wallet through the withdrawal
+	// checks.
+	wallets  *Service
+	settings config.UserSettings
+}
+
+func NewAccountService(ur repository.UserDbRepo, repo *repository.Repository, auditRepo *repository.AuditRepo, store storage.Store, notifier notifications.Notifier, wallets *Service, settings config.UserSettings) *AccountService {
+	return &AccountService{
+		userRepo:  ur,
+		repo:      repo,
+		auditRepo: auditRepo,
+		store:     store,
+		notifier:  notifier,
+		wallets:   wallets,
+		settings:  settings,
+	}
+}
+
+// DeleteAccount closes the user's wallet, paying out any balance to
+// payoutDestination, and soft-deletes the account in one transaction. Frozen
+// accounts and accounts under compliance review cannot be deleted. Personal
+// data is kept for the retention period and anonymised afterwards.
+func (as *AccountService) DeleteAccount(ctx context.Context, userID uuid.UUID, req dtos.DeleteAccount) error {
+	user, err := as.userRepo.GetUserById(ctx, userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return customError.ErrUnauthorized
+		}
+		return customError.ErrInternalServer
+	}
+	if user.IsDeleted() {
+		return customError.ErrUnauthorized
+	}
+	if user.IsFrozen() {
+		return customError.ErrAccountFrozen
+	}
+
+	ok, err := user.CompareHashedPassword(req.Password)
+	if err != nil || !ok {
+		return customError.ErrInvalidCredentials
+	}
+
+	wallet, err := as.repo.GetWalletByUserID(ctx, userID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		fmt.Println(err)
+		return customError.ErrInternalServer
+	}
+	var event *models.WalletStatusEvent
+	var payout map[string]float64
+	destination := strings.TrimSpace(req.PayoutDestination)
+	if wallet != nil && wallet.Status != models.WalletClosed {
+		payout, err = as.wallets.checkClosingPayout(ctx, wallet.ID, destination)
+		if err != nil {
+			return err
+		}
+		event = &models.WalletStatusEvent{
+			WalletID:   wallet.ID,
+			ToStatus:   models.WalletClosed,
+			ReasonCode: models.ReasonCustomerRequest,
+			ActorID:    &userID,
+		}
+		ctx = as.wallets.payoutUnderLimits(ctx, wallet.ID, destination, payout)
+	}
+
+	if err := as.repo.CloseAccount(ctx, userID, event, destination, payout); err != nil {
+		return err
+	}
+
+	notification := notifications.Notification{
+		UserID:  user.ID,
+		Email:   user.Email,
+		Subject: "Your account has been closed",
+		Body: fmt.Sprintf("Your account has been closed. Your personal data will be anonymised after %s; transaction records are kept as required by law.",
+			as.settings.DeletionRetention),
+	}
+	if err := as.notifier.Notify(ctx, notification); err != nil {
+		fmt.Println("error sending account closure notification: ", err)
+	}
+
+	return nil
+}
+
+// ExportData collects everything stored about the user.
+func (as *AccountService) ExportData(ctx context.Context, userID uuid.UUID) (*dtos.AccountExport, error) {
+	user, err := as.userRepo.GetUserById(ctx, userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, customError.ErrUnauthorized
+		}
+		return nil, customError.ErrInternalServer
+	}
+
+	export := &dtos.AccountExport{
+		ExportedAt:         time.Now().UTC(),
+		Profile:            user,
+		Transactions:       []models.Transaction{},
+		WalletStatusEvents: []models.WalletStatusEvent{},
+	}
+
+	wallet, err := as.repo.GetWalletByUserID(ctx, userID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		fmt.Println(err)
+		return nil, customError.ErrInternalServer
+	}
+
+	var walletID *string
+	if wallet != nil {
+		wallet.UserId = userID.String()
+		export.Wallet = wallet
+		walletID = &wallet.ID
+
+		transactions, err := as.repo.GetTransactionHistory(ctx, wallet.ID)
+		if err != nil {
+			fmt.Println(err)
+			return nil, customError.ErrInternalServer
+		}
+		if transactions != nil {
+			export.Transactions = transactions
+		}
+
+		export.WalletStatusEvents, err = as.repo.GetWalletStatusEvents(ctx, wallet.ID)
+		if err != nil {
+			fmt.Println(err)
+			return nil, customError.ErrInternalServer
+		}
+	}
+
+	export.AuditLogs, err = as.auditRepo.ExportAuditLogs(ctx, userID, walletID)
+	if err != nil {
+		fmt.Println(err)
+		return nil, customError.ErrInternalServer
+	}
+
+	export.APIKeys, err = as.userRepo.ListAPIKeys(ctx, userID)
+	if err != nil {
+		fmt.Println(err)
+		return nil, customError.ErrInternalServer
+	}
+
+	export.KycDocuments, err = as.userRepo.ListKycDocuments(ctx, userID)
+	if err != nil {
+		fmt.Println(err)
+		return nil, customError.ErrInternalServer
+	}
+
+	return export, nil
+}
+
+// StartAnonymiser periodically scrubs accounts whose retention period has run
+// out. It stops when ctx is cancelled.
+func (as *AccountService) StartAnonymiser(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			as.anonymiseDueAccounts(ctx)
+		}
+	}
+}
+
+func (as *AccountService) anonymiseDueAccounts(ctx context.Context) {
+	cutoff := time.Now().Add(-as.settings.DeletionRetention)
+	ids, err := as.userRepo.ListUsersDueForAnonymisation(ctx, cutoff, anonymiseBatchSize)
+	if err != nil {
+		fmt.Println("error listing accounts to anonymise: ", err)
+		return
+	}
+
+	for _, id := range ids {
+		user, err := as.userRepo.GetUserById(ctx, id)
+		if err != nil {
+			fmt.Println("error loading account to anonymise: ", err)
+			continue
+		}
+
+		// identity documents go entirely; the rows are dropped by AnonymiseUser
+		docs, err := as.userRepo.ListKycDocuments(ctx, id)
+		if err != nil {
+			fmt.Println("error listing kyc documents to remove: ", err)
+			continue
+		}
+		for _, doc := range docs {
+			if err := as.store.Delete(ctx, doc.FileKey); err != nil {
+				fmt.Println("error removing kyc document: ", err)
+			}
+		}
+
+		if err := as.userRepo.AnonymiseUser(ctx, id, accountThrottleKey(user.Email)); err != nil {
+			fmt.Println("error anonymising account: ", err)
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	customError "github.com/toluhikay/fx-exchange/internal/errors"
+	"github.com/toluhikay/fx-exchange/internal/models"
+	"github.com/toluhikay/fx-exchange/internal/notifications"
+	"github.com/toluhikay/fx-exchange/internal/repository"
+)
+
+// AdminService backs the staff-facing admin api. Route permissions are
+// enforced by middleware; the checks here only guard against operators
+// locking themselves out.
+type AdminService struct {
+	userRepo  repository.UserDbRepo
+	repo      *repository.Repository
+	auditRepo *repository.AuditRepo
+	notifier  notifications.Notifier
+}
+
+func NewAdminService(ur repository.UserDbRepo, repo *repository.Repository, auditRepo *repository.AuditRepo, notifier notifications.Notifier) *AdminService {
+	return &AdminService{
+		userRepo:  ur,
+		repo:      repo,
+		auditRepo: auditRepo,
+		notifier:  notifier,
+	}
+}
+
+func (as *AdminService) SearchUsers(ctx context.Context, q string, limit, offset int) ([]models.User, error) {
+	users, err := as.userRepo.SearchUsers(ctx, strings.TrimSpace(q), limit, offset)
+	if err != nil {
+		fmt.Println(err)
+		return nil, customError.ErrInternalServer
+	}
+	return users, nil
+}
+
+func (as *AdminService) GetUser(ctx context.Context, userID uuid.UUID) (*models.User, error) {
+	user, err := as.userRepo.GetUserById(ctx, userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, customError.ErrRecordNotFound
+		}
+		fmt.Println(err)
+		return nil, customError.ErrInternalServer
+	}
+	return user, nil
+}
+
+func (as *AdminService) SearchWallets(ctx context.Context, q string, limit, offset int) ([]models.Wallet, error) {
+	wallets, err := as.repo.SearchWallets(ctx, strings.TrimSpace(q), limit, offset)
+	if err != nil {
+		fmt.Println(err)
+		return nil, customError.ErrInternalServer
+	}
+	return wallets, nil
+}
+
+func (as *AdminService) WalletHistory(ctx context.Context, walletID uuid.UUID) ([]models.Transaction, error) {
+	if _, err := as.repo.GetWallet(ctx, walletID.String()); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, customError.ErrRecordNotFound
+		}
+		fmt.Println(err)
+		return nil, customError.ErrInternalServer
+	}
+
+	history, err := as.repo.GetTransactionHistory(ctx, walletID.String())
+	if err != nil {
+		fmt.Println(err)
+		return nil, customError.ErrInternalServer
+	}
+	return history, nil
+}
+
+func (as *AdminService) WalletAuditLogs(ctx context.Context, walletID uuid.UUID, limit, offset int) ([]models.AuditLog, error) {
+	id := walletID.String()
+	logs, err := as.auditRepo.ListAuditLogs(ctx, &id, nil, limit, offset)
+	if err != nil {
+		fmt.Println(err)
+		return nil, customError.ErrInternalServer
+	}
+	return logs, nil
+}
+
+func (as *AdminService) UserAuditLogs(ctx context.Context, userID uuid.UUID, limit, offset int) ([]models.AuditLog, error) {
+	logs, err := as.auditRepo.ListAuditLogs(ctx, nil, &userID, limit, offset)
+	if err != nil {
+		fmt.Println(err)
+		return nil, customError.ErrInternalServer
+	}
+	return logs, nil
+}
+
+// FreezeUser blocks every wallet operation except reads until UnfreezeUser is
+// called. The user is told by email, the reason is kept for staff only.
+func (as *AdminService) FreezeUser(ctx context.Context, actorID, userID uuid.UUID, reason string) error {
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return customError.ErrInvalidPayload
+	}
+	if actorID == userID {
+		return customError.ErrForbidden
+	}
+
+	user, err := as.GetUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if err := as.userRepo.SetUserFrozen(ctx, userID, &reason); err != nil {
+		fmt.Println(err)
+		return customError.ErrInternalServer
+	}
+
+	notification := notifications.Notification{
+		UserID:  user.ID,
+		Email:   user.Email,
+		Subject: "Your account has been frozen",
+		Body:    "Deposits, transfers, swaps and withdrawals on your account have been suspended. Please contact support.",
+	}
+	if err := as.notifier.Notify(ctx, notification); err != nil {
+		fmt.Println("error sending freeze notification: ", err)
+	}
+
+	return nil
+}
+
+func (as *AdminService) UnfreezeUser(ctx context.Context, userID uuid.UUID) error {
+	if _, err := as.GetUser(ctx, userID); err != nil {
+		return err
+	}
+
+	if err := as.userRepo.SetUserFrozen(ctx, userID, nil); err != nil {
+		fmt.Println(err)
+		return customError.ErrInternalServer
+	}
+	return nil
+}
+
+// SetRole changes a user's role. Existing sessions of that user are revoked so
+// the new role is picked up on the next login.
+func (as *AdminService) SetRole(ctx context.Context, actorID, userID uuid.UUID, role string) error {
+	if !models.IsValidRole(role) {
+		return customError.ErrInvalidRole
+	}
+	if actorID == userID {
+		return customError.ErrForbidden
+	}
+
+	if err := as.userRepo.SetUserRole(ctx, userID, role); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return customError.ErrRecordNotFound
+		}
+		fmt.Println(err)
+		return customError.ErrInternalServer
+	}
+	return nil
+}
@@ -0,0 +1,221 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/toluhikay/fx-exchange/internal/config"
+	customError "github.com/toluhikay/fx-exchange/internal/errors"
+	"github.com/toluhikay/fx-exchange/internal/fx"
+	"github.com/toluhikay/fx-exchange/internal/models"
+	"github.com/toluhikay/fx-exchange/internal/notifications"
+	"github.com/toluhikay/fx-exchange/internal/repository"
+)
+
+// AlertService checks users' rate alerts against every rate update and tells
+// them when one fires, both through the notifier and on any alert WebSocket
+// they have open.
+type AlertService struct {
+	repo     *repository.Repository
+	userRepo repository.UserDbRepo
+	fx       fx.FXProvider
+	notifier notifications.Notifier
+	settings config.AlertSettings
+
+	subsMu sync.Mutex
+	subs   map[uuid.UUID][]chan models.RateAlertEvent
+}
+
+func NewAlertService(repo *repository.Repository, ur repository.UserDbRepo, fx fx.FXProvider, notifier notifications.Notifier, settings config.AlertSettings) *AlertService {
+	return &AlertService{
+		repo:     repo,
+		userRepo: ur,
+		fx:       fx,
+		notifier: notifier,
+		settings: settings,
+		subs:     make(map[uuid.UUID][]chan models.RateAlertEvent),
+	}
+}
+
+func (as *AlertService) CreateAlert(ctx context.Context, userID uuid.UUID, req models.RateAlertRequest) (*models.RateAlert, error) {
+	direction := strings.ToLower(req.Direction)
+	if direction != models.AlertAbove && direction != models.AlertBelow {
+		return nil, fmt.Errorf("%w: direction must be above or below", customError.ErrInvalidAlert)
+	}
+	if req.Threshold <= 0 {
+		return nil, fmt.Errorf("%w: threshold must be positive", customError.ErrInvalidAlert)
+	}
+	if req.BaseCurrency == req.QuoteCurrency {
+		return nil, fmt.Errorf("%w: base_currency and quote_currency must differ", customError.ErrInvalidAlert)
+	}
+	if _, err := as.fx.GetRate(ctx, req.BaseCurrency, req.QuoteCurrency); err != nil {
+		return nil, fmt.Errorf("%w: %v", customError.ErrInvalidAlert, err)
+	}
+
+	active, err := as.repo.CountActiveRateAlerts(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if active >= as.settings.MaxActive {
+		return nil, customError.ErrTooManyAlerts
+	}
+
+	return as.repo.CreateRateAlert(ctx, models.RateAlert{
+		UserID:        userID,
+		BaseCurrency:  req.BaseCurrency,
+		QuoteCurrency: req.QuoteCurrency,
+		Direction:     direction,
+		Threshold:     req.Threshold,
+		Repeating:     req.Repeating,
+	})
+}
+
+func (as *AlertService) ListAlerts(ctx context.Context, userID uuid.UUID, status string, limit, offset int) ([]models.RateAlert, error) {
+	return as.repo.ListRateAlerts(ctx, userID, status, limit, offset)
+}
+
+// GetAlert returns one of the user's alerts.
+func (as *AlertService) GetAlert(ctx context.Context, userID, id uuid.UUID) (*models.RateAlert, error) {
+	alert, err := as.repo.GetRateAlert(ctx, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, customError.ErrRecordNotFound
+		}
+		return nil, err
+	}
+	if alert.UserID != userID {
+		return nil, customError.ErrRecordNotFound
+	}
+	return alert, nil
+}
+
+func (as *AlertService) CancelAlert(ctx context.Context, userID, id uuid.UUID) (*models.RateAlert, error) {
+	if _, err := as.GetAlert(ctx, userID, id); err != nil {
+		return nil, err
+	}
+	alert, err := as.repo.CancelRateAlert(ctx, userID, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, customError.ErrAlertNotActive
+		}
+		return nil, err
+	}
+	return alert, nil
+}
+
+// StartRateAlerts checks the active alerts every time the FX provider
+// publishes rates.
+func (as *AlertService) StartRateAlerts(ctx context.Context) {
+	fx.WatchRates(ctx, as.fx, func(rates map[string]map[string]float64) {
+		as.checkAlerts(ctx, rates)
+	})
+}
+
+// checkAlerts fires armed alerts the rates have crossed and arms repeating
+// alerts again once their pair is back on the other side of the threshold.
+func (as *AlertService) checkAlerts(ctx context.Context, rates map[string]map[string]float64) {
+	alerts, err := as.repo.ListActiveRateAlerts(ctx)
+	if err != nil {
+		fmt.Println("error listing rate alerts: ", err)
+		return
+	}
+	for _, alert := range alerts {
+		rate, ok := rates[alert.BaseCurrency][alert.QuoteCurrency]
+		if !ok {
+			continue
+		}
+		crossed := alert.Crossed(rate)
+		switch {
+		case alert.Armed && crossed:
+			as.fire(ctx, alert, rate)
+		case !alert.Armed && !crossed:
+			if err := as.repo.RearmRateAlert(ctx, alert.ID, rate); err != nil {
+				fmt.Println("error rearming rate alert: ", err)
+			}
+		}
+	}
+}
+
+func (as *AlertService) fire(ctx context.Context, alert models.RateAlert, rate float64) {
+	user, err := as.userRepo.GetUserById(ctx, alert.UserID)
+	if err != nil {
+		fmt.Println("error finding rate alert owner: ", err)
+		return
+	}
+	// closed accounts are not told about anything again
+	if user.IsDeleted() {
+		if _, err := as.repo.CancelRateAlert(ctx, user.ID, alert.ID); err != nil && !errors.Is(err, sql.ErrNoRows) {
+			fmt.Println("error cancelling rate alert: ", err)
+		}
+		return
+	}
+
+	triggered, err := as.repo.TriggerRateAlert(ctx, alert.ID, rate)
+	if err != nil {
+		// cancelled or fired by someone else since it was listed
+		if !errors.Is(err, sql.ErrNoRows) {
+			fmt.Println("error triggering rate alert: ", err)
+		}
+		return
+	}
+
+	as.publish(triggered.UserID, models.RateAlertEvent{
+		AlertID:     triggered.ID,
+		Pair:        triggered.Pair(),
+		Direction:   triggered.Direction,
+		Threshold:   triggered.Threshold,
+		Rate:        rate,
+		Repeating:   triggered.Repeating,
+		TriggeredAt: *triggered.LastTriggeredAt,
+	})
+
+	notification := notifications.Notification{
+		UserID:  user.ID,
+		Email:   user.Email,
+		Subject: "Rate alert: " + triggered.Pair(),
+		Body: fmt.Sprintf("%s is at %.8f, %s your alert at %.8f.",
+			triggered.Pair(), rate, triggered.Direction, triggered.Threshold),
+	}
+	if err := as.notifier.Notify(ctx, notification); err != nil {
+		fmt.Println("error sending rate alert notification: ", err)
+	}
+}
+
+// Subscribe returns a channel that receives the user's alerts as they fire.
+// Events are dropped for a subscriber that is not reading; the notification
+// is still sent. Call the returned func to unsubscribe.
+func (as *AlertService) Subscribe(userID uuid.UUID) (chan models.RateAlertEvent, func()) {
+	ch := make(chan models.RateAlertEvent, 16)
+
+	as.subsMu.Lock()
+	as.subs[userID] = append(as.subs[userID], ch)
+	as.subsMu.Unlock()
+
+	unsubscribe := func() {
+		as.subsMu.Lock()
+		defer as.subsMu.Unlock()
+		as.subs[userID] = slices.DeleteFunc(as.subs[userID], func(c chan models.RateAlertEvent) bool { return c == ch })
+		if len(as.subs[userID]) == 0 {
+			delete(as.subs, userID)
+		}
+	}
+	return ch, unsubscribe
+}
+
+func (as *AlertService) publish(userID uuid.UUID, event models.RateAlertEvent) {
+	as.subsMu.Lock()
+	defer as.subsMu.Unlock()
+
+	for _, ch := range as.subs[userID] {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
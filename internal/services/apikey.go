@@ -0,0 +1,131 @@
+package services
+
+import (
+	"context"
+	"crypto/subtle"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/toluhikay/fx-exchange/internal/dtos"
+	customError "github.com/toluhikay/fx-exchange/internal/errors"
+	"github.com/toluhikay/fx-exchange/internal/models"
+	"github.com/toluhikay/fx-exchange/pkg/utils"
+)
+
+// api keys look like fxk_<prefix>_<secret>; the prefix is stored in clear for
+// lookup and display, the secret only as a hash.
+const apiKeyPrefix = "fxk_"
+
+func (us *UserServiceImpl) CreateAPIKey(ctx context.Context, userID uuid.UUID, req dtos.CreateAPIKey) (*dtos.CreatedAPIKey, error) {
+	if strings.TrimSpace(req.Name) == "" || len(req.Scopes) == 0 {
+		return nil, customError.ErrInvalidPayload
+	}
+	for _, scope := range req.Scopes {
+		if !slices.Contains(models.APIKeyScopes, scope) {
+			return nil, customError.ConcatinateErrorMessage(scope, customError.ErrInvalidScope)
+		}
+	}
+	allowedIPs := []string{}
+	for _, ip := range req.AllowedIPs {
+		ip = strings.TrimSpace(ip)
+		if _, _, err := net.ParseCIDR(ip); err != nil && net.ParseIP(ip) == nil {
+			return nil, customError.ConcatinateErrorMessage(ip, customError.ErrInvalidIPAddress)
+		}
+		allowedIPs = append(allowedIPs, ip)
+	}
+
+	prefix, err := utils.GenerateSecureToken(6)
+	if err != nil {
+		return nil, customError.ErrInternalServer
+	}
+	secret, err := utils.GenerateSecureToken(32)
+	if err != nil {
+		return nil, customError.ErrInternalServer
+	}
+
+	key := models.APIKey{
+		ID:         uuid.New(),
+		UserID:     userID,
+		Name:       strings.TrimSpace(req.Name),
+		Prefix:     prefix,
+		KeyHash:    utils.HashToken(secret),
+		Scopes:     slices.Compact(slices.Sorted(slices.Values(req.Scopes))),
+		AllowedIPs: allowedIPs,
+		CreatedAt:  time.Now(),
+	}
+	if err := us.userRepo.CreateAPIKey(ctx, key); err != nil {
+		fmt.Println(err)
+		return nil, customError.ErrInternalServer
+	}
+
+	return &dtos.CreatedAPIKey{
+		Key:    apiKeyPrefix + prefix + "_" + secret,
+		APIKey: &key,
+	}, nil
+}
+
+func (us *UserServiceImpl) ListAPIKeys(ctx context.Context, userID uuid.UUID) ([]models.APIKey, error) {
+	keys, err := us.userRepo.ListAPIKeys(ctx, userID)
+	if err != nil {
+		fmt.Println(err)
+		return nil, customError.ErrInternalServer
+	}
+	return keys, nil
+}
+
+func (us *UserServiceImpl) RevokeAPIKey(ctx context.Context, userID, keyID uuid.UUID) error {
+	if err := us.userRepo.RevokeAPIKey(ctx, userID, keyID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return customError.ErrRecordNotFound
+		}
+		fmt.Println(err)
+		return customError.ErrInternalServer
+	}
+	return nil
+}
+
+// AuthenticateAPIKey resolves a raw key to its owner, enforcing revocation and
+// the ip allow-list.
+func (us *UserServiceImpl) AuthenticateAPIKey(ctx context.Context, rawKey, clientIP string) (*models.APIKey, *models.User, error) {
+	rest, ok := strings.CutPrefix(rawKey, apiKeyPrefix)
+	if !ok {
+		return nil, nil, customError.ErrInvalidAPIKey
+	}
+	prefix, secret, ok := strings.Cut(rest, "_")
+	if !ok || prefix == "" || secret == "" {
+		return nil, nil, customError.ErrInvalidAPIKey
+	}
+
+	key, err := us.userRepo.GetAPIKeyByPrefix(ctx, prefix)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil, customError.ErrInvalidAPIKey
+		}
+		fmt.Println(err)
+		return nil, nil, customError.ErrInternalServer
+	}
+
+	if subtle.ConstantTimeCompare([]byte(key.KeyHash), []byte(utils.HashToken(secret))) != 1 || key.RevokedAt != nil {
+		return nil, nil, customError.ErrInvalidAPIKey
+	}
+	if !key.AllowsIP(clientIP) {
+		return nil, nil, customError.ErrAPIKeyIPNotAllowed
+	}
+
+	user, err := us.getUser(ctx, key.UserID)
+	if err != nil {
+		return nil, nil, customError.ErrInvalidAPIKey
+	}
+
+	if err := us.userRepo.TouchAPIKey(ctx, key.ID); err != nil {
+		fmt.Println(err)
+	}
+
+	return key, user, nil
+}
@@ -0,0 +1,223 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	customError "github.com/toluhikay/fx-exchange/internal/errors"
+	"github.com/toluhikay/fx-exchange/internal/models"
+)
+
+const approvalExpiryBatchSize = 100
+
+func (s *Service) GetApprovalPolicy(ctx context.Context, walletID string) (*models.ApprovalPolicy, error) {
+	return s.repo.GetApprovalPolicy(ctx, walletID)
+}
+
+// SetApprovalPolicy is used by staff to turn maker-checker on for a wallet.
+// An empty approver list turns it off.
+func (s *Service) SetApprovalPolicy(ctx context.Context, walletID string, policy models.ApprovalPolicy) (*models.ApprovalPolicy, error) {
+	if policy.ThresholdUSD < 0 {
+		return nil, fmt.Errorf("%w: threshold cannot be negative", customError.ErrInvalidApprovers)
+	}
+
+	wallet, err := s.repo.GetWallet(ctx, walletID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, customError.ErrRecordNotFound
+		}
+		return nil, err
+	}
+
+	approvers := []uuid.UUID{}
+	for _, id := range policy.ApproverIDs {
+		if id.String() == wallet.UserId {
+			return nil, fmt.Errorf("%w: the wallet owner cannot approve their own transfers", customError.ErrInvalidApprovers)
+		}
+		if !slices.Contains(approvers, id) {
+			approvers = append(approvers, id)
+		}
+	}
+	policy.ApproverIDs = approvers
+
+	if err := s.repo.SetApprovalPolicy(ctx, walletID, policy); err != nil {
+		return nil, err
+	}
+	return &policy, nil
+}
+
+// requireApproval parks a transfer for sign-off when the sender's wallet has
+// approvers and the amount is over its threshold. It returns
+// ErrPendingApproval once the request is created, nil when no sign-off is
+// needed.
+func (s *Service) requireApproval(ctx context.Context, op models.CaseOperation) error {
+	policy, err := s.repo.GetApprovalPolicy(ctx, op.WalletID)
+	if err != nil {
+		return err
+	}
+	if len(policy.ApproverIDs) == 0 {
+		return nil
+	}
+
+	threshold := policy.ThresholdUSD
+	if threshold == 0 {
+		threshold = s.approvals.ThresholdUSD
+	}
+	amountUSD, err := s.USDValue(ctx, op.Currency, op.Amount)
+	if err != nil {
+		return err
+	}
+	if amountUSD <= threshold {
+		return nil
+	}
+
+	ownerID, _, err := s.repo.GetWalletHolder(ctx, op.WalletID)
+	if err != nil {
+		return err
+	}
+	if _, err := s.repo.GetWallet(ctx, op.ReceiverID); err != nil {
+		return fmt.Errorf("failed to get receiver wallet: %w", err)
+	}
+
+	approval, err := s.repo.CreateTransferApproval(ctx, models.TransferApproval{
+		WalletID:         op.WalletID,
+		ReceiverWalletID: op.ReceiverID,
+		RequestedBy:      ownerID,
+		Currency:         op.Currency,
+		Amount:           op.Amount,
+		AmountUSD:        amountUSD,
+		ExpiresAt:        time.Now().Add(s.approvals.Expiry),
+	})
+	if err != nil {
+		return err
+	}
+	return customError.NewCodedError(models.CodePendingApproval,
+		fmt.Errorf("%w: request %s expires at %s", customError.ErrPendingApproval, approval.ID, approval.ExpiresAt.UTC().Format(time.RFC3339)))
+}
+
+func (s *Service) ListPendingApprovals(ctx context.Context, approverID uuid.UUID, limit, offset int) ([]models.TransferApproval, error) {
+	return s.repo.ListApprovalsForApprover(ctx, approverID, models.ApprovalPending, limit, offset)
+}
+
+func (s *Service) ListWalletApprovals(ctx context.Context, walletID string, limit, offset int) ([]models.TransferApproval, error) {
+	return s.repo.ListWalletApprovals(ctx, walletID, limit, offset)
+}
+
+// ApproveTransfer signs off a pending request and pays it out at the current
+// rate.
+func (s *Service) ApproveTransfer(ctx context.Context, approverID, id uuid.UUID) (*models.TransferApproval, error) {
+	approval, err := s.approvableTransfer(ctx, approverID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if time.Now().After(approval.ExpiresAt) {
+		if err := s.repo.ReleaseTransferApproval(ctx, id, models.ApprovalExpired, nil, nil); err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return nil, err
+		}
+		return nil, customError.ErrApprovalExpired
+	}
+
+	toCurrency, rate, convertedAmount, err := s.quoteTransfer(ctx, approval.ReceiverWalletID, approval.Currency, approval.Amount)
+	if err != nil {
+		return nil, err
+	}
+
+	// parked requests do not count toward usage, so each one is checked
+	// against the limits and controls again as it completes
+	ctx = s.underLimits(ctx, approval.WalletID, approval.Currency, approval.Amount)
+	ctx = s.underOutgoingControls(ctx, approval.WalletID, map[string]float64{approval.Currency: approval.Amount})
+	err = s.repo.CompleteTransferApproval(s.holderActive(ctx, approval.WalletID), id, approverID, toCurrency, rate, convertedAmount)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, customError.ErrApprovalResolved
+		}
+		return nil, err
+	}
+	return s.repo.GetTransferApproval(ctx, id)
+}
+
+// RejectTransfer turns a request down and releases the hold on the sender's
+// funds.
+func (s *Service) RejectTransfer(ctx context.Context, approverID, id uuid.UUID, note string) (*models.TransferApproval, error) {
+	if _, err := s.approvableTransfer(ctx, approverID, id); err != nil {
+		return nil, err
+	}
+
+	var notePtr *string
+	if note = strings.TrimSpace(note); note != "" {
+		notePtr = &note
+	}
+
+	err := s.repo.ReleaseTransferApproval(ctx, id, models.ApprovalRejected, &approverID, notePtr)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, customError.ErrApprovalResolved
+		}
+		return nil, err
+	}
+	return s.repo.GetTransferApproval(ctx, id)
+}
+
+// approvableTransfer loads a pending request the user may decide on.
+func (s *Service) approvableTransfer(ctx context.Context, approverID, id uuid.UUID) (*models.TransferApproval, error) {
+	approval, err := s.repo.GetTransferApproval(ctx, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, customError.ErrRecordNotFound
+		}
+		return nil, err
+	}
+
+	policy, err := s.repo.GetApprovalPolicy(ctx, approval.WalletID)
+	if err != nil {
+		return nil, err
+	}
+	if !slices.Contains(policy.ApproverIDs, approverID) {
+		return nil, customError.ErrNotApprover
+	}
+	if approval.RequestedBy == approverID {
+		return nil, customError.ErrSelfApproval
+	}
+	if approval.Status != models.ApprovalPending {
+		return nil, customError.ErrApprovalResolved
+	}
+	return approval, nil
+}
+
+// StartApprovalExpiry periodically releases the holds of requests nobody
+// approved in time. It stops when ctx is cancelled.
+func (s *Service) StartApprovalExpiry(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.expireApprovals(ctx)
+		}
+	}
+}
+
+func (s *Service) expireApprovals(ctx context.Context) {
+	ids, err := s.repo.ListExpiredApprovals(ctx, time.Now(), approvalExpiryBatchSize)
+	if err != nil {
+		fmt.Println("error listing expired approvals: ", err)
+		return
+	}
+
+	for _, id := range ids {
+		err := s.repo.ReleaseTransferApproval(ctx, id, models.ApprovalExpired, nil, nil)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			fmt.Println("error expiring transfer approval: ", err)
+		}
+	}
+}
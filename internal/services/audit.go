@@ -0,0 +1,25 @@
+package services
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/toluhikay/fx-exchange/internal/models"
+	"github.com/toluhikay/fx-exchange/internal/repository"
+)
+
+type AuditService struct {
+	repo *repository.AuditRepo
+}
+
+func NewAuditService(repo *repository.AuditRepo) *AuditService {
+	return &AuditService{repo: repo}
+}
+
+// Record persists an audit entry. A failure is logged rather than returned so
+// that auditing never turns a completed operation into an error response.
+func (a *AuditService) Record(ctx context.Context, l models.AuditLog) {
+	if err := a.repo.CreateAuditLog(ctx, l); err != nil {
+		fmt.Println("error writing audit log: ", err)
+	}
+}
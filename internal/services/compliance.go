@@ -0,0 +1,213 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	customError "github.com/toluhikay/fx-exchange/internal/errors"
+	"github.com/toluhikay/fx-exchange/internal/fraud"
+	"github.com/toluhikay/fx-exchange/internal/models"
+)
+
+// screen runs the risk rules over an operation that passed the limit checks.
+// Operations the rules want reviewed are parked in a compliance case and
+// returned as ErrHeldForReview; blocked ones are recorded and rejected.
+func (s *Service) screen(ctx context.Context, op models.CaseOperation) error {
+	wallet, err := s.repo.GetWallet(ctx, op.WalletID)
+	if err != nil {
+		return err
+	}
+	level, err := s.repo.GetWalletKycLevel(ctx, op.WalletID)
+	if err != nil {
+		return err
+	}
+	amountUSD, err := s.USDValue(ctx, op.Currency, op.Amount)
+	if err != nil {
+		return err
+	}
+
+	device := fraud.DeviceFromContext(ctx)
+	decision, err := s.fraud.Evaluate(ctx, fraud.Operation{
+		Type:        op.Type,
+		UserID:      wallet.UserId,
+		WalletID:    op.WalletID,
+		ReceiverID:  op.ReceiverID,
+		Currency:    op.Currency,
+		Amount:      op.Amount,
+		AmountUSD:   amountUSD,
+		SingleLimit: s.kycLimits[level][op.Currency].Single,
+		Device:      device,
+	})
+	if err != nil {
+		return err
+	}
+	if device != "" {
+		if err := s.repo.TouchDevice(ctx, wallet.UserId, device); err != nil {
+			return err
+		}
+	}
+
+	if decision.Action == fraud.ActionAllow {
+		return nil
+	}
+
+	userID, err := uuid.Parse(wallet.UserId)
+	if err != nil {
+		return err
+	}
+	c := models.ComplianceCase{
+		UserID:    userID,
+		WalletID:  op.WalletID,
+		Source:    models.CaseSourceFraud,
+		Action:    decision.Action,
+		Status:    models.CaseOpen,
+		Operation: op,
+		Hits:      decision.Hits,
+	}
+	if decision.Action == fraud.ActionBlock {
+		c.Status = models.CaseBlocked
+	}
+
+	created, err := s.repo.CreateComplianceCase(ctx, c)
+	if err != nil {
+		return err
+	}
+
+	if created.Status == models.CaseBlocked {
+		return customError.NewCodedError(models.CodeBlockedByRisk, customError.ErrBlockedByRules)
+	}
+	return customError.NewCodedError(models.CodeHeldForReview,
+		fmt.Errorf("%w: case %s, the %s will go through once approved", customError.ErrHeldForReview, created.ID, op.Type))
+}
+
+func (s *Service) ListCases(ctx context.Context, status string, limit, offset int) ([]models.ComplianceCase, error) {
+	if status == "" {
+		status = models.CaseOpen
+	}
+	return s.repo.ListComplianceCases(ctx, status, limit, offset)
+}
+
+func (s *Service) GetCase(ctx context.Context, id uuid.UUID) (*models.ComplianceCase, error) {
+	c, err := s.repo.GetComplianceCase(ctx, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, customError.ErrRecordNotFound
+		}
+		return nil, err
+	}
+	return c, nil
+}
+
+// ApproveCase releases a held operation. It is executed without screening but
+// still has to fit the limits and spending controls at the time of approval;
+// if it does not, the case stays open. Approving a registration case lifts
+// the account freeze.
+func (s *Service) ApproveCase(ctx context.Context, reviewerID, id uuid.UUID, note string) (*models.ComplianceCase, error) {
+	c, err := s.GetCase(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	// claim the case first so two reviewers cannot execute it twice
+	if err := s.repo.ResolveComplianceCase(ctx, id, reviewerID, models.CaseApproved, strings.TrimSpace(note)); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, customError.ErrCaseResolved
+		}
+		return nil, err
+	}
+
+	if err := s.executeCase(ctx, c); err != nil {
+		if reopenErr := s.repo.ReopenComplianceCase(ctx, id); reopenErr != nil {
+			fmt.Println("failed to reopen compliance case: ", reopenErr)
+		}
+		return nil, err
+	}
+
+	// an approved sanctions case means the matches were false positives
+	if c.Source == models.CaseSourceSanctions {
+		if err := s.repo.ClearSanctionsHits(ctx, c.ID, c.Hits); err != nil {
+			fmt.Println("failed to record sanctions clearances: ", err)
+		}
+	}
+
+	return s.GetCase(ctx, id)
+}
+
+func (s *Service) RejectCase(ctx context.Context, reviewerID, id uuid.UUID, note string) (*models.ComplianceCase, error) {
+	if _, err := s.GetCase(ctx, id); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.ResolveComplianceCase(ctx, id, reviewerID, models.CaseRejected, strings.TrimSpace(note)); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, customError.ErrCaseResolved
+		}
+		return nil, err
+	}
+	return s.GetCase(ctx, id)
+}
+
+// executeCase carries out the operation of an approved case. Money only moves
+// while the wallet's owner is still active; a case opened before a freeze
+// cannot pay out after it.
+func (s *Service) executeCase(ctx context.Context, c *models.ComplianceCase) error {
+	op := c.Operation
+	switch op.Type {
+	case models.OpRegistration:
+		return s.repo.ReleaseScreeningHold(ctx, c.UserID)
+
+	case models.OpDeposit:
+		if err := s.checkLimits(ctx, op.WalletID, op.Currency, op.Amount); err != nil {
+			return err
+		}
+		ctx = s.holderActive(s.underLimits(ctx, op.WalletID, op.Currency, op.Amount), op.WalletID)
+		return s.repo.Deposit(ctx, op.WalletID, op.Currency, op.Amount)
+
+	case models.OpSwap:
+		if err := s.checkSwapControls(ctx, op.WalletID, op.Currency, op.ToCurrency); err != nil {
+			return err
+		}
+		if err := s.checkLimits(ctx, op.WalletID, op.Currency, op.Amount); err != nil {
+			return err
+		}
+		_, _, err := s.swap(ctx, op.WalletID, op.Currency, op.ToCurrency, op.Amount)
+		return err
+
+	case models.OpTransfer:
+		if err := s.checkTransferControls(ctx, op.WalletID, op.ReceiverID, op.Currency, op.Amount); err != nil {
+			return err
+		}
+		if err := s.checkLimits(ctx, op.WalletID, op.Currency, op.Amount); err != nil {
+			return err
+		}
+		// a cleared transfer may still need the wallet's own sign-off; once
+		// that request exists the case is done
+		if err := s.requireApproval(ctx, op); err != nil {
+			if errors.Is(err, customError.ErrPendingApproval) {
+				return nil
+			}
+			return err
+		}
+		_, _, err := s.transfer(ctx, op.WalletID, op.ReceiverID, op.Currency, op.Amount)
+		return err
+
+	case models.OpWithdrawal:
+		if err := s.checkWithdrawalControls(ctx, op.WalletID, op.Currency, op.Amount); err != nil {
+			return err
+		}
+		if err := s.checkLimits(ctx, op.WalletID, op.Currency, op.Amount); err != nil {
+			return err
+		}
+		if op.HoldID != "" {
+			_, err := s.captureHold(ctx, op)
+			return err
+		}
+		return s.repo.Withdraw(s.underWithdrawalChecks(ctx, op.WalletID, op.Currency, op.Amount), op.WalletID, op.Currency, op.Destination, op.Amount)
+	}
+
+	return fmt.Errorf("unknown operation type %q", op.Type)
+}
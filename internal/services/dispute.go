@@ -0,0 +1,266 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/toluhikay/fx-exchange/internal/config"
+	customError "github.com/toluhikay/fx-exchange/internal/errors"
+	"github.com/toluhikay/fx-exchange/internal/models"
+	"github.com/toluhikay/fx-exchange/internal/notifications"
+	"github.com/toluhikay/fx-exchange/internal/repository"
+	"github.com/toluhikay/fx-exchange/internal/storage"
+)
+
+// MaxDisputeEvidenceSize caps a single evidence file.
+const MaxDisputeEvidenceSize = 10 << 20
+
+var disputeEvidenceTypes = []string{"image/jpeg", "image/png", "application/pdf"}
+
+type DisputeService struct {
+	repo     *repository.Repository
+	userRepo repository.UserDbRepo
+	store    storage.Store
+	notifier notifications.Notifier
+	settings config.DisputeSettings
+}
+
+func NewDisputeService(repo *repository.Repository, ur repository.UserDbRepo, store storage.Store, notifier notifications.Notifier, settings config.DisputeSettings) *DisputeService {
+	return &DisputeService{repo: repo, userRepo: ur, store: store, notifier: notifier, settings: settings}
+}
+
+// OpenDispute lets the sender of a transfer report it as wrong or
+// fraudulent. Both parties are told, and the receiver's share of the money is
+// held while the dispute is open.
+func (ds *DisputeService) OpenDispute(ctx context.Context, userID uuid.UUID, walletID string, req models.OpenDisputeRequest) (*models.Dispute, error) {
+	if !slices.Contains(models.DisputeReasons, req.Reason) {
+		return nil, customError.ErrInvalidDisputeReason
+	}
+	description := strings.TrimSpace(req.Description)
+	if description == "" {
+		return nil, customError.ErrInvalidPayload
+	}
+
+	original, err := ds.repo.GetTransaction(ctx, req.TransactionID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, customError.ErrRecordNotFound
+		}
+		return nil, err
+	}
+	if original.Type != "transfer" || original.WalletID != walletID {
+		return nil, customError.ErrNotDisputable
+	}
+	if time.Since(original.Timestamp) > ds.settings.Window {
+		return nil, customError.ErrDisputeWindowClosed
+	}
+
+	d, err := ds.repo.CreateDispute(ctx, models.Dispute{
+		TransactionID:  original.ID,
+		SenderWalletID: walletID,
+		OpenedBy:       userID,
+		Reason:         req.Reason,
+		Description:    description,
+	}, time.Now().Add(ds.settings.HoldExpiry))
+	if err != nil {
+		return nil, err
+	}
+
+	ds.notifyParties(ctx, d, "Transfer disputed", fmt.Sprintf(
+		"A dispute was opened on transfer %s for %.4f %s. %.4f %s is held in the receiving wallet until it is resolved.",
+		d.TransactionID, d.Amount, *original.FromCurrency, d.HeldAmount, d.Currency))
+	return d, nil
+}
+
+func (ds *DisputeService) ListWalletDisputes(ctx context.Context, walletID string, limit, offset int) ([]models.Dispute, error) {
+	return ds.repo.ListWalletDisputes(ctx, walletID, limit, offset)
+}
+
+// GetWalletDispute returns a dispute the wallet is a party to.
+func (ds *DisputeService) GetWalletDispute(ctx context.Context, walletID string, id uuid.UUID) (*models.Dispute, error) {
+	d, err := ds.GetDispute(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if d.SenderWalletID != walletID && d.ReceiverWalletID != walletID {
+		return nil, customError.ErrRecordNotFound
+	}
+	return d, nil
+}
+
+// AddEvidence attaches a file from either party to an open dispute.
+// contentType is sniffed from the file by the caller.
+func (ds *DisputeService) AddEvidence(ctx context.Context, userID uuid.UUID, walletID string, id uuid.UUID, fileName, contentType string, file io.Reader) (*models.DisputeEvidence, error) {
+	d, err := ds.GetWalletDispute(ctx, walletID, id)
+	if err != nil {
+		return nil, err
+	}
+	if !models.DisputeIsOpen(d.Status) {
+		return nil, customError.ErrDisputeResolved
+	}
+	if !slices.Contains(disputeEvidenceTypes, contentType) {
+		return nil, customError.ErrUnsupportedFileType
+	}
+
+	key, size, err := ds.store.Save(ctx, file)
+	if err != nil {
+		fmt.Println(err)
+		return nil, customError.ErrInternalServer
+	}
+
+	evidence := models.DisputeEvidence{
+		ID:          uuid.New(),
+		DisputeID:   d.ID,
+		UploadedBy:  userID,
+		FileKey:     key,
+		FileName:    fileName,
+		ContentType: contentType,
+		Size:        size,
+		CreatedAt:   time.Now(),
+	}
+	if err := ds.repo.AddDisputeEvidence(ctx, evidence); err != nil {
+		fmt.Println(err)
+		ds.store.Delete(ctx, key)
+		return nil, customError.ErrInternalServer
+	}
+	return &evidence, nil
+}
+
+func (ds *DisputeService) ListDisputes(ctx context.Context, status string, limit, offset int) ([]models.Dispute, error) {
+	if status == "" {
+		status = models.DisputeOpened
+	}
+	return ds.repo.ListDisputes(ctx, status, limit, offset)
+}
+
+// GetDispute returns a dispute with its evidence.
+func (ds *DisputeService) GetDispute(ctx context.Context, id uuid.UUID) (*models.Dispute, error) {
+	d, err := ds.repo.GetDispute(ctx, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, customError.ErrRecordNotFound
+		}
+		return nil, err
+	}
+	d.Evidence, err = ds.repo.ListDisputeEvidence(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// OpenEvidence returns an evidence file for a reviewer. The caller must close
+// the reader.
+func (ds *DisputeService) OpenEvidence(ctx context.Context, disputeID, id uuid.UUID) (*models.DisputeEvidence, io.ReadCloser, error) {
+	evidence, err := ds.repo.GetDisputeEvidence(ctx, disputeID, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil, customError.ErrRecordNotFound
+		}
+		return nil, nil, err
+	}
+
+	file, err := ds.store.Open(ctx, evidence.FileKey)
+	if err != nil {
+		fmt.Println(err)
+		return nil, nil, customError.ErrInternalServer
+	}
+	return evidence, file, nil
+}
+
+// StartReview marks an opened dispute as picked up by staff.
+func (ds *DisputeService) StartReview(ctx context.Context, id uuid.UUID) (*models.Dispute, error) {
+	if _, err := ds.GetDispute(ctx, id); err != nil {
+		return nil, err
+	}
+	if err := ds.repo.MarkDisputeUnderReview(ctx, id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, customError.ErrDisputeResolved
+		}
+		return nil, err
+	}
+
+	d, err := ds.GetDispute(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	ds.notifyParties(ctx, d, "Dispute under review", fmt.Sprintf("The dispute on transfer %s is now being reviewed.", d.TransactionID))
+	return d, nil
+}
+
+// ResolveDispute decides a dispute. For the sender the transfer is reversed;
+// for the receiver it stands. Either way the receiver's hold is released.
+func (ds *DisputeService) ResolveDispute(ctx context.Context, resolverID, id uuid.UUID, req models.ResolveDisputeRequest) (*models.Dispute, error) {
+	var status string
+	switch strings.ToLower(req.Outcome) {
+	case "sender":
+		status = models.DisputeResolvedSender
+	case "receiver":
+		status = models.DisputeResolvedReceiver
+	default:
+		return nil, customError.ErrInvalidOutcome
+	}
+
+	var note *string
+	if n := strings.TrimSpace(req.Note); n != "" {
+		note = &n
+	}
+
+	if _, err := ds.GetDispute(ctx, id); err != nil {
+		return nil, err
+	}
+	if err := ds.repo.ResolveDispute(ctx, id, resolverID, status, note, req.Force); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, customError.ErrDisputeResolved
+		}
+		return nil, err
+	}
+
+	d, err := ds.GetDispute(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	body := fmt.Sprintf("The dispute on transfer %s was resolved in favour of the receiver and the held funds were released.", d.TransactionID)
+	if status == models.DisputeResolvedSender {
+		body = fmt.Sprintf("The dispute on transfer %s was resolved in favour of the sender and the transfer was reversed.", d.TransactionID)
+	}
+	if note != nil {
+		body += " Note: " + *note
+	}
+	ds.notifyParties(ctx, d, "Dispute resolved", body)
+	return d, nil
+}
+
+// notifyParties tells the owners of both wallets. Failures are logged; they
+// never undo the dispute change.
+func (ds *DisputeService) notifyParties(ctx context.Context, d *models.Dispute, subject, body string) {
+	for _, walletID := range []string{d.SenderWalletID, d.ReceiverWalletID} {
+		userID, _, err := ds.repo.GetWalletHolder(ctx, walletID)
+		if err != nil {
+			fmt.Println("error finding dispute party: ", err)
+			continue
+		}
+		user, err := ds.userRepo.GetUserById(ctx, userID)
+		if err != nil {
+			fmt.Println("error finding dispute party: ", err)
+			continue
+		}
+
+		notification := notifications.Notification{
+			UserID:  user.ID,
+			Email:   user.Email,
+			Subject: subject,
+			Body:    body,
+		}
+		if err := ds.notifier.Notify(ctx, notification); err != nil {
+			fmt.Println("error sending dispute notification: ", err)
+		}
+	}
+}
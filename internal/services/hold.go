@@ -0,0 +1,147 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	customError "github.com/toluhikay/fx-exchange/internal/errors"
+	"github.com/toluhikay/fx-exchange/internal/models"
+)
+
+// PlaceHold reserves funds for a merchant. The amount counts against the
+// limits once it is captured, not when it is held.
+func (s *Service) PlaceHold(ctx context.Context, walletID string, req models.PlaceHoldRequest) (*models.BalanceHold, error) {
+	expiry := s.holds.DefaultExpiry
+	if req.ExpiresIn != "" {
+		d, err := time.ParseDuration(req.ExpiresIn)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("%w: expires_in must be a positive duration such as 72h", customError.ErrInvalidHoldExpiry)
+		}
+		expiry = d
+	}
+	if expiry > s.holds.MaxExpiry {
+		return nil, fmt.Errorf("%w: holds cannot last longer than %s", customError.ErrInvalidHoldExpiry, s.holds.MaxExpiry)
+	}
+
+	var reference *string
+	if ref := strings.TrimSpace(req.Reference); ref != "" {
+		reference = &ref
+	}
+
+	return s.repo.PlaceHold(ctx, models.BalanceHold{
+		WalletID:  walletID,
+		Kind:      models.HoldKindMerchant,
+		Currency:  req.Currency,
+		Amount:    req.Amount,
+		Reference: reference,
+		ExpiresAt: time.Now().Add(expiry),
+	})
+}
+
+func (s *Service) ListHolds(ctx context.Context, walletID, status string, limit, offset int) ([]models.BalanceHold, error) {
+	return s.repo.ListHolds(ctx, walletID, status, limit, offset)
+}
+
+// GetHold returns a merchant hold on the wallet.
+func (s *Service) GetHold(ctx context.Context, walletID string, id uuid.UUID) (*models.BalanceHold, error) {
+	return s.merchantHold(ctx, walletID, id)
+}
+
+// CaptureHold settles a merchant hold as a withdrawal to destination, after
+// the same checks as any other withdrawal. Holds that back a transfer approval
+// are settled by the approvers instead.
+func (s *Service) CaptureHold(ctx context.Context, walletID string, id uuid.UUID, req models.CaptureHoldRequest) (*models.BalanceHold, error) {
+	destination := strings.TrimSpace(req.Destination)
+	if destination == "" {
+		return nil, fmt.Errorf("capture destination is required")
+	}
+	hold, err := s.merchantHold(ctx, walletID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	amount := req.Amount
+	if amount == 0 {
+		amount = hold.Remaining()
+	}
+	op := models.CaseOperation{
+		Type:        models.OpWithdrawal,
+		WalletID:    walletID,
+		Currency:    hold.Currency,
+		Amount:      amount,
+		Destination: destination,
+		HoldID:      id.String(),
+	}
+	if err := s.checkWithdrawal(ctx, op); err != nil {
+		return nil, err
+	}
+	return s.captureHold(ctx, op)
+}
+
+// captureHold settles a checked capture.
+func (s *Service) captureHold(ctx context.Context, op models.CaseOperation) (*models.BalanceHold, error) {
+	id, err := uuid.Parse(op.HoldID)
+	if err != nil {
+		return nil, customError.ErrRecordNotFound
+	}
+	captured, err := s.repo.CaptureHold(s.underWithdrawalChecks(ctx, op.WalletID, op.Currency, op.Amount), id, op.WalletID, op.Amount, op.Destination)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, customError.ErrRecordNotFound
+		}
+		return nil, err
+	}
+	return captured, nil
+}
+
+func (s *Service) ReleaseHold(ctx context.Context, walletID string, id uuid.UUID) (*models.BalanceHold, error) {
+	if _, err := s.merchantHold(ctx, walletID, id); err != nil {
+		return nil, err
+	}
+	if err := s.repo.ReleaseHold(ctx, id); err != nil {
+		return nil, err
+	}
+	return s.repo.GetHold(ctx, id)
+}
+
+// merchantHold loads a hold on the wallet that the owner may settle.
+func (s *Service) merchantHold(ctx context.Context, walletID string, id uuid.UUID) (*models.BalanceHold, error) {
+	hold, err := s.repo.GetHold(ctx, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, customError.ErrRecordNotFound
+		}
+		return nil, err
+	}
+	if hold.WalletID != walletID {
+		return nil, customError.ErrRecordNotFound
+	}
+	if hold.Kind != models.HoldKindMerchant {
+		return nil, customError.ErrForbidden
+	}
+	return hold, nil
+}
+
+// StartHoldExpiry periodically marks holds that ran out. Expired holds stop
+// reserving funds as soon as they pass their expiry, so this only keeps the
+// statuses honest. It stops when ctx is cancelled.
+func (s *Service) StartHoldExpiry(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := s.repo.ExpireHolds(ctx, time.Now()); err != nil {
+				fmt.Println("error expiring holds: ", err)
+			}
+		}
+	}
+}
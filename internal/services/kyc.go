@@ -0,0 +1,184 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/toluhikay/fx-exchange/internal/dtos"
+	customError "github.com/toluhikay/fx-exchange/internal/errors"
+	"github.com/toluhikay/fx-exchange/internal/models"
+	"github.com/toluhikay/fx-exchange/internal/notifications"
+	"github.com/toluhikay/fx-exchange/internal/repository"
+	"github.com/toluhikay/fx-exchange/internal/storage"
+)
+
+// MaxKycDocumentSize caps a single uploaded document.
+const MaxKycDocumentSize = 10 << 20
+
+var kycContentTypes = []string{"image/jpeg", "image/png", "application/pdf"}
+
+type KycService struct {
+	userRepo repository.UserDbRepo
+	store    storage.Store
+	notifier notifications.Notifier
+}
+
+func NewKycService(ur repository.UserDbRepo, store storage.Store, notifier notifications.Notifier) *KycService {
+	return &KycService{userRepo: ur, store: store, notifier: notifier}
+}
+
+// SubmitDocument stores an uploaded document and queues it for review.
+// contentType is sniffed from the file by the caller, not taken from the
+// client.
+func (ks *KycService) SubmitDocument(ctx context.Context, userID uuid.UUID, level, documentType, fileName, contentType string, file io.Reader) (*models.KycDocument, error) {
+	user, err := ks.userRepo.GetUserById(ctx, userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, customError.ErrUnauthorized
+		}
+		return nil, customError.ErrInternalServer
+	}
+
+	if models.KycRank(level) <= models.KycRank(user.KycLevel) {
+		return nil, customError.ErrInvalidKycLevel
+	}
+	if !slices.Contains(models.KycDocumentTypes, documentType) {
+		return nil, customError.ErrInvalidDocumentType
+	}
+	if !slices.Contains(kycContentTypes, contentType) {
+		return nil, customError.ErrUnsupportedFileType
+	}
+
+	key, _, err := ks.store.Save(ctx, file)
+	if err != nil {
+		fmt.Println(err)
+		return nil, customError.ErrInternalServer
+	}
+
+	doc := models.KycDocument{
+		ID:             uuid.New(),
+		UserID:         userID,
+		RequestedLevel: level,
+		DocumentType:   documentType,
+		FileKey:        key,
+		FileName:       fileName,
+		ContentType:    contentType,
+		Status:         models.KycPending,
+		CreatedAt:      time.Now(),
+	}
+	if err := ks.userRepo.CreateKycDocument(ctx, doc); err != nil {
+		fmt.Println(err)
+		ks.store.Delete(ctx, key)
+		return nil, customError.ErrInternalServer
+	}
+
+	return &doc, nil
+}
+
+func (ks *KycService) GetStatus(ctx context.Context, userID uuid.UUID) (*dtos.KycStatus, error) {
+	user, err := ks.userRepo.GetUserById(ctx, userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, customError.ErrUnauthorized
+		}
+		return nil, customError.ErrInternalServer
+	}
+
+	docs, err := ks.userRepo.ListKycDocuments(ctx, userID)
+	if err != nil {
+		fmt.Println(err)
+		return nil, customError.ErrInternalServer
+	}
+
+	return &dtos.KycStatus{Level: user.KycLevel, Documents: docs}, nil
+}
+
+func (ks *KycService) ListDocuments(ctx context.Context, status string, limit, offset int) ([]models.KycDocument, error) {
+	if status == "" {
+		status = models.KycPending
+	}
+
+	docs, err := ks.userRepo.ListKycDocumentsByStatus(ctx, status, limit, offset)
+	if err != nil {
+		fmt.Println(err)
+		return nil, customError.ErrInternalServer
+	}
+	return docs, nil
+}
+
+// OpenDocument returns the document and its file for a reviewer. The caller
+// must close the reader.
+func (ks *KycService) OpenDocument(ctx context.Context, id uuid.UUID) (*models.KycDocument, io.ReadCloser, error) {
+	doc, err := ks.userRepo.GetKycDocument(ctx, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil, customError.ErrRecordNotFound
+		}
+		return nil, nil, customError.ErrInternalServer
+	}
+
+	file, err := ks.store.Open(ctx, doc.FileKey)
+	if err != nil {
+		fmt.Println(err)
+		return nil, nil, customError.ErrInternalServer
+	}
+	return doc, file, nil
+}
+
+// ReviewDocument approves or rejects a pending document and tells the owner.
+func (ks *KycService) ReviewDocument(ctx context.Context, reviewerID, id uuid.UUID, req dtos.ReviewKycDocument) (*models.KycDocument, error) {
+	var status string
+	switch strings.ToLower(req.Decision) {
+	case "approve":
+		status = models.KycApproved
+	case "reject":
+		status = models.KycRejected
+	default:
+		return nil, customError.ErrInvalidDecision
+	}
+
+	var note *string
+	if n := strings.TrimSpace(req.Note); n != "" {
+		note = &n
+	}
+
+	doc, err := ks.userRepo.ReviewKycDocument(ctx, id, reviewerID, status, note)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			if _, getErr := ks.userRepo.GetKycDocument(ctx, id); getErr == nil {
+				return nil, customError.ErrDocumentReviewed
+			}
+			return nil, customError.ErrRecordNotFound
+		}
+		fmt.Println(err)
+		return nil, customError.ErrInternalServer
+	}
+
+	if user, err := ks.userRepo.GetUserById(ctx, doc.UserID); err == nil {
+		body := fmt.Sprintf("Your %s document was %s.", strings.ReplaceAll(doc.DocumentType, "_", " "), doc.Status)
+		if doc.Status == models.KycApproved {
+			body += fmt.Sprintf(" Your account is now at the %s verification level.", user.KycLevel)
+		} else if note != nil {
+			body += " Reason: " + *note
+		}
+
+		notification := notifications.Notification{
+			UserID:  user.ID,
+			Email:   user.Email,
+			Subject: "Identity verification update",
+			Body:    body,
+		}
+		if err := ks.notifier.Notify(ctx, notification); err != nil {
+			fmt.Println("error sending kyc notification: ", err)
+		}
+	}
+
+	return doc, nil
+}
@@ -0,0 +1,184 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	customError "github.com/toluhikay/fx-exchange/internal/errors"
+	"github.com/toluhikay/fx-exchange/internal/models"
+	"github.com/toluhikay/fx-exchange/internal/notifications"
+)
+
+func accountThrottleKey(email string) string {
+	return "account:" + strings.ToLower(strings.TrimSpace(email))
+}
+
+func ipThrottleKey(ip string) string {
+	return "ip:" + ip
+}
+
+func mfaThrottleKey(userID uuid.UUID) string {
+	return "mfa:" + userID.String()
+}
+
+// checkLoginThrottle refuses the attempt while the account or the client ip is
+// backing off or locked out.
+func (us *UserServiceImpl) checkLoginThrottle(ctx context.Context, email, clientIP string) error {
+	keys := []string{accountThrottleKey(email)}
+	if clientIP != "" {
+		keys = append(keys, ipThrottleKey(clientIP))
+	}
+
+	for i, key := range keys {
+		throttle, err := us.userRepo.GetLoginThrottle(ctx, key)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				continue
+			}
+			fmt.Println(err)
+			return customError.ErrInternalServer
+		}
+
+		if throttle.LockedUntil == nil || !time.Now().Before(*throttle.LockedUntil) {
+			continue
+		}
+
+		retryAfter := time.Until(*throttle.LockedUntil)
+		if i == 0 && throttle.Failures >= us.settings.Login.LockoutThreshold {
+			return customError.NewRetryAfterError(customError.ErrAccountLocked, retryAfter)
+		}
+		return customError.NewRetryAfterError(customError.ErrTooManyLoginAttempts, retryAfter)
+	}
+
+	return nil
+}
+
+// recordLoginFailure counts the failure against both the account and the ip.
+// user is nil when the email is not registered; the email is throttled anyway so
+// the response does not reveal which accounts exist.
+func (us *UserServiceImpl) recordLoginFailure(ctx context.Context, user *models.User, email, clientIP string) {
+	settings := us.settings.Login
+
+	if clientIP != "" {
+		key := ipThrottleKey(clientIP)
+		failures, err := us.userRepo.RecordLoginFailure(ctx, key, settings.FailureWindow)
+		if err != nil {
+			fmt.Println(err)
+		} else if failures > settings.FreeAttempts {
+			if err := us.userRepo.LockLogin(ctx, key, time.Now().Add(us.loginBackoff(failures))); err != nil {
+				fmt.Println(err)
+			}
+		}
+	}
+
+	key := accountThrottleKey(email)
+	failures, err := us.userRepo.RecordLoginFailure(ctx, key, settings.FailureWindow)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	switch {
+	case failures >= settings.LockoutThreshold:
+		if err := us.userRepo.LockLogin(ctx, key, time.Now().Add(settings.LockoutDuration)); err != nil {
+			fmt.Println(err)
+			return
+		}
+		if user != nil {
+			us.notifyAccountLocked(ctx, user)
+		}
+	case failures > settings.FreeAttempts:
+		if err := us.userRepo.LockLogin(ctx, key, time.Now().Add(us.loginBackoff(failures))); err != nil {
+			fmt.Println(err)
+		}
+	}
+}
+
+// checkMfaThrottle refuses two-factor codes, at login or step-up, while the
+// account is locked out after too many wrong codes. It is counted apart from the password
+// failures, which a correct password clears.
+func (us *UserServiceImpl) checkMfaThrottle(ctx context.Context, userID uuid.UUID) error {
+	throttle, err := us.userRepo.GetLoginThrottle(ctx, mfaThrottleKey(userID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		fmt.Println(err)
+		return customError.ErrInternalServer
+	}
+
+	if throttle.LockedUntil != nil && time.Now().Before(*throttle.LockedUntil) {
+		return customError.NewRetryAfterError(customError.ErrAccountLocked, time.Until(*throttle.LockedUntil))
+	}
+	return nil
+}
+
+// recordMfaFailure counts a wrong code against the account and locks the
+// second step once the lockout threshold is reached.
+func (us *UserServiceImpl) recordMfaFailure(ctx context.Context, user *models.User) {
+	settings := us.settings.Login
+	key := mfaThrottleKey(user.ID)
+
+	failures, err := us.userRepo.RecordLoginFailure(ctx, key, settings.FailureWindow)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	if failures < settings.LockoutThreshold {
+		return
+	}
+
+	if err := us.userRepo.LockLogin(ctx, key, time.Now().Add(settings.LockoutDuration)); err != nil {
+		fmt.Println(err)
+		return
+	}
+	us.notifyAccountLocked(ctx, user)
+}
+
+// loginBackoff doubles the wait for every failure past the free attempts.
+func (us *UserServiceImpl) loginBackoff(failures int) time.Duration {
+	settings := us.settings.Login
+	backoff := settings.BackoffBase
+	for i := settings.FreeAttempts + 1; i < failures; i++ {
+		backoff *= 2
+		if backoff >= settings.BackoffMax {
+			return settings.BackoffMax
+		}
+	}
+	return backoff
+}
+
+func (us *UserServiceImpl) notifyAccountLocked(ctx context.Context, user *models.User) {
+	n := notifications.Notification{
+		UserID:  user.ID,
+		Email:   user.Email,
+		Subject: "Your account has been locked",
+		Body: fmt.Sprintf("Hi %s,\n\nWe locked your account for %s after several failed sign in attempts. If this was not you, reset your password once the lock expires or contact support.",
+			user.Name, us.settings.Login.LockoutDuration),
+	}
+	if err := us.notifier.Notify(ctx, n); err != nil {
+		fmt.Println("error sending account locked notification: ", err)
+	}
+}
+
+// UnlockUser lifts a login or two-factor lockout before it expires.
+func (us *UserServiceImpl) UnlockUser(ctx context.Context, userID uuid.UUID) error {
+	user, err := us.getUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	for _, key := range []string{accountThrottleKey(user.Email), mfaThrottleKey(user.ID)} {
+		if err := us.userRepo.ClearLoginThrottle(ctx, key); err != nil {
+			fmt.Println(err)
+			return customError.ErrInternalServer
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,375 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/google/uuid"
+	customError "github.com/toluhikay/fx-exchange/internal/errors"
+	"github.com/toluhikay/fx-exchange/internal/models"
+	"github.com/toluhikay/fx-exchange/internal/orderbook"
+	"github.com/toluhikay/fx-exchange/internal/repository"
+)
+
+// DepthLevels is how many price levels a side depth snapshots carry.
+const DepthLevels = 20
+
+// MarketService matches peer-to-peer bids and asks. Each market keeps its open
+// orders in an in-memory book that is rebuilt from the database on startup;
+// the database stays the source of truth and every fill is settled there
+// before the book changes. Books are per instance, so only one instance
+// should take orders.
+type MarketService struct {
+	repo    *repository.Repository
+	wallets *Service
+	markets []string
+	books   map[string]*marketBook
+
+	subsMu sync.Mutex
+	subs   map[string][]chan orderbook.Depth
+}
+
+// marketBook serialises placing, matching and cancelling on one market.
+type marketBook struct {
+	mu   sync.Mutex
+	book *orderbook.Book
+}
+
+// NewMarketService sets up an empty book for each market, written as
+// "BASE/QUOTE".
+func NewMarketService(repo *repository.Repository, wallets *Service, markets []string) *MarketService {
+	ms := &MarketService{
+		repo:    repo,
+		wallets: wallets,
+		books:   make(map[string]*marketBook),
+		subs:    make(map[string][]chan orderbook.Depth),
+	}
+	for _, market := range markets {
+		if _, _, ok := splitMarket(market); !ok {
+			fmt.Println("skipping invalid market: ", market)
+			continue
+		}
+		ms.markets = append(ms.markets, market)
+		ms.books[market] = &marketBook{book: orderbook.New(market)}
+	}
+	return ms
+}
+
+func splitMarket(market string) (string, string, bool) {
+	base, quote, ok := strings.Cut(market, "/")
+	if !ok || base == "" || quote == "" || base == quote {
+		return "", "", false
+	}
+	return base, quote, true
+}
+
+// LoadOrderBooks puts the open orders back on their books, oldest first so
+// they keep their time priority.
+func (ms *MarketService) LoadOrderBooks(ctx context.Context) error {
+	orders, err := ms.repo.ListOpenP2POrders(ctx)
+	if err != nil {
+		return err
+	}
+	for _, o := range orders {
+		mb, ok := ms.books[o.Market]
+		if !ok {
+			fmt.Println("open p2p order on unknown market: ", o.ID, o.Market)
+			continue
+		}
+		mb.mu.Lock()
+		mb.book.Add(bookOrder(o))
+		mb.mu.Unlock()
+	}
+	return nil
+}
+
+func bookOrder(o models.P2POrder) orderbook.Order {
+	return orderbook.Order{
+		ID:        o.ID,
+		WalletID:  o.WalletID,
+		Side:      o.Side,
+		Price:     o.Price,
+		Remaining: o.Remaining(),
+		CreatedAt: o.CreatedAt,
+	}
+}
+
+func (ms *MarketService) Markets() []string {
+	return ms.markets
+}
+
+// PlaceOrder reserves what the order can spend and matches it against the
+// other side of the book, best price first and oldest first at each price.
+// Fills happen at the resting order's price. Whatever is left rests on the
+// book until it is matched or cancelled.
+func (ms *MarketService) PlaceOrder(ctx context.Context, userID uuid.UUID, walletID string, req models.P2POrderRequest) (*models.P2POrder, error) {
+	mb, ok := ms.books[req.Market]
+	if !ok {
+		return nil, customError.ErrInvalidMarket
+	}
+	base, quote, _ := splitMarket(req.Market)
+	side := strings.ToLower(req.Side)
+	if side != models.SideBid && side != models.SideAsk {
+		return nil, fmt.Errorf("%w: side must be bid or ask", customError.ErrInvalidOrder)
+	}
+	if req.Price <= 0 || req.Amount <= 0 {
+		return nil, fmt.Errorf("%w: price and amount must be positive", customError.ErrInvalidOrder)
+	}
+
+	wallet, err := ms.repo.GetWallet(ctx, walletID)
+	if err != nil {
+		return nil, err
+	}
+	for _, currency := range []string{base, quote} {
+		if _, ok := wallet.Balances[currency]; !ok {
+			return nil, fmt.Errorf("%w: unsupported currency %s", customError.ErrInvalidOrder, currency)
+		}
+	}
+
+	spend, receive, spendAmount := base, quote, req.Amount
+	if side == models.SideBid {
+		spend, receive, spendAmount = quote, base, repository.QuoteAmount(req.Price, req.Amount)
+	}
+	if err := ms.wallets.checkSwapControls(ctx, walletID, spend, receive); err != nil {
+		return nil, err
+	}
+	if err := ms.wallets.checkLimits(ctx, walletID, spend, spendAmount); err != nil {
+		return nil, err
+	}
+	op := models.CaseOperation{Type: models.OpSwap, WalletID: walletID, Currency: spend, ToCurrency: receive, Amount: spendAmount}
+	if err := ms.wallets.screen(ctx, op); err != nil {
+		return nil, err
+	}
+
+	mb.mu.Lock()
+	// never trade with yourself, and never rest across your own orders either
+	for _, maker := range mb.book.Crossing(side, req.Price) {
+		if maker.WalletID == walletID {
+			mb.mu.Unlock()
+			return nil, fmt.Errorf("%w: it would trade with your own order %s", customError.ErrInvalidOrder, maker.ID)
+		}
+	}
+	order, err := ms.repo.CreateP2POrder(ctx, models.P2POrder{
+		WalletID:      walletID,
+		CreatedBy:     userID,
+		Market:        req.Market,
+		BaseCurrency:  base,
+		QuoteCurrency: quote,
+		Side:          side,
+		Price:         req.Price,
+		Amount:        req.Amount,
+	})
+	if err != nil {
+		mb.mu.Unlock()
+		return nil, err
+	}
+	order = ms.match(ctx, mb, order)
+	depth := mb.book.Depth(DepthLevels)
+	mb.mu.Unlock()
+
+	ms.publish(depth)
+	return order, nil
+}
+
+// match fills taker against the book with mb locked and rests what is left.
+// A resting order that can no longer settle, say because its wallet was
+// frozen or the fill would take it over its limits, is closed as failed and
+// taken off the book. If a fill fails for any other reason the taker is
+// closed the same way, since resting it would leave it crossing the book.
+func (ms *MarketService) match(ctx context.Context, mb *marketBook, taker *models.P2POrder) *models.P2POrder {
+	remaining := taker.Remaining()
+	stopped := false
+	for _, maker := range mb.book.Crossing(taker.Side, taker.Price) {
+		if remaining < 0.00005 {
+			break
+		}
+
+		amount := min(remaining, maker.Remaining)
+		bidID, askID := taker.ID, maker.ID
+		if taker.Side == models.SideAsk {
+			bidID, askID = maker.ID, taker.ID
+		}
+		// both sides' limits were checked when they were placed, but usage
+		// moves with every other fill and swap, so check them again with the
+		// wallets locked
+		settleCtx := ctx
+		for _, o := range []struct {
+			walletID, side string
+		}{{taker.WalletID, taker.Side}, {maker.WalletID, maker.Side}} {
+			spend, spendAmount := taker.BaseCurrency, amount
+			if o.side == models.SideBid {
+				spend, spendAmount = taker.QuoteCurrency, repository.QuoteAmount(maker.Price, amount)
+			}
+			settleCtx = ms.wallets.holderActive(ms.wallets.underLimits(settleCtx, o.walletID, spend, spendAmount), o.walletID)
+		}
+		_, err := ms.repo.SettleP2PTrade(settleCtx, bidID, askID, maker.Price, amount, taker.Side)
+		if err == nil {
+			mb.book.Reduce(maker.ID, amount)
+			remaining -= amount
+			continue
+		}
+
+		var settleErr *customError.SettlementError
+		if errors.As(err, &settleErr) && settleErr.OrderID == maker.ID.String() {
+			ms.failOrder(ctx, maker.ID, settleErr.Err)
+			mb.book.Remove(maker.ID)
+			continue
+		}
+		// the taker still crosses this maker, so resting it would leave a
+		// crossed book; fail it whatever went wrong
+		if errors.As(err, &settleErr) && settleErr.OrderID == taker.ID.String() {
+			err = settleErr.Err
+		} else {
+			fmt.Println("error settling p2p trade: ", err)
+		}
+		ms.failOrder(ctx, taker.ID, err)
+		stopped = true
+		break
+	}
+
+	current, err := ms.repo.GetP2POrder(ctx, taker.ID)
+	if err != nil {
+		fmt.Println("error getting p2p order: ", err)
+		return taker
+	}
+	if current.Status == models.OrderOpen {
+		if stopped {
+			// closing it failed too; it goes back on the book when the books
+			// are reloaded
+			fmt.Println("p2p order left off the book after a failed settlement: ", current.ID)
+			return current
+		}
+		mb.book.Add(bookOrder(*current))
+	}
+	return current
+}
+
+func (ms *MarketService) failOrder(ctx context.Context, id uuid.UUID, cause error) {
+	reason := cause.Error()
+	err := ms.repo.CloseP2POrder(ctx, id, models.OrderFailed, &reason)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		fmt.Println("error closing p2p order: ", err)
+	}
+}
+
+// CancelOrder takes an open order off the book and releases what it still
+// holds.
+func (ms *MarketService) CancelOrder(ctx context.Context, walletID string, id uuid.UUID) (*models.P2POrder, error) {
+	order, err := ms.GetOrder(ctx, walletID, id)
+	if err != nil {
+		return nil, err
+	}
+	if order.Status != models.OrderOpen {
+		return nil, customError.ErrOrderNotOpen
+	}
+	mb, ok := ms.books[order.Market]
+	if !ok {
+		return nil, customError.ErrInvalidMarket
+	}
+
+	mb.mu.Lock()
+	err = ms.repo.CloseP2POrder(ctx, id, models.OrderCancelled, nil)
+	if err == nil {
+		mb.book.Remove(id)
+	}
+	depth := mb.book.Depth(DepthLevels)
+	mb.mu.Unlock()
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, customError.ErrOrderNotOpen
+		}
+		return nil, err
+	}
+
+	ms.publish(depth)
+	return ms.repo.GetP2POrder(ctx, id)
+}
+
+// GetOrder returns an order placed by the wallet.
+func (ms *MarketService) GetOrder(ctx context.Context, walletID string, id uuid.UUID) (*models.P2POrder, error) {
+	order, err := ms.repo.GetP2POrder(ctx, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, customError.ErrRecordNotFound
+		}
+		return nil, err
+	}
+	if order.WalletID != walletID {
+		return nil, customError.ErrRecordNotFound
+	}
+	return order, nil
+}
+
+func (ms *MarketService) ListOrders(ctx context.Context, walletID, status string, limit, offset int) ([]models.P2POrder, error) {
+	return ms.repo.ListP2POrders(ctx, walletID, status, limit, offset)
+}
+
+func (ms *MarketService) ListOrderTrades(ctx context.Context, walletID string, id uuid.UUID) ([]models.P2PTrade, error) {
+	if _, err := ms.GetOrder(ctx, walletID, id); err != nil {
+		return nil, err
+	}
+	return ms.repo.ListP2POrderTrades(ctx, id)
+}
+
+func (ms *MarketService) ListTrades(ctx context.Context, market string, limit, offset int) ([]models.P2PTrade, error) {
+	if !slices.Contains(ms.markets, market) {
+		return nil, customError.ErrInvalidMarket
+	}
+	return ms.repo.ListP2PTrades(ctx, market, limit, offset)
+}
+
+// Depth returns the best levels levels on each side of a market.
+func (ms *MarketService) Depth(market string, levels int) (*orderbook.Depth, error) {
+	mb, ok := ms.books[market]
+	if !ok {
+		return nil, customError.ErrInvalidMarket
+	}
+	mb.mu.Lock()
+	defer mb.mu.Unlock()
+	depth := mb.book.Depth(levels)
+	return &depth, nil
+}
+
+// SubscribeDepth returns a channel that receives the market's depth every
+// time its book changes. A subscriber that falls behind only misses the
+// snapshots in between. Call the returned func to unsubscribe.
+func (ms *MarketService) SubscribeDepth(market string) (chan orderbook.Depth, func(), error) {
+	if _, ok := ms.books[market]; !ok {
+		return nil, nil, customError.ErrInvalidMarket
+	}
+	ch := make(chan orderbook.Depth, 1)
+
+	ms.subsMu.Lock()
+	ms.subs[market] = append(ms.subs[market], ch)
+	ms.subsMu.Unlock()
+
+	unsubscribe := func() {
+		ms.subsMu.Lock()
+		defer ms.subsMu.Unlock()
+		ms.subs[market] = slices.DeleteFunc(ms.subs[market], func(c chan orderbook.Depth) bool { return c == ch })
+	}
+	return ch, unsubscribe, nil
+}
+
+func (ms *MarketService) publish(depth orderbook.Depth) {
+	ms.subsMu.Lock()
+	defer ms.subsMu.Unlock()
+
+	for _, ch := range ms.subs[depth.Market] {
+		// drop the snapshot the subscriber has not read yet; only the latest
+		// one matters
+		select {
+		case <-ch:
+		default:
+		}
+		select {
+		case ch <- depth:
+		default:
+		}
+	}
+}
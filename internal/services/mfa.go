@@ -0,0 +1,244 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/toluhikay/fx-exchange/internal/dtos"
+	customError "github.com/toluhikay/fx-exchange/internal/errors"
+	"github.com/toluhikay/fx-exchange/internal/models"
+	"github.com/toluhikay/fx-exchange/pkg/totp"
+	"github.com/toluhikay/fx-exchange/pkg/utils"
+)
+
+const recoveryCodeCount = 10
+
+// EnrollMfa generates a new secret and recovery codes. Two-factor stays off until
+// the user proves their authenticator works through ActivateMfa.
+func (us *UserServiceImpl) EnrollMfa(ctx context.Context, userID uuid.UUID) (*dtos.MfaEnrolment, error) {
+	user, err := us.getUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.IsMfaEnabled() {
+		return nil, customError.ErrMfaAlreadyEnabled
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		fmt.Println("error generating totp secret: ", err)
+		return nil, customError.ErrInternalServer
+	}
+	encryptedSecret, err := utils.Encrypt(us.settings.EncryptionKey, secret)
+	if err != nil {
+		return nil, err
+	}
+
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		raw, err := utils.GenerateSecureToken(5)
+		if err != nil {
+			return nil, customError.ErrInternalServer
+		}
+		codes[i] = raw[:5] + "-" + raw[5:]
+		hashes[i] = utils.HashToken(normaliseRecoveryCode(codes[i]))
+	}
+
+	if err := us.userRepo.SaveMfaEnrolment(ctx, user.ID, encryptedSecret, hashes); err != nil {
+		fmt.Println(err)
+		return nil, customError.ErrInternalServer
+	}
+
+	return &dtos.MfaEnrolment{
+		Secret:          secret,
+		ProvisioningURI: totp.ProvisioningURI(us.settings.MfaIssuer, user.Email, secret),
+		RecoveryCodes:   codes,
+	}, nil
+}
+
+func (us *UserServiceImpl) ActivateMfa(ctx context.Context, userID uuid.UUID, code string) error {
+	user, err := us.getUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if user.IsMfaEnabled() {
+		return customError.ErrMfaAlreadyEnabled
+	}
+	if user.MfaSecret == nil {
+		return customError.ErrMfaNotEnrolled
+	}
+
+	secret, err := utils.Decrypt(us.settings.EncryptionKey, *user.MfaSecret)
+	if err != nil {
+		return customError.ErrInternalServer
+	}
+
+	step, err := totp.Validate(secret, code, time.Now())
+	if err != nil {
+		return customError.ErrInvalidMfaCode
+	}
+
+	if err := us.userRepo.EnableMfa(ctx, user.ID, step); err != nil {
+		fmt.Println(err)
+		return customError.ErrInternalServer
+	}
+
+	return nil
+}
+
+func (us *UserServiceImpl) DisableMfa(ctx context.Context, userID uuid.UUID, req dtos.DisableMfa) error {
+	user, err := us.getUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !user.IsMfaEnabled() {
+		return customError.ErrMfaNotEnabled
+	}
+
+	ok, err := user.CompareHashedPassword(req.Password)
+	if err != nil || !ok {
+		return customError.ErrInvalidCredentials
+	}
+
+	if err := us.verifyThrottledMfaCode(ctx, user, req.Code); err != nil {
+		return err
+	}
+
+	if err := us.userRepo.DisableMfa(ctx, user.ID); err != nil {
+		fmt.Println(err)
+		return customError.ErrInternalServer
+	}
+
+	return nil
+}
+
+// CompleteMfaLogin finishes the second step of a login started with a password.
+// Wrong codes are counted per account and lock the step out like password
+// failures do, see verifyThrottledMfaCode.
+func (us *UserServiceImpl) CompleteMfaLogin(ctx context.Context, userID uuid.UUID, sessionVersion int, code string) (*models.User, error) {
+	user, err := us.getUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.SessionVersion != sessionVersion {
+		return nil, customError.ErrSessionRevoked
+	}
+	if !user.IsMfaEnabled() {
+		return nil, customError.ErrMfaNotEnabled
+	}
+	if err := us.verifyThrottledMfaCode(ctx, user, code); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// CapAPIKeyStepUp refuses operations worth more than the step-up threshold
+// when they are made with an api key. A key cannot answer a totp challenge, so
+// instead of skipping it the larger amounts are left to a signed-in session.
+func (us *UserServiceImpl) CapAPIKeyStepUp(amountUSD float64) error {
+	if amountUSD > us.settings.MfaStepUpThresholdUSD {
+		return customError.ErrAPIKeyStepUp
+	}
+	return nil
+}
+
+// VerifyStepUp demands a fresh code for operations worth more than the
+// configured threshold. Users who never enrolled are not challenged.
+func (us *UserServiceImpl) VerifyStepUp(ctx context.Context, userID uuid.UUID, amountUSD float64, code string) error {
+	if amountUSD <= us.settings.MfaStepUpThresholdUSD {
+		return nil
+	}
+
+	user, err := us.getUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !user.IsMfaEnabled() {
+		return nil
+	}
+
+	if code == "" {
+		return customError.ErrMfaRequired
+	}
+
+	return us.verifyThrottledMfaCode(ctx, user, code)
+}
+
+// verifyThrottledMfaCode is verifyMfaCode with wrong codes counted per
+// account. Login and step-up share the count, so guesses spread across them
+// lock both once the lockout threshold is reached.
+func (us *UserServiceImpl) verifyThrottledMfaCode(ctx context.Context, user *models.User, code string) error {
+	if err := us.checkMfaThrottle(ctx, user.ID); err != nil {
+		return err
+	}
+
+	if err := us.verifyMfaCode(ctx, user, code); err != nil {
+		if errors.Is(err, customError.ErrInvalidMfaCode) {
+			us.recordMfaFailure(ctx, user)
+		}
+		return err
+	}
+
+	if err := us.userRepo.ClearLoginThrottle(ctx, mfaThrottleKey(user.ID)); err != nil {
+		fmt.Println(err)
+	}
+	return nil
+}
+
+// verifyMfaCode accepts either a current totp code or an unused recovery code.
+func (us *UserServiceImpl) verifyMfaCode(ctx context.Context, user *models.User, code string) error {
+	if user.MfaSecret == nil {
+		return customError.ErrMfaNotEnabled
+	}
+
+	secret, err := utils.Decrypt(us.settings.EncryptionKey, *user.MfaSecret)
+	if err != nil {
+		return customError.ErrInternalServer
+	}
+
+	if step, err := totp.Validate(secret, code, time.Now()); err == nil {
+		fresh, err := us.userRepo.UpdateMfaLastStep(ctx, user.ID, step)
+		if err != nil {
+			fmt.Println(err)
+			return customError.ErrInternalServer
+		}
+		if !fresh {
+			// the code was already used
+			return customError.ErrInvalidMfaCode
+		}
+		return nil
+	}
+
+	used, err := us.userRepo.UseRecoveryCode(ctx, user.ID, utils.HashToken(normaliseRecoveryCode(code)))
+	if err != nil {
+		fmt.Println(err)
+		return customError.ErrInternalServer
+	}
+	if !used {
+		return customError.ErrInvalidMfaCode
+	}
+
+	return nil
+}
+
+func (us *UserServiceImpl) getUser(ctx context.Context, userID uuid.UUID) (*models.User, error) {
+	user, err := us.userRepo.GetUserById(ctx, userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, customError.ErrInvalidCredentials
+		}
+		return nil, customError.ErrInternalServer
+	}
+	return user, nil
+}
+
+func normaliseRecoveryCode(code string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(code), "-", ""))
+}
@@ -0,0 +1,40 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/google/uuid"
+	customError "github.com/toluhikay/fx-exchange/internal/errors"
+	"github.com/toluhikay/fx-exchange/internal/models"
+	"github.com/toluhikay/fx-exchange/internal/repository"
+)
+
+// NotificationService serves the in-app inbox that notifications.InAppNotifier
+// fills.
+type NotificationService struct {
+	repo *repository.Repository
+}
+
+func NewNotificationService(repo *repository.Repository) *NotificationService {
+	return &NotificationService{repo: repo}
+}
+
+func (ns *NotificationService) List(ctx context.Context, userID uuid.UUID, unreadOnly bool, limit, offset int) ([]models.Notification, error) {
+	return ns.repo.ListNotifications(ctx, userID, unreadOnly, limit, offset)
+}
+
+func (ns *NotificationService) MarkRead(ctx context.Context, userID, id uuid.UUID) error {
+	if err := ns.repo.MarkNotificationRead(ctx, userID, id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return customError.ErrRecordNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+func (ns *NotificationService) MarkAllRead(ctx context.Context, userID uuid.UUID) error {
+	return ns.repo.MarkAllNotificationsRead(ctx, userID)
+}
@@ -0,0 +1,234 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	customError "github.com/toluhikay/fx-exchange/internal/errors"
+	"github.com/toluhikay/fx-exchange/internal/fx"
+	"github.com/toluhikay/fx-exchange/internal/models"
+)
+
+const orderBatchSize = 100
+
+// PlaceSwapOrder reserves the amount and opens an order that fills when the
+// published rate reaches the trigger. Controls, limits and screening are
+// checked now; controls and limits are checked again when the order fills.
+// An order whose trigger the current rate already meets fills straight away.
+func (s *Service) PlaceSwapOrder(ctx context.Context, userID uuid.UUID, walletID string, req models.SwapOrderRequest) (*models.SwapOrder, error) {
+	order := models.SwapOrder{
+		WalletID:     walletID,
+		CreatedBy:    userID,
+		Type:         strings.ToLower(req.Type),
+		FromCurrency: req.FromCurrency,
+		ToCurrency:   req.ToCurrency,
+		Amount:       req.Amount,
+		TriggerRate:  req.TriggerRate,
+		TimeInForce:  strings.ToLower(req.TimeInForce),
+	}
+	if order.TimeInForce == "" {
+		order.TimeInForce = models.OrderGoodTillCancelled
+	}
+
+	if order.Type != models.OrderLimit && order.Type != models.OrderStop {
+		return nil, fmt.Errorf("%w: type must be limit or stop", customError.ErrInvalidOrder)
+	}
+	if req.Amount <= 0 || req.TriggerRate <= 0 {
+		return nil, fmt.Errorf("%w: amount and trigger_rate must be positive", customError.ErrInvalidOrder)
+	}
+	if req.FromCurrency == req.ToCurrency {
+		return nil, fmt.Errorf("%w: from_currency and to_currency must differ", customError.ErrInvalidOrder)
+	}
+
+	// good-till-cancelled holds last until the order is filled or cancelled
+	holdExpiry := time.Now().AddDate(100, 0, 0)
+	switch order.TimeInForce {
+	case models.OrderGoodTillCancelled:
+		if req.ExpiresAt != nil {
+			return nil, fmt.Errorf("%w: expires_at only applies to gtd orders", customError.ErrInvalidOrder)
+		}
+	case models.OrderGoodTillDate:
+		if req.ExpiresAt == nil || !req.ExpiresAt.After(time.Now()) {
+			return nil, fmt.Errorf("%w: gtd orders need an expires_at in the future", customError.ErrInvalidOrder)
+		}
+		expiresAt := req.ExpiresAt.UTC()
+		order.ExpiresAt = &expiresAt
+		holdExpiry = expiresAt
+	default:
+		return nil, fmt.Errorf("%w: time_in_force must be gtc or gtd", customError.ErrInvalidOrder)
+	}
+
+	wallet, err := s.repo.GetWallet(ctx, walletID)
+	if err != nil {
+		return nil, err
+	}
+	for _, currency := range []string{req.FromCurrency, req.ToCurrency} {
+		if _, ok := wallet.Balances[currency]; !ok {
+			return nil, fmt.Errorf("%w: unsupported currency %s", customError.ErrInvalidOrder, currency)
+		}
+	}
+
+	if err := s.checkSwapControls(ctx, walletID, req.FromCurrency, req.ToCurrency); err != nil {
+		return nil, err
+	}
+	if err := s.checkLimits(ctx, walletID, req.FromCurrency, req.Amount); err != nil {
+		return nil, err
+	}
+	op := models.CaseOperation{Type: models.OpSwap, WalletID: walletID, Currency: req.FromCurrency, ToCurrency: req.ToCurrency, Amount: req.Amount}
+	if err := s.screen(ctx, op); err != nil {
+		return nil, err
+	}
+
+	created, err := s.repo.CreateSwapOrder(ctx, order, holdExpiry)
+	if err != nil {
+		return nil, err
+	}
+
+	rate, err := s.fx.GetRate(ctx, created.FromCurrency, created.ToCurrency)
+	if err != nil || !created.Triggered(rate) {
+		return created, nil
+	}
+	return s.fillOrder(ctx, *created, rate), nil
+}
+
+func (s *Service) ListSwapOrders(ctx context.Context, walletID, status string, limit, offset int) ([]models.SwapOrder, error) {
+	return s.repo.ListSwapOrders(ctx, walletID, status, limit, offset)
+}
+
+// GetSwapOrder returns an order placed on the wallet.
+func (s *Service) GetSwapOrder(ctx context.Context, walletID string, id uuid.UUID) (*models.SwapOrder, error) {
+	order, err := s.repo.GetSwapOrder(ctx, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, customError.ErrRecordNotFound
+		}
+		return nil, err
+	}
+	if order.WalletID != walletID {
+		return nil, customError.ErrRecordNotFound
+	}
+	return order, nil
+}
+
+// CancelSwapOrder closes an open order and returns its funds to the available
+// balance.
+func (s *Service) CancelSwapOrder(ctx context.Context, walletID string, id uuid.UUID) (*models.SwapOrder, error) {
+	order, err := s.GetSwapOrder(ctx, walletID, id)
+	if err != nil {
+		return nil, err
+	}
+	if order.Status != models.OrderOpen {
+		return nil, customError.ErrOrderNotOpen
+	}
+	if err := s.repo.CloseSwapOrder(ctx, id, models.OrderCancelled, nil); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, customError.ErrOrderNotOpen
+		}
+		return nil, err
+	}
+	return s.repo.GetSwapOrder(ctx, id)
+}
+
+// StartOrderMatcher fills open orders whenever the FX provider publishes new
+// rates. It stops when ctx is cancelled.
+func (s *Service) StartOrderMatcher(ctx context.Context) {
+	fx.WatchRates(ctx, s.fx, func(rates map[string]map[string]float64) {
+		s.matchOrders(ctx, rates)
+	})
+}
+
+func (s *Service) matchOrders(ctx context.Context, rates map[string]map[string]float64) {
+	now := time.Now()
+	for from, rateMap := range rates {
+		for to, rate := range rateMap {
+			orders, err := s.repo.ListTriggeredOrders(ctx, from, to, rate, now, orderBatchSize)
+			if err != nil {
+				fmt.Println("error listing triggered swap orders: ", err)
+				continue
+			}
+			for _, order := range orders {
+				s.fillOrder(ctx, order, rate)
+			}
+		}
+	}
+}
+
+// fillOrder swaps a triggered order at rate. An order that can never fill,
+// because the wallet was closed or the held funds fall short, is closed as
+// failed and its funds released. Anything else, such as a control, a limit, a
+// freeze or a database error, leaves it open to be tried again.
+func (s *Service) fillOrder(ctx context.Context, order models.SwapOrder, rate float64) *models.SwapOrder {
+	err := s.checkSwapControls(ctx, order.WalletID, order.FromCurrency, order.ToCurrency)
+	if err == nil {
+		err = s.checkLimits(ctx, order.WalletID, order.FromCurrency, order.Amount)
+	}
+	if err == nil {
+		fillCtx := s.holderActive(s.underLimits(ctx, order.WalletID, order.FromCurrency, order.Amount), order.WalletID)
+		filled, fillErr := s.repo.FillSwapOrder(fillCtx, order.ID, rate)
+		if fillErr == nil {
+			return filled
+		}
+		err = fillErr
+	}
+
+	switch {
+	case errors.Is(err, sql.ErrNoRows), errors.Is(err, customError.ErrOrderNotOpen):
+		// someone else filled, cancelled or expired it first
+	case orderFillTerminal(err):
+		reason := err.Error()
+		if err := s.repo.CloseSwapOrder(ctx, order.ID, models.OrderFailed, &reason); err != nil && !errors.Is(err, sql.ErrNoRows) {
+			fmt.Println("error closing swap order: ", err)
+		}
+	default:
+		fmt.Println("swap order not filled, leaving it open: ", order.ID, err)
+	}
+
+	current, err := s.repo.GetSwapOrder(ctx, order.ID)
+	if err != nil {
+		fmt.Println("error getting swap order: ", err)
+		return &order
+	}
+	return current
+}
+
+func orderFillTerminal(err error) bool {
+	return errors.Is(err, customError.ErrWalletClosed) ||
+		errors.Is(err, customError.ErrInsufficientBalance) ||
+		errors.Is(err, customError.ErrInvalidCapture)
+}
+
+// StartOrderExpiry periodically closes good-till-date orders that ran out and
+// releases their holds. It stops when ctx is cancelled.
+func (s *Service) StartOrderExpiry(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.expireOrders(ctx)
+		}
+	}
+}
+
+func (s *Service) expireOrders(ctx context.Context) {
+	ids, err := s.repo.ListExpiredOrders(ctx, time.Now(), orderBatchSize)
+	if err != nil {
+		fmt.Println("error listing expired swap orders: ", err)
+		return
+	}
+
+	for _, id := range ids {
+		err := s.repo.CloseSwapOrder(ctx, id, models.OrderExpired, nil)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			fmt.Println("error expiring swap order: ", err)
+		}
+	}
+}
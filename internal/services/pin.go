@@ -0,0 +1,127 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/toluhikay/fx-exchange/internal/dtos"
+	customError "github.com/toluhikay/fx-exchange/internal/errors"
+	"github.com/toluhikay/fx-exchange/internal/models"
+)
+
+func (us *UserServiceImpl) SetPin(ctx context.Context, userID uuid.UUID, req dtos.SetPin) error {
+	if err := validatePin(req.Pin, req.ConfirmPin); err != nil {
+		return err
+	}
+
+	user, err := us.getUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if user.HasPin() {
+		return customError.ErrPinAlreadySet
+	}
+
+	return us.savePin(ctx, user, req.Pin)
+}
+
+func (us *UserServiceImpl) ChangePin(ctx context.Context, userID uuid.UUID, req dtos.ChangePin) error {
+	if err := validatePin(req.Pin, req.ConfirmPin); err != nil {
+		return err
+	}
+
+	user, err := us.getUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	// the old pin counts towards the same lockout as any other pin check
+	if err := us.checkPin(ctx, user, req.OldPin); err != nil {
+		return err
+	}
+
+	return us.savePin(ctx, user, req.Pin)
+}
+
+// VerifyPin authorises a money movement. Repeated failures lock the pin for the
+// configured cool-down.
+func (us *UserServiceImpl) VerifyPin(ctx context.Context, userID uuid.UUID, pin string) error {
+	if pin == "" {
+		return customError.ErrPinRequired
+	}
+
+	user, err := us.getUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	return us.checkPin(ctx, user, pin)
+}
+
+// checkPin claims an attempt before comparing, so the lockout holds against
+// concurrent guesses, and hands it back when the pin is right.
+func (us *UserServiceImpl) checkPin(ctx context.Context, user *models.User, pin string) error {
+	if !user.HasPin() {
+		return customError.ErrPinNotSet
+	}
+
+	locked, err := us.userRepo.ClaimPinAttempt(ctx, user.ID, us.settings.PinMaxAttempts, time.Now().Add(us.settings.PinLockoutDuration))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return customError.ErrPinLocked
+		}
+		fmt.Println(err)
+		return customError.ErrInternalServer
+	}
+
+	ok, err := user.CompareHashedPin(pin)
+	if err != nil {
+		fmt.Println("error comparing pin: ", err)
+		return customError.ErrInternalServer
+	}
+	if !ok {
+		if locked {
+			return customError.ErrPinLocked
+		}
+		return customError.ErrInvalidPin
+	}
+
+	if err := us.userRepo.ResetPinFailures(ctx, user.ID); err != nil {
+		fmt.Println(err)
+	}
+
+	return nil
+}
+
+func (us *UserServiceImpl) savePin(ctx context.Context, user *models.User, pin string) error {
+	pinHash, err := user.HashPassword(pin)
+	if err != nil {
+		return customError.ErrInternalServer
+	}
+
+	if err := us.userRepo.SetTransactionPin(ctx, user.ID, pinHash); err != nil {
+		fmt.Println(err)
+		return customError.ErrInternalServer
+	}
+
+	return nil
+}
+
+func validatePin(pin, confirmPin string) error {
+	if len(pin) != 4 {
+		return customError.ErrPinLength
+	}
+	for _, c := range pin {
+		if c < '0' || c > '9' {
+			return customError.ErrPinLength
+		}
+	}
+	if pin != confirmPin {
+		return customError.ErrPinMismatch
+	}
+	return nil
+}
@@ -0,0 +1,246 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/toluhikay/fx-exchange/internal/config"
+	customError "github.com/toluhikay/fx-exchange/internal/errors"
+	"github.com/toluhikay/fx-exchange/internal/fx"
+	"github.com/toluhikay/fx-exchange/internal/models"
+	"github.com/toluhikay/fx-exchange/internal/repository"
+)
+
+// candleInterval is a candle size the rate history can be bucketed into and
+// the stored resolution it is built from.
+type candleInterval struct {
+	step       time.Duration
+	resolution string
+}
+
+var candleIntervals = map[string]candleInterval{
+	"1m":  {time.Minute, models.Resolution1m},
+	"5m":  {5 * time.Minute, models.Resolution1m},
+	"15m": {15 * time.Minute, models.Resolution1m},
+	"1h":  {time.Hour, models.Resolution1h},
+	"4h":  {4 * time.Hour, models.Resolution1h},
+	"1d":  {24 * time.Hour, models.Resolution1d},
+}
+
+// rateRollups are run in order, each from the one before it.
+var rateRollups = []struct {
+	resolution string
+	source     string
+	step       time.Duration
+}{
+	{models.Resolution1m, "", time.Minute},
+	{models.Resolution1h, models.Resolution1m, time.Hour},
+	{models.Resolution1d, models.Resolution1h, 24 * time.Hour},
+}
+
+func resolutionStep(resolution string) time.Duration {
+	for _, rollup := range rateRollups {
+		if rollup.resolution == resolution {
+			return rollup.step
+		}
+	}
+	return 0
+}
+
+const (
+	// defaultHistoryCandles is how many candles a history covers when no
+	// range is given.
+	defaultHistoryCandles = 100
+	// maxHistoryCandles caps the range of one history request.
+	maxHistoryCandles = 1000
+	// maxHistoryTicks caps the raw ticks returned with the candles.
+	maxHistoryTicks = 5000
+	// rollupDelay leaves ticks published right at the end of a bucket time
+	// to be written before the bucket is rolled up.
+	rollupDelay = 5 * time.Second
+	// rollupBatch is how many buckets one rollup statement covers.
+	rollupBatch = 1440
+	// pruneBatch is how many ticks one delete removes.
+	pruneBatch = 10000
+)
+
+// RateService serves current and historical FX rates for charts, and keeps
+// fx_rates small by rolling ticks up into candles and pruning old ones.
+type RateService struct {
+	repo     *repository.Repository
+	fx       fx.FXProvider
+	settings config.RateSettings
+}
+
+func NewRateService(repo *repository.Repository, fx fx.FXProvider, settings config.RateSettings) *RateService {
+	return &RateService{repo: repo, fx: fx, settings: settings}
+}
+
+// CurrentRates returns every current rate, keyed by from and then to
+// currency.
+func (rs *RateService) CurrentRates(ctx context.Context) (map[string]map[string]float64, error) {
+	return rs.fx.GetRates(ctx)
+}
+
+// History returns a pair, written FROM/TO, as candles of interval and raw
+// ticks between from and to. A zero to means now and a zero from covers the
+// last defaultHistoryCandles intervals.
+func (rs *RateService) History(ctx context.Context, pair, interval string, from, to time.Time) (*models.RateHistory, error) {
+	fromCurrency, toCurrency, ok := strings.Cut(pair, "/")
+	if !ok || fromCurrency == "" || toCurrency == "" || fromCurrency == toCurrency {
+		return nil, fmt.Errorf("%w: pair must be written FROM/TO", customError.ErrInvalidRateQuery)
+	}
+	if _, err := rs.fx.GetRate(ctx, fromCurrency, toCurrency); err != nil {
+		return nil, fmt.Errorf("%w: %v", customError.ErrInvalidRateQuery, err)
+	}
+
+	if interval == "" {
+		interval = "1h"
+	}
+	ci, ok := candleIntervals[interval]
+	if !ok {
+		return nil, fmt.Errorf("%w: interval must be one of 1m, 5m, 15m, 1h, 4h or 1d", customError.ErrInvalidRateQuery)
+	}
+	step := ci.step
+
+	if to.IsZero() {
+		to = time.Now()
+	}
+	if from.IsZero() {
+		from = to.Add(-step * defaultHistoryCandles)
+	}
+	// start on a whole candle; time.Truncate works from midnight UTC
+	to, from = to.UTC(), from.UTC().Truncate(step)
+	if !from.Before(to) {
+		return nil, fmt.Errorf("%w: from must be before to", customError.ErrInvalidRateQuery)
+	}
+	if to.Sub(from) > step*maxHistoryCandles {
+		return nil, fmt.Errorf("%w: a %s history covers at most %s", customError.ErrInvalidRateQuery, interval, step*maxHistoryCandles)
+	}
+
+	candles, err := rs.repo.ListRateCandles(ctx, fromCurrency, toCurrency, from, to, step, ci.resolution, resolutionStep(ci.resolution))
+	if err != nil {
+		return nil, err
+	}
+	// ask for one more tick than is returned to know whether any were left out
+	ticks, err := rs.repo.ListRateTicks(ctx, fromCurrency, toCurrency, from, to, maxHistoryTicks+1)
+	if err != nil {
+		return nil, err
+	}
+	truncated := len(ticks) > maxHistoryTicks
+	if truncated {
+		ticks = ticks[:maxHistoryTicks]
+	}
+
+	return &models.RateHistory{
+		Pair:           pair,
+		Interval:       interval,
+		From:           from,
+		To:             to,
+		Candles:        candles,
+		Ticks:          ticks,
+		TicksTruncated: truncated,
+	}, nil
+}
+
+// StartRollups rolls ticks up into candles and prunes old ticks every
+// interval.
+func (rs *RateService) StartRollups(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := rs.rollup(ctx); err != nil {
+				fmt.Println("error rolling up fx rates: ", err)
+				continue
+			}
+			if err := rs.pruneTicks(ctx); err != nil {
+				fmt.Println("error pruning fx rates: ", err)
+			}
+		}
+	}
+}
+
+// rollup stores the candles of every bucket that has ended since the last
+// run. Each resolution stops where the one it is built from is complete, so
+// a failure leaves the rest for the next run.
+func (rs *RateService) rollup(ctx context.Context) error {
+	now := time.Now().UTC().Add(-rollupDelay)
+	for _, rollup := range rateRollups {
+		start, err := rs.rollupStart(ctx, rollup.resolution, rollup.source, rollup.step)
+		if err != nil {
+			return err
+		}
+		if start == nil {
+			return nil
+		}
+
+		end := now.Truncate(rollup.step)
+		for from := *start; from.Before(end); from = from.Add(rollup.step * rollupBatch) {
+			to := from.Add(rollup.step * rollupBatch)
+			if to.After(end) {
+				to = end
+			}
+			if err := rs.repo.RollupRateCandles(ctx, rollup.resolution, rollup.source, rollup.step, from, to); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// rollupStart is the first bucket of resolution not rolled up yet: the one
+// after the last stored candle, or the bucket of the earliest point in
+// source. It is nil when there is nothing to roll up.
+func (rs *RateService) rollupStart(ctx context.Context, resolution, source string, step time.Duration) (*time.Time, error) {
+	last, err := rs.repo.LastRateCandle(ctx, resolution)
+	if err != nil {
+		return nil, err
+	}
+	if last != nil {
+		next := last.Add(step)
+		return &next, nil
+	}
+
+	first, err := rs.repo.FirstRatePoint(ctx, source)
+	if err != nil || first == nil {
+		return nil, err
+	}
+	start := first.Truncate(step)
+	return &start, nil
+}
+
+// pruneTicks deletes raw ticks older than the retention window. Ticks are
+// kept until every resolution has rolled them up, because history reads the
+// ticks past the last stored candle.
+func (rs *RateService) pruneTicks(ctx context.Context) error {
+	cutoff := time.Now().UTC().Add(-rs.settings.TickRetention)
+	for _, rollup := range rateRollups {
+		start, err := rs.rollupStart(ctx, rollup.resolution, rollup.source, rollup.step)
+		if err != nil {
+			return err
+		}
+		if start == nil {
+			return nil
+		}
+		if start.Before(cutoff) {
+			cutoff = *start
+		}
+	}
+
+	for {
+		deleted, err := rs.repo.PruneRateTicks(ctx, cutoff, pruneBatch)
+		if err != nil {
+			return err
+		}
+		if deleted < pruneBatch {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package services
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/toluhikay/fx-exchange/internal/cron"
+	customError "github.com/toluhikay/fx-exchange/internal/errors"
+	"github.com/toluhikay/fx-exchange/internal/models"
+)
+
+// recurrence is when a scheduled transfer or swap plan runs.
+type recurrence struct {
+	frequency string
+	cronExpr  *string
+	start     time.Time
+	end       *time.Time
+}
+
+// parseRecurrence validates a schedule from a request and returns it with its
+// first run. start defaults to now.
+func parseRecurrence(frequency, cronExpr string, start, end *time.Time) (recurrence, time.Time, error) {
+	rc := recurrence{
+		frequency: strings.ToLower(frequency),
+		start:     time.Now().UTC().Truncate(time.Second),
+	}
+	if !slices.Contains(models.ScheduleFrequencies, rc.frequency) {
+		return rc, time.Time{}, fmt.Errorf("%w: frequency must be one of %s", customError.ErrInvalidSchedule, strings.Join(models.ScheduleFrequencies, ", "))
+	}
+	if rc.frequency == models.FrequencyCron {
+		expr := strings.TrimSpace(cronExpr)
+		if _, err := cron.Parse(expr); err != nil {
+			return rc, time.Time{}, fmt.Errorf("%w: %v", customError.ErrInvalidSchedule, err)
+		}
+		rc.cronExpr = &expr
+	}
+	if start != nil {
+		// allow for clock skew between the client and us
+		if start.Before(time.Now().Add(-time.Minute)) {
+			return rc, time.Time{}, fmt.Errorf("%w: start_at is in the past", customError.ErrInvalidSchedule)
+		}
+		rc.start = start.UTC()
+	}
+	if end != nil {
+		if !end.After(rc.start) {
+			return rc, time.Time{}, fmt.Errorf("%w: end_at must be after start_at", customError.ErrInvalidSchedule)
+		}
+		e := end.UTC()
+		rc.end = &e
+	}
+
+	first, ok := rc.next(rc.start.Add(-time.Nanosecond))
+	if !ok {
+		return rc, time.Time{}, fmt.Errorf("%w: the schedule never runs", customError.ErrInvalidSchedule)
+	}
+	return rc, first, nil
+}
+
+// next returns the first run strictly after t, or false when there are no
+// more runs.
+func (rc recurrence) next(t time.Time) (time.Time, bool) {
+	start := rc.start.UTC()
+	t = t.UTC()
+
+	var next time.Time
+	switch rc.frequency {
+	case models.FrequencyOnce:
+		if !start.After(t) {
+			return time.Time{}, false
+		}
+		next = start
+	case models.FrequencyDaily:
+		next = nextByDays(start, t, 1)
+	case models.FrequencyWeekly:
+		next = nextByDays(start, t, 7)
+	case models.FrequencyMonthly:
+		next = nextByMonths(start, t)
+	case models.FrequencyCron:
+		if rc.cronExpr == nil {
+			return time.Time{}, false
+		}
+		schedule, err := cron.Parse(*rc.cronExpr)
+		if err != nil {
+			return time.Time{}, false
+		}
+		if t.Before(start) {
+			t = start.Add(-time.Nanosecond)
+		}
+		next = schedule.Next(t)
+		if next.IsZero() {
+			return time.Time{}, false
+		}
+	default:
+		return time.Time{}, false
+	}
+
+	if rc.end != nil && next.After(*rc.end) {
+		return time.Time{}, false
+	}
+	return next, true
+}
+
+// nextByDays steps from start in whole multiples of days.
+func nextByDays(start, t time.Time, days int) time.Time {
+	if t.Before(start) {
+		return start
+	}
+	n := int(t.Sub(start) / (time.Duration(days) * 24 * time.Hour))
+	next := start.AddDate(0, 0, n*days)
+	for !next.After(t) {
+		n++
+		next = start.AddDate(0, 0, n*days)
+	}
+	return next
+}
+
+// nextByMonths steps from start a month at a time, keeping the day of the
+// month where it can and using the last day of shorter months.
+func nextByMonths(start, t time.Time) time.Time {
+	if t.Before(start) {
+		return start
+	}
+	n := (t.Year()-start.Year())*12 + int(t.Month()) - int(start.Month()) - 1
+	if n < 0 {
+		n = 0
+	}
+	next := addMonths(start, n)
+	for !next.After(t) {
+		n++
+		next = addMonths(start, n)
+	}
+	return next
+}
+
+func addMonths(start time.Time, n int) time.Time {
+	firstOfMonth := time.Date(start.Year(), start.Month()+time.Month(n), 1, start.Hour(), start.Minute(), start.Second(), 0, time.UTC)
+	lastDay := firstOfMonth.AddDate(0, 1, -1).Day()
+	return firstOfMonth.AddDate(0, 0, min(start.Day(), lastDay)-1)
+}
@@ -0,0 +1,58 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/toluhikay/fx-exchange/internal/models"
+)
+
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 9, 30, 0, 0, time.UTC)
+}
+
+func TestNextMonthlyRollsOverMonthEnd(t *testing.T) {
+	tests := []struct {
+		name  string
+		start time.Time
+		after time.Time
+		want  time.Time
+	}{
+		{"before the start runs at the start", date(2026, 1, 31), date(2026, 1, 1), date(2026, 1, 31)},
+		{"31st falls back to the end of february", date(2026, 1, 31), date(2026, 1, 31), date(2026, 2, 28)},
+		{"leap years keep the 29th", date(2028, 1, 31), date(2028, 1, 31), date(2028, 2, 29)},
+		{"back to the 31st after february", date(2026, 1, 31), date(2026, 2, 28), date(2026, 3, 31)},
+		{"30 day month after a 31 day one", date(2026, 1, 31), date(2026, 3, 31), date(2026, 4, 30)},
+		{"30th stays on the 30th after february", date(2026, 1, 30), date(2026, 2, 28), date(2026, 3, 30)},
+		{"29th in a common year", date(2025, 12, 29), date(2026, 1, 29), date(2026, 2, 28)},
+		{"across the year end", date(2026, 8, 31), date(2026, 11, 30), date(2026, 12, 31)},
+		{"into the next year", date(2026, 8, 31), date(2026, 12, 31), date(2027, 1, 31)},
+		{"between runs", date(2026, 1, 31), date(2026, 4, 10), date(2026, 4, 30)},
+		{"same day later in the month", date(2026, 1, 15), date(2026, 5, 15).Add(-time.Minute), date(2026, 5, 15)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rc := recurrence{frequency: models.FrequencyMonthly, start: tt.start}
+			got, ok := rc.next(tt.after)
+			if !ok {
+				t.Fatalf("next(%s) reported no more runs", tt.after)
+			}
+			if !got.Equal(tt.want) {
+				t.Errorf("next(%s) = %s, want %s", tt.after, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNextStopsAtEnd(t *testing.T) {
+	end := date(2026, 3, 31)
+	rc := recurrence{frequency: models.FrequencyMonthly, start: date(2026, 1, 31), end: &end}
+
+	if got, ok := rc.next(date(2026, 2, 28)); !ok || !got.Equal(end) {
+		t.Errorf("next run = %s, %v, want %s", got, ok, end)
+	}
+	if got, ok := rc.next(end); ok {
+		t.Errorf("next run after the end = %s, want none", got)
+	}
+}
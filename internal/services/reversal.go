@@ -0,0 +1,49 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+
+	customError "github.com/toluhikay/fx-exchange/internal/errors"
+	"github.com/toluhikay/fx-exchange/internal/models"
+)
+
+// GetTransactionReversals returns a transaction together with every reversal
+// written against it.
+func (s *Service) GetTransactionReversals(ctx context.Context, id string) (*models.ReversalResponse, error) {
+	original, err := s.repo.GetTransaction(ctx, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, customError.ErrRecordNotFound
+		}
+		return nil, err
+	}
+	reversals, err := s.repo.ListReversals(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return &models.ReversalResponse{Original: original, Reversals: reversals}, nil
+}
+
+// ReverseTransaction refunds all or part of a swap or transfer. Limits and
+// risk rules do not apply; reversals correct operations that already passed
+// them.
+func (s *Service) ReverseTransaction(ctx context.Context, id string, req models.ReversalRequest) (*models.ReversalResponse, error) {
+	reason := strings.TrimSpace(req.Reason)
+	if reason == "" {
+		return nil, customError.ErrInvalidPayload
+	}
+	if req.Amount < 0 {
+		return nil, customError.ErrInvalidReversal
+	}
+
+	if _, err := s.repo.ReverseTransaction(ctx, id, req.Amount, reason, req.Force); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, customError.ErrRecordNotFound
+		}
+		return nil, err
+	}
+	return s.GetTransactionReversals(ctx, id)
+}
@@ -0,0 +1,101 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/google/uuid"
+	customError "github.com/toluhikay/fx-exchange/internal/errors"
+	"github.com/toluhikay/fx-exchange/internal/fraud"
+	"github.com/toluhikay/fx-exchange/internal/models"
+)
+
+// UserScreener checks new accounts against the sanctions list.
+type UserScreener interface {
+	ScreenNewUser(user *models.User) []models.RuleHit
+	CreateUserForReview(ctx context.Context, user models.User, hits []models.RuleHit) (*models.User, error)
+}
+
+// ScreenNewUser returns the sanctions matches for an account about to be
+// registered, nil when its name is clear.
+func (s *Service) ScreenNewUser(user *models.User) []models.RuleHit {
+	return s.sanctionsHits(user.ID, user.Name, nil)
+}
+
+// CreateUserForReview registers an account whose name resembles a listed one.
+// It is created frozen together with a case for compliance to review, in one
+// transaction, so it is never usable and never exists without the case.
+func (s *Service) CreateUserForReview(ctx context.Context, user models.User, hits []models.RuleHit) (*models.User, error) {
+	return s.repo.CreateUserForReview(ctx, user, models.ComplianceCase{
+		Source:    models.CaseSourceSanctions,
+		Action:    fraud.ActionReview,
+		Status:    models.CaseOpen,
+		Operation: models.CaseOperation{Type: models.OpRegistration},
+		Hits:      hits,
+	})
+}
+
+// screenParties checks both sides of a transfer, or the wallet holder alone
+// for operations without a receiver, against the sanctions list and holds the
+// operation for review on any match.
+func (s *Service) screenParties(ctx context.Context, op models.CaseOperation) error {
+	senderID, senderName, err := s.repo.GetWalletHolder(ctx, op.WalletID)
+	if err != nil {
+		return fmt.Errorf("failed to get sender wallet: %w", err)
+	}
+	senderCleared, err := s.repo.ListSanctionsClearances(ctx, senderID)
+	if err != nil {
+		return err
+	}
+	hits := s.sanctionsHits(senderID, senderName, senderCleared)
+
+	if op.ReceiverID != "" {
+		receiverID, receiverName, err := s.repo.GetWalletHolder(ctx, op.ReceiverID)
+		if err != nil {
+			return fmt.Errorf("failed to get receiver wallet: %w", err)
+		}
+		receiverCleared, err := s.repo.ListSanctionsClearances(ctx, receiverID)
+		if err != nil {
+			return err
+		}
+		hits = append(hits, s.sanctionsHits(receiverID, receiverName, receiverCleared)...)
+	}
+	if len(hits) == 0 {
+		return nil
+	}
+
+	created, err := s.repo.CreateComplianceCase(ctx, models.ComplianceCase{
+		UserID:    senderID,
+		WalletID:  op.WalletID,
+		Source:    models.CaseSourceSanctions,
+		Action:    fraud.ActionReview,
+		Status:    models.CaseOpen,
+		Operation: op,
+		Hits:      hits,
+	})
+	if err != nil {
+		return err
+	}
+	return customError.NewCodedError(models.CodeHeldForReview,
+		fmt.Errorf("%w: case %s, the %s will go through once approved", customError.ErrHeldForReview, created.ID, op.Type))
+}
+
+// sanctionsHits screens one name, skipping entries staff already cleared for
+// that user.
+func (s *Service) sanctionsHits(userID uuid.UUID, name string, cleared []string) []models.RuleHit {
+	var hits []models.RuleHit
+	for _, match := range s.sanctions.Screen(name) {
+		if slices.Contains(cleared, match.EntryID) {
+			continue
+		}
+		hits = append(hits, models.RuleHit{
+			Rule:      "sanctions",
+			Action:    fraud.ActionReview,
+			Reason:    fmt.Sprintf("%q resembles listed name %q (%s, score %.2f)", name, match.ListedName, match.Program, match.Score),
+			Subject:   userID.String(),
+			ListEntry: match.EntryID,
+		})
+	}
+	return hits
+}
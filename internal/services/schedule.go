@@ -0,0 +1,277 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	customError "github.com/toluhikay/fx-exchange/internal/errors"
+	"github.com/toluhikay/fx-exchange/internal/fraud"
+	"github.com/toluhikay/fx-exchange/internal/models"
+)
+
+const (
+	scheduleBatchSize = 100
+	// scheduleLease is how long a claimed run stays invisible to other
+	// runners while it executes.
+	scheduleLease = 15 * time.Minute
+)
+
+// ScheduleTransfer sets up a one-off or recurring transfer. The usual checks
+// (limits, spending controls, screening and approvals) run each time the
+// transfer goes out, not when it is scheduled.
+func (s *Service) ScheduleTransfer(ctx context.Context, userID uuid.UUID, walletID string, req models.ScheduleTransferRequest) (*models.ScheduledTransfer, error) {
+	if req.Amount <= 0 {
+		return nil, fmt.Errorf("%w: amount must be positive", customError.ErrInvalidSchedule)
+	}
+	if req.ReceiverID == walletID {
+		return nil, fmt.Errorf("%w: cannot schedule a transfer to your own wallet", customError.ErrInvalidSchedule)
+	}
+
+	wallet, err := s.repo.GetWallet(ctx, walletID)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := wallet.Balances[req.Currency]; !ok {
+		return nil, fmt.Errorf("%w: unsupported currency %s", customError.ErrInvalidSchedule, req.Currency)
+	}
+	if _, err := s.repo.GetWallet(ctx, req.ReceiverID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, customError.ErrRecordNotFound
+		}
+		return nil, fmt.Errorf("failed to get receiver wallet: %w", err)
+	}
+
+	rc, first, err := parseRecurrence(req.Frequency, req.Cron, req.StartAt, req.EndAt)
+	if err != nil {
+		return nil, err
+	}
+
+	st := models.ScheduledTransfer{
+		WalletID:         walletID,
+		ReceiverWalletID: req.ReceiverID,
+		CreatedBy:        userID,
+		Currency:         req.Currency,
+		Amount:           req.Amount,
+		Frequency:        rc.frequency,
+		CronExpr:         rc.cronExpr,
+		StartAt:          rc.start,
+		EndAt:            rc.end,
+		NextRunAt:        &first,
+		Device:           fraud.DeviceFromContext(ctx),
+	}
+	if note := strings.TrimSpace(req.Note); note != "" {
+		st.Note = &note
+	}
+
+	return s.repo.CreateScheduledTransfer(ctx, st)
+}
+
+func (s *Service) ListScheduledTransfers(ctx context.Context, walletID string, limit, offset int) ([]models.ScheduledTransfer, error) {
+	return s.repo.ListScheduledTransfers(ctx, walletID, limit, offset)
+}
+
+// GetScheduledTransfer returns a schedule owned by the wallet.
+func (s *Service) GetScheduledTransfer(ctx context.Context, walletID string, id uuid.UUID) (*models.ScheduledTransfer, error) {
+	st, err := s.repo.GetScheduledTransfer(ctx, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, customError.ErrRecordNotFound
+		}
+		return nil, err
+	}
+	if st.WalletID != walletID {
+		return nil, customError.ErrRecordNotFound
+	}
+	return st, nil
+}
+
+func (s *Service) ListScheduledTransferRuns(ctx context.Context, walletID string, id uuid.UUID, limit, offset int) ([]models.ScheduledTransferRun, error) {
+	if _, err := s.GetScheduledTransfer(ctx, walletID, id); err != nil {
+		return nil, err
+	}
+	return s.repo.ListScheduledTransferRuns(ctx, id, limit, offset)
+}
+
+// SkipScheduledTransfer drops the next run, including a pending retry, and
+// moves the schedule on to the one after. Skipping the last run completes the
+// schedule.
+func (s *Service) SkipScheduledTransfer(ctx context.Context, walletID string, id uuid.UUID) (*models.ScheduledTransfer, error) {
+	st, err := s.GetScheduledTransfer(ctx, walletID, id)
+	if err != nil {
+		return nil, err
+	}
+	if st.NextRunAt == nil || st.Status == models.ScheduleCancelled || st.Status == models.ScheduleCompleted {
+		return nil, customError.ErrScheduleFinished
+	}
+
+	var next *time.Time
+	if t, ok := scheduleRecurrence(*st).next(*st.NextRunAt); ok {
+		next = &t
+	}
+	run := models.ScheduledTransferRun{
+		ScheduleID:   st.ID,
+		ScheduledFor: *st.NextRunAt,
+		Attempt:      st.RetryCount + 1,
+		Status:       models.RunSkipped,
+		CreatedAt:    time.Now(),
+	}
+	if err := s.repo.SkipScheduledRun(ctx, run, next); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, customError.ErrInvalidScheduleChange
+		}
+		return nil, err
+	}
+	return s.repo.GetScheduledTransfer(ctx, id)
+}
+
+// PauseScheduledTransfer stops runs until the schedule is resumed.
+func (s *Service) PauseScheduledTransfer(ctx context.Context, walletID string, id uuid.UUID) (*models.ScheduledTransfer, error) {
+	st, err := s.GetScheduledTransfer(ctx, walletID, id)
+	if err != nil {
+		return nil, err
+	}
+	return s.setScheduleStatus(ctx, st, []string{models.ScheduleActive}, models.SchedulePaused, st.NextRunAt)
+}
+
+// ResumeScheduledTransfer restarts a paused schedule. Runs that fell due
+// while it was paused are not made up; it carries on from the next one.
+func (s *Service) ResumeScheduledTransfer(ctx context.Context, walletID string, id uuid.UUID) (*models.ScheduledTransfer, error) {
+	st, err := s.GetScheduledTransfer(ctx, walletID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	next := st.NextRunAt
+	if next == nil || next.Before(now) {
+		next = nil
+		if t, ok := scheduleRecurrence(*st).next(now); ok {
+			next = &t
+		}
+	}
+
+	status := models.ScheduleActive
+	if next == nil {
+		status = models.ScheduleCompleted
+	}
+	return s.setScheduleStatus(ctx, st, []string{models.SchedulePaused}, status, next)
+}
+
+func (s *Service) CancelScheduledTransfer(ctx context.Context, walletID string, id uuid.UUID) (*models.ScheduledTransfer, error) {
+	st, err := s.GetScheduledTransfer(ctx, walletID, id)
+	if err != nil {
+		return nil, err
+	}
+	return s.setScheduleStatus(ctx, st, []string{models.ScheduleActive, models.SchedulePaused}, models.ScheduleCancelled, nil)
+}
+
+func (s *Service) setScheduleStatus(ctx context.Context, st *models.ScheduledTransfer, from []string, status string, next *time.Time) (*models.ScheduledTransfer, error) {
+	if st.Status == models.ScheduleCancelled || st.Status == models.ScheduleCompleted {
+		return nil, customError.ErrScheduleFinished
+	}
+	if err := s.repo.SetScheduledTransferStatus(ctx, st.ID, from, status, next); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, customError.ErrInvalidScheduleChange
+		}
+		return nil, err
+	}
+	return s.repo.GetScheduledTransfer(ctx, st.ID)
+}
+
+// StartScheduler periodically runs the scheduled transfers and swap plans
+// that are due. It stops when ctx is cancelled.
+func (s *Service) StartScheduler(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.runDueTransfers(ctx)
+			s.runDueSwapPlans(ctx)
+		}
+	}
+}
+
+func (s *Service) runDueTransfers(ctx context.Context) {
+	now := time.Now()
+	due, err := s.repo.ListDueScheduledTransfers(ctx, now, scheduleBatchSize)
+	if err != nil {
+		fmt.Println("error listing due scheduled transfers: ", err)
+		return
+	}
+
+	for _, st := range due {
+		claimed, err := s.repo.ClaimScheduledTransfer(ctx, st.ID, *st.NextRunAt, now.Add(scheduleLease))
+		if err != nil {
+			fmt.Println("error claiming scheduled transfer: ", err)
+			continue
+		}
+		if !claimed {
+			continue
+		}
+		s.runScheduledTransfer(ctx, st)
+	}
+}
+
+// runScheduledTransfer sends one run through Transfer and records what
+// happened. A run that fails for lack of funds is retried after the retry
+// delay, unless that would run into the next occurrence.
+func (s *Service) runScheduledTransfer(ctx context.Context, st models.ScheduledTransfer) {
+	run := models.ScheduledTransferRun{
+		ScheduleID:   st.ID,
+		ScheduledFor: *st.NextRunAt,
+		Attempt:      st.RetryCount + 1,
+	}
+
+	// runs are judged as coming from the device that scheduled them
+	convertedAmount, rate, err := s.Transfer(fraud.WithDevice(ctx, st.Device), st.WalletID, st.ReceiverWalletID, st.Currency, st.Amount)
+	run.CreatedAt = time.Now()
+
+	var next *time.Time
+	if t, ok := scheduleRecurrence(st).next(run.CreatedAt); ok {
+		next = &t
+	}
+	retryCount := 0
+
+	switch {
+	case err == nil:
+		run.Status = models.RunSucceeded
+		run.ConvertedAmount = &convertedAmount
+		run.Rate = &rate
+	case errors.Is(err, customError.ErrPendingApproval):
+		run.Status = models.RunPendingApproval
+	case errors.Is(err, customError.ErrHeldForReview):
+		run.Status = models.RunHeldForReview
+	case errors.Is(err, customError.ErrInsufficientBalance) && st.RetryCount < s.schedules.MaxRetries:
+		retryAt := run.CreatedAt.Add(s.schedules.RetryDelay)
+		if next == nil || retryAt.Before(*next) {
+			run.Status = models.RunRetrying
+			retryCount = st.RetryCount + 1
+			next = &retryAt
+		} else {
+			run.Status = models.RunFailed
+		}
+	default:
+		run.Status = models.RunFailed
+	}
+	if err != nil {
+		msg := err.Error()
+		run.Error = &msg
+	}
+
+	if err := s.repo.RecordScheduledRun(ctx, run, next, retryCount); err != nil {
+		fmt.Println("error recording scheduled transfer run: ", err)
+	}
+}
+
+func scheduleRecurrence(st models.ScheduledTransfer) recurrence {
+	return recurrence{frequency: st.Frequency, cronExpr: st.CronExpr, start: st.StartAt, end: st.EndAt}
+}
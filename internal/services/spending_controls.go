@@ -0,0 +1,190 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	customError "github.com/toluhikay/fx-exchange/internal/errors"
+	"github.com/toluhikay/fx-exchange/internal/models"
+	"github.com/toluhikay/fx-exchange/internal/repository"
+)
+
+func (s *Service) GetSpendingControls(ctx context.Context, walletID string) (*models.SpendingControls, error) {
+	return s.repo.GetSpendingControls(ctx, walletID)
+}
+
+// SetSpendingControls replaces the wallet's controls after checking that every
+// currency is one the wallet holds and every counterparty is a wallet id.
+func (s *Service) SetSpendingControls(ctx context.Context, walletID string, controls models.SpendingControls) (*models.SpendingControls, error) {
+	if controls.MaxTransferUSD < 0 || controls.DailyOutgoingUSD < 0 {
+		return nil, fmt.Errorf("%w: amounts cannot be negative", customError.ErrInvalidControls)
+	}
+
+	wallet, err := s.repo.GetWallet(ctx, walletID)
+	if err != nil {
+		return nil, err
+	}
+
+	currencies := []string{}
+	for _, currency := range controls.AllowedCurrencies {
+		if _, ok := wallet.Balances[currency]; !ok {
+			return nil, fmt.Errorf("%w: unsupported currency %s", customError.ErrInvalidControls, currency)
+		}
+		if !slices.Contains(currencies, currency) {
+			currencies = append(currencies, currency)
+		}
+	}
+
+	counterparties := []string{}
+	for _, id := range controls.AllowedCounterparties {
+		parsed, err := uuid.Parse(strings.TrimSpace(id))
+		if err != nil {
+			return nil, fmt.Errorf("%w: %q is not a wallet id", customError.ErrInvalidControls, id)
+		}
+		if !slices.Contains(counterparties, parsed.String()) {
+			counterparties = append(counterparties, parsed.String())
+		}
+	}
+
+	controls.AllowedCurrencies = currencies
+	controls.AllowedCounterparties = counterparties
+
+	if err := s.repo.SetSpendingControls(ctx, walletID, controls); err != nil {
+		return nil, err
+	}
+	return &controls, nil
+}
+
+// checkTransferControls applies the sender's own controls to an outgoing
+// transfer. The daily total covers everything sent since midnight UTC, valued
+// at current rates. The transfer itself checks the daily total again under the
+// wallet lock, see underOutgoingControls.
+func (s *Service) checkTransferControls(ctx context.Context, senderID, receiverID, currency string, amount float64) error {
+	controls, err := s.repo.GetSpendingControls(ctx, senderID)
+	if err != nil {
+		return err
+	}
+
+	if err := checkCurrencyAllowed(controls, currency); err != nil {
+		return err
+	}
+
+	if len(controls.AllowedCounterparties) > 0 && !slices.Contains(controls.AllowedCounterparties, receiverID) {
+		return customError.NewCodedError(models.CodeCounterpartyNotAllowed,
+			fmt.Errorf("%w: wallet %s is not an allowed counterparty", customError.ErrSpendingControl, receiverID))
+	}
+
+	return s.checkOutgoingControls(ctx, controls, senderID, currency, amount)
+}
+
+// checkWithdrawalControls applies the owner's currency, size and daily
+// controls to a payout. Counterparties are wallets, so they only restrict
+// transfers.
+func (s *Service) checkWithdrawalControls(ctx context.Context, walletID, currency string, amount float64) error {
+	controls, err := s.repo.GetSpendingControls(ctx, walletID)
+	if err != nil {
+		return err
+	}
+
+	if err := checkCurrencyAllowed(controls, currency); err != nil {
+		return err
+	}
+
+	return s.checkOutgoingControls(ctx, controls, walletID, currency, amount)
+}
+
+func (s *Service) checkOutgoingControls(ctx context.Context, controls *models.SpendingControls, walletID, currency string, amount float64) error {
+	if controls.MaxTransferUSD == 0 && controls.DailyOutgoingUSD == 0 {
+		return nil
+	}
+
+	amountUSD, err := s.USDValue(ctx, currency, amount)
+	if err != nil {
+		return err
+	}
+
+	if controls.MaxTransferUSD > 0 && amountUSD > controls.MaxTransferUSD {
+		return customError.NewCodedError(models.CodeMaxTransferExceeded,
+			fmt.Errorf("%w: transfers are limited to %.2f USDx each", customError.ErrSpendingControl, controls.MaxTransferUSD))
+	}
+
+	return s.checkDailyOutgoing(ctx, controls, amountUSD, func(dayStart time.Time) (map[string]float64, error) {
+		return s.repo.GetOutgoingByCurrency(ctx, walletID, dayStart)
+	})
+}
+
+// underOutgoingControls has the next balance change of walletID check the
+// daily outgoing control again once the wallet row is locked, so concurrent
+// transfers and withdrawals cannot all fit under the same total. amounts is
+// what the change sends, per currency.
+func (s *Service) underOutgoingControls(ctx context.Context, walletID string, amounts map[string]float64) context.Context {
+	return repository.WithWalletCheck(ctx, walletID, func(w *repository.LockedWallet) error {
+		controls, err := s.repo.GetSpendingControls(ctx, walletID)
+		if err != nil {
+			return err
+		}
+		amountUSD := 0.0
+		for currency, amount := range amounts {
+			value, err := s.USDValue(ctx, currency, amount)
+			if err != nil {
+				return err
+			}
+			amountUSD += value
+		}
+		return s.checkDailyOutgoing(ctx, controls, amountUSD, w.OutgoingByCurrency)
+	})
+}
+
+func (s *Service) checkDailyOutgoing(ctx context.Context, controls *models.SpendingControls, amountUSD float64, outgoingSince func(since time.Time) (map[string]float64, error)) error {
+	if controls.DailyOutgoingUSD <= 0 {
+		return nil
+	}
+
+	now := time.Now().UTC()
+	dayStart := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+
+	totals, err := outgoingSince(dayStart)
+	if err != nil {
+		return err
+	}
+
+	sentUSD := 0.0
+	for c, total := range totals {
+		value, err := s.USDValue(ctx, c, total)
+		if err != nil {
+			return err
+		}
+		sentUSD += value
+	}
+
+	if sentUSD+amountUSD > controls.DailyOutgoingUSD {
+		return customError.NewCodedError(models.CodeDailyOutgoingExceeded,
+			fmt.Errorf("%w: daily outgoing limit is %.2f USDx, %.2f remaining", customError.ErrSpendingControl,
+				controls.DailyOutgoingUSD, max(controls.DailyOutgoingUSD-sentUSD, 0)))
+	}
+	return nil
+}
+
+func (s *Service) checkSwapControls(ctx context.Context, walletID, fromCurrency, toCurrency string) error {
+	controls, err := s.repo.GetSpendingControls(ctx, walletID)
+	if err != nil {
+		return err
+	}
+
+	if err := checkCurrencyAllowed(controls, fromCurrency); err != nil {
+		return err
+	}
+	return checkCurrencyAllowed(controls, toCurrency)
+}
+
+func checkCurrencyAllowed(controls *models.SpendingControls, currency string) error {
+	if len(controls.AllowedCurrencies) > 0 && !slices.Contains(controls.AllowedCurrencies, currency) {
+		return customError.NewCodedError(models.CodeCurrencyNotAllowed,
+			fmt.Errorf("%w: %s is not an allowed currency", customError.ErrSpendingControl, currency))
+	}
+	return nil
+}
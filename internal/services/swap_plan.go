@@ -0,0 +1,254 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	customError "github.com/toluhikay/fx-exchange/internal/errors"
+	"github.com/toluhikay/fx-exchange/internal/models"
+)
+
+// CreateSwapPlan sets up a recurring swap of a fixed amount. Each run goes
+// through Swap, so limits, spending controls and screening apply every time.
+func (s *Service) CreateSwapPlan(ctx context.Context, userID uuid.UUID, walletID string, req models.SwapPlanRequest) (*models.SwapPlan, error) {
+	if req.Amount <= 0 {
+		return nil, fmt.Errorf("%w: amount must be positive", customError.ErrInvalidSchedule)
+	}
+	if req.MaxRate < 0 {
+		return nil, fmt.Errorf("%w: max_rate cannot be negative", customError.ErrInvalidSchedule)
+	}
+	if req.FromCurrency == req.ToCurrency {
+		return nil, fmt.Errorf("%w: from_currency and to_currency must differ", customError.ErrInvalidSchedule)
+	}
+
+	wallet, err := s.repo.GetWallet(ctx, walletID)
+	if err != nil {
+		return nil, err
+	}
+	for _, currency := range []string{req.FromCurrency, req.ToCurrency} {
+		if _, ok := wallet.Balances[currency]; !ok {
+			return nil, fmt.Errorf("%w: unsupported currency %s", customError.ErrInvalidSchedule, currency)
+		}
+	}
+
+	rc, first, err := parseRecurrence(req.Frequency, req.Cron, req.StartAt, req.EndAt)
+	if err != nil {
+		return nil, err
+	}
+
+	plan := models.SwapPlan{
+		WalletID:     walletID,
+		CreatedBy:    userID,
+		FromCurrency: req.FromCurrency,
+		ToCurrency:   req.ToCurrency,
+		Amount:       req.Amount,
+		Frequency:    rc.frequency,
+		CronExpr:     rc.cronExpr,
+		StartAt:      rc.start,
+		EndAt:        rc.end,
+		NextRunAt:    &first,
+	}
+	if req.MaxRate > 0 {
+		plan.MaxRate = &req.MaxRate
+	}
+
+	return s.repo.CreateSwapPlan(ctx, plan)
+}
+
+func (s *Service) ListSwapPlans(ctx context.Context, walletID string, limit, offset int) ([]models.SwapPlan, error) {
+	return s.repo.ListSwapPlans(ctx, walletID, limit, offset)
+}
+
+// GetSwapPlan returns a plan owned by the wallet.
+func (s *Service) GetSwapPlan(ctx context.Context, walletID string, id uuid.UUID) (*models.SwapPlan, error) {
+	plan, err := s.repo.GetSwapPlan(ctx, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, customError.ErrRecordNotFound
+		}
+		return nil, err
+	}
+	if plan.WalletID != walletID {
+		return nil, customError.ErrRecordNotFound
+	}
+	return plan, nil
+}
+
+func (s *Service) ListSwapPlanRuns(ctx context.Context, walletID string, id uuid.UUID, limit, offset int) ([]models.SwapPlanRun, error) {
+	if _, err := s.GetSwapPlan(ctx, walletID, id); err != nil {
+		return nil, err
+	}
+	return s.repo.ListSwapPlanRuns(ctx, id, limit, offset)
+}
+
+// GetSwapPlanSummary totals what the plan has spent and received and the
+// average rate it achieved.
+func (s *Service) GetSwapPlanSummary(ctx context.Context, walletID string, id uuid.UUID) (*models.SwapPlanSummary, error) {
+	plan, err := s.GetSwapPlan(ctx, walletID, id)
+	if err != nil {
+		return nil, err
+	}
+	summary, err := s.repo.GetSwapPlanSummary(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	summary.FromCurrency = plan.FromCurrency
+	summary.ToCurrency = plan.ToCurrency
+	if summary.TotalSpent > 0 && summary.TotalReceived > 0 {
+		summary.AverageRate = summary.TotalReceived / summary.TotalSpent
+		summary.AveragePrice = summary.TotalSpent / summary.TotalReceived
+	}
+	return summary, nil
+}
+
+// PauseSwapPlan stops runs until the plan is resumed.
+func (s *Service) PauseSwapPlan(ctx context.Context, walletID string, id uuid.UUID) (*models.SwapPlan, error) {
+	plan, err := s.GetSwapPlan(ctx, walletID, id)
+	if err != nil {
+		return nil, err
+	}
+	return s.setSwapPlanStatus(ctx, plan, []string{models.ScheduleActive}, models.SchedulePaused, plan.NextRunAt)
+}
+
+// ResumeSwapPlan restarts a paused plan from its next run; runs missed while
+// it was paused are not made up.
+func (s *Service) ResumeSwapPlan(ctx context.Context, walletID string, id uuid.UUID) (*models.SwapPlan, error) {
+	plan, err := s.GetSwapPlan(ctx, walletID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	next := plan.NextRunAt
+	if next == nil || next.Before(now) {
+		next = nil
+		if t, ok := swapPlanRecurrence(*plan).next(now); ok {
+			next = &t
+		}
+	}
+
+	status := models.ScheduleActive
+	if next == nil {
+		status = models.ScheduleCompleted
+	}
+	return s.setSwapPlanStatus(ctx, plan, []string{models.SchedulePaused}, status, next)
+}
+
+func (s *Service) CancelSwapPlan(ctx context.Context, walletID string, id uuid.UUID) (*models.SwapPlan, error) {
+	plan, err := s.GetSwapPlan(ctx, walletID, id)
+	if err != nil {
+		return nil, err
+	}
+	return s.setSwapPlanStatus(ctx, plan, []string{models.ScheduleActive, models.SchedulePaused}, models.ScheduleCancelled, nil)
+}
+
+func (s *Service) setSwapPlanStatus(ctx context.Context, plan *models.SwapPlan, from []string, status string, next *time.Time) (*models.SwapPlan, error) {
+	if plan.Status == models.ScheduleCancelled || plan.Status == models.ScheduleCompleted {
+		return nil, customError.ErrScheduleFinished
+	}
+	if err := s.repo.SetSwapPlanStatus(ctx, plan.ID, from, status, next); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, customError.ErrInvalidScheduleChange
+		}
+		return nil, err
+	}
+	return s.repo.GetSwapPlan(ctx, plan.ID)
+}
+
+func (s *Service) runDueSwapPlans(ctx context.Context) {
+	now := time.Now()
+	due, err := s.repo.ListDueSwapPlans(ctx, now, scheduleBatchSize)
+	if err != nil {
+		fmt.Println("error listing due swap plans: ", err)
+		return
+	}
+
+	for _, plan := range due {
+		claimed, err := s.repo.ClaimSwapPlan(ctx, plan.ID, *plan.NextRunAt, now.Add(scheduleLease))
+		if err != nil {
+			fmt.Println("error claiming swap plan: ", err)
+			continue
+		}
+		if !claimed {
+			continue
+		}
+		s.runSwapPlan(ctx, plan)
+	}
+}
+
+// runSwapPlan swaps the plan's amount at the current rate unless the price is
+// above the plan's maximum. The swap goes out at the rate that was checked.
+// Failed runs are not retried; the plan carries on with its next run.
+func (s *Service) runSwapPlan(ctx context.Context, plan models.SwapPlan) {
+	run := models.SwapPlanRun{
+		PlanID:       plan.ID,
+		ScheduledFor: *plan.NextRunAt,
+		Amount:       plan.Amount,
+	}
+
+	rate, err := s.swapPlanRate(ctx, plan)
+	if err == nil {
+		err = s.checkSwap(ctx, plan.WalletID, plan.FromCurrency, plan.ToCurrency, plan.Amount)
+		if err == nil {
+			var convertedAmount float64
+			convertedAmount, rate, err = s.swapAt(ctx, plan.WalletID, plan.FromCurrency, plan.ToCurrency, plan.Amount, rate)
+			if err == nil {
+				run.ConvertedAmount = &convertedAmount
+				run.Rate = &rate
+			}
+		}
+	}
+	run.CreatedAt = time.Now()
+
+	switch {
+	case err == nil:
+		run.Status = models.RunSucceeded
+	case errors.Is(err, customError.ErrRateAboveMax):
+		run.Status = models.RunRateExceeded
+	case errors.Is(err, customError.ErrHeldForReview):
+		run.Status = models.RunHeldForReview
+	default:
+		run.Status = models.RunFailed
+	}
+	if err != nil {
+		msg := err.Error()
+		run.Error = &msg
+	}
+
+	var next *time.Time
+	if t, ok := swapPlanRecurrence(plan).next(run.CreatedAt); ok {
+		next = &t
+	}
+	if err := s.repo.RecordSwapPlanRun(ctx, run, next); err != nil {
+		fmt.Println("error recording swap plan run: ", err)
+	}
+}
+
+// swapPlanRate gets the rate for a run just before the swap goes out and
+// compares its price with the plan's maximum. The swap executes at this rate,
+// so the price checked is the price paid.
+func (s *Service) swapPlanRate(ctx context.Context, plan models.SwapPlan) (float64, error) {
+	rate, err := s.fx.GetRate(ctx, plan.FromCurrency, plan.ToCurrency)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get FX rate: %w", err)
+	}
+	if plan.MaxRate == nil {
+		return rate, nil
+	}
+	if rate <= 0 {
+		return 0, fmt.Errorf("invalid FX rate %v", rate)
+	}
+	if price := 1 / rate; price > *plan.MaxRate {
+		return 0, fmt.Errorf("%w: %.4f %s per %s", customError.ErrRateAboveMax, price, plan.FromCurrency, plan.ToCurrency)
+	}
+	return rate, nil
+}
+
+func swapPlanRecurrence(plan models.SwapPlan) recurrence {
+	return recurrence{frequency: plan.Frequency, cronExpr: plan.CronExpr, start: plan.StartAt, end: plan.EndAt}
+}
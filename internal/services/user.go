@@ -6,45 +6,53 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/toluhikay/fx-exchange/internal/config"
 	"github.com/toluhikay/fx-exchange/internal/dtos"
 	customError "github.com/toluhikay/fx-exchange/internal/errors"
+	"github.com/toluhikay/fx-exchange/internal/mailer"
 	"github.com/toluhikay/fx-exchange/internal/models"
+	"github.com/toluhikay/fx-exchange/internal/notifications"
 	"github.com/toluhikay/fx-exchange/internal/repository"
+	"github.com/toluhikay/fx-exchange/pkg/utils"
+	"golang.org/x/crypto/bcrypt"
 )
 
 type UserServiceImpl struct {
 	userRepo repository.UserDbRepo
+	mailer   mailer.Mailer
+	notifier notifications.Notifier
+	screener UserScreener
+	settings config.UserSettings
 }
 
-func NewUserService(ur repository.UserDbRepo) *UserServiceImpl {
+func NewUserService(ur repository.UserDbRepo, m mailer.Mailer, notifier notifications.Notifier, screener UserScreener, settings config.UserSettings) *UserServiceImpl {
 	return &UserServiceImpl{
 		userRepo: ur,
+		mailer:   m,
+		notifier: notifier,
+		screener: screener,
+		settings: settings,
 	}
 }
 
-func (us *UserServiceImpl) CreateUser(ctx context.Context, req dtos.RegisterUser) (*models.User, error) {
+// CreateUser registers an unverified user and emails them an otp. The returned
+// verification token has to be sent back together with the otp to VerifyEmail.
+func (us *UserServiceImpl) CreateUser(ctx context.Context, req dtos.RegisterUser) (*models.User, string, error) {
 	if req.Email == "" {
-		return &models.User{}, errors.New("email required")
+		return &models.User{}, "", errors.New("email required")
 	}
 
-	if len(req.Password) < 8 {
-		return &models.User{}, customError.ErrPasswordTooShort
-	}
-	if len(req.Password) > 32 {
-		return &models.User{}, customError.ErrPasswordTooLong
-	}
-
-	if req.Password != req.ConfirmPassword {
-		return &models.User{}, customError.ErrPasswordMismatch
-
+	if err := validatePassword(req.Password, req.ConfirmPassword); err != nil {
+		return &models.User{}, "", err
 	}
 
 	uId, err := uuid.NewV7()
 	if err != nil {
 		fmt.Println("err generating uuid")
-		return &models.User{}, customError.ErrInternalServer
+		return &models.User{}, "", customError.ErrInternalServer
 	}
 
 	user := models.User{
@@ -55,23 +63,171 @@ func (us *UserServiceImpl) CreateUser(ctx context.Context, req dtos.RegisterUser
 	hashedPassword, err := user.HashPassword(req.Password)
 	if err != nil {
 		fmt.Println("error hashing user password")
-		return &models.User{}, customError.ErrInternalServer
+		return &models.User{}, "", customError.ErrInternalServer
 	}
 
 	user.Password = hashedPassword
 
-	newUser, err := us.userRepo.CreateUser(ctx, user)
+	// matches are held for review rather than refused, so a false positive
+	// does not tell anyone which names are listed
+	var newUser *models.User
+	if hits := us.screener.ScreenNewUser(&user); len(hits) > 0 {
+		newUser, err = us.screener.CreateUserForReview(ctx, user, hits)
+	} else {
+		newUser, err = us.userRepo.CreateUser(ctx, user)
+	}
 
 	if err != nil {
 		fmt.Println(err, "error at create new user")
 		if strings.Contains(err.Error(), "duplicate key value") {
 			fmt.Println("error duplicate")
-			return nil, customError.ErrDuplicateEmail
+			return nil, "", customError.ErrDuplicateEmail
+		}
+		return nil, "", customError.ErrInternalServer
+	}
+
+	token, err := us.sendVerificationOtp(ctx, newUser)
+	if err != nil {
+		// the account exists at this point, the user can ask for a new otp
+		return newUser, "", err
+	}
+
+	return newUser, token, nil
+}
+
+// ResendVerificationOtp emails a fresh code to an unverified account, at most
+// OtpMaxResends times per OtpWindow. Once the payload is valid it always
+// reports success, logging any failure, so the endpoint cannot be used to
+// discover accounts. The token returned at registration stays valid for the
+// new code.
+func (us *UserServiceImpl) ResendVerificationOtp(ctx context.Context, req dtos.ResendOtp) error {
+	if req.Email == "" {
+		return customError.ErrInvalidPayload
+	}
+
+	user, err := us.userRepo.GetUserByEmail(ctx, req.Email)
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			fmt.Println("error getting user for otp resend: ", err)
+		}
+		return nil
+	}
+	if user.IsEmailVerified() {
+		return nil
+	}
+
+	if _, err := us.sendVerificationOtp(ctx, user); err != nil {
+		fmt.Println("error resending otp: ", err)
+	}
+	return nil
+}
+
+func (us *UserServiceImpl) VerifyEmail(ctx context.Context, req dtos.VerifyEmail) (*models.User, error) {
+	email, err := utils.Decrypt(us.settings.EncryptionKey, req.Token)
+	if err != nil {
+		return nil, customError.ErrInvalidOtp
+	}
+
+	user, err := us.userRepo.GetUserByEmail(ctx, email)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, customError.ErrInvalidOtp
+		}
+		return nil, customError.ErrInternalServer
+	}
+
+	if user.IsEmailVerified() {
+		return nil, customError.ErrEmailAlreadyVerified
+	}
+
+	verification, err := us.userRepo.GetActiveEmailVerification(ctx, user.ID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, customError.ErrInvalidOtp
 		}
 		return nil, customError.ErrInternalServer
 	}
 
-	return newUser, nil
+	// wrong guesses count across resends, so a new code does not reset them
+	attempts, err := us.userRepo.CountEmailVerificationAttempts(ctx, user.ID, time.Now().Add(-us.settings.OtpWindow))
+	if err != nil {
+		fmt.Println(err)
+		return nil, customError.ErrInternalServer
+	}
+	if attempts >= us.settings.OtpMaxAttempts {
+		return nil, customError.ErrTooManyOtpAttempts
+	}
+	if time.Now().After(verification.ExpiresAt) {
+		return nil, customError.ErrOtpExpired
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(verification.OtpHash), []byte(req.Otp)); err != nil {
+		if _, err := us.userRepo.IncrementEmailVerificationAttempts(ctx, verification.ID); err != nil {
+			fmt.Println(err)
+			return nil, customError.ErrInternalServer
+		}
+		if attempts+1 >= us.settings.OtpMaxAttempts {
+			return nil, customError.ErrTooManyOtpAttempts
+		}
+		return nil, customError.ErrInvalidOtp
+	}
+
+	if err := us.userRepo.MarkEmailVerified(ctx, verification.ID, user.ID); err != nil {
+		fmt.Println(err)
+		return nil, customError.ErrInternalServer
+	}
+
+	now := time.Now()
+	user.EmailVerifiedAt = &now
+
+	return user, nil
+}
+
+func (us *UserServiceImpl) sendVerificationOtp(ctx context.Context, user *models.User) (string, error) {
+	otp, err := utils.GenerateOtp()
+	if err != nil {
+		fmt.Println("error generating otp: ", err)
+		return "", customError.ErrInternalServer
+	}
+	otpHash, err := bcrypt.GenerateFromPassword([]byte(otp), bcrypt.DefaultCost)
+	if err != nil {
+		fmt.Println("error hashing otp: ", err)
+		return "", customError.ErrInternalServer
+	}
+
+	verification := models.EmailVerification{
+		ID:        uuid.New(),
+		UserID:    user.ID,
+		OtpHash:   string(otpHash),
+		ExpiresAt: time.Now().Add(us.settings.OtpExpireAt),
+	}
+	// the registration code counts towards the resends too
+	since := time.Now().Add(-us.settings.OtpWindow)
+	if err := us.userRepo.CreateEmailVerification(ctx, verification, since, us.settings.OtpMaxResends+1); err != nil {
+		if errors.Is(err, customError.ErrTooManyOtpResends) {
+			return "", err
+		}
+		fmt.Println(err)
+		return "", customError.ErrInternalServer
+	}
+
+	token, err := utils.Encrypt(us.settings.EncryptionKey, user.Email)
+	if err != nil {
+		return "", err
+	}
+
+	msg := mailer.Message{
+		To:      user.Email,
+		Subject: "Verify your email address",
+		Body: fmt.Sprintf("Hi %s,\n\nYour verification code is %s. It expires in %s.",
+			user.Name, otp, us.settings.OtpExpireAt),
+	}
+	if err := us.mailer.Send(ctx, msg); err != nil {
+		fmt.Println("error sending otp: ", err)
+		return "", customError.ErrSendingOtp
+	}
+
+	return token, nil
 }
 
 func (us *UserServiceImpl) GetUserById(ctx context.Context, id uuid.UUID) (*models.User, error) {
@@ -109,7 +265,7 @@ func (us *UserServiceImpl) GetUserByEmail(ctx context.Context, req dtos.UserLogi
 	return user, nil
 }
 
-func (us *UserServiceImpl) UserLogin(ctx context.Context, email, password string) (*models.User, error) {
+func (us *UserServiceImpl) UserLogin(ctx context.Context, email, password, clientIP string) (*models.User, error) {
 	if email == "" {
 		return nil, customError.ErrInvalidPayload
 	}
@@ -117,10 +273,15 @@ func (us *UserServiceImpl) UserLogin(ctx context.Context, email, password string
 		return nil, customError.ErrInvalidPayload
 	}
 
+	if err := us.checkLoginThrottle(ctx, email, clientIP); err != nil {
+		return nil, err
+	}
+
 	user, err := us.userRepo.GetUserByEmail(ctx, email)
 	if err != nil {
 		fmt.Println(err, "here 1")
 		if errors.Is(err, sql.ErrNoRows) {
+			us.recordLoginFailure(ctx, nil, email, clientIP)
 			return nil, customError.ErrInvalidCredentials
 		}
 		return nil, customError.ErrInternalServer
@@ -128,12 +289,176 @@ func (us *UserServiceImpl) UserLogin(ctx context.Context, email, password string
 
 	ok, err := user.CompareHashedPassword(password)
 	if !ok {
+		us.recordLoginFailure(ctx, user, email, clientIP)
 		return nil, customError.ErrInvalidCredentials
 	}
 	if err != nil {
 		return nil, customError.ErrInvalidCredentials
 	}
 
+	if err := us.userRepo.ClearLoginThrottle(ctx, accountThrottleKey(email)); err != nil {
+		fmt.Println(err)
+	}
+
 	return user, nil
 
 }
+
+// ForgotPassword emails a single-use reset token. Once the payload is valid it
+// always reports success, logging any failure, so the endpoint cannot be used
+// to discover accounts.
+func (us *UserServiceImpl) ForgotPassword(ctx context.Context, req dtos.ForgotPassword) error {
+	if req.Email == "" {
+		return customError.ErrInvalidPayload
+	}
+
+	user, err := us.userRepo.GetUserByEmail(ctx, req.Email)
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			fmt.Println("error getting user for password reset: ", err)
+		}
+		return nil
+	}
+
+	token, err := utils.GenerateSecureToken(32)
+	if err != nil {
+		fmt.Println("error generating reset token: ", err)
+		return nil
+	}
+
+	reset := models.PasswordReset{
+		ID:        uuid.New(),
+		UserID:    user.ID,
+		TokenHash: utils.HashToken(token),
+		ExpiresAt: time.Now().Add(us.settings.PasswordResetExpireAt),
+	}
+	if err := us.userRepo.CreatePasswordReset(ctx, reset); err != nil {
+		fmt.Println("error creating password reset: ", err)
+		return nil
+	}
+
+	msg := mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Hi %s,\n\nUse this token to reset your password: %s\nIt expires in %s and can only be used once. If you did not request a reset you can ignore this email.",
+			user.Name, token, us.settings.PasswordResetExpireAt),
+	}
+	if err := us.mailer.Send(ctx, msg); err != nil {
+		fmt.Println("error sending reset email: ", err)
+	}
+
+	return nil
+}
+
+func (us *UserServiceImpl) ResetPassword(ctx context.Context, req dtos.ResetPassword) error {
+	if err := validatePassword(req.Password, req.ConfirmPassword); err != nil {
+		return err
+	}
+
+	reset, err := us.userRepo.GetPasswordResetByTokenHash(ctx, utils.HashToken(req.Token))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return customError.ErrInvalidResetToken
+		}
+		return customError.ErrInternalServer
+	}
+	if reset.UsedAt != nil || time.Now().After(reset.ExpiresAt) {
+		return customError.ErrInvalidResetToken
+	}
+
+	var user models.User
+	hashedPassword, err := user.HashPassword(req.Password)
+	if err != nil {
+		return customError.ErrInternalServer
+	}
+
+	if _, err := us.userRepo.UpdatePassword(ctx, reset.UserID, hashedPassword, &reset.ID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return customError.ErrInvalidResetToken
+		}
+		fmt.Println(err)
+		return customError.ErrInternalServer
+	}
+
+	return nil
+}
+
+// ChangePassword revokes every existing session, including the caller's, and
+// returns the updated user so a fresh token pair can be issued.
+func (us *UserServiceImpl) ChangePassword(ctx context.Context, userID uuid.UUID, req dtos.ChangePassword) (*models.User, error) {
+	if err := validatePassword(req.Password, req.ConfirmPassword); err != nil {
+		return nil, err
+	}
+
+	user, err := us.userRepo.GetUserById(ctx, userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, customError.ErrInvalidCredentials
+		}
+		return nil, customError.ErrInternalServer
+	}
+
+	ok, err := user.CompareHashedPassword(req.OldPassword)
+	if err != nil || !ok {
+		return nil, customError.ErrInvalidCredentials
+	}
+
+	hashedPassword, err := user.HashPassword(req.Password)
+	if err != nil {
+		return nil, customError.ErrInternalServer
+	}
+
+	sessionVersion, err := us.userRepo.UpdatePassword(ctx, user.ID, hashedPassword, nil)
+	if err != nil {
+		fmt.Println(err)
+		return nil, customError.ErrInternalServer
+	}
+
+	user.Password = hashedPassword
+	user.SessionVersion = sessionVersion
+
+	msg := mailer.Message{
+		To:      user.Email,
+		Subject: "Your password was changed",
+		Body:    fmt.Sprintf("Hi %s,\n\nYour password was just changed and all other sessions were signed out. If this was not you, reset your password immediately.", user.Name),
+	}
+	if err := us.mailer.Send(ctx, msg); err != nil {
+		fmt.Println("error sending password change email: ", err)
+	}
+
+	return user, nil
+}
+
+// ValidateSession rejects tokens minted before the user's last password change.
+func (us *UserServiceImpl) ValidateSession(ctx context.Context, userID uuid.UUID, sessionVersion int) error {
+	user, err := us.userRepo.GetUserById(ctx, userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return customError.ErrUnauthorized
+		}
+		return customError.ErrInternalServer
+	}
+
+	if user.IsDeleted() {
+		return customError.ErrUnauthorized
+	}
+
+	if user.SessionVersion != sessionVersion {
+		return customError.ErrSessionRevoked
+	}
+
+	return nil
+}
+
+func validatePassword(password, confirmPassword string) error {
+	if len(password) < 8 {
+		return customError.ErrPasswordTooShort
+	}
+	if len(password) > 32 {
+		return customError.ErrPasswordTooLong
+	}
+	if password != confirmPassword {
+		return customError.ErrPasswordMismatch
+	}
+	return nil
+}
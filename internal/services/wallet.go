@@ -4,21 +4,41 @@ import (
 	"context"
 	"fmt"
 	"sync"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/toluhikay/fx-exchange/internal/config"
+	"github.com/toluhikay/fx-exchange/internal/fraud"
 	"github.com/toluhikay/fx-exchange/internal/fx"
+	"github.com/toluhikay/fx-exchange/internal/limits"
 	"github.com/toluhikay/fx-exchange/internal/models"
 	"github.com/toluhikay/fx-exchange/internal/repository"
+	"github.com/toluhikay/fx-exchange/internal/sanctions"
 )
 
 type Service struct {
-	repo repository.RepositoryImpl
-	fx   fx.FXProvider
-	mu   sync.Mutex
+	repo      repository.RepositoryImpl
+	fx        fx.FXProvider
+	kycLimits limits.Table
+	fraud     *fraud.Engine
+	sanctions *sanctions.Screener
+	approvals config.ApprovalSettings
+	holds     config.HoldSettings
+	schedules config.ScheduleSettings
+	mu        sync.Mutex
 }
 
-func NewService(repo *repository.Repository, fx fx.FXProvider) *Service {
-	return &Service{repo: repo, fx: fx}
+func NewService(repo *repository.Repository, fx fx.FXProvider, kycLimits limits.Table, fraudRules []fraud.Rule, screener *sanctions.Screener, approvals config.ApprovalSettings, holds config.HoldSettings, schedules config.ScheduleSettings) *Service {
+	return &Service{
+		repo:      repo,
+		fx:        fx,
+		kycLimits: kycLimits,
+		fraud:     fraud.NewEngine(fraudRules, repo),
+		sanctions: screener,
+		approvals: approvals,
+		holds:     holds,
+		schedules: schedules,
+	}
 }
 
 func (s *Service) CreateWallet(ctx context.Context, email string, userId uuid.UUID) (*models.Wallet, error) {
@@ -30,18 +50,52 @@ func (s *Service) GetWalletByUserId(ctx context.Context, userId uuid.UUID) (*mod
 }
 
 func (s *Service) Deposit(ctx context.Context, walletID, currency string, amount float64) error {
-	return s.repo.Deposit(ctx, walletID, currency, amount)
+	if err := s.checkLimits(ctx, walletID, currency, amount); err != nil {
+		return err
+	}
+	op := models.CaseOperation{Type: models.OpDeposit, WalletID: walletID, Currency: currency, Amount: amount}
+	if err := s.screen(ctx, op); err != nil {
+		return err
+	}
+	return s.repo.Deposit(s.underLimits(ctx, walletID, currency, amount), walletID, currency, amount)
 }
 
 func (s *Service) Swap(ctx context.Context, walletID, fromCurrency, toCurrency string, amount float64) (float64, float64, error) {
+	if err := s.checkSwap(ctx, walletID, fromCurrency, toCurrency, amount); err != nil {
+		return 0, 0, err
+	}
+	return s.swap(ctx, walletID, fromCurrency, toCurrency, amount)
+}
+
+// checkSwap runs a swap through the wallet's controls, the limits and the
+// risk rules.
+func (s *Service) checkSwap(ctx context.Context, walletID, fromCurrency, toCurrency string, amount float64) error {
+	if err := s.checkSwapControls(ctx, walletID, fromCurrency, toCurrency); err != nil {
+		return err
+	}
+	if err := s.checkLimits(ctx, walletID, fromCurrency, amount); err != nil {
+		return err
+	}
+	op := models.CaseOperation{Type: models.OpSwap, WalletID: walletID, Currency: fromCurrency, ToCurrency: toCurrency, Amount: amount}
+	return s.screen(ctx, op)
+}
+
+// swap executes a swap that has already passed the checks.
+func (s *Service) swap(ctx context.Context, walletID, fromCurrency, toCurrency string, amount float64) (float64, float64, error) {
 	fmt.Println(s.fx)
 	rate, err := s.fx.GetRate(ctx, fromCurrency, toCurrency)
 	if err != nil {
 		fmt.Println(err)
 		return 0, 0, fmt.Errorf("failed to get FX rate: %w", err)
 	}
+	return s.swapAt(ctx, walletID, fromCurrency, toCurrency, amount, rate)
+}
+
+// swapAt executes a checked swap at a rate the caller already has.
+func (s *Service) swapAt(ctx context.Context, walletID, fromCurrency, toCurrency string, amount, rate float64) (float64, float64, error) {
 	convertedAmount := amount * rate
-	err = s.repo.Swap(ctx, walletID, fromCurrency, toCurrency, amount, rate, convertedAmount)
+	ctx = s.holderActive(s.underLimits(ctx, walletID, fromCurrency, amount), walletID)
+	err := s.repo.Swap(ctx, walletID, fromCurrency, toCurrency, amount, rate, convertedAmount)
 	if err != nil {
 		return 0, 0, err
 	}
@@ -53,9 +107,48 @@ func (s *Service) Transfer(ctx context.Context, senderID, receiverID, currency s
 	if err != nil {
 		return 0, 0, fmt.Errorf("failed to get sender wallet: %w", err)
 	}
+	if err := s.checkTransferControls(ctx, senderID, receiverID, currency, amount); err != nil {
+		return 0, 0, err
+	}
+	if err := s.checkLimits(ctx, senderID, currency, amount); err != nil {
+		return 0, 0, err
+	}
+	op := models.CaseOperation{Type: models.OpTransfer, WalletID: senderID, ReceiverID: receiverID, Currency: currency, Amount: amount}
+	if err := s.screenParties(ctx, op); err != nil {
+		return 0, 0, err
+	}
+	if err := s.screen(ctx, op); err != nil {
+		return 0, 0, err
+	}
+	if err := s.requireApproval(ctx, op); err != nil {
+		return 0, 0, err
+	}
+	return s.transfer(ctx, senderID, receiverID, currency, amount)
+}
+
+// transfer executes a transfer that has already passed the checks, converting
+// into the receiver's currency when they do not hold the one sent.
+func (s *Service) transfer(ctx context.Context, senderID, receiverID, currency string, amount float64) (float64, float64, error) {
+	receiverCurrency, rate, convertedAmount, err := s.quoteTransfer(ctx, receiverID, currency, amount)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	ctx = s.underOutgoingControls(s.underLimits(ctx, senderID, currency, amount), senderID, map[string]float64{currency: amount})
+	ctx = s.holderActive(ctx, senderID)
+	err = s.repo.Transfer(ctx, senderID, receiverID, currency, receiverCurrency, amount, rate, convertedAmount)
+	if err != nil {
+		return 0, 0, err
+	}
+	return convertedAmount, rate, nil
+}
+
+// quoteTransfer works out the currency the receiver is credited in and how
+// much they get.
+func (s *Service) quoteTransfer(ctx context.Context, receiverID, currency string, amount float64) (string, float64, float64, error) {
 	receiver, err := s.repo.GetWallet(ctx, receiverID)
 	if err != nil {
-		return 0, 0, fmt.Errorf("failed to get receiver wallet: %w", err)
+		return "", 0, 0, fmt.Errorf("failed to get receiver wallet: %w", err)
 	}
 
 	receiverCurrency := currency
@@ -69,17 +162,105 @@ func (s *Service) Transfer(ctx context.Context, senderID, receiverID, currency s
 		if currency != receiverCurrency {
 			rate, err = s.fx.GetRate(ctx, currency, receiverCurrency)
 			if err != nil {
-				return 0, 0, fmt.Errorf("failed to get FX rate: %w", err)
+				return "", 0, 0, fmt.Errorf("failed to get FX rate: %w", err)
 			}
 			convertedAmount = amount * rate
 		}
 	}
+	return receiverCurrency, rate, convertedAmount, nil
+}
 
-	err = s.repo.Transfer(ctx, senderID, receiverID, currency, receiverCurrency, amount, rate, convertedAmount)
+func (s *Service) Withdraw(ctx context.Context, walletID, currency, destination string, amount float64) error {
+	if destination == "" {
+		return fmt.Errorf("withdrawal destination is required")
+	}
+	op := models.CaseOperation{Type: models.OpWithdrawal, WalletID: walletID, Currency: currency, Amount: amount, Destination: destination}
+	if err := s.checkWithdrawal(ctx, op); err != nil {
+		return err
+	}
+	return s.repo.Withdraw(s.underWithdrawalChecks(ctx, walletID, currency, amount), walletID, currency, destination, amount)
+}
+
+// checkWithdrawal runs a payout through the owner's spending controls, the
+// limits, the sanctions list and the risk rules.
+func (s *Service) checkWithdrawal(ctx context.Context, op models.CaseOperation) error {
+	if err := s.checkWithdrawalControls(ctx, op.WalletID, op.Currency, op.Amount); err != nil {
+		return err
+	}
+	if err := s.checkLimits(ctx, op.WalletID, op.Currency, op.Amount); err != nil {
+		return err
+	}
+	if err := s.screenParties(ctx, op); err != nil {
+		return err
+	}
+	return s.screen(ctx, op)
+}
+
+// underWithdrawalChecks has a payout check the limits, the daily outgoing
+// control and the owner's status again once the wallet is locked.
+func (s *Service) underWithdrawalChecks(ctx context.Context, walletID, currency string, amount float64) context.Context {
+	ctx = s.underOutgoingControls(s.underLimits(ctx, walletID, currency, amount), walletID, map[string]float64{currency: amount})
+	return s.holderActive(ctx, walletID)
+}
+
+// checkLimits enforces the caps of the wallet owner's kyc level. Daily and
+// monthly windows follow the UTC calendar. It fails an operation early; the
+// balance change itself checks again under the wallet lock, see underLimits.
+func (s *Service) checkLimits(ctx context.Context, walletID, currency string, amount float64) error {
+	return s.checkUsage(ctx, walletID, currency, amount, func(dayStart, monthStart time.Time) (limits.Usage, error) {
+		return s.repo.GetCurrencyUsage(ctx, walletID, currency, dayStart, monthStart)
+	})
+}
+
+// underLimits has the next balance change of walletID check the kyc limits
+// again once the wallet row is locked, so concurrent debits cannot all pass
+// on the same usage.
+func (s *Service) underLimits(ctx context.Context, walletID, currency string, amount float64) context.Context {
+	return repository.WithWalletCheck(ctx, walletID, func(w *repository.LockedWallet) error {
+		return s.checkUsage(ctx, walletID, currency, amount, func(dayStart, monthStart time.Time) (limits.Usage, error) {
+			return w.CurrencyUsage(currency, dayStart, monthStart)
+		})
+	})
+}
+
+// holderActive has the next balance change of walletID refused if its owner
+// was frozen or deleted. Requests are turned away for frozen users before
+// they get here; this covers what runs on their behalf later, such as
+// schedules, resting orders and approvals.
+func (s *Service) holderActive(ctx context.Context, walletID string) context.Context {
+	return repository.WithWalletCheck(ctx, walletID, func(w *repository.LockedWallet) error {
+		return w.CheckHolder()
+	})
+}
+
+func (s *Service) checkUsage(ctx context.Context, walletID, currency string, amount float64, usageSince func(dayStart, monthStart time.Time) (limits.Usage, error)) error {
+	level, err := s.repo.GetWalletKycLevel(ctx, walletID)
 	if err != nil {
-		return 0, 0, err
+		return err
 	}
-	return convertedAmount, rate, nil
+
+	now := time.Now().UTC()
+	dayStart := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	monthStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+
+	usage, err := usageSince(dayStart, monthStart)
+	if err != nil {
+		return err
+	}
+
+	return s.kycLimits.Check(level, currency, amount, usage)
+}
+
+// USDValue converts amount to its USDx equivalent at the current rate.
+func (s *Service) USDValue(ctx context.Context, currency string, amount float64) (float64, error) {
+	if currency == "USDx" {
+		return amount, nil
+	}
+	rate, err := s.fx.GetRate(ctx, currency, "USDx")
+	if err != nil {
+		return 0, fmt.Errorf("failed to get FX rate: %w", err)
+	}
+	return amount * rate, nil
 }
 
 func (s *Service) GetTransactionHistory(ctx context.Context, walletID string) ([]models.Transaction, error) {
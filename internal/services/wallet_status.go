@@ -0,0 +1,105 @@
+package services
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/google/uuid"
+	customError "github.com/toluhikay/fx-exchange/internal/errors"
+	"github.com/toluhikay/fx-exchange/internal/models"
+)
+
+// ChangeWalletStatus is used by staff to freeze, unfreeze or close a wallet.
+// actorID is the staff member making the change.
+func (s *Service) ChangeWalletStatus(ctx context.Context, walletID string, actorID uuid.UUID, req models.WalletStatusRequest) (*models.WalletStatusEvent, error) {
+	if !slices.Contains(models.WalletStatuses, req.Status) {
+		return nil, customError.ErrInvalidWalletStatus
+	}
+	if !slices.Contains(models.WalletReasonCodes, req.ReasonCode) {
+		return nil, customError.ErrInvalidReasonCode
+	}
+
+	event := models.WalletStatusEvent{
+		WalletID:   walletID,
+		ToStatus:   req.Status,
+		ReasonCode: req.ReasonCode,
+		ActorID:    &actorID,
+	}
+	if note := strings.TrimSpace(req.Note); note != "" {
+		event.Note = &note
+	}
+
+	return s.repo.ChangeWalletStatus(ctx, event, strings.TrimSpace(req.PayoutDestination), nil)
+}
+
+// CloseWallet lets owners close their own active wallet. Any remaining
+// balance is paid out to destination after going through the same checks as
+// a withdrawal.
+func (s *Service) CloseWallet(ctx context.Context, walletID string, userID uuid.UUID, destination string) (*models.WalletStatusEvent, error) {
+	destination = strings.TrimSpace(destination)
+	payout, err := s.checkClosingPayout(ctx, walletID, destination)
+	if err != nil {
+		return nil, err
+	}
+
+	event := models.WalletStatusEvent{
+		WalletID:   walletID,
+		ToStatus:   models.WalletClosed,
+		ReasonCode: models.ReasonCustomerRequest,
+		ActorID:    &userID,
+	}
+	return s.repo.ChangeWalletStatus(s.payoutUnderLimits(ctx, walletID, destination, payout), event, destination, payout)
+}
+
+// checkClosingPayout runs every balance a customer closing their wallet would
+// be paid out through checkWithdrawal and returns the balances it checked.
+// Without a destination there is nothing to check; the close itself refuses
+// a wallet that still holds funds.
+func (s *Service) checkClosingPayout(ctx context.Context, walletID, destination string) (map[string]float64, error) {
+	wallet, err := s.repo.GetWallet(ctx, walletID)
+	if err != nil {
+		return nil, err
+	}
+	if wallet.Status != models.WalletActive {
+		return nil, customError.ErrInvalidStatusChange
+	}
+
+	payout := map[string]float64{}
+	for currency, amount := range wallet.Balances {
+		if amount > 0 {
+			payout[currency] = amount
+		}
+	}
+	if destination == "" {
+		return payout, nil
+	}
+
+	currencies := slices.Sorted(maps.Keys(payout))
+	for _, currency := range currencies {
+		op := models.CaseOperation{Type: models.OpWithdrawal, WalletID: walletID, Currency: currency, Amount: payout[currency], Destination: destination}
+		if err := s.checkWithdrawal(ctx, op); err != nil {
+			return nil, err
+		}
+	}
+	return payout, nil
+}
+
+// payoutUnderLimits checks every balance of a closing payout against the
+// limits, and the whole payout against the daily outgoing control, again once
+// the wallet is locked. Without a destination nothing is paid out and the
+// close is refused anyway.
+func (s *Service) payoutUnderLimits(ctx context.Context, walletID, destination string, payout map[string]float64) context.Context {
+	if destination == "" {
+		return ctx
+	}
+	for currency, amount := range payout {
+		ctx = s.underLimits(ctx, walletID, currency, amount)
+	}
+	return s.underOutgoingControls(ctx, walletID, payout)
+}
+
+func (s *Service) GetWalletStatusEvents(ctx context.Context, walletID string) ([]models.WalletStatusEvent, error) {
+	return s.repo.GetWalletStatusEvents(ctx, walletID)
+}
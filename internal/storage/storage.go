@@ -0,0 +1,87 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/google/uuid"
+)
+
+type Config struct {
+	Dir string
+}
+
+// Store keeps uploaded files such as kyc documents. Object storage backends
+// only need to satisfy this interface to be plugged in.
+type Store interface {
+	// Save writes r under a new key and returns it along with the number of
+	// bytes written.
+	Save(ctx context.Context, r io.Reader) (string, int64, error)
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+}
+
+var ErrInvalidKey = errors.New("invalid storage key")
+
+// LocalStore keeps files in a directory on disk.
+type LocalStore struct {
+	dir string
+}
+
+func NewLocalStore(cfg Config) *LocalStore {
+	return &LocalStore{dir: cfg.Dir}
+}
+
+func (s *LocalStore) Save(ctx context.Context, r io.Reader) (string, int64, error) {
+	if err := os.MkdirAll(s.dir, 0o750); err != nil {
+		return "", 0, fmt.Errorf("failed to create storage directory: %w", err)
+	}
+
+	key := uuid.New().String()
+	f, err := os.OpenFile(filepath.Join(s.dir, key), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o640)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to create file: %w", err)
+	}
+
+	n, err := io.Copy(f, r)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return "", 0, fmt.Errorf("failed to write file: %w", err)
+	}
+
+	return key, n, nil
+}
+
+func (s *LocalStore) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	return os.Open(path)
+}
+
+func (s *LocalStore) Delete(ctx context.Context, key string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// path only accepts keys generated by Save so callers cannot escape dir.
+func (s *LocalStore) path(key string) (string, error) {
+	if _, err := uuid.Parse(key); err != nil {
+		return "", ErrInvalidKey
+	}
+	return filepath.Join(s.dir, key), nil
+}
@@ -19,6 +19,7 @@ type Auth struct {
 	Secret               string
 	TokenExpireAt        time.Duration
 	RefreshTokenExpireAt time.Duration
+	MfaTokenExpireAt     time.Duration
 }
 
 func NewAuth(auth Auth) *Auth {
@@ -28,12 +29,20 @@ func NewAuth(auth Auth) *Auth {
 		Secret:               auth.Secret,
 		TokenExpireAt:        auth.TokenExpireAt,
 		RefreshTokenExpireAt: auth.RefreshTokenExpireAt.Abs(),
+		MfaTokenExpireAt:     auth.MfaTokenExpireAt,
 	}
 }
 
+// PurposeMfaChallenge marks the short-lived token handed out between the
+// password and the second factor step of a login. It is not an access token.
+const PurposeMfaChallenge = "mfa_challenge"
+
 type JwtClaims struct {
-	ID    uuid.UUID `json:"id"`
-	Email string    `json:"email"`
+	ID             uuid.UUID `json:"id"`
+	Email          string    `json:"email"`
+	SessionVersion int       `json:"sv"`
+	Role           string    `json:"role"`
+	Purpose        string    `json:"purpose,omitempty"`
 	jwt.RegisteredClaims
 }
 
@@ -64,6 +73,20 @@ func (a *Auth) GetTokenFromHeaderAndVerify(w http.ResponseWriter, r *http.Reques
 
 	token := tokenParts[1]
 
+	claims, err := a.parseToken(token)
+	if err != nil {
+		return "", nil, err
+	}
+
+	// mfa challenge tokens only unlock the second login step
+	if claims.Purpose != "" {
+		return "", nil, customError.ErrUnauthorized
+	}
+
+	return token, claims, nil
+}
+
+func (a *Auth) parseToken(token string) (*JwtClaims, error) {
 	claims := &JwtClaims{}
 
 	_, err := jwt.ParseWithClaims(token, claims, func(token *jwt.Token) (any, error) {
@@ -76,16 +99,43 @@ func (a *Auth) GetTokenFromHeaderAndVerify(w http.ResponseWriter, r *http.Reques
 
 	if err != nil {
 		if strings.HasPrefix(err.Error(), "token expired by") {
-			return "", nil, errors.New("token expired")
+			return nil, errors.New("token expired")
 		}
-		return "", nil, err
+		return nil, err
 	}
 
 	if claims.Issuer != a.Issuer {
-		return "", nil, errors.New("issuer not verified")
+		return nil, errors.New("issuer not verified")
 	}
 
-	return token, claims, nil
+	return claims, nil
+}
+
+func (a *Auth) GenerateMfaChallengeToken(u models.User) (string, error) {
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"iss":     a.Issuer,
+		"id":      u.ID,
+		"aud":     a.Audience,
+		"sub":     fmt.Sprint(u.ID),
+		"sv":      u.SessionVersion,
+		"purpose": PurposeMfaChallenge,
+		"exp":     time.Now().Add(a.MfaTokenExpireAt).Unix(),
+	})
+
+	return token.SignedString([]byte(a.Secret))
+}
+
+func (a *Auth) VerifyMfaChallengeToken(token string) (*JwtClaims, error) {
+	claims, err := a.parseToken(token)
+	if err != nil {
+		return nil, customError.ErrUnauthorized
+	}
+
+	if claims.Purpose != PurposeMfaChallenge {
+		return nil, customError.ErrUnauthorized
+	}
+
+	return claims, nil
 }
 
 func (a *Auth) GenerateTokens(u models.User) (TokenPairs, error) {
@@ -96,6 +146,8 @@ func (a *Auth) GenerateTokens(u models.User) (TokenPairs, error) {
 		"id":    u.ID,
 		"aud":   a.Audience,
 		"sub":   fmt.Sprint(u.ID),
+		"sv":    u.SessionVersion,
+		"role":  u.Role,
 		"exp":   time.Now().Add(a.TokenExpireAt).Unix(),
 	})
 
@@ -4,15 +4,17 @@ import (
 	"crypto/aes"
 	"crypto/cipher"
 	cryptoRand "crypto/rand"
+	"crypto/sha256"
 	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
-	"math/rand"
+	"math/big"
+	"net"
 	"net/http"
 	"strings"
-	"time"
 
 	"maps"
 
@@ -21,22 +23,43 @@ import (
 	customErrors "github.com/toluhikay/fx-exchange/internal/errors"
 )
 
-func init() {
-	rand.New(rand.NewSource(time.Now().UnixNano()))
+// GenerateOtp returns a random 6 digit code.
+func GenerateOtp() (string, error) {
+	n, err := cryptoRand.Int(cryptoRand.Reader, big.NewInt(1000000))
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%06d", n.Int64()), nil
 }
 
-func GenerateOtp() string {
-	otp := fmt.Sprintf("%06d", rand.Intn(1000000))
-	return otp
+// GenerateSecureToken returns a hex encoded random token of n bytes, suitable
+// for links and keys that must not be guessable.
+func GenerateSecureToken(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := io.ReadFull(cryptoRand.Reader, b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
 }
 
-func GeneratePassword() string {
-	otp := fmt.Sprintf("%x", rand.Intn(999999999))
-	return otp
+// HashToken is used to store tokens at rest; unlike passwords they carry enough
+// entropy that a fast hash is sufficient.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func GeneratePassword() (string, error) {
+	n, err := cryptoRand.Int(cryptoRand.Reader, big.NewInt(999999999))
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%x", n.Int64()), nil
 }
 
 type JSONResponse struct {
 	Error   bool        `json:"error"`
+	Code    string      `json:"code,omitempty"`
 	Message string      `json:"message"`
 	Data    interface{} `json:"data"`
 }
@@ -128,6 +151,30 @@ func ReadJSON(w http.ResponseWriter, r *http.Request, data interface{}) error {
 	return nil
 }
 
+// ClientIP returns the address of the client. Behind a load balancer the
+// RealIP middleware has already replaced RemoteAddr with the client address
+// taken from the trusted proxy headers, so the headers are never read here.
+func ClientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// DeviceFingerprint identifies the client device from the X-Device-ID header
+// apps send, falling back to the user agent for browsers.
+func DeviceFingerprint(r *http.Request) string {
+	device := r.Header.Get("X-Device-ID")
+	if device == "" {
+		device = r.UserAgent()
+	}
+	if device == "" {
+		return ""
+	}
+	return HashToken(device)
+}
+
 func ErrorJSON(w http.ResponseWriter, err error, status ...int) error {
 	// set a default status code in case of  none returned
 	statusHeader := http.StatusBadRequest
@@ -140,6 +187,7 @@ func ErrorJSON(w http.ResponseWriter, err error, status ...int) error {
 	// build the return data
 	var payload = JSONResponse{
 		Error:   true,
+		Code:    customErrors.Code(err),
 		Message: err.Error(),
 	}
 
@@ -147,54 +195,58 @@ func ErrorJSON(w http.ResponseWriter, err error, status ...int) error {
 
 }
 
-// create a function to encrypt mail that  will be sent to the user's mail which the frontend will send back
-// and be decrypted
-var key = []byte("32-byte-long-secret-key-12345678") // 32 bytes exactly
-
-func EncryptNewOtp(otpMail string) (string, error) {
-	block, err := aes.NewCipher(key)
+// Encrypt seals plaintext with AES-256-GCM under a key derived from secret,
+// for tokens handed to the user and values stored at rest. The random nonce is
+// prepended and the result is URL-safe base64.
+func Encrypt(secret, plaintext string) (string, error) {
+	gcm, err := newGCM(secret)
 	if err != nil {
-		fmt.Println("error creating block at encrypt", err)
+		fmt.Println("error creating cipher at encrypt", err)
 		return "", customErrors.ErrInternalServer
 	}
 
-	ciphertext := make([]byte, aes.BlockSize+len(otpMail))
-	iv := ciphertext[:aes.BlockSize]
-	if _, err := io.ReadFull(cryptoRand.Reader, iv); err != nil {
-		fmt.Println("error reading at encry", err)
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(cryptoRand.Reader, nonce); err != nil {
+		fmt.Println("error reading nonce at encrypt", err)
 		return "", customErrors.ErrInternalServer
 	}
 
-	stream := cipher.NewCFBEncrypter(block, iv)
-	stream.XORKeyStream(ciphertext[aes.BlockSize:], []byte(otpMail))
-
-	return base64.URLEncoding.EncodeToString(ciphertext), nil
-
+	return base64.URLEncoding.EncodeToString(gcm.Seal(nonce, nonce, []byte(plaintext), nil)), nil
 }
 
-// Decrypt decrypts data using AES
-func Decrypt(encrypted string) (string, error) {
+// Decrypt opens a value sealed by Encrypt with the same secret. Anything that
+// was not, or was tampered with, is rejected with ErrInvalidOtp.
+func Decrypt(secret, encrypted string) (string, error) {
 	ciphertext, err := base64.URLEncoding.DecodeString(encrypted)
 	if err != nil {
-		fmt.Println("error here")
-		return "", err
+		return "", customErrors.ErrInvalidOtp
 	}
 
-	block, err := aes.NewCipher(key)
+	gcm, err := newGCM(secret)
 	if err != nil {
-		fmt.Println("err decrypt - 0", err)
+		fmt.Println("error creating cipher at decrypt", err)
+		return "", customErrors.ErrInternalServer
+	}
+	if len(ciphertext) < gcm.NonceSize() {
 		return "", customErrors.ErrInvalidOtp
 	}
 
-	if len(ciphertext) < aes.BlockSize {
+	nonce, ciphertext := ciphertext[:gcm.NonceSize()], ciphertext[gcm.NonceSize():]
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
 		return "", customErrors.ErrInvalidOtp
 	}
 
-	iv := ciphertext[:aes.BlockSize]
-	ciphertext = ciphertext[aes.BlockSize:]
-
-	stream := cipher.NewCFBDecrypter(block, iv)
-	stream.XORKeyStream(ciphertext, ciphertext)
+	return string(plaintext), nil
+}
 
-	return string(ciphertext), nil
+// newGCM builds the AES-256-GCM cipher for secret, hashed to a 32 byte key so
+// the configured secret can be any length.
+func newGCM(secret string) (cipher.AEAD, error) {
+	key := sha256.Sum256([]byte(secret))
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
 }
@@ -9,13 +9,118 @@ CREATE EXTENSION IF NOT EXISTS "uuid-ossp";
 
 -- Creating users table to store users information
 CREATE TABLE IF NOT EXISTS users (
-    id UUID PRIMARY KEY DEFAULT uuid_generate_v4()),    
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
     name VARCHAR(255) NOT NULL,
     email VARCHAR(255) UNIQUE NOT NULL,
     password VARCHAR(255) NOT NULL,
+    role VARCHAR(20) DEFAULT 'customer' NOT NULL,
+    kyc_level VARCHAR(20) DEFAULT 'unverified' NOT NULL,
+    email_verified_at TIMESTAMP NULL,
+    session_version INT DEFAULT 0 NOT NULL,
+    password_changed_at TIMESTAMP NULL,
+    mfa_secret TEXT NULL,
+    mfa_enabled_at TIMESTAMP NULL,
+    mfa_last_step BIGINT DEFAULT 0 NOT NULL,
+    transaction_pin VARCHAR(255) NULL,
+    pin_failed_attempts INT DEFAULT 0 NOT NULL,
+    pin_locked_until TIMESTAMP NULL,
+    frozen_at TIMESTAMP NULL,
+    frozen_reason TEXT NULL,
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
     updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
-    deleted_at TIMESTAMP NULL
+    deleted_at TIMESTAMP NULL,
+    anonymised_at TIMESTAMP NULL
+);
+
+-- Creating email_verifications table to store registration otps
+-- Only a bcrypt hash of the otp is kept; attempts caps brute forcing
+CREATE TABLE IF NOT EXISTS email_verifications (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL,
+    otp_hash VARCHAR(255) NOT NULL,
+    attempts INT DEFAULT 0 NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    consumed_at TIMESTAMP NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- Creating password_resets table to store single-use reset tokens
+-- Only a sha256 hash of the token is kept
+CREATE TABLE IF NOT EXISTS password_resets (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL,
+    token_hash VARCHAR(64) UNIQUE NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    used_at TIMESTAMP NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- Creating mfa_recovery_codes table for two-factor fallback codes
+-- Each code can be used once; only sha256 hashes are stored
+CREATE TABLE IF NOT EXISTS mfa_recovery_codes (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL,
+    code_hash VARCHAR(64) NOT NULL,
+    used_at TIMESTAMP NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- Creating kyc_documents table for identity documents submitted for review
+-- file_key points into the upload store; approving raises users.kyc_level to requested_level
+CREATE TABLE IF NOT EXISTS kyc_documents (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL,
+    requested_level VARCHAR(20) NOT NULL,
+    document_type VARCHAR(30) NOT NULL,
+    file_key VARCHAR(64) NOT NULL,
+    file_name VARCHAR(255) NOT NULL,
+    content_type VARCHAR(100) NOT NULL,
+    status VARCHAR(20) DEFAULT 'pending' NOT NULL,
+    reviewer_id UUID NULL,
+    review_note TEXT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    reviewed_at TIMESTAMP NULL,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (reviewer_id) REFERENCES users(id) ON DELETE SET NULL
+);
+
+-- Creating user_devices table to remember the devices a user has acted from
+-- fingerprint is a sha256 of the client supplied device id or user agent
+CREATE TABLE IF NOT EXISTS user_devices (
+    user_id UUID NOT NULL,
+    fingerprint VARCHAR(64) NOT NULL,
+    first_seen_at TIMESTAMP NOT NULL,
+    last_seen_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (user_id, fingerprint),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- Creating login_throttles table to track failed logins per account and per ip
+-- key is either "account:<email>" or "ip:<address>"
+CREATE TABLE IF NOT EXISTS login_throttles (
+    key VARCHAR(300) PRIMARY KEY,
+    failures INT DEFAULT 0 NOT NULL,
+    last_failure_at TIMESTAMP NOT NULL,
+    locked_until TIMESTAMP NULL
+);
+
+-- Creating api_keys table for server-to-server clients
+-- Keys are looked up by prefix and verified against a sha256 hash
+CREATE TABLE IF NOT EXISTS api_keys (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    prefix VARCHAR(16) UNIQUE NOT NULL,
+    key_hash VARCHAR(64) NOT NULL,
+    scopes JSONB NOT NULL,
+    allowed_ips JSONB NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    last_used_at TIMESTAMP NULL,
+    revoked_at TIMESTAMP NULL,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
 );
 
 -- Creating wallets table to store user wallet information
@@ -25,12 +130,16 @@ CREATE TABLE wallets (
     email VARCHAR(255) UNIQUE NOT NULL,
     user_id UUID NOT NULL,
     balances JSONB NOT NULL,
-    created_at TIMESTAMP NOT NULL
+    status VARCHAR(20) DEFAULT 'active' NOT NULL,
+    spending_controls JSONB DEFAULT '{}' NOT NULL,
+    approval_threshold_usd NUMERIC(19,4),
+    created_at TIMESTAMP NOT NULL,
     FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
 );
 
 -- Creating transactions table to store wallet operation history
 -- Foreign key to wallets with ON DELETE CASCADE to remove transactions when wallet is deleted
+-- a reversal is its own row pointing at the original through reversal_of
 CREATE TABLE transactions (
     id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
     wallet_id UUID NOT NULL,
@@ -40,25 +149,373 @@ CREATE TABLE transactions (
     amount NUMERIC(19,4) NOT NULL,
     converted_amount NUMERIC(19,4),
     rate NUMERIC(19,4),
+    reference VARCHAR(255),
+    receiver_wallet_id UUID,
+    status VARCHAR(20) DEFAULT 'completed' NOT NULL,
+    reversed_amount NUMERIC(19,4) DEFAULT 0 NOT NULL,
+    reversal_of UUID,
     timestamp TIMESTAMP NOT NULL,
+    FOREIGN KEY (wallet_id) REFERENCES wallets(id) ON DELETE CASCADE,
+    FOREIGN KEY (receiver_wallet_id) REFERENCES wallets(id) ON DELETE SET NULL,
+    FOREIGN KEY (reversal_of) REFERENCES transactions(id) ON DELETE SET NULL
+);
+
+-- Creating wallet_status_events table to record every wallet state change
+-- reason_code is one of the codes accepted by the api; actor_id is null for system changes
+CREATE TABLE wallet_status_events (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    wallet_id UUID NOT NULL,
+    from_status VARCHAR(20) NOT NULL,
+    to_status VARCHAR(20) NOT NULL,
+    reason_code VARCHAR(50) NOT NULL,
+    note TEXT,
+    actor_id UUID,
+    created_at TIMESTAMP NOT NULL,
+    FOREIGN KEY (wallet_id) REFERENCES wallets(id) ON DELETE CASCADE,
+    FOREIGN KEY (actor_id) REFERENCES users(id) ON DELETE SET NULL
+);
+
+-- Creating balance_holds table for funds reserved out of a wallet balance
+-- active holds reduce the available balance; wallets.balances only changes when a hold is captured
+CREATE TABLE balance_holds (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    wallet_id UUID NOT NULL,
+    kind VARCHAR(20) NOT NULL,
+    currency VARCHAR(10) NOT NULL,
+    amount NUMERIC(19,4) NOT NULL,
+    captured NUMERIC(19,4) DEFAULT 0 NOT NULL,
+    status VARCHAR(20) NOT NULL,
+    reference VARCHAR(255),
+    expires_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    resolved_at TIMESTAMP,
     FOREIGN KEY (wallet_id) REFERENCES wallets(id) ON DELETE CASCADE
 );
 
+-- Creating wallet_approvers table for wallets whose large transfers need a second person's sign-off
+CREATE TABLE wallet_approvers (
+    wallet_id UUID NOT NULL,
+    user_id UUID NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (wallet_id, user_id),
+    FOREIGN KEY (wallet_id) REFERENCES wallets(id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- Creating transfer_approvals table for transfers waiting on an approver
+-- hold_id reserves the amount on the sender's wallet while the request is pending
+CREATE TABLE transfer_approvals (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    wallet_id UUID NOT NULL,
+    receiver_wallet_id UUID NOT NULL,
+    requested_by UUID NOT NULL,
+    hold_id UUID NOT NULL,
+    currency VARCHAR(10) NOT NULL,
+    amount NUMERIC(19,4) NOT NULL,
+    amount_usd NUMERIC(19,4) NOT NULL,
+    status VARCHAR(20) NOT NULL,
+    approver_id UUID,
+    note TEXT,
+    created_at TIMESTAMP NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    resolved_at TIMESTAMP,
+    FOREIGN KEY (wallet_id) REFERENCES wallets(id) ON DELETE CASCADE,
+    FOREIGN KEY (receiver_wallet_id) REFERENCES wallets(id) ON DELETE CASCADE,
+    FOREIGN KEY (requested_by) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (hold_id) REFERENCES balance_holds(id) ON DELETE CASCADE,
+    FOREIGN KEY (approver_id) REFERENCES users(id) ON DELETE SET NULL
+);
+
+-- Creating compliance_cases table for operations held or blocked by the risk rules or sanctions screening
+-- operation keeps the original request so an approved case can be executed as-is
+-- wallet_id is null for accounts held at registration
+CREATE TABLE compliance_cases (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL,
+    wallet_id UUID,
+    source VARCHAR(20) NOT NULL,
+    action VARCHAR(20) NOT NULL,
+    status VARCHAR(20) NOT NULL,
+    operation JSONB NOT NULL,
+    hits JSONB NOT NULL,
+    reviewer_id UUID,
+    review_note TEXT,
+    created_at TIMESTAMP NOT NULL,
+    resolved_at TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (wallet_id) REFERENCES wallets(id) ON DELETE CASCADE,
+    FOREIGN KEY (reviewer_id) REFERENCES users(id) ON DELETE SET NULL
+);
+
+-- Creating sanctions_clearances table for sanctions matches staff ruled out
+-- a cleared entry is no longer reported for that user
+CREATE TABLE sanctions_clearances (
+    user_id UUID NOT NULL,
+    entry_id VARCHAR(100) NOT NULL,
+    case_id UUID,
+    cleared_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (user_id, entry_id),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (case_id) REFERENCES compliance_cases(id) ON DELETE SET NULL
+);
+
+-- Creating disputes table for transfers the sender reports as wrong or fraudulent
+-- hold_id reserves what could be held of the disputed amount in the receiver's wallet
+CREATE TABLE disputes (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    transaction_id UUID NOT NULL,
+    sender_wallet_id UUID NOT NULL,
+    receiver_wallet_id UUID NOT NULL,
+    opened_by UUID NOT NULL,
+    reason VARCHAR(30) NOT NULL,
+    description TEXT NOT NULL,
+    currency VARCHAR(10) NOT NULL,
+    amount NUMERIC(19,4) NOT NULL,
+    held_amount NUMERIC(19,4) DEFAULT 0 NOT NULL,
+    hold_id UUID,
+    status VARCHAR(30) NOT NULL,
+    resolved_by UUID,
+    resolution_note TEXT,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    resolved_at TIMESTAMP,
+    FOREIGN KEY (transaction_id) REFERENCES transactions(id) ON DELETE CASCADE,
+    FOREIGN KEY (sender_wallet_id) REFERENCES wallets(id) ON DELETE CASCADE,
+    FOREIGN KEY (receiver_wallet_id) REFERENCES wallets(id) ON DELETE CASCADE,
+    FOREIGN KEY (opened_by) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (hold_id) REFERENCES balance_holds(id) ON DELETE SET NULL,
+    FOREIGN KEY (resolved_by) REFERENCES users(id) ON DELETE SET NULL
+);
+
+-- Creating dispute_evidence table for files either party attaches to a dispute
+-- file_key points into the local upload store
+CREATE TABLE dispute_evidence (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    dispute_id UUID NOT NULL,
+    uploaded_by UUID NOT NULL,
+    file_key VARCHAR(100) NOT NULL,
+    file_name VARCHAR(255) NOT NULL,
+    content_type VARCHAR(100) NOT NULL,
+    size BIGINT NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    FOREIGN KEY (dispute_id) REFERENCES disputes(id) ON DELETE CASCADE,
+    FOREIGN KEY (uploaded_by) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- Creating notifications table for the in-app notification inbox
+CREATE TABLE notifications (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL,
+    subject VARCHAR(255) NOT NULL,
+    body TEXT NOT NULL,
+    read_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- Creating rate_alerts table for users watching an FX pair cross a threshold
+-- a repeating alert is disarmed when it fires and armed again once the rate is
+-- back on the other side of the threshold
+CREATE TABLE rate_alerts (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL,
+    base_currency VARCHAR(10) NOT NULL,
+    quote_currency VARCHAR(10) NOT NULL,
+    direction VARCHAR(10) NOT NULL,
+    threshold NUMERIC(19,8) NOT NULL,
+    repeating BOOLEAN NOT NULL DEFAULT FALSE,
+    armed BOOLEAN NOT NULL DEFAULT TRUE,
+    status VARCHAR(20) NOT NULL,
+    trigger_count INT NOT NULL DEFAULT 0,
+    last_rate NUMERIC(19,8),
+    last_triggered_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- Creating scheduled_transfers table for one-off and recurring transfers the scheduler runs
+-- next_run_at is null once the schedule is cancelled or completed; cron_expr is only set for cron schedules
+-- device is the fingerprint of the request that scheduled it, so the risk rules see runs as coming from that device
+CREATE TABLE scheduled_transfers (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    wallet_id UUID NOT NULL,
+    receiver_wallet_id UUID NOT NULL,
+    created_by UUID NOT NULL,
+    currency VARCHAR(10) NOT NULL,
+    amount NUMERIC(19,4) NOT NULL,
+    frequency VARCHAR(20) NOT NULL,
+    cron_expr VARCHAR(100),
+    note VARCHAR(255),
+    status VARCHAR(20) NOT NULL,
+    start_at TIMESTAMP NOT NULL,
+    end_at TIMESTAMP,
+    next_run_at TIMESTAMP,
+    last_run_at TIMESTAMP,
+    retry_count INTEGER DEFAULT 0 NOT NULL,
+    device VARCHAR(64) DEFAULT '' NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    FOREIGN KEY (wallet_id) REFERENCES wallets(id) ON DELETE CASCADE,
+    FOREIGN KEY (receiver_wallet_id) REFERENCES wallets(id) ON DELETE CASCADE,
+    FOREIGN KEY (created_by) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- Creating scheduled_transfer_runs table for the execution history of scheduled transfers
+-- attempt counts retries of the same occurrence, starting at 1
+CREATE TABLE scheduled_transfer_runs (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    schedule_id UUID NOT NULL,
+    scheduled_for TIMESTAMP NOT NULL,
+    attempt INTEGER NOT NULL,
+    status VARCHAR(20) NOT NULL,
+    converted_amount NUMERIC(19,4),
+    rate NUMERIC(19,4),
+    error TEXT,
+    created_at TIMESTAMP NOT NULL,
+    FOREIGN KEY (schedule_id) REFERENCES scheduled_transfers(id) ON DELETE CASCADE
+);
+
+-- Creating swap_plans table for recurring swaps that average into a currency over time
+-- max_rate is the most from_currency the owner pays for one unit of to_currency
+CREATE TABLE swap_plans (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    wallet_id UUID NOT NULL,
+    created_by UUID NOT NULL,
+    from_currency VARCHAR(10) NOT NULL,
+    to_currency VARCHAR(10) NOT NULL,
+    amount NUMERIC(19,4) NOT NULL,
+    max_rate NUMERIC(19,8),
+    frequency VARCHAR(20) NOT NULL,
+    cron_expr VARCHAR(100),
+    status VARCHAR(20) NOT NULL,
+    start_at TIMESTAMP NOT NULL,
+    end_at TIMESTAMP,
+    next_run_at TIMESTAMP,
+    last_run_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    FOREIGN KEY (wallet_id) REFERENCES wallets(id) ON DELETE CASCADE,
+    FOREIGN KEY (created_by) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- Creating swap_plan_runs table for the execution report of every swap plan run
+-- rate keeps eight decimals so small rates such as cNGN to USDx average correctly
+CREATE TABLE swap_plan_runs (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    plan_id UUID NOT NULL,
+    scheduled_for TIMESTAMP NOT NULL,
+    status VARCHAR(20) NOT NULL,
+    amount NUMERIC(19,4) NOT NULL,
+    converted_amount NUMERIC(19,4),
+    rate NUMERIC(19,8),
+    error TEXT,
+    created_at TIMESTAMP NOT NULL,
+    FOREIGN KEY (plan_id) REFERENCES swap_plans(id) ON DELETE CASCADE
+);
+
+-- Creating swap_orders table for limit and stop orders waiting on the FX rate
+-- the amount is reserved by hold_id until the order fills, is cancelled or expires
+CREATE TABLE swap_orders (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    wallet_id UUID NOT NULL,
+    created_by UUID NOT NULL,
+    type VARCHAR(10) NOT NULL,
+    from_currency VARCHAR(10) NOT NULL,
+    to_currency VARCHAR(10) NOT NULL,
+    amount NUMERIC(19,4) NOT NULL,
+    trigger_rate NUMERIC(19,8) NOT NULL,
+    time_in_force VARCHAR(10) NOT NULL,
+    expires_at TIMESTAMP,
+    status VARCHAR(20) NOT NULL,
+    hold_id UUID NOT NULL,
+    rate NUMERIC(19,8),
+    converted_amount NUMERIC(19,4),
+    transaction_id UUID,
+    error TEXT,
+    created_at TIMESTAMP NOT NULL,
+    resolved_at TIMESTAMP,
+    FOREIGN KEY (wallet_id) REFERENCES wallets(id) ON DELETE CASCADE,
+    FOREIGN KEY (created_by) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (hold_id) REFERENCES balance_holds(id) ON DELETE CASCADE,
+    FOREIGN KEY (transaction_id) REFERENCES transactions(id) ON DELETE SET NULL
+);
+
+-- Creating p2p_orders table for bids and asks on the peer-to-peer order book
+-- open orders are loaded back into the in-memory books on startup
+CREATE TABLE p2p_orders (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    wallet_id UUID NOT NULL,
+    created_by UUID NOT NULL,
+    market VARCHAR(21) NOT NULL,
+    base_currency VARCHAR(10) NOT NULL,
+    quote_currency VARCHAR(10) NOT NULL,
+    side VARCHAR(3) NOT NULL,
+    price NUMERIC(19,8) NOT NULL,
+    amount NUMERIC(19,4) NOT NULL,
+    filled NUMERIC(19,4) DEFAULT 0 NOT NULL,
+    status VARCHAR(20) NOT NULL,
+    hold_id UUID NOT NULL,
+    error TEXT,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    resolved_at TIMESTAMP,
+    FOREIGN KEY (wallet_id) REFERENCES wallets(id) ON DELETE CASCADE,
+    FOREIGN KEY (created_by) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (hold_id) REFERENCES balance_holds(id) ON DELETE CASCADE
+);
+
+-- Creating p2p_trades table for matches between a bid and an ask
+-- each trade is also logged as a 'trade' transaction on both wallets
+CREATE TABLE p2p_trades (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    market VARCHAR(21) NOT NULL,
+    price NUMERIC(19,8) NOT NULL,
+    amount NUMERIC(19,4) NOT NULL,
+    quote_amount NUMERIC(19,4) NOT NULL,
+    bid_order_id UUID NOT NULL,
+    ask_order_id UUID NOT NULL,
+    buyer_wallet_id UUID NOT NULL,
+    seller_wallet_id UUID NOT NULL,
+    taker_side VARCHAR(3) NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    FOREIGN KEY (bid_order_id) REFERENCES p2p_orders(id) ON DELETE CASCADE,
+    FOREIGN KEY (ask_order_id) REFERENCES p2p_orders(id) ON DELETE CASCADE,
+    FOREIGN KEY (buyer_wallet_id) REFERENCES wallets(id) ON DELETE CASCADE,
+    FOREIGN KEY (seller_wallet_id) REFERENCES wallets(id) ON DELETE CASCADE
+);
+
 -- Creating fx_rates table to store historical FX rates
 -- No foreign keys, independent of other tables
 CREATE TABLE fx_rates (
     id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
     from_currency VARCHAR(10) NOT NULL,
     to_currency VARCHAR(10) NOT NULL,
-    rate NUMERIC(19,4) NOT NULL,
+    rate NUMERIC(19,8) NOT NULL,
     timestamp TIMESTAMP NOT NULL
 );
 
+-- Creating fx_rate_candles table for the minute, hour and day rollups of fx_rates
+-- raw ticks are pruned after a retention window; candles are kept
+CREATE TABLE fx_rate_candles (
+    from_currency VARCHAR(10) NOT NULL,
+    to_currency VARCHAR(10) NOT NULL,
+    resolution VARCHAR(5) NOT NULL,
+    bucket TIMESTAMP NOT NULL,
+    open NUMERIC(19,8) NOT NULL,
+    high NUMERIC(19,8) NOT NULL,
+    low NUMERIC(19,8) NOT NULL,
+    close NUMERIC(19,8) NOT NULL,
+    ticks INT NOT NULL,
+    PRIMARY KEY (from_currency, to_currency, resolution, bucket)
+);
+
 -- Creating audit_logs table for operation auditing
--- Foreign key to wallets with ON DELETE SET NULL to preserve audit records
+-- Foreign keys to wallets and users with ON DELETE SET NULL to preserve audit records
 CREATE TABLE audit_logs (
     id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
     wallet_id UUID,
+    user_id UUID,
     operation VARCHAR(50) NOT NULL,
     client_ip VARCHAR(45) NOT NULL,
     user_agent TEXT,
@@ -66,11 +523,53 @@ CREATE TABLE audit_logs (
     request_path TEXT,
     request_body TEXT,
     timestamp TIMESTAMP NOT NULL,
-    FOREIGN KEY (wallet_id) REFERENCES wallets(id) ON DELETE SET NULL
+    FOREIGN KEY (wallet_id) REFERENCES wallets(id) ON DELETE SET NULL,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE SET NULL
 );
 
 -- Creating indexes for performance
+CREATE INDEX idx_users_deleted_at ON users(deleted_at) WHERE anonymised_at IS NULL;
+CREATE INDEX idx_email_verifications_user_id ON email_verifications(user_id);
+CREATE INDEX idx_password_resets_user_id ON password_resets(user_id);
+CREATE INDEX idx_mfa_recovery_codes_user_id ON mfa_recovery_codes(user_id);
+CREATE INDEX idx_api_keys_user_id ON api_keys(user_id);
+CREATE INDEX idx_kyc_documents_user_id ON kyc_documents(user_id);
+CREATE INDEX idx_kyc_documents_status ON kyc_documents(status, created_at);
 CREATE INDEX idx_transactions_wallet_id ON transactions(wallet_id);
+CREATE INDEX idx_transactions_receiver_wallet_id ON transactions(receiver_wallet_id);
+CREATE INDEX idx_transactions_reversal_of ON transactions(reversal_of);
+CREATE INDEX idx_wallet_status_events_wallet_id ON wallet_status_events(wallet_id);
+CREATE UNIQUE INDEX idx_disputes_open_transaction ON disputes(transaction_id) WHERE status IN ('opened', 'under_review');
+CREATE INDEX idx_disputes_sender_wallet_id ON disputes(sender_wallet_id);
+CREATE INDEX idx_disputes_receiver_wallet_id ON disputes(receiver_wallet_id);
+CREATE INDEX idx_disputes_status ON disputes(status, created_at);
+CREATE INDEX idx_dispute_evidence_dispute_id ON dispute_evidence(dispute_id);
+CREATE INDEX idx_notifications_user_id ON notifications(user_id, created_at);
+CREATE INDEX idx_rate_alerts_user_id ON rate_alerts(user_id, created_at);
+CREATE INDEX idx_rate_alerts_active ON rate_alerts(created_at) WHERE status = 'active';
+CREATE INDEX idx_scheduled_transfers_wallet_id ON scheduled_transfers(wallet_id);
+CREATE INDEX idx_scheduled_transfers_next_run_at ON scheduled_transfers(next_run_at) WHERE status = 'active';
+CREATE INDEX idx_scheduled_transfer_runs_schedule_id ON scheduled_transfer_runs(schedule_id, created_at);
+CREATE INDEX idx_swap_plans_wallet_id ON swap_plans(wallet_id);
+CREATE INDEX idx_swap_plans_next_run_at ON swap_plans(next_run_at) WHERE status = 'active';
+CREATE INDEX idx_swap_plan_runs_plan_id ON swap_plan_runs(plan_id, created_at);
+CREATE INDEX idx_swap_orders_wallet_id ON swap_orders(wallet_id, created_at);
+CREATE INDEX idx_swap_orders_open ON swap_orders(from_currency, to_currency) WHERE status = 'open';
+CREATE INDEX idx_swap_orders_expires_at ON swap_orders(expires_at) WHERE status = 'open';
+CREATE INDEX idx_p2p_orders_wallet_id ON p2p_orders(wallet_id, created_at);
+CREATE INDEX idx_p2p_orders_open ON p2p_orders(created_at) WHERE status = 'open';
+CREATE INDEX idx_p2p_trades_market ON p2p_trades(market, created_at);
+CREATE INDEX idx_p2p_trades_bid_order_id ON p2p_trades(bid_order_id);
+CREATE INDEX idx_p2p_trades_ask_order_id ON p2p_trades(ask_order_id);
+CREATE INDEX idx_balance_holds_wallet_id ON balance_holds(wallet_id, currency) WHERE status = 'active';
+CREATE INDEX idx_balance_holds_expires_at ON balance_holds(expires_at) WHERE status = 'active';
+CREATE INDEX idx_wallet_approvers_user_id ON wallet_approvers(user_id);
+CREATE INDEX idx_transfer_approvals_wallet_id ON transfer_approvals(wallet_id);
+CREATE INDEX idx_transfer_approvals_status ON transfer_approvals(status, expires_at);
+CREATE INDEX idx_compliance_cases_status ON compliance_cases(status, created_at);
 CREATE INDEX idx_fx_rates_timestamp ON fx_rates(timestamp);
+CREATE INDEX idx_fx_rates_pair ON fx_rates(from_currency, to_currency, timestamp);
+CREATE INDEX idx_fx_rate_candles_bucket ON fx_rate_candles(resolution, bucket);
 CREATE INDEX idx_audit_logs_wallet_id ON audit_logs(wallet_id);
+CREATE INDEX idx_audit_logs_user_id ON audit_logs(user_id);
 CREATE INDEX idx_audit_logs_timestamp ON audit_logs(timestamp);
\ No newline at end of file